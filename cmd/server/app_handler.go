@@ -1,7 +1,9 @@
 package main
 
 import (
+	"crypto/rand"
 	"embed"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -9,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/crueber/loom/internal/api"
 	"github.com/crueber/loom/internal/auth"
 	"github.com/crueber/loom/internal/cache"
 	"github.com/crueber/loom/internal/db"
@@ -23,6 +26,12 @@ type AppHandler struct {
 	cache          *cache.Cache
 	buildVersion   string
 	isStandalone   bool
+
+	// basePath prefixes asset and app URLs when served under a subpath ("" at the root)
+	basePath string
+
+	// cspNonce tags the page's inline scripts with a per-response nonce allowed by its CSP
+	cspNonce bool
 }
 
 // NewAppHandler creates a new app handler
@@ -37,6 +46,17 @@ func NewAppHandler(staticFiles embed.FS, database *db.DB, sessionManager *auth.S
 	}
 }
 
+// SetBasePath sets the subpath the app is served under, e.g. "/loom"
+func (h *AppHandler) SetBasePath(basePath string) {
+	h.basePath = basePath
+}
+
+// SetCSPNonce makes every page response carry a fresh nonce on its inline scripts and
+// allow only that nonce (not 'unsafe-inline') in the Content-Security-Policy script-src
+func (h *AppHandler) SetCSPNonce(enabled bool) {
+	h.cspNonce = enabled
+}
+
 // InvalidateCache invalidates the cache for a specific user and board
 func (h *AppHandler) InvalidateCache(userID, boardID int) {
 	key := fmt.Sprintf("%d:%d", userID, boardID)
@@ -72,9 +92,7 @@ func (h *AppHandler) ServeApp(w http.ResponseWriter, r *http.Request) {
 		if boardID > 0 {
 			key := fmt.Sprintf("%d:%d", userID, boardID)
 			if cachedHTML, found := h.cache.Get(key); found {
-				w.Header().Set("Content-Type", "text/html; charset=utf-8")
-				w.Header().Set("X-Cache", "HIT")
-				w.Write([]byte(cachedHTML))
+				h.writePage(w, h.injectTheme(cachedHTML, r), "HIT")
 				return
 			}
 		}
@@ -90,9 +108,6 @@ func (h *AppHandler) ServeApp(w http.ResponseWriter, r *http.Request) {
 	// Inject version query strings for cache busting
 	html := h.injectVersions(string(data))
 
-	// Inject theme preference
-	html = h.injectTheme(html, r)
-
 	// Inject i18n data
 	html = h.injectI18nData(html, r)
 
@@ -108,12 +123,73 @@ func (h *AppHandler) ServeApp(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Inject theme preference; done after caching since "auto" depends on the request
+	html = h.injectTheme(html, r)
+
+	h.writePage(w, html, "MISS")
+}
+
+// writePage sends the app HTML, adding the CSP nonce last since it must differ per
+// response and cached pages are shared
+func (h *AppHandler) writePage(w http.ResponseWriter, html, cacheStatus string) {
+	if h.cspNonce {
+		nonce, err := generateCSPNonce()
+		if err != nil {
+			http.Error(w, "Failed to load page", http.StatusInternalServerError)
+			return
+		}
+		html = strings.ReplaceAll(html, "<script>", fmt.Sprintf(`<script nonce="%s">`, nonce))
+		w.Header().Set("Content-Security-Policy", withScriptNonce(w.Header().Get("Content-Security-Policy"), nonce))
+	}
+
+	setThemeHintHeaders(w)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Header().Set("X-Cache", "MISS")
+	w.Header().Set("X-Cache", cacheStatus)
 	w.Write([]byte(html))
 }
 
-// injectVersions adds version query parameters to static assets for cache busting
+// generateCSPNonce returns a random base64 nonce for a script-src 'nonce-...' source
+func generateCSPNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// withScriptNonce rewrites csp so script-src allows the nonce instead of 'unsafe-inline',
+// adding script-src 'self' when the policy has none
+func withScriptNonce(csp, nonce string) string {
+	source := fmt.Sprintf("'nonce-%s'", nonce)
+
+	var directives []string
+	found := false
+	for _, directive := range strings.Split(csp, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+		if strings.EqualFold(fields[0], "script-src") {
+			kept := []string{fields[0]}
+			for _, value := range fields[1:] {
+				if value != "'unsafe-inline'" {
+					kept = append(kept, value)
+				}
+			}
+			fields = append(kept, source)
+			found = true
+		}
+		directives = append(directives, strings.Join(fields, " "))
+	}
+	if !found {
+		directives = append(directives, "script-src 'self' "+source)
+	}
+
+	return strings.Join(directives, "; ")
+}
+
+// injectVersions adds version query parameters to static assets for cache busting,
+// prefixes asset URLs with the base path, and tells the SPA the base path
 func (h *AppHandler) injectVersions(html string) string {
 	html = strings.ReplaceAll(html,
 		`src="/static/dist/app.bundle.js"`,
@@ -121,6 +197,12 @@ func (h *AppHandler) injectVersions(html string) string {
 	html = strings.ReplaceAll(html,
 		`href="/static/styles.css"`,
 		fmt.Sprintf(`href="/static/styles.css?v=%s"`, h.buildVersion))
+
+	if h.basePath != "" {
+		html = strings.ReplaceAll(html, `="/static/`, `="`+h.basePath+`/static/`)
+		basePath, _ := json.Marshal(h.basePath)
+		html = strings.Replace(html, "<!-- BasePath -->", fmt.Sprintf(`<script>window.__BASE_PATH__ = %s;</script>`, basePath), 1)
+	}
 	return html
 }
 
@@ -130,18 +212,45 @@ func (h *AppHandler) injectBootstrapData(html, bootstrapData string) string {
 	return strings.Replace(html, "<!-- Bootstrap -->", bootstrapScript, 1)
 }
 
-// injectTheme adds the data-theme attribute to the HTML tag
+// prefersColorSchemeHeader is the client hint carrying the browser's light/dark preference
+const prefersColorSchemeHeader = "Sec-CH-Prefers-Color-Scheme"
+
+// setThemeHintHeaders asks the browser to send its color scheme on later requests
+// and marks the page as varying on it
+func setThemeHintHeaders(w http.ResponseWriter) {
+	w.Header().Set("Accept-CH", prefersColorSchemeHeader)
+	w.Header().Add("Vary", prefersColorSchemeHeader)
+}
+
+// injectTheme adds the data-theme attribute to the HTML tag. A saved light/dark
+// theme wins; otherwise ("auto" or unset) the color scheme client hint is used.
 func (h *AppHandler) injectTheme(html string, r *http.Request) string {
-	theme := "dark" // Default
+	theme := ""
 
 	if userID, ok := h.sessionManager.GetUserID(r); ok {
 		if user, err := h.database.GetUserByID(userID); err == nil && user != nil && user.Theme != "" && user.Theme != "auto" {
 			theme = user.Theme
 		}
 	}
+	if theme == "" {
+		theme = themeFromClientHint(r)
+	}
 	return strings.Replace(html, `data-theme="dark"`, fmt.Sprintf(`data-theme="%s"`, theme), 1)
 }
 
+// themeFromClientHint reads Sec-CH-Prefers-Color-Scheme (a quoted string such as
+// "light"), falling back to the default theme when it's missing or unknown
+func themeFromClientHint(r *http.Request) string {
+	switch strings.Trim(strings.TrimSpace(r.Header.Get(prefersColorSchemeHeader)), `"`) {
+	case "light":
+		return "light"
+	case "dark":
+		return "dark"
+	default:
+		return api.DefaultTheme
+	}
+}
+
 // injectI18nData adds the i18n data script to the HTML
 func (h *AppHandler) injectI18nData(html string, r *http.Request) string {
 	locale := h.detectLocale(r)
@@ -178,7 +287,7 @@ func (h *AppHandler) detectLocale(r *http.Request) string {
 		}
 	}
 
-	return "en"
+	return api.DefaultLocale
 }
 
 // getBootstrapData fetches and serializes bootstrap data for authenticated users
@@ -229,8 +338,13 @@ func (h *AppHandler) parseBoardID(path string) int {
 	return 0
 }
 
-// getDefaultBoardID retrieves the user's default board ID
+// getDefaultBoardID retrieves the board to open when the URL names none: the user's
+// home board if set and still theirs, otherwise their default board
 func (h *AppHandler) getDefaultBoardID(userID int) int {
+	if homeBoardID, err := h.database.GetHomeBoardID(userID); err == nil && homeBoardID > 0 {
+		return homeBoardID
+	}
+
 	boards, err := h.database.GetBoards(userID)
 	if err != nil || len(boards) == 0 {
 		return 0
@@ -269,11 +383,17 @@ func (h *AppHandler) fetchBoardData(userID, boardID int) string {
 	if err != nil {
 		return ""
 	}
+	if lists == nil {
+		lists = []*models.List{}
+	}
 
-	// Get items for this board only (efficient single query with JOIN)
-	items, err := h.database.GetItemsByBoard(userID, boardID)
-	if err != nil {
-		return ""
+	// Get items for this board only (efficient single query with JOIN); an empty board has none
+	var items []*models.Item
+	if len(lists) > 0 {
+		items, err = h.database.GetItemsByBoard(userID, boardID)
+		if err != nil {
+			return ""
+		}
 	}
 
 	// Build user object without sensitive data
@@ -1,13 +1,28 @@
 package main
 
 import (
+	"crypto/tls"
 	"encoding/hex"
 	"fmt"
 	"log"
+	"log/slog"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crueber/loom/internal/configfile"
+	"github.com/crueber/loom/internal/db"
+	"github.com/crueber/loom/internal/oauth"
 )
 
+// defaultContentSecurityPolicy allows the SPA's inline bootstrap scripts and styles
+// and embedded (data:) or remote favicons, while blocking framing by other origins.
+// Plain http: images stay allowed so custom icons on LAN hosts keep rendering.
+const defaultContentSecurityPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data: http: https:; frame-ancestors 'self'"
+
 // Config holds all application configuration
 type Config struct {
 	// Server settings
@@ -15,9 +30,40 @@ type Config struct {
 	BuildVersion  string
 	SecureCookie  bool
 	SessionMaxAge int
+	LogLevel      slog.Level
+
+	// Expire sessions after this long without a request (0 = only SESSION_MAX_AGE applies)
+	SessionIdleTimeout time.Duration
+
+	// Native TLS (both files set = serve HTTPS)
+	TLSCertFile   string
+	TLSKeyFile    string
+	TLSMinVersion uint16
+
+	// Content-Security-Policy header sent with every response
+	ContentSecurityPolicy string
+
+	// Tag the app page's inline scripts with a per-response nonce and allow only that
+	// nonce in its script-src
+	CSPNonce bool
+
+	// HTTP server timeouts
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// Database (DatabasePath is absolute, resolved from DATABASE_PATH or DATA_DIR + DB_FILENAME)
+	DataDir        string
+	DatabasePath   string
+	DBQueryTimeout time.Duration
+
+	// One-time v4 favicon migration tuning
+	MigrationFaviconConcurrency int
+	MigrationFaviconTimeout     time.Duration
 
-	// Database
-	DatabasePath string
+	// Drop the bookmarks table replaced by items instead of keeping bookmarks_backup_v3
+	MigrationDropBookmarksBackup bool
 
 	// Session keys
 	AuthKey       []byte
@@ -31,15 +77,142 @@ type Config struct {
 
 	// Standalone mode
 	IsStandalone bool
+
+	// Whether POST /api/register accepts new accounts
+	RegistrationEnabled bool
+
+	// Whether the OAuth callback creates accounts for unknown emails
+	OAuth2AutoProvision bool
+
+	// ID token claim listing group membership, and the group whose members are admins
+	OAuth2GroupsClaim string
+	OAuth2AdminGroup  string
+
+	// Usernames or emails with access to /api/admin
+	AdminUsers []string
+
+	// Data rules
+	UniqueListTitles bool
+	CollapseNewLists bool
+	AutoTitle        bool
+
+	// Outbound proxy for favicon requests (empty = standard proxy env vars)
+	FaviconProxyURL string
+
+	// FaviconDisabled stops all outbound favicon requests
+	FaviconDisabled bool
+
+	// Favicon host policy (empty allowlist = all hosts not blocked)
+	FaviconAllowedHosts []string
+	FaviconBlockedHosts []string
+
+	// Attempts per favicon fetch (transient failures only)
+	FaviconRetryAttempts int
+
+	// Outbound favicon requests allowed in flight at once
+	FaviconMaxConcurrent int
+
+	// Consecutive connection failures that pause favicon fetching for FaviconBreakerCooldown (0 disables)
+	FaviconBreakerThreshold int
+	FaviconBreakerCooldown  time.Duration
+
+	// Re-encode raster favicons as square PNGs of this size (0 = keep as fetched)
+	FaviconPNGSize int
+
+	// Where fetched favicons are kept: "inline" data URIs in the database, or "file"
+	// for content-addressed files under DATA_DIR/favicons served from /api/favicons
+	FaviconStorage string
+
+	// Favicons older than this are re-fetched on request (0 disables), or on
+	// every board load and item edit when FaviconAutoRefresh is set
+	FaviconRefreshAge  time.Duration
+	FaviconAutoRefresh bool
+
+	// Capture each new bookmark's og:image of at most PreviewImageMaxBytes as a file
+	PreviewImages        bool
+	PreviewImageMaxBytes int
+
+	// Subpath the app is served under behind a reverse proxy, e.g. "/loom" ("" = root)
+	BasePath string
+
+	// Seed new users' default board from NewUserTemplate (empty = built-in welcome content)
+	SeedNewUsers    bool
+	NewUserTemplate string
+
+	// API rate limit per user/IP (0 disables)
+	APIRateLimit float64
+	APIRateBurst int
+
+	// Daily per-user quotas, reset at midnight; admins are exempt (0 disables)
+	DailyWriteQuota int
+	DailyFetchQuota int // page titles, favicons and preview images fetched for a user
+
+	// Per-user caps on stored bookmarks and notes; admins are exempt (0 = unlimited)
+	MaxBookmarksPerUser int
+	MaxNotesPerUser     int
+
+	// Import limits (0 disables a limit)
+	MaxImportLists int
+	MaxImportItems int
+	MaxImportBytes int64
+
+	// Imported favicon data URIs longer than this many bytes are dropped (0 disables)
+	MaxImportFaviconLength int
+
+	// Bytes of /api/import request bodies logged at debug level (0 disables)
+	DebugLogBodyBytes int
 }
 
-// LoadConfig loads and validates configuration from environment variables
+// LoadConfig loads and validates configuration from environment variables,
+// falling back to values from the optional CONFIG_FILE for any that are unset
 func LoadConfig(buildVersion string) (*Config, error) {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := applyConfigFile(path); err != nil {
+			return nil, fmt.Errorf("invalid CONFIG_FILE: %w", err)
+		}
+	}
+
 	cfg := &Config{
 		BuildVersion: buildVersion,
 		Port:         getEnv("PORT", "8080"),
-		DatabasePath: getEnv("DATABASE_PATH", "./data/bookmarks.db"),
+		DataDir:      getEnv("DATA_DIR", db.DefaultDataDir),
 		SecureCookie: getEnv("SECURE_COOKIE", "false") == "true",
+
+		TLSCertFile:           os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:            os.Getenv("TLS_KEY_FILE"),
+		ContentSecurityPolicy: getEnv("CONTENT_SECURITY_POLICY", defaultContentSecurityPolicy),
+
+		UniqueListTitles: getEnv("UNIQUE_LIST_TITLES", "false") == "true",
+		CollapseNewLists: getEnv("COLLAPSE_NEW_LISTS", "false") == "true",
+		AutoTitle:        getEnv("AUTO_TITLE", "true") == "true",
+		FaviconProxyURL:  os.Getenv("FAVICON_PROXY_URL"),
+
+		AdminUsers:          getEnvList("ADMIN_USERS"),
+		FaviconAllowedHosts: getEnvList("FAVICON_ALLOWED_HOSTS"),
+		FaviconBlockedHosts: getEnvList("FAVICON_BLOCKED_HOSTS"),
+	}
+
+	// Parse TLS settings
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	switch minVersion := getEnv("TLS_MIN_VERSION", "1.2"); minVersion {
+	case "1.2":
+		cfg.TLSMinVersion = tls.VersionTLS12
+	case "1.3":
+		cfg.TLSMinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("invalid TLS_MIN_VERSION: must be 1.2 or 1.3, got %q", minVersion)
+	}
+
+	// Resolve the database location and make sure it is writable
+	databasePath, err := db.ResolvePath(os.Getenv("DATABASE_PATH"), cfg.DataDir, getEnv("DB_FILENAME", db.DefaultFilename))
+	if err != nil {
+		return nil, err
+	}
+	cfg.DatabasePath = databasePath
+	if cfg.DataDir, err = filepath.Abs(cfg.DataDir); err != nil {
+		return nil, fmt.Errorf("invalid DATA_DIR: %w", err)
 	}
 
 	// Parse session max age
@@ -49,6 +222,158 @@ func LoadConfig(buildVersion string) (*Config, error) {
 	}
 	cfg.SessionMaxAge = sessionMaxAge
 
+	// Parse session idle timeout (in seconds, 0 disables)
+	if cfg.SessionIdleTimeout, err = getEnvSeconds("SESSION_IDLE_TIMEOUT", 0); err != nil {
+		return nil, err
+	}
+
+	// Parse import limits
+	if cfg.MaxImportLists, err = strconv.Atoi(getEnv("MAX_IMPORT_LISTS", "500")); err != nil {
+		return nil, fmt.Errorf("invalid MAX_IMPORT_LISTS: %w", err)
+	}
+	if cfg.MaxImportItems, err = strconv.Atoi(getEnv("MAX_IMPORT_ITEMS", "10000")); err != nil {
+		return nil, fmt.Errorf("invalid MAX_IMPORT_ITEMS: %w", err)
+	}
+	if cfg.MaxImportBytes, err = strconv.ParseInt(getEnv("MAX_IMPORT_BYTES", "52428800"), 10, 64); err != nil || cfg.MaxImportBytes < 0 {
+		return nil, fmt.Errorf("invalid MAX_IMPORT_BYTES: must be a non-negative integer")
+	}
+	if cfg.MaxImportFaviconLength, err = strconv.Atoi(getEnv("MAX_IMPORT_FAVICON_LENGTH", "65536")); err != nil || cfg.MaxImportFaviconLength < 0 {
+		return nil, fmt.Errorf("invalid MAX_IMPORT_FAVICON_LENGTH: must be a non-negative integer")
+	}
+
+	// Parse per-user item caps
+	if cfg.MaxBookmarksPerUser, err = strconv.Atoi(getEnv("MAX_BOOKMARKS_PER_USER", "0")); err != nil || cfg.MaxBookmarksPerUser < 0 {
+		return nil, fmt.Errorf("invalid MAX_BOOKMARKS_PER_USER: must be a non-negative integer")
+	}
+	if cfg.MaxNotesPerUser, err = strconv.Atoi(getEnv("MAX_NOTES_PER_USER", "0")); err != nil || cfg.MaxNotesPerUser < 0 {
+		return nil, fmt.Errorf("invalid MAX_NOTES_PER_USER: must be a non-negative integer")
+	}
+
+	// Parse debug body logging
+	if cfg.DebugLogBodyBytes, err = strconv.Atoi(getEnv("DEBUG_LOG_BODY_BYTES", "0")); err != nil || cfg.DebugLogBodyBytes < 0 {
+		return nil, fmt.Errorf("invalid DEBUG_LOG_BODY_BYTES: must be a non-negative integer")
+	}
+
+	// Parse API rate limit
+	if cfg.APIRateLimit, err = strconv.ParseFloat(getEnv("API_RATE_LIMIT", "0"), 64); err != nil || cfg.APIRateLimit < 0 {
+		return nil, fmt.Errorf("invalid API_RATE_LIMIT: must be a non-negative number")
+	}
+	if cfg.APIRateBurst, err = strconv.Atoi(getEnv("API_RATE_BURST", "20")); err != nil || cfg.APIRateBurst < 1 {
+		return nil, fmt.Errorf("invalid API_RATE_BURST: must be a positive integer")
+	}
+
+	// Parse daily quotas
+	if cfg.DailyWriteQuota, err = strconv.Atoi(getEnv("DAILY_WRITE_QUOTA", "0")); err != nil || cfg.DailyWriteQuota < 0 {
+		return nil, fmt.Errorf("invalid DAILY_WRITE_QUOTA: must be a non-negative integer")
+	}
+	if cfg.DailyFetchQuota, err = strconv.Atoi(getEnv("DAILY_FETCH_QUOTA", "0")); err != nil || cfg.DailyFetchQuota < 0 {
+		return nil, fmt.Errorf("invalid DAILY_FETCH_QUOTA: must be a non-negative integer")
+	}
+
+	// Parse favicon retry attempts
+	if cfg.FaviconRetryAttempts, err = strconv.Atoi(getEnv("FAVICON_RETRY_ATTEMPTS", "2")); err != nil || cfg.FaviconRetryAttempts < 1 {
+		return nil, fmt.Errorf("invalid FAVICON_RETRY_ATTEMPTS: must be a positive integer")
+	}
+
+	// Parse favicon concurrency limit
+	if cfg.FaviconMaxConcurrent, err = strconv.Atoi(getEnv("FAVICON_MAX_CONCURRENT", "8")); err != nil || cfg.FaviconMaxConcurrent < 1 {
+		return nil, fmt.Errorf("invalid FAVICON_MAX_CONCURRENT: must be a positive integer")
+	}
+
+	// Parse favicon circuit breaker
+	if cfg.FaviconBreakerThreshold, err = strconv.Atoi(getEnv("FAVICON_BREAKER_THRESHOLD", "5")); err != nil || cfg.FaviconBreakerThreshold < 0 {
+		return nil, fmt.Errorf("invalid FAVICON_BREAKER_THRESHOLD: must be a non-negative integer")
+	}
+	if cfg.FaviconBreakerCooldown, err = getEnvSeconds("FAVICON_BREAKER_COOLDOWN", 60); err != nil {
+		return nil, err
+	}
+
+	// Parse favicon PNG transcoding size
+	if cfg.FaviconPNGSize, err = strconv.Atoi(getEnv("FAVICON_PNG_SIZE", "0")); err != nil || cfg.FaviconPNGSize < 0 || cfg.FaviconPNGSize > 256 {
+		return nil, fmt.Errorf("invalid FAVICON_PNG_SIZE: must be an integer between 0 and 256")
+	}
+
+	// Parse favicon storage mode
+	cfg.FaviconStorage = getEnv("FAVICON_STORAGE", "inline")
+	if cfg.FaviconStorage != "inline" && cfg.FaviconStorage != "file" {
+		return nil, fmt.Errorf("invalid FAVICON_STORAGE: must be 'inline' or 'file'")
+	}
+
+	// Parse favicon refresh age (in days) and auto mode
+	refreshDays, err := strconv.Atoi(getEnv("FAVICON_REFRESH_DAYS", "0"))
+	if err != nil || refreshDays < 0 {
+		return nil, fmt.Errorf("invalid FAVICON_REFRESH_DAYS: must be a non-negative integer")
+	}
+	cfg.FaviconRefreshAge = time.Duration(refreshDays) * 24 * time.Hour
+	if cfg.FaviconAutoRefresh, err = strconv.ParseBool(getEnv("FAVICON_AUTO_REFRESH", "false")); err != nil {
+		return nil, fmt.Errorf("invalid FAVICON_AUTO_REFRESH: %w", err)
+	}
+
+	// Parse preview image capture
+	if cfg.PreviewImages, err = strconv.ParseBool(getEnv("PREVIEW_IMAGES", "false")); err != nil {
+		return nil, fmt.Errorf("invalid PREVIEW_IMAGES: %w", err)
+	}
+	if cfg.PreviewImageMaxBytes, err = strconv.Atoi(getEnv("PREVIEW_IMAGE_MAX_BYTES", "262144")); err != nil || cfg.PreviewImageMaxBytes < 1 {
+		return nil, fmt.Errorf("invalid PREVIEW_IMAGE_MAX_BYTES: must be a positive integer")
+	}
+
+	// Parse CSP nonce injection
+	if cfg.CSPNonce, err = strconv.ParseBool(getEnv("CSP_NONCE", "false")); err != nil {
+		return nil, fmt.Errorf("invalid CSP_NONCE: %w", err)
+	}
+
+	// Parse reverse-proxy base path
+	if cfg.BasePath, err = normalizeBasePath(getEnv("BASE_PATH", "")); err != nil {
+		return nil, fmt.Errorf("invalid BASE_PATH: %w", err)
+	}
+
+	// Parse new user seeding
+	if cfg.SeedNewUsers, err = strconv.ParseBool(getEnv("SEED_NEW_USERS", "false")); err != nil {
+		return nil, fmt.Errorf("invalid SEED_NEW_USERS: %w", err)
+	}
+	cfg.NewUserTemplate = getEnv("NEW_USER_TEMPLATE", "")
+
+	// Parse favicon kill switch
+	if cfg.FaviconDisabled, err = strconv.ParseBool(getEnv("FAVICON_DISABLED", "false")); err != nil {
+		return nil, fmt.Errorf("invalid FAVICON_DISABLED: %w", err)
+	}
+
+	// Parse log level (debug, info, warn, error)
+	if err := cfg.LogLevel.UnmarshalText([]byte(getEnv("LOG_LEVEL", "info"))); err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+	}
+
+	// Parse HTTP server timeouts (in seconds)
+	if cfg.ReadTimeout, err = getEnvSeconds("READ_TIMEOUT", 30); err != nil {
+		return nil, err
+	}
+	if cfg.ReadHeaderTimeout, err = getEnvSeconds("READ_HEADER_TIMEOUT", 10); err != nil {
+		return nil, err
+	}
+	if cfg.WriteTimeout, err = getEnvSeconds("WRITE_TIMEOUT", 60); err != nil {
+		return nil, err
+	}
+	if cfg.IdleTimeout, err = getEnvSeconds("IDLE_TIMEOUT", 120); err != nil {
+		return nil, err
+	}
+	if cfg.DBQueryTimeout, err = getEnvSeconds("DB_QUERY_TIMEOUT", 10); err != nil {
+		return nil, err
+	}
+
+	// Parse favicon migration tuning
+	if cfg.MigrationFaviconConcurrency, err = strconv.Atoi(getEnv("MIGRATION_FAVICON_CONCURRENCY", "8")); err != nil || cfg.MigrationFaviconConcurrency < 1 {
+		return nil, fmt.Errorf("invalid MIGRATION_FAVICON_CONCURRENCY: must be a positive integer")
+	}
+	if cfg.MigrationFaviconTimeout, err = getEnvSeconds("MIGRATION_FAVICON_TIMEOUT", 2); err != nil {
+		return nil, err
+	}
+	if cfg.MigrationFaviconTimeout == 0 {
+		return nil, fmt.Errorf("invalid MIGRATION_FAVICON_TIMEOUT: must be positive")
+	}
+	if cfg.MigrationDropBookmarksBackup, err = strconv.ParseBool(getEnv("MIGRATION_DROP_BOOKMARKS_BACKUP", "false")); err != nil {
+		return nil, fmt.Errorf("invalid MIGRATION_DROP_BOOKMARKS_BACKUP: %w", err)
+	}
+
 	// Load OAuth2 configuration
 	cfg.OAuth2IssuerURL = os.Getenv("OAUTH2_ISSUER_URL")
 	cfg.OAuth2ClientID = os.Getenv("OAUTH2_CLIENT_ID")
@@ -63,7 +388,20 @@ func LoadConfig(buildVersion string) (*Config, error) {
 		if cfg.OAuth2ClientID == "" || cfg.OAuth2ClientSecret == "" || cfg.OAuth2RedirectURL == "" {
 			return nil, fmt.Errorf("OAUTH2_CLIENT_ID, OAUTH2_CLIENT_SECRET, and OAUTH2_REDIRECT_URL must be set when OAUTH2_ISSUER_URL is provided")
 		}
+		if err := validateRedirectURL(cfg.OAuth2RedirectURL); err != nil {
+			return nil, fmt.Errorf("invalid OAUTH2_REDIRECT_URL: %w", err)
+		}
+	}
+
+	// Registration defaults to open only in standalone mode
+	if cfg.RegistrationEnabled, err = strconv.ParseBool(getEnv("REGISTRATION_ENABLED", strconv.FormatBool(cfg.IsStandalone))); err != nil {
+		return nil, fmt.Errorf("invalid REGISTRATION_ENABLED: %w", err)
+	}
+	if cfg.OAuth2AutoProvision, err = strconv.ParseBool(getEnv("OAUTH2_AUTO_PROVISION", "true")); err != nil {
+		return nil, fmt.Errorf("invalid OAUTH2_AUTO_PROVISION: %w", err)
 	}
+	cfg.OAuth2GroupsClaim = getEnv("OAUTH2_GROUPS_CLAIM", oauth.DefaultGroupsClaim)
+	cfg.OAuth2AdminGroup = strings.TrimSpace(os.Getenv("OAUTH2_ADMIN_GROUP"))
 
 	// Load and validate session keys (mandatory)
 	sessionKeyHex := os.Getenv("SESSION_KEY")
@@ -93,10 +431,72 @@ func LoadConfig(buildVersion string) (*Config, error) {
 	}
 	cfg.EncryptionKey = encryptionKey
 
-	log.Printf("Configuration loaded - Port: %s, Database: %s", cfg.Port, cfg.DatabasePath)
+	log.Printf("Configuration loaded - Port: %s, Data directory: %s, Database: %s", cfg.Port, cfg.DataDir, cfg.DatabasePath)
 	return cfg, nil
 }
 
+// applyConfigFile exports the values of a YAML or TOML config file as environment
+// variables, leaving any variable that is already set untouched so the environment wins.
+// The merged values then go through the same parsing and validation as env-only config.
+func applyConfigFile(path string) error {
+	values, err := configfile.Load(path)
+	if err != nil {
+		return err
+	}
+
+	applied := 0
+	for key, value := range values {
+		if key == "CONFIG_FILE" {
+			return fmt.Errorf("CONFIG_FILE cannot be set from a config file")
+		}
+		if os.Getenv(key) != "" {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to apply %s: %w", key, err)
+		}
+		applied++
+	}
+
+	log.Printf("Loaded %d settings from config file %s (%d overridden by environment)", applied, path, len(values)-applied)
+	return nil
+}
+
+// TLSEnabled reports whether the server terminates TLS itself
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// normalizeBasePath turns a BASE_PATH such as "loom/" into "/loom"; "" and "/" mean the root
+func normalizeBasePath(raw string) (string, error) {
+	basePath := "/" + strings.Trim(strings.TrimSpace(raw), "/")
+	if basePath == "/" {
+		return "", nil
+	}
+	if strings.ContainsAny(basePath, "?#*{} ") || strings.Contains(basePath, "//") {
+		return "", fmt.Errorf("must be a plain URL path such as /loom")
+	}
+	return basePath, nil
+}
+
+// validateRedirectURL checks that an OAuth2 redirect URL is an absolute http(s) URL
+func validateRedirectURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("must be an absolute http or https URL, got %q", rawURL)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("missing host in %q", rawURL)
+	}
+	if parsed.Fragment != "" {
+		return fmt.Errorf("must not contain a fragment")
+	}
+	return nil
+}
+
 // getEnv retrieves an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -104,3 +504,26 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList splits a comma-separated environment variable, dropping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvSeconds parses an environment variable holding a whole number of seconds
+func getEnvSeconds(key string, defaultSeconds int) (time.Duration, error) {
+	seconds, err := strconv.Atoi(getEnv(key, strconv.Itoa(defaultSeconds)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	if seconds < 0 {
+		return 0, fmt.Errorf("invalid %s: must not be negative", key)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
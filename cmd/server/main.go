@@ -1,16 +1,23 @@
 package main
 
 import (
+	"crypto/tls"
 	"embed"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/crueber/loom/internal/api"
 	"github.com/crueber/loom/internal/auth"
 	"github.com/crueber/loom/internal/db"
+	"github.com/crueber/loom/internal/favicon"
+	"github.com/crueber/loom/internal/models"
 	"github.com/crueber/loom/internal/oauth"
+	"github.com/crueber/loom/internal/ratelimit"
 )
 
 //go:embed static
@@ -32,47 +39,164 @@ func main() {
 		log.Fatal(err)
 	}
 
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: cfg.LogLevel}))
+
 	// Initialize core services
-	database, sessionManager, oauthClient := initializeServices(cfg)
+	database, sessionManager, oauthClient := initializeServices(cfg, logger)
 	defer database.Close()
 
+	// Load the content seeded onto new users' boards
+	var newUserTemplate *models.ExportData
+	if cfg.SeedNewUsers {
+		if newUserTemplate, err = api.LoadNewUserTemplate(cfg.NewUserTemplate); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// Ensure standalone user exists if in standalone mode
 	if cfg.IsStandalone {
-		if err := database.EnsureStandaloneUser(); err != nil {
+		if err := database.EnsureStandaloneUser(newUserTemplate); err != nil {
 			log.Fatalf("Failed to ensure standalone user: %v", err)
 		}
 	}
 
 	// Setup application handler
 	appHandler := NewAppHandler(staticFiles, database, sessionManager, cfg.BuildVersion, cfg.IsStandalone)
+	appHandler.SetBasePath(cfg.BasePath)
+	appHandler.SetCSPNonce(cfg.CSPNonce)
 
 	// Setup API handlers
-	authAPI := api.NewAuthAPI(database, sessionManager, oauthClient, cfg.IsStandalone)
+	faviconFetcher, err := favicon.NewWithProxy(cfg.FaviconProxyURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize favicon fetcher: %v", err)
+	}
+	faviconFetcher.SetRetryAttempts(cfg.FaviconRetryAttempts)
+	faviconFetcher.SetMaxConcurrent(cfg.FaviconMaxConcurrent)
+	faviconFetcher.SetCircuitBreaker(cfg.FaviconBreakerThreshold, cfg.FaviconBreakerCooldown)
+	faviconFetcher.SetHostPolicy(cfg.FaviconAllowedHosts, cfg.FaviconBlockedHosts)
+	faviconFetcher.SetDisabled(cfg.FaviconDisabled)
+	faviconFetcher.SetTranscodeSize(cfg.FaviconPNGSize)
+	var faviconStore *favicon.Store
+	if cfg.FaviconStorage == "file" {
+		if faviconStore, err = favicon.NewStore(filepath.Join(cfg.DataDir, "favicons")); err != nil {
+			log.Fatalf("Failed to initialize favicon storage: %v", err)
+		}
+		faviconFetcher.SetStore(faviconStore)
+	}
+	authAPI := api.NewAuthAPI(database, sessionManager, oauthClient, cfg.IsStandalone, cfg.RegistrationEnabled, cfg.OAuth2AutoProvision, cfg.OAuth2AdminGroup, logger)
+	authAPI.SetLocaleDetector(appHandler.detectLocale)
+	authAPI.SetNewUserTemplate(newUserTemplate)
+	authAPI.SetBasePath(cfg.BasePath)
 	dataAPI := api.NewDataAPI(database)
+	listsAPI := api.NewListsAPI(database, cfg.UniqueListTitles, cfg.CollapseNewLists)
+	itemsAPI := api.NewItemsAPI(database, faviconFetcher, cfg.AutoTitle)
+	faviconRefresher := api.NewFaviconRefresher(database, faviconFetcher, cfg.FaviconRefreshAge)
+	faviconRefresher.SetAuto(cfg.FaviconAutoRefresh)
+	itemsAPI.SetFaviconRefresher(faviconRefresher)
+	// Preview images stay servable and exportable after capture is turned off
+	previewStore, err := favicon.NewStoreAt(filepath.Join(cfg.DataDir, "previews"), api.StoredPreviewPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize preview image storage: %v", err)
+	}
+	itemsAPI.SetPreviewStore(previewStore)
+	if cfg.PreviewImages {
+		itemsAPI.SetPreviewImages(cfg.PreviewImageMaxBytes)
+	}
+	adminAPI := api.NewAdminAPI(database, cfg.AdminUsers, cfg.IsStandalone)
+	adminAPI.SetUserCacheInvalidator(appHandler.InvalidateUserCache)
+	itemLimits := api.ItemLimits{MaxBookmarks: cfg.MaxBookmarksPerUser, MaxNotes: cfg.MaxNotesPerUser, IsAdmin: adminAPI.IsAdmin}
+	itemsAPI.SetItemLimits(itemLimits)
+	listsAPI.SetItemLimits(itemLimits)
+	authAPI.SetItemLimits(itemLimits)
+	exportAPI := api.NewExportAPI(database, cfg.AuthKey, cfg.MaxImportLists, cfg.MaxImportItems)
+	exportAPI.SetMaxFaviconLength(cfg.MaxImportFaviconLength)
+	exportAPI.SetMaxImportBytes(cfg.MaxImportBytes)
+	exportAPI.SetFaviconStore(faviconStore)
+	exportAPI.SetPreviewStore(previewStore)
+	exportAPI.SetItemLimits(itemLimits)
+	exportAPI.SetUniqueListTitles(cfg.UniqueListTitles)
+
+	// API rate limiting (disabled when API_RATE_LIMIT is 0)
+	var rateLimiter *ratelimit.Limiter
+	if cfg.APIRateLimit > 0 {
+		rateLimiter = ratelimit.New(cfg.APIRateLimit, cfg.APIRateBurst)
+	}
+
+	// Daily per-user quotas (each disabled when 0)
+	writeQuota := api.DailyQuota{IsAdmin: adminAPI.IsAdmin}
+	if cfg.DailyWriteQuota > 0 {
+		writeQuota.Quota = ratelimit.NewQuota(cfg.DailyWriteQuota)
+	}
+	fetchQuota := api.DailyQuota{IsAdmin: adminAPI.IsAdmin}
+	if cfg.DailyFetchQuota > 0 {
+		fetchQuota.Quota = ratelimit.NewQuota(cfg.DailyFetchQuota)
+	}
+	itemsAPI.SetFetchQuota(fetchQuota)
+	faviconRefresher.SetFetchQuota(fetchQuota)
 
 	// Configure router
 	router := SetupRouter(&RouterDependencies{
-		StaticFiles: staticFiles,
-		Database:    database,
-		AuthAPI:     authAPI,
-		DataAPI:     dataAPI,
-		AppHandler:  appHandler,
+		StaticFiles:    staticFiles,
+		Database:       database,
+		AuthAPI:        authAPI,
+		DataAPI:        dataAPI,
+		ListsAPI:       listsAPI,
+		ItemsAPI:       itemsAPI,
+		ExportAPI:      exportAPI,
+		AdminAPI:       adminAPI,
+		RateLimiter:    rateLimiter,
+		WriteQuota:     writeQuota,
+		FetchQuota:     fetchQuota,
+		FaviconFetcher: faviconFetcher,
+		FaviconStore:   faviconStore,
+		FaviconRefresh: faviconRefresher,
+		ItemLimits:     itemLimits,
+		AppHandler:     appHandler,
+
+		PublicConfig: api.PublicConfig{
+			Version:             cfg.BuildVersion,
+			Standalone:          cfg.IsStandalone,
+			OAuthEnabled:        !cfg.IsStandalone,
+			RegistrationEnabled: cfg.RegistrationEnabled,
+			AutoTitle:           cfg.AutoTitle,
+		},
+
+		ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+		HSTS:                  cfg.TLSEnabled(),
+		BasePath:              cfg.BasePath,
+
+		Logger:            logger,
+		DebugLogBodyBytes: cfg.DebugLogBodyBytes,
 	})
 
 	// Start background cleanup routine
 	startCleanupRoutine(database)
 
 	// Start server
-	startServer(cfg.Port, router)
+	startServer(cfg, router)
 }
 
 // initializeServices initializes database, session manager, and OAuth2 client
-func initializeServices(cfg *Config) (*db.DB, *auth.SessionManager, *oauth.Client) {
+func initializeServices(cfg *Config, logger *slog.Logger) (*db.DB, *auth.SessionManager, *oauth.Client) {
 	// Initialize database
-	database, err := db.New(cfg.DatabasePath)
+	database, err := db.NewWithOptions(cfg.DatabasePath, db.Options{
+		FaviconMigrationConcurrency: cfg.MigrationFaviconConcurrency,
+		FaviconMigrationTimeout:     cfg.MigrationFaviconTimeout,
+		DropBookmarksBackup:         cfg.MigrationDropBookmarksBackup,
+	})
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
+	database.SetQueryTimeout(cfg.DBQueryTimeout)
+
+	// Fix users left with zero or several default boards by older data
+	promoted, demoted, err := database.RepairDefaultBoards()
+	if err != nil {
+		log.Fatalf("Failed to repair default boards: %v", err)
+	}
+	if promoted > 0 || demoted > 0 {
+		log.Printf("Repaired default boards: promoted %d, demoted %d", promoted, demoted)
+	}
 
 	// Initialize session manager
 	sessionManager := auth.NewSessionManager(
@@ -80,7 +204,13 @@ func initializeServices(cfg *Config) (*db.DB, *auth.SessionManager, *oauth.Clien
 		cfg.EncryptionKey,
 		cfg.SessionMaxAge,
 		cfg.SecureCookie,
+		logger,
 	)
+	sessionManager.SetIdleTimeout(cfg.SessionIdleTimeout)
+	sessionManager.SetStore(database)
+	if cfg.BasePath != "" {
+		sessionManager.SetCookiePath(cfg.BasePath)
+	}
 
 	// Initialize OAuth2 client (only if not in standalone mode)
 	var oauthClient *oauth.Client
@@ -90,6 +220,7 @@ func initializeServices(cfg *Config) (*db.DB, *auth.SessionManager, *oauth.Clien
 			cfg.OAuth2ClientID,
 			cfg.OAuth2ClientSecret,
 			cfg.OAuth2RedirectURL,
+			cfg.OAuth2GroupsClaim,
 		)
 		if err != nil {
 			log.Fatalf("Failed to initialize OAuth2 client: %v", err)
@@ -114,12 +245,29 @@ func startCleanupRoutine(database *db.DB) {
 	}()
 }
 
-// startServer starts the HTTP server
-func startServer(port string, handler http.Handler) {
-	addr := ":" + port
-	log.Printf("Server starting on http://localhost%s", addr)
+// startServer starts the HTTP server with the configured timeouts
+func startServer(cfg *Config, handler http.Handler) {
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	if cfg.TLSEnabled() {
+		server.TLSConfig = &tls.Config{MinVersion: cfg.TLSMinVersion}
+		log.Printf("Server starting on https://localhost%s", server.Addr)
+		if err := server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+			log.Fatalf("Server failed to start: %v", err)
+		}
+		return
+	}
+
+	log.Printf("Server starting on http://localhost%s", server.Addr)
 
-	if err := http.ListenAndServe(addr, handler); err != nil {
+	if err := server.ListenAndServe(); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
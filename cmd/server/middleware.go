@@ -4,11 +4,18 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/crueber/loom/internal/api"
+	"github.com/crueber/loom/internal/ratelimit"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 // cacheControlMiddleware adds appropriate cache headers for static assets
@@ -32,6 +39,96 @@ func cacheControlMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// securityHeadersMiddleware sets hardening headers on every response.
+// An empty csp omits the Content-Security-Policy header.
+func securityHeadersMiddleware(csp string, hsts bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", "SAMEORIGIN")
+			if csp != "" {
+				h.Set("Content-Security-Policy", csp)
+			}
+			if hsts {
+				h.Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitMiddleware limits request rates per user when authenticated and per client IP otherwise,
+// responding 429 with a Retry-After header once a caller's bucket is empty
+func rateLimitMiddleware(limiter *ratelimit.Limiter, authAPI *api.AuthAPI) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var key string
+			if userID, ok := authAPI.RequestUserID(r); ok {
+				key = "user:" + strconv.Itoa(userID)
+			} else {
+				host, _, err := net.SplitHostPort(r.RemoteAddr)
+				if err != nil {
+					host = r.RemoteAddr
+				}
+				key = "ip:" + host
+			}
+
+			if ok, wait := limiter.Allow(key); !ok {
+				// Same JSON shape as the API's other errors so clients can parse it
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				json.NewEncoder(w).Encode(api.ErrorResponse{Error: "Too many requests"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isWriteRequest reports whether a request mutates data
+func isWriteRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodDelete
+}
+
+// bodyLogPaths are the only endpoints whose request bodies may be logged; auth
+// endpoints carry credentials and must never be added here
+var bodyLogPaths = []string{"/api/import"}
+
+// requestBodyLogMiddleware logs up to maxBytes of the request body for bodyLogPaths
+// at debug level, tagged with the request ID. The body is restored for the handler.
+func requestBodyLogMiddleware(logger *slog.Logger, maxBytes int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || !slices.Contains(bodyLogPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			captured, err := io.ReadAll(io.LimitReader(r.Body, int64(maxBytes)))
+			// Put the captured prefix back in front of whatever the handler hasn't read yet
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(captured), r.Body))
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			logger.Debug("request body",
+				"request_id", middleware.GetReqID(r.Context()),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"content_length", r.ContentLength,
+				"captured_bytes", len(captured),
+				"body", string(captured),
+			)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // cacheInvalidationMiddleware invalidates the app cache on POST, PUT, DELETE requests
 func cacheInvalidationMiddleware(appHandler *AppHandler) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -62,7 +159,10 @@ func cacheInvalidationMiddleware(appHandler *AppHandler) func(http.Handler) http
 			path := r.URL.Path
 			var boardID int
 
-			if strings.HasPrefix(path, "/api/boards/") {
+			if path == "/api/boards/import" {
+				// The imported board is new, but it joins the board switcher on every cached board
+				appHandler.InvalidateUserCache(userID)
+			} else if strings.HasPrefix(path, "/api/boards/") {
 				idStr := chi.URLParam(r, "id")
 				if idStr == "" {
 					// Fallback if chi param not yet populated (middleware order)
@@ -72,8 +172,18 @@ func cacheInvalidationMiddleware(appHandler *AppHandler) func(http.Handler) http
 					}
 				}
 				boardID, _ = strconv.Atoi(idStr)
+				if strings.HasSuffix(path, "/archive") || strings.HasSuffix(path, "/unarchive") {
+					// Archiving changes the board switcher on every cached board
+					appHandler.InvalidateUserCache(userID)
+				} else if strings.HasSuffix(path, "/adopt-lists") {
+					// Adopted lists leave their source boards, which the request doesn't name
+					appHandler.InvalidateUserCache(userID)
+				}
 			} else if strings.HasPrefix(path, "/api/lists") {
-				if r.Method == http.MethodPost && strings.HasSuffix(path, "/copy-or-move") {
+				if path == "/api/lists/rename-batch" {
+					// A batch may span boards, so every board is refreshed
+					appHandler.InvalidateUserCache(userID)
+				} else if r.Method == http.MethodPost && strings.HasSuffix(path, "/copy-or-move") {
 					// copy-or-move: read body once to get target_board_id and copy flag
 					var sourceBoardID int
 					parts := strings.Split(path, "/")
@@ -103,7 +213,7 @@ func cacheInvalidationMiddleware(appHandler *AppHandler) func(http.Handler) http
 						}
 					}
 					// boardID stays 0 so we don't double-invalidate below
-				} else if r.Method == http.MethodPost {
+				} else if r.Method == http.MethodPost && path == "/api/lists" {
 					// For POST /api/lists, the board_id is in the request body
 					body, err := io.ReadAll(r.Body)
 					if err == nil {
@@ -133,7 +243,10 @@ func cacheInvalidationMiddleware(appHandler *AppHandler) func(http.Handler) http
 					}
 				}
 			} else if strings.HasPrefix(path, "/api/items") {
-				if r.Method == http.MethodPost {
+				if path == "/api/items/pin-batch" {
+					// A batch may span boards, so every board is refreshed
+					appHandler.InvalidateUserCache(userID)
+				} else if r.Method == http.MethodPost && path == "/api/items" {
 					// For POST /api/items, the list_id is in the request body
 					body, err := io.ReadAll(r.Body)
 					if err == nil {
@@ -169,7 +282,10 @@ func cacheInvalidationMiddleware(appHandler *AppHandler) func(http.Handler) http
 						}
 					}
 				}
-			} else if strings.HasPrefix(path, "/api/user/locale") || strings.HasPrefix(path, "/api/user/theme") {
+			} else if strings.HasPrefix(path, "/api/readlater") {
+				// The read-later list can sit on any board (or be created), so every board is refreshed
+				appHandler.InvalidateUserCache(userID)
+			} else if strings.HasPrefix(path, "/api/user/locale") || strings.HasPrefix(path, "/api/user/theme") || strings.HasPrefix(path, "/api/user/settings") {
 				// Invalidate all boards for this user if they change global settings
 				appHandler.InvalidateUserCache(userID)
 			}
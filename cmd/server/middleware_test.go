@@ -0,0 +1,94 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crueber/loom/internal/api"
+	"github.com/crueber/loom/internal/auth"
+	"github.com/crueber/loom/internal/db"
+	"github.com/crueber/loom/internal/ratelimit"
+)
+
+func TestCacheInvalidationMiddleware(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "loom.db"))
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	defer database.Close()
+	if err := database.EnsureStandaloneUser(nil); err != nil {
+		t.Fatalf("create standalone user: %v", err)
+	}
+	user, err := database.GetUserByEmail("user@standalone")
+	if err != nil {
+		t.Fatalf("get standalone user: %v", err)
+	}
+	board, err := database.GetDefaultBoard(user.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	other, err := database.CreateBoard(user.ID, "Other", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	appHandler := NewAppHandler(embed.FS{}, database, auth.NewSessionManager(key, key, 3600, false, nil), "test", true)
+	handler := cacheInvalidationMiddleware(appHandler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		method, path, body string
+	}{
+		{http.MethodPost, "/api/items/pin-batch", `{"item_ids":[1],"pinned":true}`},
+		{http.MethodPut, "/api/lists/rename-batch", `{"lists":[{"id":1,"title":"Renamed"}]}`},
+		{http.MethodPost, "/api/readlater", `{"url":"https://example.com"}`},
+		{http.MethodPost, "/api/readlater/1/done", ""},
+		{http.MethodPost, "/api/boards/import", `{"version":1,"lists":[]}`},
+		{http.MethodPost, "/api/boards/1/adopt-lists", `{"list_ids":[2]}`},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			for _, boardID := range []int{board.ID, other.ID} {
+				appHandler.cache.Set(fmt.Sprintf("%d:%d", user.ID, boardID), "cached")
+			}
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			for _, boardID := range []int{board.ID, other.ID} {
+				if _, ok := appHandler.cache.Get(fmt.Sprintf("%d:%d", user.ID, boardID)); ok {
+					t.Fatalf("board %d is still cached", boardID)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddleware_RespondsWithJSONError(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	authAPI := api.NewAuthAPI(nil, auth.NewSessionManager(key, key, 3600, false, nil), nil, false, false, false, "", nil)
+	handler := rateLimitMiddleware(ratelimit.New(0.001, 1), authAPI)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	perform := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/lists", nil))
+		return rec
+	}
+	if rec := perform(); rec.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec := perform()
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("second request = %d (Retry-After %q), want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	var body api.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" {
+		t.Fatalf("429 body = %q, want a JSON error", rec.Body.String())
+	}
+}
@@ -4,22 +4,49 @@ import (
 	"embed"
 	"io/fs"
 	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/crueber/loom/internal/api"
 	"github.com/crueber/loom/internal/db"
 	"github.com/crueber/loom/internal/favicon"
+	"github.com/crueber/loom/internal/ratelimit"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
 // RouterDependencies holds all dependencies needed for route setup
 type RouterDependencies struct {
-	StaticFiles embed.FS
-	Database    *db.DB
-	AuthAPI     *api.AuthAPI
-	DataAPI     *api.DataAPI
-	AppHandler  *AppHandler
+	StaticFiles    embed.FS
+	Database       *db.DB
+	AuthAPI        *api.AuthAPI
+	DataAPI        *api.DataAPI
+	ListsAPI       *api.ListsAPI
+	ItemsAPI       *api.ItemsAPI
+	ExportAPI      *api.ExportAPI
+	AdminAPI       *api.AdminAPI
+	RateLimiter    *ratelimit.Limiter
+	WriteQuota     api.DailyQuota
+	FetchQuota     api.DailyQuota
+	FaviconFetcher *favicon.Fetcher
+	FaviconStore   *favicon.Store
+	FaviconRefresh *api.FaviconRefresher
+	ItemLimits     api.ItemLimits
+	AppHandler     *AppHandler
+
+	// Non-secret settings served by GET /api/config
+	PublicConfig api.PublicConfig
+
+	// Security headers (HSTS is only sent when the server terminates TLS)
+	ContentSecurityPolicy string
+	HSTS                  bool
+
+	// Subpath the app is served under behind a reverse proxy, e.g. "/loom" ("" = root)
+	BasePath string
+
+	// Debug logging of import request bodies (0 disables)
+	Logger            *slog.Logger
+	DebugLogBodyBytes int
 }
 
 // SetupRouter configures all routes and middleware
@@ -27,9 +54,14 @@ func SetupRouter(deps *RouterDependencies) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Global middleware
+	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Compress(5))
+	r.Use(securityHeadersMiddleware(deps.ContentSecurityPolicy, deps.HSTS))
+	if deps.DebugLogBodyBytes > 0 {
+		r.Use(requestBodyLogMiddleware(deps.Logger, deps.DebugLogBodyBytes))
+	}
 
 	// Setup static file serving
 	setupStaticFiles(r, deps.StaticFiles)
@@ -41,8 +73,20 @@ func SetupRouter(deps *RouterDependencies) *chi.Mux {
 	setupOAuthRoutes(r, deps.AuthAPI)
 
 	// Setup API routes
-	setupAPIRoutes(r, deps.Database, deps.AuthAPI, deps.DataAPI, deps.AppHandler)
+	setupAPIRoutes(r, deps)
 
+	if deps.BasePath != "" {
+		return mountAtBasePath(r, deps.BasePath)
+	}
+	return r
+}
+
+// mountAtBasePath serves app under basePath, stripping the prefix so routes and handlers
+// see root-relative paths. The bare base path redirects to its trailing-slash form.
+func mountAtBasePath(app http.Handler, basePath string) *chi.Mux {
+	r := chi.NewRouter()
+	r.Handle(basePath, http.RedirectHandler(basePath+"/", http.StatusMovedPermanently))
+	r.Handle(basePath+"/*", http.StripPrefix(basePath, app))
 	return r
 }
 
@@ -71,43 +115,76 @@ func setupOAuthRoutes(r *chi.Mux, authAPI *api.AuthAPI) {
 }
 
 // setupAPIRoutes configures all API endpoints
-func setupAPIRoutes(r *chi.Mux, database *db.DB, authAPI *api.AuthAPI, dataAPI *api.DataAPI, appHandler *AppHandler) {
+func setupAPIRoutes(r *chi.Mux, deps *RouterDependencies) {
 	// Initialize API handlers
-	listsAPI := api.NewListsAPI(database)
-	bookmarksAPI := api.NewBookmarksAPI(database, favicon.New())
-	itemsAPI := api.NewItemsAPI(database, favicon.New())
-	exportAPI := api.NewExportAPI(database)
+	bookmarksAPI := api.NewBookmarksAPI(deps.Database, deps.FaviconFetcher)
+	bookmarksAPI.SetItemLimits(deps.ItemLimits)
+	bookmarksAPI.SetFetchQuota(deps.FetchQuota)
+	faviconsAPI := api.NewFaviconsAPI(deps.FaviconFetcher)
+	faviconsAPI.SetStore(deps.FaviconStore)
+	faviconsAPI.SetFetchQuota(deps.FetchQuota)
 
 	r.Route("/api", func(r chi.Router) {
+		if deps.RateLimiter != nil {
+			r.Use(rateLimitMiddleware(deps.RateLimiter, deps.AuthAPI))
+		}
+
+		// Instance configuration (public, non-secret)
+		r.Get("/config", api.HandleConfig(deps.PublicConfig))
+
 		// Public routes (deprecated - will be removed)
-		r.Post("/login", authAPI.HandleLogin)
-		r.Post("/register", authAPI.HandleRegister)
+		r.Post("/login", deps.AuthAPI.HandleLogin)
+		r.Post("/register", deps.AuthAPI.HandleRegister)
+
+		// Signed export downloads (authorized by token, not session)
+		r.Get("/export/download", deps.ExportAPI.HandleExportDownload)
+
+		// Stored favicons and preview images (public so they load on public_read boards for anonymous readers)
+		r.Get("/favicons/{hash}", faviconsAPI.HandleGetFavicon)
+		r.Get("/previews/{hash}", deps.ItemsAPI.HandleGetPreviewImage)
+
+		// Board reads (anonymous callers may read boards flagged public_read)
+		r.Group(func(r chi.Router) {
+			r.Use(deps.AuthAPI.OptionalAuthMiddleware)
+			r.Get("/boards/{id}/data", api.GetBoardData(deps.Database, deps.FaviconRefresh))
+			r.Get("/boards/{id}/items", api.GetBoardItems(deps.Database))
+			r.Get("/boards/{id}/index.json", api.GetBoardIndex(deps.Database))
+		})
 
 		// Protected routes
 		r.Group(func(r chi.Router) {
-			r.Use(authAPI.AuthMiddleware)
-			r.Use(cacheInvalidationMiddleware(appHandler))
+			r.Use(deps.AuthAPI.AuthMiddleware)
+			r.Use(cacheInvalidationMiddleware(deps.AppHandler))
+			if deps.WriteQuota.Quota != nil {
+				r.Use(deps.WriteQuota.Middleware(isWriteRequest))
+			}
 
 			// Auth endpoints
-			setupAuthEndpoints(r, authAPI)
+			setupAuthEndpoints(r, deps.AuthAPI)
 
 			// Data endpoints
-			setupDataEndpoints(r, database, dataAPI)
+			setupDataEndpoints(r, deps.Database, deps.DataAPI)
 
 			// Board endpoints
-			setupBoardEndpoints(r, database)
+			setupBoardEndpoints(r, deps.Database, deps.FaviconStore, deps.BasePath)
 
 			// List endpoints
-			setupListEndpoints(r, listsAPI)
+			setupListEndpoints(r, deps.ListsAPI)
 
 			// Bookmark endpoints (deprecated)
 			setupBookmarkEndpoints(r, bookmarksAPI)
 
 			// Item endpoints
-			setupItemEndpoints(r, itemsAPI)
+			setupItemEndpoints(r, deps.ItemsAPI)
+
+			// Favicon lookups for URLs that aren't saved yet
+			r.Post("/favicons", faviconsAPI.HandleBatchFavicons)
 
 			// Export/Import endpoints
-			setupExportEndpoints(r, exportAPI)
+			setupExportEndpoints(r, deps.ExportAPI)
+
+			// Admin endpoints
+			setupAdminEndpoints(r, deps.AdminAPI)
 		})
 	})
 }
@@ -118,6 +195,12 @@ func setupAuthEndpoints(r chi.Router, authAPI *api.AuthAPI) {
 	r.Get("/user", authAPI.HandleGetUser)
 	r.Post("/user/locale", authAPI.HandleUpdateLocale)
 	r.Post("/user/theme", authAPI.HandleUpdateTheme)
+	r.Post("/user/email", authAPI.HandleUpdateEmail)
+	r.Post("/user/home-board", authAPI.HandleSetHomeBoard)
+	r.Get("/user/settings", authAPI.HandleGetSettings)
+	r.Post("/user/settings/reset", authAPI.HandleResetSettings)
+	r.Get("/user/sessions", authAPI.HandleGetSessions)
+	r.Delete("/user/sessions/{id}", authAPI.HandleRevokeSession)
 }
 
 // setupDataEndpoints configures combined data endpoints
@@ -126,13 +209,19 @@ func setupDataEndpoints(r chi.Router, database *db.DB, dataAPI *api.DataAPI) {
 }
 
 // setupBoardEndpoints configures board-related endpoints
-func setupBoardEndpoints(r chi.Router, database *db.DB) {
+func setupBoardEndpoints(r chi.Router, database *db.DB, faviconStore *favicon.Store, basePath string) {
 	r.Get("/boards", api.GetBoards(database))
 	r.Post("/boards", api.CreateBoard(database))
+	r.Get("/boards/with-lists", api.GetBoardsWithLists(database))
 	r.Get("/boards/{id}", api.GetBoard(database))
 	r.Put("/boards/{id}", api.UpdateBoard(database))
 	r.Delete("/boards/{id}", api.DeleteBoard(database))
-	r.Get("/boards/{id}/data", api.GetBoardData(database))
+	r.Post("/boards/{id}/sort-lists", api.SortBoardLists(database))
+	r.Post("/boards/{id}/reorder-items", api.ReorderBoardItems(database))
+	r.Post("/boards/{id}/archive", api.ArchiveBoard(database))
+	r.Post("/boards/{id}/unarchive", api.UnarchiveBoard(database))
+	r.Get("/boards/{id}/missing-favicons", api.GetBoardMissingFavicons(database))
+	r.Get("/boards/{id}/startpage.html", api.GetBoardStartPage(database, faviconStore, basePath))
 }
 
 // setupListEndpoints configures list-related endpoints
@@ -142,7 +231,12 @@ func setupListEndpoints(r chi.Router, listsAPI *api.ListsAPI) {
 	r.Put("/lists/{id}", listsAPI.HandleUpdateList)
 	r.Delete("/lists/{id}", listsAPI.HandleDeleteList)
 	r.Put("/lists/reorder", listsAPI.HandleReorderLists)
+	r.Put("/lists/rename-batch", listsAPI.HandleRenameLists)
 	r.Post("/lists/{id}/copy-or-move", listsAPI.HandleCopyOrMoveList)
+	r.Post("/lists/{id}/duplicate", listsAPI.HandleDuplicateList)
+	r.Post("/lists/{id}/move", listsAPI.HandleMoveList)
+	r.Post("/boards/{id}/lists/batch", listsAPI.HandleBatchCreateLists)
+	r.Post("/boards/{id}/adopt-lists", listsAPI.HandleAdoptLists)
 }
 
 // setupBookmarkEndpoints configures bookmark-related endpoints (deprecated)
@@ -157,14 +251,41 @@ func setupBookmarkEndpoints(r chi.Router, bookmarksAPI *api.BookmarksAPI) {
 // setupItemEndpoints configures item-related endpoints (unified bookmarks and notes)
 func setupItemEndpoints(r chi.Router, itemsAPI *api.ItemsAPI) {
 	r.Get("/lists/{list_id}/items", itemsAPI.HandleGetItems)
+	r.Get("/items", itemsAPI.HandleGetItemsByIDs)
+	r.Get("/items/recent", itemsAPI.HandleGetRecentItems)
+	r.Get("/items/all", itemsAPI.HandleGetAllItems)
+	r.Get("/items/exists", itemsAPI.HandleItemExists)
+	r.Get("/search", itemsAPI.HandleSearch)
+	r.Get("/stats/items", itemsAPI.HandleGetItemStats)
 	r.Post("/items", itemsAPI.HandleCreateItem)
 	r.Put("/items/{id}", itemsAPI.HandleUpdateItem)
 	r.Delete("/items/{id}", itemsAPI.HandleDeleteItem)
 	r.Put("/items/reorder", itemsAPI.HandleReorderItems)
+	r.Post("/items/pin-batch", itemsAPI.HandlePinItems)
+	r.Post("/items/{id}/move-to-top", itemsAPI.HandleMoveItemToTop)
+	r.Post("/items/{id}/move-to-bottom", itemsAPI.HandleMoveItemToBottom)
+	r.Post("/boards/{id}/lists/{title}/items", itemsAPI.HandleAppendToNamedList)
+	r.Get("/readlater", itemsAPI.HandleGetReadLater)
+	r.Post("/readlater", itemsAPI.HandleAddReadLater)
+	r.Post("/readlater/{id}/done", itemsAPI.HandleReadLaterDone)
 }
 
 // setupExportEndpoints configures export/import endpoints
 func setupExportEndpoints(r chi.Router, exportAPI *api.ExportAPI) {
 	r.Get("/export", exportAPI.HandleExport)
+	r.Post("/export/token", exportAPI.HandleCreateExportToken)
 	r.Post("/import", exportAPI.HandleImport)
+	r.Post("/import/validate", exportAPI.HandleValidateImport)
+	r.Post("/boards/import", exportAPI.HandleImportBoard)
+}
+
+// setupAdminEndpoints configures operator-only endpoints
+func setupAdminEndpoints(r chi.Router, adminAPI *api.AdminAPI) {
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(adminAPI.AdminMiddleware)
+		r.Get("/backup", adminAPI.HandleBackup)
+		r.Post("/users/{id}/merge-into/{targetId}", adminAPI.HandleMergeUser)
+		r.Get("/orphans", adminAPI.HandleGetOrphans)
+		r.Post("/orphans/fix", adminAPI.HandleFixOrphans)
+	})
 }
@@ -1,5 +1,5 @@
 import { createSignal, createContext, useContext } from 'solid-js';
-import { logout as apiLogout } from '../utils/api';
+import { appURL, logout as apiLogout } from '../utils/api';
 
 const AuthContext = createContext();
 
@@ -15,7 +15,7 @@ export function AuthProvider(props) {
     const newTheme = currentUser.theme === 'light' ? 'dark' : 'light';
     
     try {
-      const response = await fetch('/api/user/theme', {
+      const response = await fetch(appURL('/api/user/theme'), {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ theme: newTheme })
@@ -31,7 +31,7 @@ export function AuthProvider(props) {
   };
 
   const login = () => {
-    window.location.href = '/auth/login';
+    window.location.href = appURL('/auth/login');
   };
 
   const logout = async () => {
@@ -1,6 +1,7 @@
 import { createSignal, createContext, useContext } from 'solid-js';
 import { createStore } from 'solid-js/store';
 import { 
+  appURL,
   updateBoard as apiUpdateBoard, 
   deleteBoard as apiDeleteBoard, 
   reorderItems as apiReorderItems, 
@@ -26,7 +27,7 @@ export function BoardProvider(props) {
 
   const createBoard = async () => {
     try {
-      const response = await fetch('/api/boards', {
+      const response = await fetch(appURL('/api/boards'), {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ title: 'New Board' })
@@ -38,7 +39,7 @@ export function BoardProvider(props) {
 
       const newBoard = await response.json();
       try { sessionStorage.setItem('loom:newBoard', '1'); } catch (_) {}
-      window.location.href = `/boards/${newBoard.id}`;
+      window.location.href = appURL(`/boards/${newBoard.id}`);
     } catch (error) {
       console.error('Failed to create board:', error);
       alert('Failed to create board: ' + error.message);
@@ -71,9 +72,9 @@ export function BoardProvider(props) {
       await apiDeleteBoard(id);
       const remainingBoard = boards.find(b => b.id !== id);
       if (remainingBoard) {
-        window.location.href = remainingBoard.is_default ? '/' : `/boards/${remainingBoard.id}`;
+        window.location.href = appURL(remainingBoard.is_default ? '/' : `/boards/${remainingBoard.id}`);
       } else {
-        window.location.href = '/';
+        window.location.href = appURL('/');
       }
     } catch (error) {
       console.error('Failed to delete board:', error);
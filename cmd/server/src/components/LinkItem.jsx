@@ -1,6 +1,7 @@
 import { Show, createSignal, createEffect } from 'solid-js';
 import { ItemHeader } from './ItemHeader';
 import { useI18n } from './I18nContext';
+import { appURL } from '../utils/api';
 
 export function LinkItem(props) {
   const { t } = useI18n();
@@ -190,7 +191,7 @@ export function LinkItem(props) {
               <Show when={props.item.favicon_url} fallback={
                 <div class="link-favicon-placeholder">🔗</div>
               }>
-                <img src={props.item.favicon_url} alt="" />
+                <img src={appURL(props.item.favicon_url)} alt="" />
               </Show>
             </div>
             <div class="link-content">
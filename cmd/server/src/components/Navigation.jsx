@@ -2,7 +2,7 @@ import { createSignal, Show, For, onMount, onCleanup } from 'solid-js';
 import { useAuth } from './AuthContext';
 import { useBoard } from './BoardContext';
 import { useI18n } from './I18nContext';
-import { exportData, importData } from '../utils/api';
+import { appURL, exportData, importData } from '../utils/api';
 
 const LOCALE_FLAGS = {
   'en': '🇺🇸',
@@ -129,7 +129,17 @@ export function Navigation() {
       reader.onload = async (event) => {
         try {
           const data = JSON.parse(event.target.result);
-          await importData(data, importMode());
+          const result = await importData(data, importMode());
+          // Replace imports only run once the summary of what they delete is confirmed
+          if (result && result.confirmation_required) {
+            const message = t('nav.import_replace_confirm')
+              .replace('{{lists}}', result.lists_deleted)
+              .replace('{{items}}', result.items_deleted);
+            if (!window.confirm(message)) {
+              return;
+            }
+            await importData(data, importMode(), result.confirm_token);
+          }
           window.location.reload();
         } catch (err) {
           setImportError('Invalid JSON file: ' + err.message);
@@ -169,7 +179,7 @@ export function Navigation() {
                           class={user()?.locale === code ? 'active' : ''}
                           onClick={async (e) => {
                             e.preventDefault();
-                            await fetch('/api/user/locale', {
+                            await fetch(appURL('/api/user/locale'), {
                               method: 'POST',
                               headers: { 'Content-Type': 'application/json' },
                               body: JSON.stringify({ locale: code })
@@ -249,7 +259,7 @@ export function Navigation() {
                   }>
                     <For each={boards}>
                       {(board) => (
-                        <a href={board.is_default ? '/' : `/boards/${board.id}`}>
+                        <a href={appURL(board.is_default ? '/' : `/boards/${board.id}`)}>
                           {board.is_default ? '። ' : ''}{board.title}
                         </a>
                       )}
@@ -330,7 +340,7 @@ export function Navigation() {
                       }>
                         <For each={boards}>
                           {(board) => (
-                            <a href={board.is_default ? '/' : `/boards/${board.id}`}>
+                            <a href={appURL(board.is_default ? '/' : `/boards/${board.id}`)}>
                               {board.is_default ? '። ' : ''}{board.title}
                             </a>
                           )}
@@ -368,7 +378,7 @@ export function Navigation() {
                               class={user()?.locale === code ? 'active' : ''}
                               onClick={async (e) => {
                                 e.preventDefault();
-                                await fetch('/api/user/locale', {
+                                await fetch(appURL('/api/user/locale'), {
                                   method: 'POST',
                                   headers: { 'Content-Type': 'application/json' },
                                   body: JSON.stringify({ locale: code })
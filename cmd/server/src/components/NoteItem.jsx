@@ -53,6 +53,8 @@ export function NoteItem(props) {
   };
 
   const renderMarkdown = (text) => {
+    // HTML notes are sanitized server-side and rendered as stored
+    if (props.item.content_format === 'html') return text;
     if (typeof window.marked === 'undefined') return text;
     return window.marked.parse(text);
   };
@@ -92,7 +94,11 @@ export function NoteItem(props) {
             style={{ cursor: 'pointer' }}
           >
             <div class="note-content">
-              <div class="note-text" innerHTML={renderMarkdown(content())} />
+              <Show when={props.item.content_format !== 'text'} fallback={
+                <div class="note-text note-text-plain">{content()}</div>
+              }>
+                <div class="note-text" innerHTML={renderMarkdown(content())} />
+              </Show>
             </div>
           </div>
         </Show>
@@ -1,6 +1,14 @@
+// Path prefix when served behind a reverse proxy under a subpath (set by the server)
+const basePath = window.__BASE_PATH__ || '';
+
+// Prefix a root-relative app path such as '/boards/1' with the base path; other URLs are returned as-is
+function appURL(path) {
+    return path.startsWith('/') && !path.startsWith('//') ? basePath + path : path;
+}
+
 // API Helper Functions
 async function _apiCall(endpoint, options = {}) {
-    const response = await fetch(`/api${endpoint}`, {
+    const response = await fetch(appURL(`/api${endpoint}`), {
         ...options,
         headers: {
             'Content-Type': 'application/json',
@@ -185,7 +193,7 @@ async function getAllData() {
 // Export/Import API
 async function exportData(boardId, boardTitle) {
     const urlParams = boardId ? `?board_id=${boardId}` : '';
-    const response = await fetch(`/api/export${urlParams}`);
+    const response = await fetch(appURL(`/api/export${urlParams}`));
     const blob = await response.blob();
     const url = window.URL.createObjectURL(blob);
     const a = document.createElement('a');
@@ -200,11 +208,23 @@ async function exportData(boardId, boardTitle) {
     window.URL.revokeObjectURL(url);
 }
 
-async function importData(data, mode) {
-    return apiCall('/import', {
+async function importData(data, mode, confirmToken) {
+    const response = await fetch(appURL('/api/import'), {
         method: 'POST',
-        body: JSON.stringify({ data, mode })
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ data, mode, confirm_token: confirmToken })
     });
+
+    // An unconfirmed replace answers 428 with a summary of what it would delete
+    if (response.status === 428) {
+        return response.json();
+    }
+    if (!response.ok) {
+        const error = await response.json();
+        throw new Error(error.error || 'Request failed');
+    }
+
+    return response.json();
 }
 
 // Utility Functions
@@ -251,6 +271,7 @@ function dispatchEvent(eventName, detail = null) {
 
 // Export all functions
 export {
+    appURL,
     apiCall,
     login,
     logout,
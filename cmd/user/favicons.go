@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/crueber/loom/internal/db"
+	"github.com/crueber/loom/internal/favicon"
+	"github.com/crueber/loom/internal/models"
+	"github.com/crueber/loom/internal/urlutil"
+)
+
+const defaultWarmConcurrency = 8
+
+func handleFavicons(database *db.DB) {
+	if len(os.Args) < 3 || os.Args[2] != "warm" {
+		fmt.Fprintln(os.Stderr, "Usage: user favicons warm [-concurrency N]")
+		os.Exit(1)
+	}
+
+	flags := flag.NewFlagSet("favicons warm", flag.ExitOnError)
+	concurrency := flags.Int("concurrency", defaultWarmConcurrency, "number of favicons to fetch in parallel")
+	flags.Parse(os.Args[3:])
+	if *concurrency < 1 {
+		fmt.Fprintln(os.Stderr, "Concurrency must be at least 1")
+		os.Exit(1)
+	}
+
+	if disabled, _ := strconv.ParseBool(os.Getenv("FAVICON_DISABLED")); disabled {
+		fmt.Fprintln(os.Stderr, "Favicon fetching is disabled (FAVICON_DISABLED)")
+		os.Exit(1)
+	}
+
+	fetcher, err := favicon.NewWithProxy(os.Getenv("FAVICON_PROXY_URL"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize favicon fetcher: %v\n", err)
+		os.Exit(1)
+	}
+	fetcher.SetMaxConcurrent(*concurrency)
+	fetcher.SetHostPolicy(
+		strings.Split(os.Getenv("FAVICON_ALLOWED_HOSTS"), ","),
+		strings.Split(os.Getenv("FAVICON_BLOCKED_HOSTS"), ","),
+	)
+	if size, err := strconv.Atoi(os.Getenv("FAVICON_PNG_SIZE")); err == nil {
+		fetcher.SetTranscodeSize(size)
+	}
+	if os.Getenv("FAVICON_STORAGE") == "file" {
+		store, err := favicon.NewStore(filepath.Join(getEnv("DATA_DIR", db.DefaultDataDir), "favicons"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to initialize favicon storage: %v\n", err)
+			os.Exit(1)
+		}
+		fetcher.SetStore(store)
+	}
+
+	items, err := database.GetBookmarksNeedingFavicons()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Database error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(items) == 0 {
+		fmt.Println("All favicons are already cached")
+		return
+	}
+
+	fmt.Printf("Warming favicons for %d bookmarks (concurrency %d)...\n", len(items), *concurrency)
+
+	var done, succeeded, failed atomic.Int64
+	var printMu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan *models.Item)
+
+	for range *concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				err := warmFavicon(database, fetcher, item)
+				n := done.Add(1)
+
+				printMu.Lock()
+				if err != nil {
+					failed.Add(1)
+					fmt.Printf("  [%d/%d] item %d: failed (%v)\n", n, len(items), item.ID, err)
+				} else {
+					succeeded.Add(1)
+					fmt.Printf("  [%d/%d] item %d: ok\n", n, len(items), item.ID)
+				}
+				printMu.Unlock()
+			}
+		}()
+	}
+
+	for _, item := range items {
+		jobs <- item
+	}
+	close(jobs)
+	wg.Wait()
+
+	fmt.Printf("Done: %d succeeded, %d failed\n", succeeded.Load(), failed.Load())
+}
+
+// warmFavicon fetches and embeds the favicon for a single bookmark
+func warmFavicon(database *db.DB, fetcher *favicon.Fetcher, item *models.Item) error {
+	domain, _ := urlutil.Domain(*item.URL)
+
+	iconURL, err := fetcher.FetchIcon(item.IconSource, item.CustomIconURL, domain)
+	if err != nil {
+		return err
+	}
+	if iconURL == nil {
+		return fmt.Errorf("no icon available")
+	}
+
+	return database.UpdateItemFields(item.ID, map[string]interface{}{"favicon_url": *iconURL})
+}
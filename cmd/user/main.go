@@ -16,7 +16,11 @@ func main() {
 		os.Exit(1)
 	}
 
-	dbPath := getEnv("DATABASE_PATH", "./data/bookmarks.db")
+	dbPath, err := db.ResolvePath(os.Getenv("DATABASE_PATH"), getEnv("DATA_DIR", db.DefaultDataDir), getEnv("DB_FILENAME", db.DefaultFilename))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid database location: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Initialize database
 	database, err := db.New(dbPath)
@@ -31,12 +35,16 @@ func main() {
 	switch command {
 	case "create":
 		handleCreate(database)
+	case "provision":
+		handleProvision(database)
 	case "delete":
 		handleDelete(database)
 	case "list":
 		handleList(database)
 	case "reset-password":
 		handleResetPassword(database)
+	case "favicons":
+		handleFavicons(database)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
 		printUsage()
@@ -107,6 +115,34 @@ func handleCreate(database *db.DB) {
 	fmt.Printf("User '%s' created successfully (ID: %d)\n", user.Username, user.ID)
 }
 
+func handleProvision(database *db.DB) {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "Usage: user provision <email>")
+		os.Exit(1)
+	}
+
+	email := os.Args[2]
+
+	if _, err := database.GetUserByEmail(email); err == nil {
+		fmt.Fprintf(os.Stderr, "A user with email '%s' already exists\n", email)
+		os.Exit(1)
+	}
+
+	// The OAuth subject is unknown until the user first signs in
+	user, err := database.CreateOAuthUser(email, "authentik", "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to provision user: %v\n", err)
+		os.Exit(1)
+	}
+
+	if _, err := database.CreateBoard(user.ID, "My Bookmarks", true); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create default board: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("User '%s' provisioned for OAuth login (ID: %d)\n", user.Email, user.ID)
+}
+
 func handleDelete(database *db.DB) {
 	if len(os.Args) < 3 {
 		fmt.Fprintln(os.Stderr, "Usage: user delete <username>")
@@ -219,12 +255,21 @@ func printUsage() {
 	fmt.Println()
 	fmt.Println("Usage:")
 	fmt.Println("  user create <username>          Create a new user")
+	fmt.Println("  user provision <email>          Pre-create an account for OAuth login")
 	fmt.Println("  user delete <username>          Delete a user")
 	fmt.Println("  user list                       List all users")
 	fmt.Println("  user reset-password <username>  Reset a user's password")
+	fmt.Println("  user favicons warm [-concurrency N]")
+	fmt.Println("                                  Fetch and embed all missing or remote favicons")
 	fmt.Println()
 	fmt.Println("Environment Variables:")
-	fmt.Println("  DATABASE_PATH   Path to the SQLite database (default: ./data/bookmarks.db)")
+	fmt.Println("  DATA_DIR           Directory holding the database (default: ./data)")
+	fmt.Println("  DB_FILENAME        Database file name inside DATA_DIR (default: bookmarks.db)")
+	fmt.Println("  DATABASE_PATH      Full database path; overrides DATA_DIR and DB_FILENAME")
+	fmt.Println("  FAVICON_PROXY_URL  Proxy for favicon requests (default: HTTPS_PROXY/HTTP_PROXY)")
+	fmt.Println("  FAVICON_ALLOWED_HOSTS, FAVICON_BLOCKED_HOSTS")
+	fmt.Println("                     Comma-separated icon hosts to allow or block")
+	fmt.Println("  FAVICON_STORAGE    'file' stores warmed icons under DATA_DIR/favicons (default: inline)")
 }
 
 func getEnv(key, defaultValue string) string {
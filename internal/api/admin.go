@@ -0,0 +1,257 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crueber/loom/internal/db"
+	"github.com/crueber/loom/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminAPI handles operator-only endpoints
+type AdminAPI struct {
+	db           *db.DB
+	adminUsers   map[string]bool
+	isStandalone bool
+
+	// invalidateUser drops a user's cached pages after their data changes; nil when unset
+	invalidateUser func(userID int)
+}
+
+// NewAdminAPI creates a new admin API handler.
+// adminUsers lists the usernames or emails granted admin access; entries containing
+// "@" match only emails verified by the identity provider, never usernames. In
+// standalone mode the single standalone user is always an admin.
+func NewAdminAPI(database *db.DB, adminUsers []string, isStandalone bool) *AdminAPI {
+	admins := make(map[string]bool, len(adminUsers))
+	for _, name := range adminUsers {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			admins[name] = true
+		}
+	}
+
+	return &AdminAPI{
+		db:           database,
+		adminUsers:   admins,
+		isStandalone: isStandalone,
+	}
+}
+
+// SetUserCacheInvalidator sets how a user's cached pages are dropped after an admin
+// operation changes their boards or lists
+func (a *AdminAPI) SetUserCacheInvalidator(invalidate func(userID int)) {
+	a.invalidateUser = invalidate
+}
+
+// invalidateUserCache drops the user's cached pages, if an invalidator is set
+func (a *AdminAPI) invalidateUserCache(userID int) {
+	if a.invalidateUser != nil {
+		a.invalidateUser(userID)
+	}
+}
+
+// isAdmin reports whether a user has admin access
+func (a *AdminAPI) isAdmin(user *models.User) bool {
+	if user.IsAdmin {
+		return true
+	}
+	if a.isStandalone && user.Email == "user@standalone" {
+		return true
+	}
+	// Usernames and self-set emails can be chosen freely, so neither may claim an email entry
+	if !strings.Contains(user.Username, "@") && a.adminUsers[strings.ToLower(user.Username)] {
+		return true
+	}
+	return user.EmailVerified && user.Email != "" && a.adminUsers[strings.ToLower(user.Email)]
+}
+
+// IsAdmin reports whether the user with the given ID has admin access
+func (a *AdminAPI) IsAdmin(userID int) (bool, error) {
+	user, err := a.db.GetUserByID(userID)
+	if err != nil || user == nil {
+		return false, err
+	}
+	return a.isAdmin(user), nil
+}
+
+// AdminMiddleware rejects authenticated users who are not admins.
+// It must run after AuthMiddleware.
+func (a *AdminAPI) AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := getUserID(r.Context())
+		if !ok {
+			respondError(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+
+		user, err := a.db.GetUserByID(userID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if user == nil || !a.isAdmin(user) {
+			respondError(w, http.StatusForbidden, "Admin access required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HandleBackup streams a consistent snapshot of the whole database
+func (a *AdminAPI) HandleBackup(w http.ResponseWriter, r *http.Request) {
+	tmpDir, err := os.MkdirTemp("", "loom-backup-")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to create backup")
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	backupPath := filepath.Join(tmpDir, "backup.db")
+	if err := a.db.BackupTo(backupPath); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to create backup")
+		return
+	}
+
+	file, err := os.Open(backupPath)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to read backup")
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to read backup")
+		return
+	}
+
+	filename := fmt.Sprintf("loom-backup-%s.db", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	io.Copy(w, file)
+}
+
+// MergeUserResponse reports what HandleMergeUser moved
+type MergeUserResponse struct {
+	TargetUserID int `json:"target_user_id"`
+	BoardsMoved  int `json:"boards_moved"`
+	ListsMoved   int `json:"lists_moved"`
+}
+
+// HandleMergeUser moves all of one user's boards and lists to another user and deletes
+// the first, e.g. after someone signs in again under a new OAuth identity
+func (a *AdminAPI) HandleMergeUser(w http.ResponseWriter, r *http.Request) {
+	sourceID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	targetID, err := strconv.Atoi(chi.URLParam(r, "targetId"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid target user ID")
+		return
+	}
+	if sourceID == targetID {
+		respondError(w, http.StatusBadRequest, "Cannot merge a user into itself")
+		return
+	}
+
+	for _, id := range []int{sourceID, targetID} {
+		user, err := a.db.GetUserByID(id)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if user == nil {
+			respondError(w, http.StatusNotFound, fmt.Sprintf("User %d not found", id))
+			return
+		}
+	}
+
+	boards, lists, err := a.db.MergeUserInto(sourceID, targetID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to merge users")
+		return
+	}
+	a.invalidateUserCache(sourceID)
+	a.invalidateUserCache(targetID)
+
+	respondJSON(w, http.StatusOK, MergeUserResponse{
+		TargetUserID: targetID,
+		BoardsMoved:  boards,
+		ListsMoved:   lists,
+	})
+}
+
+// OrphansResponse reports lists and items the board-scoped UI cannot reach
+type OrphansResponse struct {
+	Lists []*models.OrphanedList `json:"lists"`
+	Items []*models.OrphanedItem `json:"items"`
+}
+
+// HandleGetOrphans reports every user's lists that are not on one of that user's boards
+// and items whose list no longer exists
+func (a *AdminAPI) HandleGetOrphans(w http.ResponseWriter, r *http.Request) {
+	lists, err := a.db.GetOrphanedLists()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to find orphaned lists")
+		return
+	}
+	items, err := a.db.GetOrphanedItems()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to find orphaned items")
+		return
+	}
+
+	orphans := OrphansResponse{Lists: lists, Items: items}
+	if orphans.Lists == nil {
+		orphans.Lists = []*models.OrphanedList{}
+	}
+	if orphans.Items == nil {
+		orphans.Items = []*models.OrphanedItem{}
+	}
+	respondJSON(w, http.StatusOK, orphans)
+}
+
+// FixOrphansResponse reports what HandleFixOrphans repaired
+type FixOrphansResponse struct {
+	ListsReassigned int `json:"lists_reassigned"`
+	// Items without a list record no owner, so they are reported but left in place
+	ItemsUnassigned int `json:"items_unassigned"`
+}
+
+// HandleFixOrphans moves each orphaned list, with its items, to the end of its owner's
+// default board, creating that board if needed. All lists move in one transaction.
+func (a *AdminAPI) HandleFixOrphans(w http.ResponseWriter, r *http.Request) {
+	items, err := a.db.GetOrphanedItems()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to find orphaned items")
+		return
+	}
+
+	movedByUser, err := a.db.ReassignOrphanedLists()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to reassign orphaned lists")
+		return
+	}
+
+	reassigned := 0
+	for userID, moved := range movedByUser {
+		reassigned += moved
+		a.invalidateUserCache(userID)
+	}
+
+	respondJSON(w, http.StatusOK, FixOrphansResponse{
+		ListsReassigned: reassigned,
+		ItemsUnassigned: len(items),
+	})
+}
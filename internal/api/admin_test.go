@@ -0,0 +1,312 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/crueber/loom/internal/oauth"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestAdminBackup(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	admin, err := database.CreateUser("operator", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	regular, err := database.CreateUser("regular", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	adminAPI := NewAdminAPI(database, []string{"Operator"}, false)
+	handler := adminAPI.AdminMiddleware(http.HandlerFunc(adminAPI.HandleBackup))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/backup", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(setUserID(req.Context(), regular.ID)))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(setUserID(req.Context(), admin.ID)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("admin status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if !bytes.HasPrefix(rec.Body.Bytes(), []byte("SQLite format 3\x00")) {
+		t.Fatalf("backup does not look like a SQLite database")
+	}
+}
+
+func TestAdminMergeUser(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	admin, err := database.CreateUser("operator", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	oldAccount, err := database.CreateUser("old", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	newAccount, err := database.CreateUser("new", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// Both accounts have a default board and a read-later list
+	for _, userID := range []int{oldAccount.ID, newAccount.ID} {
+		if _, err := database.GetReadLaterList(userID); err != nil {
+			t.Fatalf("create read-later list: %v", err)
+		}
+	}
+	oldBoard, err := database.GetDefaultBoard(oldAccount.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	if _, err := database.CreateList(oldAccount.ID, oldBoard.ID, "Work", "#3D6D95", 1, false); err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	if err := database.CreateSession("old-session", oldAccount.ID, "test", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	adminAPI := NewAdminAPI(database, []string{"operator"}, false)
+	invalidated := map[int]bool{}
+	adminAPI.SetUserCacheInvalidator(func(userID int) { invalidated[userID] = true })
+	merge := func(sourceID, targetID int) *httptest.ResponseRecorder {
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("id", strconv.Itoa(sourceID))
+		routeCtx.URLParams.Add("targetId", strconv.Itoa(targetID))
+		ctx := setUserID(context.WithValue(context.Background(), chi.RouteCtxKey, routeCtx), admin.ID)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/users/merge", nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		adminAPI.AdminMiddleware(http.HandlerFunc(adminAPI.HandleMergeUser)).ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := merge(oldAccount.ID, oldAccount.ID); rec.Code != http.StatusBadRequest {
+		t.Fatalf("self-merge status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := merge(oldAccount.ID, 9999); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing target status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec := merge(oldAccount.ID, newAccount.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	if user, err := database.GetUserByID(oldAccount.ID); err != nil || user != nil {
+		t.Fatalf("source user = %+v (err %v), want deleted", user, err)
+	}
+	if exists, err := database.SessionExists("old-session", oldAccount.ID); err != nil || exists {
+		t.Fatalf("source session exists = %v (err %v), want it deleted", exists, err)
+	}
+	if !invalidated[oldAccount.ID] || !invalidated[newAccount.ID] || len(invalidated) != 2 {
+		t.Fatalf("invalidated users = %v, want %d and %d", invalidated, oldAccount.ID, newAccount.ID)
+	}
+	lists, err := database.GetLists(newAccount.ID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	if len(lists) != 3 {
+		t.Fatalf("target has %d lists, want 3 (both read-later lists and Work)", len(lists))
+	}
+	boards, err := database.GetBoards(newAccount.ID)
+	if err != nil {
+		t.Fatalf("get boards: %v", err)
+	}
+	defaults := 0
+	for _, board := range boards {
+		if board.IsDefault {
+			defaults++
+		}
+	}
+	if len(boards) != 2 || defaults != 1 {
+		t.Fatalf("target has %d boards with %d defaults, want 2 with 1", len(boards), defaults)
+	}
+}
+
+func TestAdminOrphans(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	admin, err := database.CreateUser("operator", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	owner, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	adminBoard, err := database.GetDefaultBoard(admin.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	board, err := database.GetDefaultBoard(owner.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	if _, err := database.CreateList(owner.ID, board.ID, "Healthy", "#3D6D95", 0, false); err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+
+	// One list per kind of broken board_id: null, a deleted board and another user's board
+	var orphanIDs []int
+	for i, boardID := range []any{nil, 9999, adminBoard.ID} {
+		list, err := database.CreateList(owner.ID, board.ID, "Lost "+strconv.Itoa(i), "#3D6D95", i+1, false)
+		if err != nil {
+			t.Fatalf("create list: %v", err)
+		}
+		if _, err := database.Exec("UPDATE lists SET board_id = ? WHERE id = ?", boardID, list.ID); err != nil {
+			t.Fatalf("orphan list: %v", err)
+		}
+		orphanIDs = append(orphanIDs, list.ID)
+	}
+
+	// An item whose list was deleted while foreign keys were off
+	doomed, err := database.CreateList(owner.ID, board.ID, "Doomed", "#3D6D95", 9, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	content := "stranded"
+	item, err := database.CreateItem(doomed.ID, "note", nil, nil, &content, nil, "auto", nil, "markdown", 0, true)
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	conn, err := database.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("get connection: %v", err)
+	}
+	for _, stmt := range []string{"PRAGMA foreign_keys = OFF", "DELETE FROM lists WHERE id = " + strconv.Itoa(doomed.ID), "PRAGMA foreign_keys = ON"} {
+		if _, err := conn.ExecContext(context.Background(), stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	conn.Close()
+
+	adminAPI := NewAdminAPI(database, []string{"operator"}, false)
+	invalidated := map[int]bool{}
+	adminAPI.SetUserCacheInvalidator(func(userID int) { invalidated[userID] = true })
+	call := func(handler http.HandlerFunc, method string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/api/admin/orphans", nil)
+		rec := httptest.NewRecorder()
+		adminAPI.AdminMiddleware(handler).ServeHTTP(rec, req.WithContext(setUserID(req.Context(), admin.ID)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		return rec
+	}
+
+	var report OrphansResponse
+	if err := json.Unmarshal(call(adminAPI.HandleGetOrphans, http.MethodGet).Body.Bytes(), &report); err != nil {
+		t.Fatalf("unmarshal report: %v", err)
+	}
+	if len(report.Lists) != len(orphanIDs) {
+		t.Fatalf("orphaned lists = %+v, want %v", report.Lists, orphanIDs)
+	}
+	for i, list := range report.Lists {
+		if list.ID != orphanIDs[i] || list.UserID != owner.ID {
+			t.Fatalf("orphaned list %d = %+v, want list %d of user %d", i, list, orphanIDs[i], owner.ID)
+		}
+	}
+	if report.Lists[0].BoardID != nil {
+		t.Fatalf("null board_id reported as %d", *report.Lists[0].BoardID)
+	}
+	if len(report.Items) != 1 || report.Items[0].ID != item.ID || report.Items[0].ListID != doomed.ID {
+		t.Fatalf("orphaned items = %+v, want item %d", report.Items, item.ID)
+	}
+
+	var fixed FixOrphansResponse
+	if err := json.Unmarshal(call(adminAPI.HandleFixOrphans, http.MethodPost).Body.Bytes(), &fixed); err != nil {
+		t.Fatalf("unmarshal fix: %v", err)
+	}
+	if fixed.ListsReassigned != 3 || fixed.ItemsUnassigned != 1 {
+		t.Fatalf("fix = %+v, want 3 lists reassigned and 1 item left", fixed)
+	}
+	if !invalidated[owner.ID] || len(invalidated) != 1 {
+		t.Fatalf("invalidated users = %v, want only %d", invalidated, owner.ID)
+	}
+	lists, err := database.GetListsByBoard(owner.ID, board.ID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	if len(lists) != 4 {
+		t.Fatalf("default board has %d lists, want the healthy list and 3 reassigned", len(lists))
+	}
+
+	if err := json.Unmarshal(call(adminAPI.HandleGetOrphans, http.MethodGet).Body.Bytes(), &report); err != nil {
+		t.Fatalf("unmarshal report: %v", err)
+	}
+	if len(report.Lists) != 0 || len(report.Items) != 1 {
+		t.Fatalf("report after fix = %+v, want only the ownerless item", report)
+	}
+}
+
+func TestAdminUsers_OnlyTrustVerifiedEmails(t *testing.T) {
+	database := newBoardsTestDB(t)
+	authAPI := NewAuthAPI(database, nil, nil, false, true, true, "", nil)
+	adminAPI := NewAdminAPI(database, []string{"boss@example.com"}, false)
+
+	isAdmin := func(userID int) bool {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/orphans", nil)
+		rec := httptest.NewRecorder()
+		adminAPI.AdminMiddleware(http.HandlerFunc(adminAPI.HandleGetOrphans)).ServeHTTP(rec, req.WithContext(setUserID(req.Context(), userID)))
+		return rec.Code == http.StatusOK
+	}
+
+	// A local user can set any email without verification
+	local, err := database.CreateUser("mallory", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPut, "/api/user/email", bytes.NewBufferString(`{"email":"boss@example.com"}`))
+	rec := httptest.NewRecorder()
+	authAPI.HandleUpdateEmail(rec, req.WithContext(setUserID(req.Context(), local.ID)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update email status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if isAdmin(local.ID) {
+		t.Fatalf("user with a self-set admin email was granted admin")
+	}
+
+	// Nor does a username that looks like the admin email count
+	lookalike, err := database.CreateUser("Boss@example.com", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if isAdmin(lookalike.ID) {
+		t.Fatalf("user named after an admin email was granted admin")
+	}
+
+	if _, err := database.Exec("UPDATE users SET email = NULL WHERE id = ?", local.ID); err != nil {
+		t.Fatalf("clear email: %v", err)
+	}
+	boss, err := database.CreateOAuthUser("boss@example.com", oauthProvider, "boss")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if err := authAPI.syncEmailVerified(boss, &oauth.UserInfo{Email: "boss@example.com", EmailVerified: false}); err != nil {
+		t.Fatalf("syncEmailVerified() error = %v", err)
+	}
+	if isAdmin(boss.ID) {
+		t.Fatalf("unverified provider email was granted admin")
+	}
+	if err := authAPI.syncEmailVerified(boss, &oauth.UserInfo{Email: "boss@example.com", EmailVerified: true}); err != nil {
+		t.Fatalf("syncEmailVerified() error = %v", err)
+	}
+	if !isAdmin(boss.ID) {
+		t.Fatalf("verified provider email was not granted admin")
+	}
+}
@@ -4,9 +4,14 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/mail"
+	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/crueber/loom/internal/auth"
 	"github.com/crueber/loom/internal/db"
@@ -16,22 +21,69 @@ import (
 
 // AuthAPI handles authentication endpoints
 type AuthAPI struct {
-	db             *db.DB
-	sessionManager *auth.SessionManager
-	oauthClient    *oauth.Client
-	isStandalone   bool
+	db                  *db.DB
+	sessionManager      *auth.SessionManager
+	oauthClient         *oauth.Client
+	isStandalone        bool
+	registrationEnabled bool
+	autoProvision       bool
+	adminGroup          string
+	logger              *slog.Logger
+
+	// detectLocale resolves the locale used when the user has none saved
+	detectLocale func(r *http.Request) string
+
+	// itemLimits are reported with the user's settings
+	itemLimits ItemLimits
+
+	// basePath prefixes app URLs when served under a subpath ("" at the root)
+	basePath string
+
+	// newUserTemplate seeds the default board of auto-provisioned users; nil seeds nothing
+	newUserTemplate *models.ExportData
 }
 
 // NewAuthAPI creates a new authentication API handler
-func NewAuthAPI(database *db.DB, sessionManager *auth.SessionManager, oauthClient *oauth.Client, isStandalone bool) *AuthAPI {
+// registrationEnabled controls whether POST /api/register accepts new accounts.
+// A nil logger falls back to slog.Default()
+func NewAuthAPI(database *db.DB, sessionManager *auth.SessionManager, oauthClient *oauth.Client, isStandalone, registrationEnabled, autoProvision bool, adminGroup string, logger *slog.Logger) *AuthAPI {
+	if logger == nil {
+		logger = slog.Default()
+	}
 	return &AuthAPI{
-		db:             database,
-		sessionManager: sessionManager,
-		oauthClient:    oauthClient,
-		isStandalone:   isStandalone,
+		db:                  database,
+		sessionManager:      sessionManager,
+		oauthClient:         oauthClient,
+		isStandalone:        isStandalone,
+		registrationEnabled: registrationEnabled,
+		autoProvision:       autoProvision,
+		adminGroup:          adminGroup,
+		logger:              logger,
 	}
 }
 
+// SetItemLimits sets the bookmark and note caps reported by GET /api/user/settings
+func (a *AuthAPI) SetItemLimits(limits ItemLimits) {
+	a.itemLimits = limits
+}
+
+// SetBasePath sets the subpath the app is served under, e.g. "/loom", so redirects
+// back to the app land there
+func (a *AuthAPI) SetBasePath(basePath string) {
+	a.basePath = basePath
+}
+
+// SetNewUserTemplate sets the content seeded onto an auto-provisioned user's default board
+func (a *AuthAPI) SetNewUserTemplate(template *models.ExportData) {
+	a.newUserTemplate = template
+}
+
+// SetLocaleDetector sets how the effective locale is resolved for a user without
+// a saved preference (normally from Accept-Language); unset means DefaultLocale
+func (a *AuthAPI) SetLocaleDetector(detect func(r *http.Request) string) {
+	a.detectLocale = detect
+}
+
 // LoginRequest represents a login request
 type LoginRequest struct {
 	Username string `json:"username"`
@@ -90,12 +142,12 @@ func (a *AuthAPI) HandleLogin(w http.ResponseWriter, r *http.Request) {
 
 	// Create session
 	if err := a.sessionManager.CreateSession(w, r, user.ID); err != nil {
-		println("DEBUG: Failed to create session:", err.Error())
+		a.logger.Error("failed to create session", "error", err)
 		respondError(w, http.StatusInternalServerError, "Failed to create session")
 		return
 	}
 
-	println("DEBUG: Session created successfully for user:", user.ID)
+	a.logger.Debug("login succeeded", "user_id", user.ID)
 	respondJSON(w, http.StatusOK, UserResponse{
 		ID:       user.ID,
 		Username: user.Username,
@@ -104,6 +156,11 @@ func (a *AuthAPI) HandleLogin(w http.ResponseWriter, r *http.Request) {
 
 // HandleRegister handles user registration
 func (a *AuthAPI) HandleRegister(w http.ResponseWriter, r *http.Request) {
+	if !a.registrationEnabled {
+		respondError(w, http.StatusForbidden, "Registration is disabled")
+		return
+	}
+
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondError(w, http.StatusBadRequest, "Invalid request body")
@@ -116,7 +173,7 @@ func (a *AuthAPI) HandleRegister(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "Username is required")
 		return
 	}
-	if len(req.Username) < 3 || len(req.Username) > 50 {
+	if length := utf8.RuneCountInString(req.Username); length < 3 || length > 50 {
 		respondError(w, http.StatusBadRequest, "Username must be between 3 and 50 characters")
 		return
 	}
@@ -176,6 +233,127 @@ func (a *AuthAPI) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// UserSettingsResponse represents a user's account settings
+type UserSettingsResponse struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Locale   string `json:"locale"`
+	Theme    string `json:"theme"`
+
+	// ItemUsage is only reported by GET /api/user/settings
+	ItemUsage *ItemUsage `json:"item_usage,omitempty"`
+}
+
+// HandleGetSettings returns the user's account settings along with their bookmark
+// and note counts and the caps that apply to them
+func (a *AuthAPI) HandleGetSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	user, err := a.db.GetUserByID(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if user == nil {
+		respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	usage, err := a.itemLimits.usage(a.db, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to count items")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, UserSettingsResponse{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Locale:    user.Locale,
+		Theme:     user.Theme,
+		ItemUsage: &usage,
+	})
+}
+
+const (
+	// DefaultTheme is the theme served when the user has none saved (or "auto")
+	DefaultTheme = "dark"
+	// DefaultLocale is the locale served when neither the user nor the browser picks one
+	DefaultLocale = "en"
+)
+
+// maxEmailLength is the longest address permitted by RFC 5321
+const maxEmailLength = 254
+
+// HandleUpdateEmail changes the authenticated user's email address.
+// Accounts backed by an identity provider (including the standalone user) are
+// looked up by email at login, so their address cannot be changed here.
+func (a *AuthAPI) HandleUpdateEmail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		respondError(w, http.StatusBadRequest, "Email is required")
+		return
+	}
+	if len(email) > maxEmailLength {
+		respondError(w, http.StatusBadRequest, "Email is too long")
+		return
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		respondError(w, http.StatusBadRequest, "Invalid email address")
+		return
+	}
+
+	user, err := a.db.GetUserByID(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if user == nil {
+		respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if user.OAuthProvider != nil {
+		respondError(w, http.StatusForbidden, "Email is managed by your identity provider")
+		return
+	}
+
+	if err := a.db.UpdateUserEmail(userID, email); err != nil {
+		if strings.Contains(err.Error(), "already in use") {
+			respondError(w, http.StatusConflict, "Email is already in use")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to update email")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, UserSettingsResponse{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    email,
+		Locale:   user.Locale,
+		Theme:    user.Theme,
+	})
+}
+
 // HandleUpdateLocale updates the user's locale preference
 func (a *AuthAPI) HandleUpdateLocale(w http.ResponseWriter, r *http.Request) {
 	userID, ok := a.sessionManager.GetUserID(r)
@@ -242,6 +420,76 @@ func (a *AuthAPI) HandleUpdateTheme(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"status": "success"})
 }
 
+// HandleResetSettings clears the user's saved locale and theme and returns the
+// settings now in effect: the browser's Accept-Language locale and the default theme
+func (a *AuthAPI) HandleResetSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := a.db.ResetUserSettings(userID); err != nil {
+		if err.Error() == "user not found" {
+			respondError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to reset settings")
+		return
+	}
+
+	user, err := a.db.GetUserByID(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if user == nil {
+		respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	locale := DefaultLocale
+	if a.detectLocale != nil {
+		locale = a.detectLocale(r)
+	}
+
+	respondJSON(w, http.StatusOK, UserSettingsResponse{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Locale:   locale,
+		Theme:    DefaultTheme,
+	})
+}
+
+// HandleSetHomeBoard sets the board opened after login ({"board_id": 3}); null clears it
+func (a *AuthAPI) HandleSetHomeBoard(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		BoardID *int `json:"board_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	if err := a.db.SetHomeBoard(userID, req.BoardID); err != nil {
+		if err.Error() == "board not found" {
+			respondError(w, http.StatusNotFound, "Board not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to update home board")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{"home_board_id": req.BoardID})
+}
+
 // HandleGetUser returns the current user's information
 func (a *AuthAPI) HandleGetUser(w http.ResponseWriter, r *http.Request) {
 	userID, ok := a.sessionManager.GetUserID(r)
@@ -270,22 +518,12 @@ func (a *AuthAPI) HandleGetUser(w http.ResponseWriter, r *http.Request) {
 // AuthMiddleware checks if the user is authenticated
 func (a *AuthAPI) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		userID, ok := a.sessionManager.GetUserID(r)
+		userID, ok := a.authenticate(r)
 		if !ok {
-			// If in standalone mode, automatically authenticate as the standalone user
-			if a.isStandalone {
-				user, err := a.db.GetUserByEmail("user@standalone")
-				if err == nil && user != nil {
-					userID = user.ID
-					ok = true
-				}
-			}
-
-			if !ok {
-				respondError(w, http.StatusUnauthorized, "Authentication required")
-				return
-			}
+			respondError(w, http.StatusUnauthorized, "Authentication required")
+			return
 		}
+		a.sessionManager.Touch(w, r)
 
 		// Add user ID to context
 		ctx := r.Context()
@@ -294,6 +532,42 @@ func (a *AuthAPI) AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// OptionalAuthMiddleware adds the user ID to the context when the request is
+// authenticated, but lets anonymous requests through. Handlers behind it must
+// decide for themselves what an anonymous caller may see.
+func (a *AuthAPI) OptionalAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID, ok := a.authenticate(r); ok {
+			r = r.WithContext(setUserID(r.Context(), userID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequestUserID resolves the user for a request without requiring authentication,
+// for middleware that runs outside AuthMiddleware
+func (a *AuthAPI) RequestUserID(r *http.Request) (int, bool) {
+	return a.authenticate(r)
+}
+
+// authenticate resolves the user for a request from its session, falling back
+// to the standalone user in standalone mode
+func (a *AuthAPI) authenticate(r *http.Request) (int, bool) {
+	if userID, ok := a.sessionManager.GetUserID(r); ok {
+		return userID, true
+	}
+
+	// If in standalone mode, automatically authenticate as the standalone user
+	if a.isStandalone {
+		user, err := a.db.GetUserByEmail("user@standalone")
+		if err == nil && user != nil {
+			return user.ID, true
+		}
+	}
+
+	return 0, false
+}
+
 // HandleOAuthLogin redirects the user to the OAuth2 provider
 func (a *AuthAPI) HandleOAuthLogin(w http.ResponseWriter, r *http.Request) {
 	// Generate random state for CSRF protection
@@ -303,8 +577,8 @@ func (a *AuthAPI) HandleOAuthLogin(w http.ResponseWriter, r *http.Request) {
 	session, _ := a.sessionManager.GetSession(r)
 	session.Values["oauth_state"] = state
 	if err := a.sessionManager.SaveSession(w, r, session); err != nil {
-		log.Printf("Failed to save oauth state to session: %v", err)
-		http.Error(w, "Failed to initiate login", http.StatusInternalServerError)
+		a.logger.Error("failed to save oauth state to session", "error", err)
+		renderOAuthError(w, http.StatusInternalServerError, "Failed to initiate login")
 		return
 	}
 
@@ -317,114 +591,235 @@ func (a *AuthAPI) HandleOAuthLogin(w http.ResponseWriter, r *http.Request) {
 func (a *AuthAPI) HandleOAuthCallback(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	// A host mismatch usually means OAUTH2_REDIRECT_URL is misconfigured, which
+	// surfaces as a confusing missing-state error below since the cookie is host-bound
+	if redirectHost := a.oauthClient.RedirectHost(); redirectHost != "" {
+		requestHost := r.Host
+		if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			requestHost = strings.TrimSpace(first)
+		}
+		if !strings.EqualFold(requestHost, redirectHost) {
+			a.logger.Warn("OAuth callback host does not match OAUTH2_REDIRECT_URL", "request_host", requestHost, "redirect_host", redirectHost)
+		}
+	}
+
 	// Get state from session
 	session, _ := a.sessionManager.GetSession(r)
 	expectedState, ok := session.Values["oauth_state"].(string)
 	if !ok || expectedState == "" {
-		http.Error(w, "Invalid session state", http.StatusBadRequest)
+		renderOAuthError(w, http.StatusBadRequest, "Your login session expired or was started in another browser. Please sign in again.")
 		return
 	}
 
 	// Verify state parameter (CSRF protection)
 	actualState := r.URL.Query().Get("state")
 	if actualState != expectedState {
-		http.Error(w, "Invalid state parameter", http.StatusBadRequest)
+		renderOAuthError(w, http.StatusBadRequest, "Invalid state parameter")
 		return
 	}
 
 	// Exchange authorization code for token
 	code := r.URL.Query().Get("code")
 	if code == "" {
-		http.Error(w, "Missing authorization code", http.StatusBadRequest)
+		renderOAuthError(w, http.StatusBadRequest, "Missing authorization code")
 		return
 	}
 
 	token, err := a.oauthClient.Exchange(ctx, code)
 	if err != nil {
-		log.Printf("Failed to exchange token: %v", err)
-		http.Error(w, "Failed to exchange authorization code", http.StatusInternalServerError)
+		a.logger.Error("failed to exchange token", "error", err)
+		renderOAuthError(w, http.StatusInternalServerError, "Failed to exchange authorization code")
 		return
 	}
 
 	// Extract ID token
 	rawIDToken, ok := token.Extra("id_token").(string)
 	if !ok {
-		log.Printf("No id_token in token response. Token type: %T, Available extras: %+v", token.Extra("id_token"), token)
-		http.Error(w, "No id_token in response", http.StatusInternalServerError)
+		a.logger.Error("no id_token in token response", "type", fmt.Sprintf("%T", token.Extra("id_token")))
+		renderOAuthError(w, http.StatusInternalServerError, "No id_token in response")
 		return
 	}
 
-	log.Printf("DEBUG: Raw ID token length: %d, first 50 chars: %s", len(rawIDToken), rawIDToken[:min(50, len(rawIDToken))])
+	a.logger.Debug("received ID token", "length", len(rawIDToken))
 
 	// Verify ID token
 	idToken, err := a.oauthClient.VerifyIDToken(ctx, rawIDToken)
 	if err != nil {
-		log.Printf("Failed to verify ID token: %v", err)
-		http.Error(w, "Failed to verify ID token", http.StatusInternalServerError)
+		a.logger.Error("failed to verify ID token", "error", err)
+		renderOAuthError(w, http.StatusInternalServerError, "Failed to verify ID token")
 		return
 	}
 
 	// Extract user info from claims
 	userInfo, err := a.oauthClient.GetUserInfo(ctx, idToken)
 	if err != nil {
-		log.Printf("Failed to extract user info: %v", err)
-		http.Error(w, "Failed to extract user info", http.StatusInternalServerError)
+		a.logger.Error("failed to extract user info", "error", err)
+		renderOAuthError(w, http.StatusInternalServerError, "Failed to extract user info")
 		return
 	}
 
 	// Email is required
 	if userInfo.Email == "" {
-		http.Error(w, "No email in token claims", http.StatusBadRequest)
+		renderOAuthError(w, http.StatusBadRequest, "No email in token claims")
+		return
+	}
+
+	// The subject identifies the account, so a token without one can't be linked safely
+	if userInfo.Sub == "" {
+		a.logger.Error("no sub in token claims", "email", userInfo.Email)
+		renderOAuthError(w, http.StatusBadRequest, "No subject (sub) in token claims")
 		return
 	}
 
 	// Get or create user (auto-provisioning)
 	user, err := a.provisionUser(userInfo)
+	if errors.Is(err, errAccountNotProvisioned) {
+		a.logger.Info("refused OAuth login for unprovisioned account", "email", userInfo.Email)
+		renderOAuthError(w, http.StatusForbidden, "No account has been provisioned for "+userInfo.Email+". Ask an administrator to create one.")
+		return
+	}
+	if errors.Is(err, errEmailLinkedElsewhere) {
+		a.logger.Warn("refused OAuth login for email linked to another identity", "email", userInfo.Email)
+		renderOAuthError(w, http.StatusConflict, "The account for "+userInfo.Email+" is linked to a different identity or uses password sign-in. Ask an administrator for help.")
+		return
+	}
 	if err != nil {
-		log.Printf("Failed to provision user: %v", err)
-		http.Error(w, "Failed to provision user", http.StatusInternalServerError)
+		a.logger.Error("failed to provision user", "error", err)
+		renderOAuthError(w, http.StatusInternalServerError, "Failed to provision user")
 		return
 	}
 
-	// Create session
-	delete(session.Values, "oauth_state") // Clear state
-	session.Values["user_id"] = user.ID
-	if err := a.sessionManager.SaveSession(w, r, session); err != nil {
-		log.Printf("Failed to create session: %v", err)
-		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+	if err := a.syncEmailVerified(user, userInfo); err != nil {
+		a.logger.Error("failed to sync email verification", "user_id", user.ID, "error", err)
+		renderOAuthError(w, http.StatusInternalServerError, "Failed to provision user")
+		return
+	}
+
+	if err := a.syncAdminFromGroups(user, userInfo.Groups); err != nil {
+		a.logger.Error("failed to sync admin flag", "user_id", user.ID, "error", err)
+		renderOAuthError(w, http.StatusInternalServerError, "Failed to provision user")
+		return
+	}
+
+	// Create session; it replaces the login session, so the state goes with it
+	delete(session.Values, "oauth_state")
+	if err := a.sessionManager.CreateSession(w, r, user.ID); err != nil {
+		a.logger.Error("failed to create session", "error", err)
+		renderOAuthError(w, http.StatusInternalServerError, "Failed to create session")
 		return
 	}
 
 	// Redirect to app
-	http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+	http.Redirect(w, r, a.basePath+"/", http.StatusTemporaryRedirect)
 }
 
-// provisionUser gets existing user or creates new one with default board
+// provisionUser gets existing user or, when auto-provisioning is enabled, creates new one with default board
 func (a *AuthAPI) provisionUser(userInfo *oauth.UserInfo) (*models.User, error) {
-	// Try to get existing user by email
-	user, err := a.db.GetUserByEmail(userInfo.Email)
+	// Prefer the account linked to this identity, since emails can change upstream
+	user, err := a.db.GetUserByOAuthSub(oauthProvider, userInfo.Sub)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		if user.Email != userInfo.Email {
+			// Keep the old address if the new one belongs to another account; the login still succeeds
+			if err := a.db.UpdateUserEmail(user.ID, userInfo.Email); err != nil {
+				a.logger.Warn("failed to update email from OAuth claims", "user_id", user.ID, "error", err)
+			} else {
+				a.logger.Info("updated email from OAuth claims", "user_id", user.ID, "email", userInfo.Email)
+				user.Email = userInfo.Email
+			}
+		}
+		return user, nil
+	}
+
+	// Fall back to the email only for accounts provisioned for OAuth with no identity
+	// linked yet, then link this one so later logins survive an email change. Password
+	// accounts are never linked: their email may be self-set and unverified.
+	user, err = a.db.GetUserByEmail(userInfo.Email)
 	if err == nil {
-		// User exists, return it
+		if user.OAuthSub != nil || user.OAuthProvider == nil || user.PasswordHash != "" {
+			return nil, errEmailLinkedElsewhere
+		}
+		if err := a.db.LinkOAuthSub(user.ID, oauthProvider, userInfo.Sub); err != nil {
+			return nil, err
+		}
+		provider, sub := oauthProvider, userInfo.Sub
+		user.OAuthProvider, user.OAuthSub = &provider, &sub
 		return user, nil
 	}
 
+	if !a.autoProvision {
+		return nil, errAccountNotProvisioned
+	}
+
 	// User doesn't exist, create new user
-	provider := "authentik"
-	user, err = a.db.CreateOAuthUser(userInfo.Email, provider, userInfo.Sub)
+	user, err = a.db.CreateOAuthUser(userInfo.Email, oauthProvider, userInfo.Sub)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Printf("Created new user via OAuth2: %s (ID: %d)", userInfo.Email, user.ID)
+	a.logger.Info("created new user via OAuth2", "email", userInfo.Email, "user_id", user.ID)
 
 	// Create default board for new user
-	if _, err := a.db.CreateBoard(user.ID, "My Bookmarks", true); err != nil {
-		log.Printf("Warning: failed to create default board for user %d: %v", user.ID, err)
+	board, err := a.db.CreateBoard(user.ID, "My Bookmarks", true)
+	if err != nil {
+		a.logger.Warn("failed to create default board", "user_id", user.ID, "error", err)
+	} else if err := a.db.SeedBoard(user.ID, board.ID, a.newUserTemplate); err != nil {
+		a.logger.Warn("failed to seed default board", "user_id", user.ID, "error", err)
 	}
 
 	return user, nil
 }
 
+// syncEmailVerified records whether the provider verified the user's email, so only
+// verified addresses are trusted for admin access. An address the claims didn't set
+// (one kept because it belongs to another account) is left unverified.
+func (a *AuthAPI) syncEmailVerified(user *models.User, userInfo *oauth.UserInfo) error {
+	verified := userInfo.EmailVerified && strings.EqualFold(user.Email, userInfo.Email)
+	if user.EmailVerified == verified {
+		return nil
+	}
+
+	if err := a.db.SetEmailVerified(user.ID, verified); err != nil {
+		return err
+	}
+	user.EmailVerified = verified
+	return nil
+}
+
+// syncAdminFromGroups grants or revokes admin based on membership of the configured
+// admin group. It runs on every login so removing the group revokes admin.
+func (a *AuthAPI) syncAdminFromGroups(user *models.User, groups []string) error {
+	if a.adminGroup == "" {
+		return nil
+	}
+
+	isAdmin := slices.Contains(groups, a.adminGroup)
+	if user.IsAdmin == isAdmin {
+		return nil
+	}
+
+	if err := a.db.SetUserAdmin(user.ID, isAdmin); err != nil {
+		return err
+	}
+	user.IsAdmin = isAdmin
+	a.logger.Info("updated admin flag from OAuth groups", "user_id", user.ID, "is_admin", isAdmin)
+	return nil
+}
+
+// oauthProvider is the provider name recorded on accounts created through OAuth2 login
+const oauthProvider = "authentik"
+
+// errAccountNotProvisioned is returned by provisionUser when auto-provisioning is
+// disabled and no account exists for the authenticated email
+var errAccountNotProvisioned = errors.New("account not provisioned")
+
+// errEmailLinkedElsewhere is returned by provisionUser when the email belongs to an
+// account already linked to a different OAuth identity, or to a password account
+var errEmailLinkedElsewhere = errors.New("email linked to another identity")
+
 // generateRandomState generates a random state string for OAuth2 CSRF protection
 func generateRandomState() string {
 	b := make([]byte, 32)
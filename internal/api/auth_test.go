@@ -0,0 +1,371 @@
+package api
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crueber/loom/internal/auth"
+	"github.com/crueber/loom/internal/oauth"
+)
+
+func TestHandleRegister_DisabledReturnsForbidden(t *testing.T) {
+	database := newBoardsTestDB(t)
+	authAPI := NewAuthAPI(database, nil, nil, false, false, true, "", nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewBufferString(`{"username":"newuser","password":"password123"}`))
+	rec := httptest.NewRecorder()
+
+	authAPI.HandleRegister(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusForbidden, rec.Body.String())
+	}
+	if user, err := database.GetUserByUsername("newuser"); err != nil || user != nil {
+		t.Fatalf("GetUserByUsername() = %v, %v, want no user", user, err)
+	}
+}
+
+func TestHandleRegister_CountsUsernameCharacters(t *testing.T) {
+	database := newBoardsTestDB(t)
+	key := []byte("0123456789abcdef0123456789abcdef")
+	authAPI := NewAuthAPI(database, auth.NewSessionManager(key, key, 3600, false, nil), nil, false, true, true, "", nil)
+
+	tests := []struct {
+		name     string
+		username string
+		wantCode int
+	}{
+		{name: "two CJK characters", username: "山田", wantCode: http.StatusBadRequest},
+		{name: "three CJK characters", username: "山田太", wantCode: http.StatusCreated},
+		{name: "50 emoji", username: strings.Repeat("😀", 50), wantCode: http.StatusCreated},
+		{name: "51 CJK characters", username: strings.Repeat("界", 51), wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		body, err := json.Marshal(map[string]string{"username": tt.username, "password": "password123"})
+		if err != nil {
+			t.Fatalf("marshal request body: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		authAPI.HandleRegister(rec, req)
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d, body=%s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+		}
+	}
+}
+
+func TestProvisionUser_AutoProvisionDisabled(t *testing.T) {
+	database := newBoardsTestDB(t)
+	authAPI := NewAuthAPI(database, nil, nil, false, false, false, "", nil)
+
+	if _, err := authAPI.provisionUser(&oauth.UserInfo{Email: "new@example.com", Sub: "new"}); !errors.Is(err, errAccountNotProvisioned) {
+		t.Fatalf("provisionUser() error = %v, want errAccountNotProvisioned", err)
+	}
+	if _, err := database.GetUserByEmail("new@example.com"); err == nil {
+		t.Fatalf("unprovisioned user was created")
+	}
+
+	existing, err := database.CreateOAuthUser("known@example.com", "authentik", "")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	user, err := authAPI.provisionUser(&oauth.UserInfo{Email: "known@example.com", Sub: "known"})
+	if err != nil {
+		t.Fatalf("provisionUser() error = %v", err)
+	}
+	if user.ID != existing.ID {
+		t.Fatalf("provisionUser() = user %d, want %d", user.ID, existing.ID)
+	}
+}
+
+func TestProvisionUser_MatchesOAuthSubject(t *testing.T) {
+	database := newBoardsTestDB(t)
+	authAPI := NewAuthAPI(database, nil, nil, false, false, true, "", nil)
+
+	user, err := authAPI.provisionUser(&oauth.UserInfo{Email: "old@example.com", Sub: "subject-1"})
+	if err != nil {
+		t.Fatalf("provisionUser() error = %v", err)
+	}
+
+	again, err := authAPI.provisionUser(&oauth.UserInfo{Email: "new@example.com", Sub: "subject-1"})
+	if err != nil {
+		t.Fatalf("provisionUser() after email change error = %v", err)
+	}
+	if again.ID != user.ID || again.Email != "new@example.com" {
+		t.Fatalf("provisionUser() after email change = user %d (%s), want %d with the new email", again.ID, again.Email, user.ID)
+	}
+	if stored, err := database.GetUserByOAuthSub(oauthProvider, "subject-1"); err != nil || stored.Email != "new@example.com" {
+		t.Fatalf("stored user = %v, %v, want the email updated", stored, err)
+	}
+
+	// A pre-provisioned account is matched by email once, then linked to the identity
+	legacy, err := database.CreateOAuthUser("legacy@example.com", oauthProvider, "")
+	if err != nil {
+		t.Fatalf("create legacy user: %v", err)
+	}
+	linked, err := authAPI.provisionUser(&oauth.UserInfo{Email: "legacy@example.com", Sub: "subject-2"})
+	if err != nil || linked.ID != legacy.ID {
+		t.Fatalf("provisionUser() for legacy account = %v, %v, want user %d", linked, err, legacy.ID)
+	}
+	if stored, err := database.GetUserByOAuthSub(oauthProvider, "subject-2"); err != nil || stored == nil || stored.ID != legacy.ID {
+		t.Fatalf("GetUserByOAuthSub() = %v, %v, want the legacy account linked", stored, err)
+	}
+
+	// An email already linked to another identity is not taken over
+	if _, err := authAPI.provisionUser(&oauth.UserInfo{Email: "legacy@example.com", Sub: "subject-3"}); !errors.Is(err, errEmailLinkedElsewhere) {
+		t.Fatalf("provisionUser() for linked email error = %v, want errEmailLinkedElsewhere", err)
+	}
+
+	// Nor is a password account whose email was set (unverified) by its owner
+	local, err := database.CreateUser("local", "hash")
+	if err != nil {
+		t.Fatalf("create local user: %v", err)
+	}
+	if err := database.UpdateUserEmail(local.ID, "victim@example.com"); err != nil {
+		t.Fatalf("set local email: %v", err)
+	}
+	if _, err := authAPI.provisionUser(&oauth.UserInfo{Email: "victim@example.com", Sub: "subject-4"}); !errors.Is(err, errEmailLinkedElsewhere) {
+		t.Fatalf("provisionUser() for password account email error = %v, want errEmailLinkedElsewhere", err)
+	}
+	if stored, err := database.GetUserByOAuthSub(oauthProvider, "subject-4"); err != nil || stored != nil {
+		t.Fatalf("GetUserByOAuthSub() = %v, %v, want the password account left unlinked", stored, err)
+	}
+	if err := database.LinkOAuthSub(local.ID, oauthProvider, "subject-4"); err == nil {
+		t.Fatalf("LinkOAuthSub() linked a password account")
+	}
+
+	if _, err := database.CreateOAuthUser("other@example.com", oauthProvider, "subject-1"); err == nil {
+		t.Fatalf("CreateOAuthUser() with a duplicate subject succeeded")
+	}
+	for _, email := range []string{"first@example.com", "second@example.com"} {
+		if _, err := database.CreateOAuthUser(email, oauthProvider, ""); err != nil {
+			t.Fatalf("CreateOAuthUser(%s) without a subject error = %v", email, err)
+		}
+	}
+}
+
+// newTestOIDCProvider serves the discovery document, signing keys and token endpoint of
+// an OIDC provider whose token endpoint issues an ID token for subject and email
+func newTestOIDCProvider(t *testing.T, clientID, subject, email string) *httptest.Server {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                server.URL,
+			"authorization_endpoint":                server.URL + "/authorize",
+			"token_endpoint":                        server.URL + "/token",
+			"jwks_uri":                              server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "alg": "RS256", "use": "sig", "kid": "test",
+			"n": b64(key.N.Bytes()),
+			"e": b64(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+		claims, _ := json.Marshal(map[string]any{
+			"iss": server.URL, "aud": clientID, "sub": subject, "email": email,
+			"iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix(),
+		})
+		signingInput := b64(header) + "." + b64(claims)
+		digest := sha256.Sum256([]byte(signingInput))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access", "token_type": "Bearer", "expires_in": 3600,
+			"id_token": signingInput + "." + b64(signature),
+		})
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHandleOAuthCallback_CreatesRecordedSession(t *testing.T) {
+	database := newBoardsTestDB(t)
+	provider := newTestOIDCProvider(t, "loom", "subject-1", "oauth@example.com")
+	oauthClient, err := oauth.NewClient(provider.URL, "loom", "secret", "http://example.com/auth/callback", "")
+	if err != nil {
+		t.Fatalf("create oauth client: %v", err)
+	}
+	key := []byte("0123456789abcdef0123456789abcdef")
+	sessionManager := auth.NewSessionManager(key, key, 3600, false, nil)
+	sessionManager.SetStore(database)
+	authAPI := NewAuthAPI(database, sessionManager, oauthClient, false, false, true, "", nil)
+
+	rec := httptest.NewRecorder()
+	authAPI.HandleOAuthLogin(rec, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("parse login redirect: %v", err)
+	}
+	state := location.Query().Get("state")
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/callback?code=code&state="+url.QueryEscape(state), nil)
+	for _, cookie := range rec.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	rec = httptest.NewRecorder()
+	authAPI.HandleOAuthCallback(rec, req)
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("callback status = %d, want %d, body=%s", rec.Code, http.StatusTemporaryRedirect, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/user", nil)
+	for _, cookie := range rec.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	userID, ok := sessionManager.GetUserID(req)
+	if !ok {
+		t.Fatalf("GetUserID() after the callback failed, want the new session accepted")
+	}
+	if user, err := database.GetUserByOAuthSub(oauthProvider, "subject-1"); err != nil || user == nil || user.ID != userID {
+		t.Fatalf("session user = %d, want the provisioned user %v (%v)", userID, user, err)
+	}
+}
+
+func TestSyncAdminFromGroups_GrantsAndRevokes(t *testing.T) {
+	database := newBoardsTestDB(t)
+	authAPI := NewAuthAPI(database, nil, nil, false, false, true, "loom-admins", nil)
+
+	user, err := database.CreateOAuthUser("admin@example.com", "authentik", "sub")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	for _, tc := range []struct {
+		groups []string
+		want   bool
+	}{
+		{groups: []string{"staff", "loom-admins"}, want: true},
+		{groups: []string{"staff"}, want: false},
+	} {
+		if err := authAPI.syncAdminFromGroups(user, tc.groups); err != nil {
+			t.Fatalf("syncAdminFromGroups(%v) error = %v", tc.groups, err)
+		}
+		stored, err := database.GetUserByID(user.ID)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+		if stored.IsAdmin != tc.want {
+			t.Fatalf("groups %v: is_admin = %v, want %v", tc.groups, stored.IsAdmin, tc.want)
+		}
+	}
+}
+
+func TestHandleSetHomeBoard(t *testing.T) {
+	database := newBoardsTestDB(t)
+	authAPI := NewAuthAPI(database, nil, nil, false, false, true, "", nil)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.CreateBoard(user.ID, "Work", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	other, err := database.CreateUser("other", "hash")
+	if err != nil {
+		t.Fatalf("create other user: %v", err)
+	}
+	otherBoard, err := database.CreateBoard(other.ID, "Theirs", false)
+	if err != nil {
+		t.Fatalf("create other board: %v", err)
+	}
+
+	setHomeBoard := func(body string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/user/home-board", bytes.NewBufferString(body))
+		req = req.WithContext(setUserID(req.Context(), user.ID))
+		rec := httptest.NewRecorder()
+		authAPI.HandleSetHomeBoard(rec, req)
+		return rec.Code
+	}
+
+	if code := setHomeBoard(fmt.Sprintf(`{"board_id":%d}`, otherBoard.ID)); code != http.StatusNotFound {
+		t.Fatalf("foreign board status = %d, want %d", code, http.StatusNotFound)
+	}
+
+	if code := setHomeBoard(fmt.Sprintf(`{"board_id":%d}`, board.ID)); code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+	if id, err := database.GetHomeBoardID(user.ID); err != nil || id != board.ID {
+		t.Fatalf("GetHomeBoardID() = %d, %v, want %d", id, err, board.ID)
+	}
+
+	// Deleting the board falls back to the default board
+	if err := database.DeleteBoard(board.ID, user.ID); err != nil {
+		t.Fatalf("delete board: %v", err)
+	}
+	if id, err := database.GetHomeBoardID(user.ID); err != nil || id != 0 {
+		t.Fatalf("GetHomeBoardID() after delete = %d, %v, want 0", id, err)
+	}
+}
+
+func TestHandleResetSettings(t *testing.T) {
+	database := newBoardsTestDB(t)
+	authAPI := NewAuthAPI(database, nil, nil, false, false, true, "", nil)
+	authAPI.SetLocaleDetector(func(r *http.Request) string { return "fr" })
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if _, err := database.Exec("UPDATE users SET locale = 'xx', theme = 'light' WHERE id = ?", user.ID); err != nil {
+		t.Fatalf("set settings: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/user/settings/reset", nil)
+	req = req.WithContext(setUserID(req.Context(), user.ID))
+	rec := httptest.NewRecorder()
+	authAPI.HandleResetSettings(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var got UserSettingsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if got.Locale != "fr" || got.Theme != DefaultTheme {
+		t.Fatalf("settings = %q/%q, want fr/%s", got.Locale, got.Theme, DefaultTheme)
+	}
+
+	stored, err := database.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if stored.Locale != "" || stored.Theme != "" {
+		t.Fatalf("stored settings = %q/%q, want cleared", stored.Locale, stored.Theme)
+	}
+}
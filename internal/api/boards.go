@@ -1,61 +1,144 @@
 package api
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/crueber/loom/internal/db"
+	"github.com/crueber/loom/internal/models"
 	"github.com/go-chi/chi/v5"
 )
 
-// GetBoards returns all boards for the authenticated user
+// GetBoards returns the authenticated user's boards (?sort=recent|alpha|position, ?include_archived=true)
 func GetBoards(database *db.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := getUserID(r.Context())
 		if !ok {
-			http.Error(w, "Not authenticated", http.StatusUnauthorized)
+			respondError(w, http.StatusUnauthorized, "Not authenticated")
 			return
 		}
 
-		boards, err := database.GetBoards(userID)
+		sort := r.URL.Query().Get("sort")
+		if sort == "" {
+			sort = db.BoardSortRecent
+		}
+		if !db.IsValidBoardSort(sort) {
+			respondError(w, http.StatusBadRequest, "Invalid sort (must be 'recent', 'alpha', or 'position')")
+			return
+		}
+
+		includeArchived := r.URL.Query().Get("include_archived") == "true"
+
+		boards, err := database.GetBoardsSorted(userID, sort, includeArchived)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			respondError(w, http.StatusInternalServerError, "Failed to get boards")
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(boards)
+		respondJSON(w, http.StatusOK, boards)
 	}
 }
 
-// GetBoard returns a specific board by ID
+// boardWithLists is a board plus a summary of its lists, for navigation trees
+type boardWithLists struct {
+	*models.Board
+	Lists []listSummary `json:"lists"`
+}
+
+// listSummary carries only the list fields needed for navigation
+type listSummary struct {
+	ID       int    `json:"id"`
+	Title    string `json:"title"`
+	Position int    `json:"position"`
+}
+
+// GetBoardsWithLists returns the user's boards, each with its lists' ids, titles and positions (?include_archived=true)
+func GetBoardsWithLists(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := getUserID(r.Context())
+		if !ok {
+			respondError(w, http.StatusUnauthorized, "Not authenticated")
+			return
+		}
+
+		includeArchived := r.URL.Query().Get("include_archived") == "true"
+
+		boards, err := database.GetBoardsSortedContext(r.Context(), userID, db.BoardSortRecent, includeArchived)
+		if err != nil {
+			respondQueryError(w, err, "Failed to get boards")
+			return
+		}
+
+		// One query for every list, grouped by board (lists come back ordered by position)
+		lists, err := database.GetListsContext(r.Context(), userID)
+		if err != nil {
+			respondQueryError(w, err, "Failed to get lists")
+			return
+		}
+		listsByBoard := make(map[int][]listSummary)
+		for _, list := range lists {
+			listsByBoard[list.BoardID] = append(listsByBoard[list.BoardID], listSummary{ID: list.ID, Title: list.Title, Position: list.Position})
+		}
+
+		result := make([]boardWithLists, 0, len(boards))
+		for _, board := range boards {
+			boardLists := listsByBoard[board.ID]
+			if boardLists == nil {
+				boardLists = []listSummary{}
+			}
+			result = append(result, boardWithLists{Board: board, Lists: boardLists})
+		}
+
+		respondJSON(w, http.StatusOK, result)
+	}
+}
+
+// BoardSummary is a board's metadata with its list count, without lists or items
+type BoardSummary struct {
+	*models.Board
+	ListCount int `json:"list_count"`
+}
+
+// GetBoard returns a specific board by ID with its list count. The response carries
+// an ETag, so a repeat request with If-None-Match gets an empty 304 when nothing changed.
 func GetBoard(database *db.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := getUserID(r.Context())
 		if !ok {
-			http.Error(w, "Not authenticated", http.StatusUnauthorized)
+			respondError(w, http.StatusUnauthorized, "Not authenticated")
 			return
 		}
 		boardID, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
-			http.Error(w, "Invalid board ID", http.StatusBadRequest)
+			respondError(w, http.StatusBadRequest, "Invalid board ID")
 			return
 		}
 
 		board, err := database.GetBoardByID(boardID, userID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			respondError(w, http.StatusInternalServerError, "Failed to get board")
 			return
 		}
 
 		if board == nil {
-			http.Error(w, "Board not found", http.StatusNotFound)
+			respondError(w, http.StatusNotFound, "Board not found")
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(board)
+		listCount, err := database.CountBoardLists(boardID, userID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to get board")
+			return
+		}
+
+		respondJSONWithETag(w, r, BoardSummary{Board: board, ListCount: listCount})
 	}
 }
 
@@ -64,7 +147,7 @@ func CreateBoard(database *db.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := getUserID(r.Context())
 		if !ok {
-			http.Error(w, "Not authenticated", http.StatusUnauthorized)
+			respondError(w, http.StatusUnauthorized, "Not authenticated")
 			return
 		}
 
@@ -73,72 +156,88 @@ func CreateBoard(database *db.DB) http.HandlerFunc {
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
 		}
+		req.Title = sanitizeText(req.Title)
 
 		if req.Title == "" {
 			req.Title = "New Board"
 		}
 
-		if len(req.Title) > 100 {
-			http.Error(w, "Title must be 100 characters or less", http.StatusBadRequest)
+		if utf8.RuneCountInString(req.Title) > 100 {
+			respondError(w, http.StatusBadRequest, "Title must be 100 characters or less")
 			return
 		}
 
 		board, err := database.CreateBoard(userID, req.Title, false)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			respondError(w, http.StatusInternalServerError, "Failed to create board")
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(board)
+		respondJSON(w, http.StatusCreated, board)
 	}
 }
 
-// UpdateBoard updates a board's title
+// UpdateBoard updates a board's title and/or public read flag
 func UpdateBoard(database *db.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := getUserID(r.Context())
 		if !ok {
-			http.Error(w, "Not authenticated", http.StatusUnauthorized)
+			respondError(w, http.StatusUnauthorized, "Not authenticated")
 			return
 		}
 		boardID, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
-			http.Error(w, "Invalid board ID", http.StatusBadRequest)
+			respondError(w, http.StatusBadRequest, "Invalid board ID")
 			return
 		}
 
 		var req struct {
-			Title string `json:"title"`
+			Title      string `json:"title"`
+			PublicRead *bool  `json:"public_read,omitempty"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
 		}
+		req.Title = sanitizeText(req.Title)
 
-		if req.Title == "" {
-			http.Error(w, "Title is required", http.StatusBadRequest)
+		// Title may be omitted when only toggling public read access
+		if req.Title == "" && req.PublicRead == nil {
+			respondError(w, http.StatusBadRequest, "Title is required")
 			return
 		}
 
-		if len(req.Title) > 100 {
-			http.Error(w, "Title must be 100 characters or less", http.StatusBadRequest)
+		if utf8.RuneCountInString(req.Title) > 100 {
+			respondError(w, http.StatusBadRequest, "Title must be 100 characters or less")
 			return
 		}
 
-		err = database.UpdateBoard(boardID, userID, req.Title)
-		if err != nil {
-			if err.Error() == "board not found" {
-				http.Error(w, err.Error(), http.StatusNotFound)
+		if req.Title != "" {
+			err = database.UpdateBoard(boardID, userID, req.Title)
+			if err != nil {
+				if err.Error() == "board not found" {
+					respondError(w, http.StatusNotFound, "Board not found")
+					return
+				}
+				respondError(w, http.StatusInternalServerError, "Failed to update board")
+				return
+			}
+		}
+
+		if req.PublicRead != nil {
+			err = database.SetBoardPublicRead(boardID, userID, *req.PublicRead)
+			if err != nil {
+				if err.Error() == "board not found" {
+					respondError(w, http.StatusNotFound, "Board not found")
+					return
+				}
+				respondError(w, http.StatusInternalServerError, "Failed to update board")
 				return
 			}
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -150,26 +249,26 @@ func DeleteBoard(database *db.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := getUserID(r.Context())
 		if !ok {
-			http.Error(w, "Not authenticated", http.StatusUnauthorized)
+			respondError(w, http.StatusUnauthorized, "Not authenticated")
 			return
 		}
 		boardID, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
-			http.Error(w, "Invalid board ID", http.StatusBadRequest)
+			respondError(w, http.StatusBadRequest, "Invalid board ID")
 			return
 		}
 
 		err = database.DeleteBoard(boardID, userID)
 		if err != nil {
 			if err.Error() == "board not found" {
-				http.Error(w, err.Error(), http.StatusNotFound)
+				respondError(w, http.StatusNotFound, "Board not found")
 				return
 			}
 			if err.Error() == "cannot delete default board" {
-				http.Error(w, err.Error(), http.StatusBadRequest)
+				respondError(w, http.StatusBadRequest, "Cannot delete default board")
 				return
 			}
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			respondError(w, http.StatusInternalServerError, "Failed to delete board")
 			return
 		}
 
@@ -177,71 +276,475 @@ func DeleteBoard(database *db.DB) http.HandlerFunc {
 	}
 }
 
-// GetBoardData returns all data for a board (board info, lists, and bookmarks)
-func GetBoardData(database *db.DB) http.HandlerFunc {
+// ArchiveBoard hides a board from the board list without deleting it
+func ArchiveBoard(database *db.DB) http.HandlerFunc {
+	return setBoardArchived(database, true)
+}
+
+// UnarchiveBoard restores an archived board to the board list
+func UnarchiveBoard(database *db.DB) http.HandlerFunc {
+	return setBoardArchived(database, false)
+}
+
+// setBoardArchived returns a handler that sets a board's archived flag
+func setBoardArchived(database *db.DB, archived bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := getUserID(r.Context())
 		if !ok {
-			http.Error(w, "Not authenticated", http.StatusUnauthorized)
+			respondError(w, http.StatusUnauthorized, "Not authenticated")
 			return
 		}
 		boardID, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
-			http.Error(w, "Invalid board ID", http.StatusBadRequest)
+			respondError(w, http.StatusBadRequest, "Invalid board ID")
 			return
 		}
 
-		// Get all boards for the user (for board switcher)
-		boards, err := database.GetBoards(userID)
+		err = database.SetBoardArchived(boardID, userID, archived)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			if err.Error() == "board not found" {
+				respondError(w, http.StatusNotFound, "Board not found")
+				return
+			}
+			if err.Error() == "cannot archive default board" {
+				respondError(w, http.StatusBadRequest, "Cannot archive default board")
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to update board")
 			return
 		}
 
-		// Verify board ownership
-		board, err := database.GetBoardByID(boardID, userID)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// GetBoardData returns a board with its lists and items.
+// Anonymous callers may read boards flagged public_read.
+func GetBoardData(database *db.DB, refresher *FaviconRefresher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		boardID, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			respondError(w, http.StatusBadRequest, "Invalid board ID")
 			return
 		}
 
+		board, owned, err := readableBoard(r, database, boardID)
+		if err != nil {
+			respondQueryError(w, err, "Failed to get board")
+			return
+		}
 		if board == nil {
-			http.Error(w, "Board not found", http.StatusNotFound)
+			respondBoardNotReadable(w, r)
 			return
 		}
 
+		// Owners get all their boards for the board switcher; public readers only see this one
+		boards := []*models.Board{board}
+		if owned {
+			boards, err = database.GetBoardsContext(r.Context(), board.UserID)
+			if err != nil {
+				respondQueryError(w, err, "Failed to get boards")
+				return
+			}
+		}
+
 		// Get lists for this board
-		lists, err := database.GetListsByBoard(userID, boardID)
+		lists, err := database.GetListsByBoardContext(r.Context(), board.UserID, boardID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			respondQueryError(w, err, "Failed to get lists")
 			return
 		}
 
-		// Get items for this board only (efficient single query with JOIN)
-		items, err := database.GetItemsByBoard(userID, boardID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+		// Always send collections (never null) so the frontend needn't special-case empty boards
+		if lists == nil {
+			lists = []*models.List{}
 		}
 
-		// Group items by list ID for frontend consumption
-		filteredItems := make(map[int]any)
-		for _, item := range items {
-			if _, exists := filteredItems[item.ListID]; !exists {
-				filteredItems[item.ListID] = []any{}
+		// Get items for this board only (efficient single query with JOIN); an empty board has none
+		var items []*models.Item
+		if len(lists) > 0 {
+			items, err = database.GetItemsByBoardContext(r.Context(), board.UserID, boardID)
+			if err != nil {
+				respondQueryError(w, err, "Failed to get items")
+				return
 			}
-			listItems := filteredItems[item.ListID].([]any)
-			filteredItems[item.ListID] = append(listItems, item)
+		}
+
+		// Owners can ask for stale favicons to be re-fetched; the response doesn't wait for them
+		if owned && (refresher.Auto() || r.URL.Query().Get("refresh_stale") == "true") {
+			refresher.RefreshBoard(board.UserID, boardID)
+		}
+
+		// Public readers aren't told who owns the board
+		if !owned {
+			board, lists = anonymizeBoard(board, lists)
+			boards = []*models.Board{board}
 		}
 
 		response := map[string]any{
 			"board":  board,
 			"boards": boards,
 			"lists":  lists,
-			"items":  filteredItems,
+			"items":  groupItemsByList(items),
+		}
+
+		respondJSON(w, http.StatusOK, response)
+	}
+}
+
+// GetBoardItems returns all items for a board grouped by list ID.
+// Anonymous callers may read boards flagged public_read.
+func GetBoardItems(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		boardID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid board ID")
+			return
+		}
+
+		board, _, err := readableBoard(r, database, boardID)
+		if err != nil {
+			respondQueryError(w, err, "Failed to get board")
+			return
+		}
+		if board == nil {
+			respondBoardNotReadable(w, r)
+			return
+		}
+
+		items, err := database.GetItemsByBoardContext(r.Context(), board.UserID, boardID)
+		if err != nil {
+			respondQueryError(w, err, "Failed to get items")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, groupItemsByList(items))
+	}
+}
+
+// boardIndexEntry is one bookmark in a board index
+type boardIndexEntry struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	List  string `json:"list"`
+}
+
+// GetBoardIndex returns a flat, machine-readable index of a board's bookmarks
+// (title, URL and list title) in display order, for crawlers and simple clients.
+// Anonymous callers may read boards flagged public_read.
+func GetBoardIndex(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		boardID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid board ID")
+			return
+		}
+
+		board, _, err := readableBoard(r, database, boardID)
+		if err != nil {
+			respondQueryError(w, err, "Failed to get board")
+			return
+		}
+		if board == nil {
+			respondBoardNotReadable(w, r)
+			return
+		}
+
+		lists, err := database.GetListsByBoardContext(r.Context(), board.UserID, boardID)
+		if err != nil {
+			respondQueryError(w, err, "Failed to get lists")
+			return
+		}
+
+		var items []*models.Item
+		if len(lists) > 0 {
+			items, err = database.GetItemsByBoardContext(r.Context(), board.UserID, boardID)
+			if err != nil {
+				respondQueryError(w, err, "Failed to get items")
+				return
+			}
+		}
+		itemsByList := groupItemsByList(items)
+
+		entries := []boardIndexEntry{}
+		for _, list := range lists {
+			for _, item := range itemsByList[list.ID] {
+				if item.Type != db.ItemTypeBookmark || item.URL == nil {
+					continue
+				}
+				entry := boardIndexEntry{URL: *item.URL, List: list.Title}
+				if item.Title != nil {
+					entry.Title = *item.Title
+				}
+				entries = append(entries, entry)
+			}
+		}
+
+		respondJSON(w, http.StatusOK, map[string]any{
+			"board":     board.Title,
+			"bookmarks": entries,
+		})
+	}
+}
+
+// SortBoardLists reorders a board's lists once (?by=title|created) and returns them in their new order
+func SortBoardLists(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := getUserID(r.Context())
+		if !ok {
+			respondError(w, http.StatusUnauthorized, "Not authenticated")
+			return
+		}
+		boardID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid board ID")
+			return
+		}
+
+		by := r.URL.Query().Get("by")
+		if by == "" {
+			by = db.ListSortTitle
+		}
+		if !db.IsValidListSort(by) {
+			respondError(w, http.StatusBadRequest, "Invalid sort (must be 'title' or 'created')")
+			return
+		}
+
+		lists, err := database.SortBoardLists(boardID, userID, by)
+		if err != nil {
+			if err.Error() == "board not found" {
+				respondError(w, http.StatusNotFound, "Board not found")
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to sort lists")
+			return
+		}
+		if lists == nil {
+			lists = []*models.List{}
+		}
+
+		respondJSON(w, http.StatusOK, lists)
+	}
+}
+
+// ReorderBoardItems applies drag-and-drop positions to items on one board, allowing
+// items to move between the board's lists, and responds with the board's items grouped
+// by list. Every item and target list must already be on the board.
+func ReorderBoardItems(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := getUserID(r.Context())
+		if !ok {
+			respondError(w, http.StatusUnauthorized, "Not authenticated")
+			return
+		}
+		boardID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid board ID")
+			return
+		}
+
+		var req ReorderItemsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if len(req.Items) == 0 {
+			respondError(w, http.StatusBadRequest, "No items to reorder")
+			return
+		}
+
+		itemIDs := make([]int, 0, len(req.Items))
+		itemPositions := make(map[int]struct {
+			Position int
+			ListID   int
+		}, len(req.Items))
+		for _, item := range req.Items {
+			if _, dup := itemPositions[item.ID]; dup {
+				respondError(w, http.StatusBadRequest, fmt.Sprintf("Duplicate item ID %d", item.ID))
+				return
+			}
+			itemIDs = append(itemIDs, item.ID)
+			itemPositions[item.ID] = struct {
+				Position int
+				ListID   int
+			}{Position: item.Position, ListID: item.ListID}
+		}
+
+		board, err := database.GetBoardByID(boardID, userID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to get board")
+			return
+		}
+		if board == nil {
+			respondError(w, http.StatusNotFound, "Board not found")
+			return
+		}
+
+		lists, err := database.GetListsByBoard(userID, boardID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to get lists")
+			return
+		}
+		onBoard := make(map[int]bool, len(lists))
+		for _, list := range lists {
+			onBoard[list.ID] = true
+		}
+
+		items, err := database.GetItemsByIDs(itemIDs, userID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to get items")
+			return
+		}
+		if len(items) != len(itemIDs) {
+			respondError(w, http.StatusNotFound, "One or more items not found")
+			return
+		}
+		for _, item := range items {
+			if !onBoard[item.ListID] || !onBoard[itemPositions[item.ID].ListID] {
+				respondError(w, http.StatusBadRequest, "All items and target lists must be on this board")
+				return
+			}
+		}
+
+		if err := database.UpdateItemPositions(itemPositions); err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to update item positions")
+			return
+		}
+
+		boardItems, err := database.GetItemsByBoard(userID, boardID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to get items")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, groupItemsByList(boardItems))
+	}
+}
+
+// GetBoardMissingFavicons returns the board's bookmarks whose favicon is missing or an unusable data URI
+func GetBoardMissingFavicons(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := getUserID(r.Context())
+		if !ok {
+			respondError(w, http.StatusUnauthorized, "Not authenticated")
+			return
+		}
+		boardID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid board ID")
+			return
+		}
+
+		owns, err := database.VerifyBoardOwnership(boardID, userID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to verify board ownership")
+			return
+		}
+		if !owns {
+			respondError(w, http.StatusNotFound, "Board not found")
+			return
+		}
+
+		items, err := database.GetItemsByBoardContext(r.Context(), userID, boardID)
+		if err != nil {
+			respondQueryError(w, err, "Failed to get items")
+			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		missing := []*models.Item{}
+		for _, item := range items {
+			if item.Type == "bookmark" && faviconLooksBroken(item.FaviconURL) {
+				missing = append(missing, item)
+			}
+		}
+
+		respondJSON(w, http.StatusOK, missing)
+	}
+}
+
+// faviconLooksBroken reports whether a stored favicon is absent or a data URI that cannot render
+func faviconLooksBroken(faviconURL *string) bool {
+	if faviconURL == nil || strings.TrimSpace(*faviconURL) == "" {
+		return true
+	}
+	if !strings.HasPrefix(*faviconURL, "data:") {
+		return false
+	}
+
+	meta, payload, found := strings.Cut(strings.TrimPrefix(*faviconURL, "data:"), ",")
+	if !found || payload == "" {
+		return true
+	}
+	if strings.HasSuffix(meta, ";base64") {
+		if _, err := base64.StdEncoding.DecodeString(payload); err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// readableBoard returns the board a request may read: one of the caller's own
+// boards, or any board flagged public_read. owned reports which case applied.
+// Returns a nil board if the request may not read it.
+func readableBoard(r *http.Request, database *db.DB, boardID int) (board *models.Board, owned bool, err error) {
+	if userID, ok := getUserID(r.Context()); ok {
+		board, err = database.GetBoardByIDContext(r.Context(), boardID, userID)
+		if err != nil || board != nil {
+			return board, board != nil, err
+		}
+	}
+
+	board, err = database.GetPublicBoardContext(r.Context(), boardID)
+	return board, false, err
+}
+
+// anonymizeBoard returns copies of a board and its lists without the owner's user ID,
+// for responses to readers who don't own the board
+func anonymizeBoard(board *models.Board, lists []*models.List) (*models.Board, []*models.List) {
+	public := *board
+	public.UserID = 0
+	publicLists := make([]*models.List, len(lists))
+	for i, list := range lists {
+		publicList := *list
+		publicList.UserID = 0
+		publicLists[i] = &publicList
+	}
+	return &public, publicLists
+}
+
+// respondBoardNotReadable reports a board the caller may not read: anonymous
+// callers are asked to authenticate, authenticated ones get a 404
+func respondBoardNotReadable(w http.ResponseWriter, r *http.Request) {
+	if _, ok := getUserID(r.Context()); !ok {
+		respondError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	respondError(w, http.StatusNotFound, "Board not found")
+}
+
+// respondQueryError reports a failed database read, using 503 when the query timeout cut it off
+func respondQueryError(w http.ResponseWriter, err error, message string) {
+	if status := queryErrorStatus(err); status == http.StatusServiceUnavailable {
+		respondError(w, status, "Request timed out")
+		return
+	}
+	respondError(w, http.StatusInternalServerError, message)
+}
+
+// queryErrorStatus maps a database error to a response status, reporting
+// queries cut off by the query timeout as 503 rather than a generic 500
+func queryErrorStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// groupItemsByList groups items by their list ID for frontend consumption
+func groupItemsByList(items []*models.Item) map[int][]*models.Item {
+	itemsByList := make(map[int][]*models.Item)
+	for _, item := range items {
+		itemsByList[item.ListID] = append(itemsByList[item.ListID], item)
 	}
+	return itemsByList
 }
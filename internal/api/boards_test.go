@@ -0,0 +1,812 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crueber/loom/internal/db"
+	"github.com/crueber/loom/internal/favicon"
+	"github.com/crueber/loom/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestGetBoardData_AnonymousReadRequiresPublicBoard(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.CreateBoard(user.ID, "Kiosk", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+
+	if rec := performGetBoardData(t, database, board.ID, 0); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("private board status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	if err := database.SetBoardPublicRead(board.ID, user.ID, true); err != nil {
+		t.Fatalf("set public read: %v", err)
+	}
+
+	if _, err := database.CreateList(user.ID, board.ID, "Links", "#ffffff", 0, false); err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+
+	rec := performGetBoardData(t, database, board.ID, 0)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("public board status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "user_id") {
+		t.Fatalf("public board body = %s, want no owner IDs", rec.Body.String())
+	}
+	if rec := performGetBoardData(t, database, board.ID, user.ID); !strings.Contains(rec.Body.String(), `"user_id":`) {
+		t.Fatalf("owner body = %s, want owner IDs kept", rec.Body.String())
+	}
+
+	other, err := database.CreateUser("other", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if rec := performGetBoardData(t, database, board.ID, other.ID); rec.Code != http.StatusOK {
+		t.Fatalf("public board for other user status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestGetBoardData_EmptyBoardReturnsEmptyCollections(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.CreateBoard(user.ID, "Empty", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+
+	rec := performGetBoardData(t, database, board.ID, user.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if got := string(body["lists"]); got != "[]" {
+		t.Fatalf("lists = %s, want []", got)
+	}
+	if got := string(body["items"]); got != "{}" {
+		t.Fatalf("items = %s, want {}", got)
+	}
+}
+
+func TestArchiveBoard_HidesBoardUntilUnarchived(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	defaultBoard, err := database.GetDefaultBoard(user.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	board, err := database.CreateBoard(user.ID, "Old Project", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+
+	if rec := performBoardAction(t, ArchiveBoard(database), defaultBoard.ID, user.ID); rec.Code != http.StatusBadRequest {
+		t.Fatalf("archive default board status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := performBoardAction(t, ArchiveBoard(database), board.ID, user.ID); rec.Code != http.StatusOK {
+		t.Fatalf("archive status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	boards, err := database.GetBoards(user.ID)
+	if err != nil {
+		t.Fatalf("get boards: %v", err)
+	}
+	if len(boards) != 1 || boards[0].ID != defaultBoard.ID {
+		t.Fatalf("boards = %+v, want only the default board", boards)
+	}
+
+	boards, err = database.GetBoardsSorted(user.ID, db.BoardSortRecent, true)
+	if err != nil {
+		t.Fatalf("get boards including archived: %v", err)
+	}
+	if len(boards) != 2 {
+		t.Fatalf("len(boards) including archived = %d, want 2", len(boards))
+	}
+
+	if rec := performBoardAction(t, UnarchiveBoard(database), board.ID, user.ID); rec.Code != http.StatusOK {
+		t.Fatalf("unarchive status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if boards, err = database.GetBoards(user.ID); err != nil || len(boards) != 2 {
+		t.Fatalf("boards after unarchive = %d (err %v), want 2", len(boards), err)
+	}
+}
+
+func TestGetBoardsWithLists(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.GetDefaultBoard(user.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	empty, err := database.CreateBoard(user.ID, "Empty", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	for i, title := range []string{"First", "Second"} {
+		if _, err := database.CreateList(user.ID, board.ID, title, "#ffffff", i, false); err != nil {
+			t.Fatalf("create list: %v", err)
+		}
+	}
+
+	rec := performBoardAction(t, GetBoardsWithLists(database), 0, user.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var got []struct {
+		ID    int `json:"id"`
+		Lists []struct {
+			Title    string `json:"title"`
+			Position int    `json:"position"`
+		} `json:"lists"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal boards: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("len(boards) = %d, want 2", len(got))
+	}
+	for _, b := range got {
+		switch b.ID {
+		case board.ID:
+			if len(b.Lists) != 2 || b.Lists[0].Title != "First" || b.Lists[1].Position != 1 {
+				t.Fatalf("default board lists = %+v, want First then Second", b.Lists)
+			}
+		case empty.ID:
+			if b.Lists == nil || len(b.Lists) != 0 {
+				t.Fatalf("empty board lists = %+v, want []", b.Lists)
+			}
+		}
+	}
+}
+
+func TestBoardHandlers_ReturnJSONErrors(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	rec := performBoardAction(t, GetBoard(database), 9999, user.ID)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected application/json, got %q", ct)
+	}
+
+	var body map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode error body: %v", err)
+	}
+	if body["error"] != "Board not found" {
+		t.Fatalf("expected error %q, got %q", "Board not found", body["error"])
+	}
+}
+
+func TestGetBoardIndex_PublicBoardOnly(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.CreateBoard(user.ID, "Links", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	list, err := database.CreateList(user.ID, board.ID, "Docs", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	title := "Go"
+	link := "https://go.dev"
+	if _, err := database.CreateItem(list.ID, "bookmark", &title, &link, nil, nil, "auto", nil, "markdown", 0, true); err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	content := "not indexed"
+	if _, err := database.CreateItem(list.ID, "note", nil, nil, &content, nil, "auto", nil, "markdown", 1, true); err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	getIndex := func() *httptest.ResponseRecorder {
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("id", strconv.Itoa(board.ID))
+		ctx := context.WithValue(context.Background(), chi.RouteCtxKey, routeCtx)
+		req := httptest.NewRequest(http.MethodGet, "/api/boards/"+strconv.Itoa(board.ID)+"/index.json", nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		GetBoardIndex(database)(rec, req)
+		return rec
+	}
+
+	if rec := getIndex(); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("private board status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	if err := database.SetBoardPublicRead(board.ID, user.ID, true); err != nil {
+		t.Fatalf("set public read: %v", err)
+	}
+	rec := getIndex()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("public board status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var body struct {
+		Board     string            `json:"board"`
+		Bookmarks []boardIndexEntry `json:"bookmarks"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	want := boardIndexEntry{Title: "Go", URL: "https://go.dev", List: "Docs"}
+	if body.Board != "Links" || len(body.Bookmarks) != 1 || body.Bookmarks[0] != want {
+		t.Fatalf("index = %+v, want board Links with %+v", body, want)
+	}
+}
+
+func TestGetBoardStartPage(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.CreateBoard(user.ID, "Home <Page>", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	list, err := database.CreateList(user.ID, board.ID, "Daily", "#3D6D95", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	title := "Example"
+	link := "https://example.com/"
+	icon := "data:image/png;base64,aGVsbG8="
+	if _, err := database.CreateItem(list.ID, "bookmark", &title, &link, nil, &icon, "auto", nil, "markdown", 0, true); err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	evil := "javascript:alert(1)"
+	if _, err := database.CreateItem(list.ID, "bookmark", &title, &evil, nil, nil, "auto", nil, "markdown", 1, true); err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	store, err := favicon.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	hash, err := store.Put([]byte("stored"), "image/png")
+	if err != nil {
+		t.Fatalf("store icon: %v", err)
+	}
+	storedTitle := "Stored"
+	storedIcon := favicon.StoredIconPath + hash
+	if _, err := database.CreateItem(list.ID, "bookmark", &storedTitle, &link, nil, &storedIcon, "auto", nil, "markdown", 2, true); err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	// Without the store the icon can't be inlined, so it is linked under the base path
+	missingTitle := "Missing"
+	missingIcon := favicon.StoredIconPath + strings.Repeat("0", 64)
+	if _, err := database.CreateItem(list.ID, "bookmark", &missingTitle, &link, nil, &missingIcon, "auto", nil, "markdown", 3, true); err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	rec := performBoardAction(t, GetBoardStartPage(database, store, "/loom"), board.ID, user.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	page := rec.Body.String()
+	for _, want := range []string{
+		"<title>Home &lt;Page&gt;</title>",
+		`<section style="border-top-color: #3D6D95">`,
+		`<a href="https://example.com/"><img src="data:image/png;base64,aGVsbG8=" alt="">Example</a>`,
+		`<a href="https://example.com/"><img src="data:image/png;base64,c3RvcmVk" alt="">Stored</a>`,
+		`<a href="https://example.com/"><img src="/loom/api/favicons/` + strings.Repeat("0", 64) + `" alt="">Missing</a>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("page missing %q:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "<script") || strings.Contains(page, "javascript:") {
+		t.Fatalf("page contains script content:\n%s", page)
+	}
+
+	other, err := database.CreateUser("other", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if rec := performBoardAction(t, GetBoardStartPage(database, nil, ""), board.ID, other.ID); rec.Code != http.StatusNotFound {
+		t.Fatalf("other user status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestReorderBoardItems(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.CreateBoard(user.ID, "Home", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	otherBoard, err := database.CreateBoard(user.ID, "Work", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	todo, err := database.CreateList(user.ID, board.ID, "Todo", "#3D6D95", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	done, err := database.CreateList(user.ID, board.ID, "Done", "#3D6D95", 1, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	elsewhere, err := database.CreateList(user.ID, otherBoard.ID, "Elsewhere", "#3D6D95", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	content := "note"
+	first, err := database.CreateItem(todo.ID, "note", nil, nil, &content, nil, "auto", nil, "markdown", 0, true)
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	second, err := database.CreateItem(todo.ID, "note", nil, nil, &content, nil, "auto", nil, "markdown", 1, true)
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	outside, err := database.CreateItem(elsewhere.ID, "note", nil, nil, &content, nil, "auto", nil, "markdown", 0, true)
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	reorder := func(userID int, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("id", strconv.Itoa(board.ID))
+		ctx := setUserID(context.WithValue(context.Background(), chi.RouteCtxKey, routeCtx), userID)
+		req := httptest.NewRequest(http.MethodPost, "/api/boards/"+strconv.Itoa(board.ID)+"/reorder-items", strings.NewReader(body)).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		ReorderBoardItems(database)(rec, req)
+		return rec
+	}
+	move := func(itemID, position, listID int) string {
+		return `{"id":` + strconv.Itoa(itemID) + `,"position":` + strconv.Itoa(position) + `,"list_id":` + strconv.Itoa(listID) + `}`
+	}
+
+	for _, tt := range []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "empty", body: `{"items":[]}`, want: http.StatusBadRequest},
+		{name: "duplicate item", body: `{"items":[` + move(first.ID, 0, done.ID) + `,` + move(first.ID, 1, done.ID) + `]}`, want: http.StatusBadRequest},
+		{name: "target list on another board", body: `{"items":[` + move(first.ID, 0, elsewhere.ID) + `]}`, want: http.StatusBadRequest},
+		{name: "item on another board", body: `{"items":[` + move(outside.ID, 0, done.ID) + `]}`, want: http.StatusBadRequest},
+		{name: "unknown item", body: `{"items":[` + move(999999, 0, done.ID) + `]}`, want: http.StatusNotFound},
+	} {
+		if rec := reorder(user.ID, tt.body); rec.Code != tt.want {
+			t.Fatalf("%s: status = %d, want %d, body=%s", tt.name, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+	if item, err := database.GetItem(first.ID); err != nil || item.ListID != todo.ID {
+		t.Fatalf("rejected reorder moved item: %+v, %v", item, err)
+	}
+
+	rec := reorder(user.ID, `{"items":[`+move(second.ID, 0, done.ID)+`,`+move(first.ID, 0, todo.ID)+`]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var grouped map[int][]struct {
+		ID     int `json:"id"`
+		ListID int `json:"list_id"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &grouped); err != nil {
+		t.Fatalf("unmarshal items: %v", err)
+	}
+	if len(grouped[todo.ID]) != 1 || grouped[todo.ID][0].ID != first.ID || len(grouped[done.ID]) != 1 || grouped[done.ID][0].ID != second.ID {
+		t.Fatalf("grouped items = %+v, want one item in each list", grouped)
+	}
+	if _, ok := grouped[elsewhere.ID]; ok {
+		t.Fatalf("grouped items include another board's list")
+	}
+
+	other, err := database.CreateUser("other", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if rec := reorder(other.ID, `{"items":[`+move(first.ID, 0, todo.ID)+`]}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("other user status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func newBoardsTestDB(t *testing.T) *db.DB {
+	t.Helper()
+
+	database, err := db.New(filepath.Join(t.TempDir(), "bookmarks.db"))
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("close db: %v", err)
+		}
+	})
+
+	return database
+}
+
+func TestGetBoardData_RefreshStaleRefetchesOldFavicons(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.GetDefaultBoard(user.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	list, err := database.CreateList(user.ID, board.ID, "Links", "#3D6D95", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+
+	// The "loom" icon source resolves without any network access
+	url := "https://example.com"
+	oldIcon := "data:image/png;base64,b2xk"
+	stale, err := database.CreateItem(list.ID, "bookmark", nil, &url, nil, &oldIcon, "loom", nil, "text", 0, true)
+	if err != nil {
+		t.Fatalf("create stale item: %v", err)
+	}
+	fresh, err := database.CreateItem(list.ID, "bookmark", nil, &url, nil, &oldIcon, "loom", nil, "text", 1, true)
+	if err != nil {
+		t.Fatalf("create fresh item: %v", err)
+	}
+	if _, err := database.Exec("UPDATE items SET last_favicon_fetch = datetime('now', '-30 days') WHERE id = ?", stale.ID); err != nil {
+		t.Fatalf("age favicon: %v", err)
+	}
+
+	refresher := NewFaviconRefresher(database, favicon.New(), 7*24*time.Hour)
+
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", strconv.Itoa(board.ID))
+	ctx := setUserID(context.WithValue(context.Background(), chi.RouteCtxKey, routeCtx), user.ID)
+	req := httptest.NewRequest(http.MethodGet, "/api/boards/"+strconv.Itoa(board.ID)+"/data?refresh_stale=true", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	GetBoardData(database, refresher)(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	refresher.Wait()
+
+	if item, err := database.GetItem(stale.ID); err != nil || item.FaviconURL == nil || *item.FaviconURL != "/static/favicon-32x32.png" {
+		t.Fatalf("stale item favicon = %v (err %v), want it re-fetched", item.FaviconURL, err)
+	}
+	if item, err := database.GetItem(fresh.ID); err != nil || item.FaviconURL == nil || *item.FaviconURL != oldIcon {
+		t.Fatalf("fresh item favicon = %v (err %v), want it untouched", item.FaviconURL, err)
+	}
+}
+
+func TestFaviconRefresher_StopsWhileCircuitBreakerIsOpen(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.GetDefaultBoard(user.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	list, err := database.CreateList(user.ID, board.ID, "Links", "#3D6D95", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	url := "https://example.com"
+	oldIcon := "data:image/png;base64,b2xk"
+	var items []*models.Item
+	for i := range 2 {
+		item, err := database.CreateItem(list.ID, "bookmark", nil, &url, nil, &oldIcon, "auto", nil, "text", i, true)
+		if err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+		items = append(items, item)
+	}
+	if _, err := database.Exec("UPDATE items SET last_favicon_fetch = datetime('now', '-30 days')"); err != nil {
+		t.Fatalf("age favicons: %v", err)
+	}
+
+	// One connection failure opens the breaker
+	server := httptest.NewServer(http.NotFoundHandler())
+	unreachable := server.URL + "/favicon.ico"
+	server.Close()
+	fetcher := favicon.New()
+	fetcher.SetRetryAttempts(1)
+	fetcher.SetCircuitBreaker(1, time.Hour)
+	if _, err := fetcher.FetchFromCustomURL(unreachable); err == nil {
+		t.Fatalf("fetch from closed server succeeded")
+	}
+
+	maxAge := 7 * 24 * time.Hour
+	refresher := NewFaviconRefresher(database, fetcher, maxAge)
+	refresher.RefreshBoard(user.ID, board.ID)
+	refresher.Wait()
+
+	for _, item := range items {
+		stored, err := database.GetItem(item.ID)
+		if err != nil {
+			t.Fatalf("get item: %v", err)
+		}
+		stale, err := database.IsFaviconStale(item.ID, maxAge)
+		if err != nil {
+			t.Fatalf("check favicon age: %v", err)
+		}
+		if stored.FaviconURL == nil || *stored.FaviconURL != oldIcon || !stale {
+			t.Fatalf("item %d favicon = %v (stale %v), want the old icon kept for a later refresh", item.ID, stored.FaviconURL, stale)
+		}
+	}
+}
+
+func TestRepairDefaultBoards_PrefersUnarchivedBoards(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	oldest, err := database.GetDefaultBoard(user.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	newer, err := database.CreateBoard(user.ID, "Newer", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	if _, err := database.Exec("UPDATE boards SET archived = 1, is_default = 0 WHERE id = ?", oldest.ID); err != nil {
+		t.Fatalf("archive board: %v", err)
+	}
+
+	defaultBoard := func() int {
+		t.Helper()
+		var id int
+		if err := database.QueryRow("SELECT id FROM boards WHERE user_id = ? AND is_default = 1", user.ID).Scan(&id); err != nil {
+			t.Fatalf("get default board: %v", err)
+		}
+		return id
+	}
+
+	// With no default, the unarchived board is promoted even though it is newer
+	if promoted, _, err := database.RepairDefaultBoards(); err != nil || promoted != 1 {
+		t.Fatalf("RepairDefaultBoards() promoted %d (err %v), want 1", promoted, err)
+	}
+	if id := defaultBoard(); id != newer.ID {
+		t.Fatalf("default board = %d, want the unarchived board %d", id, newer.ID)
+	}
+
+	// With two defaults, the archived one is demoted even though it is older. The
+	// one-default index is dropped to set up the state older data can be in.
+	if _, err := database.Exec("DROP INDEX idx_boards_default"); err != nil {
+		t.Fatalf("drop index: %v", err)
+	}
+	if _, err := database.Exec("UPDATE boards SET is_default = 1 WHERE user_id = ?", user.ID); err != nil {
+		t.Fatalf("set defaults: %v", err)
+	}
+	if _, demoted, err := database.RepairDefaultBoards(); err != nil || demoted != 1 {
+		t.Fatalf("RepairDefaultBoards() demoted %d (err %v), want 1", demoted, err)
+	}
+	if id := defaultBoard(); id != newer.ID {
+		t.Fatalf("default board = %d, want the unarchived board %d", id, newer.ID)
+	}
+}
+
+func TestGetBoardItems_GroupsBoardItemsByList(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.GetDefaultBoard(user.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	other, err := database.CreateBoard(user.ID, "Work", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	todo, err := database.CreateList(user.ID, board.ID, "Todo", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	done, err := database.CreateList(user.ID, board.ID, "Done", "#ffffff", 1, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	work, err := database.CreateList(user.ID, other.ID, "Work", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	for i, listID := range []int{todo.ID, todo.ID, done.ID, work.ID} {
+		content := "note " + strconv.Itoa(i)
+		if _, err := database.CreateItem(listID, "note", nil, nil, &content, nil, "auto", nil, "text", i, true); err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+	}
+
+	rec := performBoardAction(t, GetBoardItems(database), board.ID, user.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var items map[int][]models.Item
+	if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if len(items) != 2 || len(items[todo.ID]) != 2 || len(items[done.ID]) != 1 {
+		t.Fatalf("items = %+v, want two in Todo and one in Done, none from the other board", items)
+	}
+
+	stranger, err := database.CreateUser("stranger", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if rec := performBoardAction(t, GetBoardItems(database), board.ID, stranger.ID); rec.Code == http.StatusOK {
+		t.Fatalf("another user's board status = %d, want it refused", rec.Code)
+	}
+}
+
+// performGetBoardData requests a board's data; a userID of 0 makes the request anonymous
+func performGetBoardData(t *testing.T, database *db.DB, boardID, userID int) *httptest.ResponseRecorder {
+	t.Helper()
+
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", strconv.Itoa(boardID))
+
+	ctx := context.WithValue(context.Background(), chi.RouteCtxKey, routeCtx)
+	if userID != 0 {
+		ctx = setUserID(ctx, userID)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/boards/"+strconv.Itoa(boardID)+"/data", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	GetBoardData(database, nil)(rec, req)
+
+	return rec
+}
+
+// performBoardAction calls a board handler with the board ID route param set and userID authenticated
+func performBoardAction(t *testing.T, handler http.HandlerFunc, boardID, userID int) *httptest.ResponseRecorder {
+	t.Helper()
+
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", strconv.Itoa(boardID))
+
+	ctx := context.WithValue(context.Background(), chi.RouteCtxKey, routeCtx)
+	ctx = setUserID(ctx, userID)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/boards/"+strconv.Itoa(boardID), nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	handler(rec, req)
+
+	return rec
+}
+
+func TestFaviconLooksBroken(t *testing.T) {
+	tests := []struct {
+		favicon *string
+		want    bool
+	}{
+		{favicon: nil, want: true},
+		{favicon: ptr("  "), want: true},
+		{favicon: ptr("data:image/png;base64,"), want: true},
+		{favicon: ptr("data:image/png;base64"), want: true},
+		{favicon: ptr("data:image/png;base64,not*base64"), want: true},
+		{favicon: ptr("data:image/png;base64,iVBORw0KGgo="), want: false},
+		{favicon: ptr("/static/favicon-32x32.png"), want: false},
+	}
+
+	for _, tt := range tests {
+		if got := faviconLooksBroken(tt.favicon); got != tt.want {
+			name := "<nil>"
+			if tt.favicon != nil {
+				name = *tt.favicon
+			}
+			t.Fatalf("faviconLooksBroken(%q) = %v, want %v", name, got, tt.want)
+		}
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestGetBoard_ListCountAndETag(t *testing.T) {
+	database := newBoardsTestDB(t)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.GetDefaultBoard(user.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	if _, err := database.CreateList(user.ID, board.ID, "Reading", "#ffffff", 0, false); err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+
+	// getBoard requests the board, sending ifNoneMatch when set
+	getBoard := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("id", strconv.Itoa(board.ID))
+		ctx := setUserID(context.WithValue(context.Background(), chi.RouteCtxKey, routeCtx), user.ID)
+		req := httptest.NewRequest(http.MethodGet, "/api/boards/"+strconv.Itoa(board.ID), nil).WithContext(ctx)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		GetBoard(database)(rec, req)
+		return rec
+	}
+
+	rec := getBoard("")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var summary BoardSummary
+	if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+		t.Fatalf("unmarshal board: %v", err)
+	}
+	if summary.Board == nil || summary.ID != board.ID || summary.ListCount != 1 {
+		t.Fatalf("board = %s, want board %d with 1 list", rec.Body.String(), board.ID)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("missing ETag header")
+	}
+
+	if rec := getBoard(etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("revalidation status = %d (body %q), want an empty %d", rec.Code, rec.Body.String(), http.StatusNotModified)
+	}
+
+	if _, err := database.CreateList(user.ID, board.ID, "Tools", "#ffffff", 1, false); err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	if rec := getBoard(etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("status after change = %d with ETag %q, want %d with a new ETag", rec.Code, rec.Header().Get("ETag"), http.StatusOK)
+	}
+}
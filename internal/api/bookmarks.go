@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/crueber/loom/internal/db"
 	"github.com/crueber/loom/internal/favicon"
@@ -17,6 +18,11 @@ import (
 type BookmarksAPI struct {
 	db             *db.DB
 	faviconFetcher *favicon.Fetcher
+
+	// itemLimits caps each user's bookmark and note counts
+	itemLimits ItemLimits
+	// fetchQuota caps the favicons each user's bookmarks fetch per day
+	fetchQuota DailyQuota
 }
 
 // NewBookmarksAPI creates a new bookmarks API handler
@@ -27,6 +33,17 @@ func NewBookmarksAPI(database *db.DB, faviconFetcher *favicon.Fetcher) *Bookmark
 	}
 }
 
+// SetItemLimits caps how many bookmarks each non-admin user may create
+func (b *BookmarksAPI) SetItemLimits(limits ItemLimits) {
+	b.itemLimits = limits
+}
+
+// SetFetchQuota counts the favicons fetched for each user's bookmarks against quota;
+// once it is used up bookmarks are saved without one
+func (b *BookmarksAPI) SetFetchQuota(quota DailyQuota) {
+	b.fetchQuota = quota
+}
+
 // CreateBookmarkRequest represents a request to create a bookmark
 type CreateBookmarkRequest struct {
 	ListID int    `json:"list_id"`
@@ -108,12 +125,12 @@ func (b *BookmarksAPI) HandleCreateBookmark(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Validate input
-	req.Title = strings.TrimSpace(req.Title)
+	req.Title = strings.TrimSpace(sanitizeText(req.Title))
 	if req.Title == "" {
 		respondError(w, http.StatusBadRequest, "Title is required")
 		return
 	}
-	if len(req.Title) > 200 {
+	if utf8.RuneCountInString(req.Title) > 200 {
 		respondError(w, http.StatusBadRequest, "Title must be less than 200 characters")
 		return
 	}
@@ -139,6 +156,10 @@ func (b *BookmarksAPI) HandleCreateBookmark(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if !b.itemLimits.checkItemLimit(w, b.db, userID, db.ItemTypeBookmark, 1) {
+		return
+	}
+
 	// Get current max position
 	bookmarks, err := b.db.GetBookmarks(req.ListID)
 	if err != nil {
@@ -152,7 +173,10 @@ func (b *BookmarksAPI) HandleCreateBookmark(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Fetch favicon
-	faviconURL := b.faviconFetcher.FetchFaviconURL(req.URL)
+	var faviconURL *string
+	if b.fetchQuota.allowFetch(w, userID) {
+		faviconURL = b.faviconFetcher.FetchFaviconURL(req.URL)
+	}
 
 	// Create bookmark
 	bookmark, err := b.db.CreateBookmark(req.ListID, req.Title, req.URL, faviconURL, position)
@@ -197,12 +221,12 @@ func (b *BookmarksAPI) HandleUpdateBookmark(w http.ResponseWriter, r *http.Reque
 
 	// Validate input
 	if req.Title != nil {
-		*req.Title = strings.TrimSpace(*req.Title)
+		*req.Title = strings.TrimSpace(sanitizeText(*req.Title))
 		if *req.Title == "" {
 			respondError(w, http.StatusBadRequest, "Title cannot be empty")
 			return
 		}
-		if len(*req.Title) > 200 {
+		if utf8.RuneCountInString(*req.Title) > 200 {
 			respondError(w, http.StatusBadRequest, "Title must be less than 200 characters")
 			return
 		}
@@ -220,8 +244,12 @@ func (b *BookmarksAPI) HandleUpdateBookmark(w http.ResponseWriter, r *http.Reque
 			return
 		}
 
-		// Fetch new favicon if URL changed
-		faviconURL := b.faviconFetcher.FetchFaviconURL(*req.URL)
+		// Fetch new favicon if URL changed; over the fetch quota the old one is cleared
+		// rather than left showing another site's icon
+		var faviconURL *string
+		if b.fetchQuota.allowFetch(w, userID) {
+			faviconURL = b.faviconFetcher.FetchFaviconURL(*req.URL)
+		}
 		newFaviconURL = &faviconURL
 	}
 
@@ -294,35 +322,20 @@ func (b *BookmarksAPI) HandleReorderBookmarks(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Verify ownership of all bookmarks and lists
-	for _, item := range req.Bookmarks {
-		exists, err := b.db.VerifyBookmarkOwnership(item.ID, userID)
-		if err != nil {
-			respondError(w, http.StatusInternalServerError, "Database error")
-			return
-		}
-		if !exists {
-			respondError(w, http.StatusNotFound, "Bookmark not found")
-			return
-		}
-
-		exists, err = b.db.VerifyListOwnership(item.ListID, userID)
-		if err != nil {
-			respondError(w, http.StatusInternalServerError, "Database error")
-			return
-		}
-		if !exists {
-			respondError(w, http.StatusNotFound, "List not found")
-			return
-		}
-	}
-
-	// Build positions map
+	// Bookmarks now live in the items table, so reorder them exactly like items
+	itemIDs := make([]int, 0, len(req.Bookmarks))
+	listIDs := make([]int, 0, len(req.Bookmarks))
+	listIDSet := make(map[int]bool)
 	positions := make(map[int]struct {
 		Position int
 		ListID   int
 	})
 	for _, item := range req.Bookmarks {
+		itemIDs = append(itemIDs, item.ID)
+		if !listIDSet[item.ListID] {
+			listIDs = append(listIDs, item.ListID)
+			listIDSet[item.ListID] = true
+		}
 		positions[item.ID] = struct {
 			Position int
 			ListID   int
@@ -332,8 +345,19 @@ func (b *BookmarksAPI) HandleReorderBookmarks(w http.ResponseWriter, r *http.Req
 		}
 	}
 
+	// Verify ownership of all bookmarks and lists in a single query
+	owned, err := b.db.VerifyItemsAndListsOwnership(itemIDs, listIDs, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if !owned {
+		respondError(w, http.StatusNotFound, "One or more bookmarks or lists not found")
+		return
+	}
+
 	// Update positions
-	if err := b.db.UpdateBookmarkPositions(positions); err != nil {
+	if err := b.db.UpdateItemPositions(positions); err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to reorder bookmarks")
 		return
 	}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleReorderBookmarks_MixedOwnershipRejectedAtomically(t *testing.T) {
+	database := newBoardsTestDB(t)
+	bookmarksAPI := NewBookmarksAPI(database, nil)
+
+	owner, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	other, err := database.CreateUser("other", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	url := "https://example.com"
+	createBookmark := func(userID int) (listID, itemID int) {
+		t.Helper()
+		board, err := database.GetDefaultBoard(userID)
+		if err != nil {
+			t.Fatalf("get default board: %v", err)
+		}
+		list, err := database.CreateList(userID, board.ID, "Links", "#3D6D95", 0, false)
+		if err != nil {
+			t.Fatalf("create list: %v", err)
+		}
+		item, err := database.CreateItem(list.ID, "bookmark", nil, &url, nil, nil, "auto", nil, "text", 0, true)
+		if err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+		return list.ID, item.ID
+	}
+	ownListID, ownItemID := createBookmark(owner.ID)
+	otherListID, otherItemID := createBookmark(other.ID)
+
+	reorder := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/bookmarks/reorder", bytes.NewBufferString(body))
+		req = req.WithContext(setUserID(req.Context(), owner.ID))
+		rec := httptest.NewRecorder()
+		bookmarksAPI.HandleReorderBookmarks(rec, req)
+		return rec
+	}
+
+	mixed := fmt.Sprintf(`{"bookmarks":[{"id":%d,"position":5,"list_id":%d},{"id":%d,"position":6,"list_id":%d}]}`, ownItemID, ownListID, otherItemID, otherListID)
+	if rec := reorder(mixed); rec.Code != http.StatusNotFound {
+		t.Fatalf("mixed batch status = %d, want %d, body=%s", rec.Code, http.StatusNotFound, rec.Body.String())
+	}
+	for _, id := range []int{ownItemID, otherItemID} {
+		item, err := database.GetItem(id)
+		if err != nil {
+			t.Fatalf("get item: %v", err)
+		}
+		if item.Position != 0 {
+			t.Fatalf("item %d position = %d, want it untouched", id, item.Position)
+		}
+	}
+
+	own := fmt.Sprintf(`{"bookmarks":[{"id":%d,"position":5,"list_id":%d}]}`, ownItemID, ownListID)
+	if rec := reorder(own); rec.Code != http.StatusNoContent {
+		t.Fatalf("own batch status = %d, want %d, body=%s", rec.Code, http.StatusNoContent, rec.Body.String())
+	}
+	if item, err := database.GetItem(ownItemID); err != nil || item.Position != 5 {
+		t.Fatalf("own item = %+v (err %v), want position 5", item, err)
+	}
+}
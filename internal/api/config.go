@@ -0,0 +1,20 @@
+package api
+
+import "net/http"
+
+// PublicConfig is the non-secret instance configuration exposed to clients.
+// Only add fields here that are safe to show an anonymous visitor.
+type PublicConfig struct {
+	Version             string `json:"version"`
+	Standalone          bool   `json:"standalone"`
+	OAuthEnabled        bool   `json:"oauth_enabled"`
+	RegistrationEnabled bool   `json:"registration_enabled"`
+	AutoTitle           bool   `json:"auto_title"`
+}
+
+// HandleConfig returns the instance's public configuration so the SPA can pick the right login UI
+func HandleConfig(cfg PublicConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		respondJSON(w, http.StatusOK, cfg)
+	}
+}
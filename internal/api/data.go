@@ -35,36 +35,30 @@ func (api *DataAPI) HandleGetAllData(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch all boards
-	boards, err := api.db.GetBoards(userID)
+	boards, err := api.db.GetBoardsContext(r.Context(), userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), queryErrorStatus(err))
 		return
 	}
 
 	// Fetch all lists
-	lists, err := api.db.GetLists(userID)
+	lists, err := api.db.GetListsContext(r.Context(), userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), queryErrorStatus(err))
 		return
 	}
 
 	// Fetch all items (bookmarks and notes) in single query
-	allItems, err := api.db.GetAllItems(userID)
+	allItems, err := api.db.GetAllItemsContext(r.Context(), userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), queryErrorStatus(err))
 		return
 	}
 
-	// Group items by list_id for easier frontend consumption
-	itemsByList := make(map[int][]*models.Item)
-	for _, item := range allItems {
-		itemsByList[item.ListID] = append(itemsByList[item.ListID], item)
-	}
-
 	response := DataResponse{
 		Boards: boards,
 		Lists:  lists,
-		Items:  itemsByList,
+		Items:  groupItemsByList(allItems),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1,30 +1,225 @@
 package api
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/crueber/loom/internal/auth"
 	"github.com/crueber/loom/internal/db"
+	"github.com/crueber/loom/internal/favicon"
 	"github.com/crueber/loom/internal/models"
+	"github.com/crueber/loom/internal/sanitize"
+	"github.com/crueber/loom/internal/urlutil"
 )
 
+// exportTokenTTL is how long a signed export download token stays valid
+const exportTokenTTL = 15 * time.Minute
+
+// replaceConfirmTTL is how long the token confirming a replace import stays valid
+const replaceConfirmTTL = 2 * time.Minute
+
 // ExportAPI handles export/import endpoints
 type ExportAPI struct {
-	db *db.DB
+	db             *db.DB
+	tokenKey       []byte
+	replaceKey     []byte
+	maxImportLists int
+	maxImportItems int
+	// maxImportBytes caps the size of an import request body; zero disables the cap
+	maxImportBytes int64
+
+	// maxFaviconLength drops imported favicons longer than this many bytes; zero keeps all
+	maxFaviconLength int
+	// faviconStore holds icons referenced by URL, which exports inline
+	faviconStore *favicon.Store
+	// previewStore holds preview images; exports inline them and imports store into it
+	previewStore *favicon.Store
+	// itemLimits caps each user's bookmark and note counts
+	itemLimits ItemLimits
+	// uniqueListTitles rejects imports that would repeat a list title on a board
+	uniqueListTitles bool
+}
+
+// NewExportAPI creates a new export API handler.
+// Download and replace-confirmation tokens are signed with keys derived from signingKey.
+// maxImportLists and maxImportItems cap a single import; zero disables a cap.
+func NewExportAPI(database *db.DB, signingKey []byte, maxImportLists, maxImportItems int) *ExportAPI {
+	return &ExportAPI{
+		db:             database,
+		tokenKey:       auth.DeriveKey(signingKey, "loom-export-download"),
+		replaceKey:     auth.DeriveKey(signingKey, "loom-import-replace"),
+		maxImportLists: maxImportLists,
+		maxImportItems: maxImportItems,
+	}
+}
+
+// SetMaxFaviconLength drops imported favicon data URIs longer than n bytes so hostile
+// or bloated files can't fill the database; zero keeps every favicon. Dropped icons are
+// stored as missing, so a favicon refresh fetches them again.
+func (e *ExportAPI) SetMaxFaviconLength(n int) {
+	e.maxFaviconLength = n
+}
+
+// SetMaxImportBytes rejects import request bodies larger than n bytes with 413 before
+// they are read into memory; zero disables the cap
+func (e *ExportAPI) SetMaxImportBytes(n int64) {
+	e.maxImportBytes = n
+}
+
+// limitImportBody applies the import body size cap to r
+func (e *ExportAPI) limitImportBody(w http.ResponseWriter, r *http.Request) {
+	if e.maxImportBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, e.maxImportBytes)
+	}
+}
+
+// respondImportBodyError answers a failure to read an import body: 413 when it went
+// over the size cap, otherwise 400
+func (e *ExportAPI) respondImportBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Import exceeds the maximum of %d bytes", tooLarge.Limit))
+		return
+	}
+	respondError(w, http.StatusBadRequest, "Invalid request body")
+}
+
+// SetFaviconStore makes exports inline icons kept in store as data URIs, so an export
+// stays self-contained when favicons are stored as files
+func (e *ExportAPI) SetFaviconStore(store *favicon.Store) {
+	e.faviconStore = store
+}
+
+// SetItemLimits caps how many bookmarks and notes each non-admin user may import.
+// Merges count every imported item as new, even those that end up updating one.
+func (e *ExportAPI) SetItemLimits(limits ItemLimits) {
+	e.itemLimits = limits
+}
+
+// SetUniqueListTitles makes imports honour UNIQUE_LIST_TITLES: lists are merged by
+// title regardless of case, and an import naming two lists alike is rejected with 409
+func (e *ExportAPI) SetUniqueListTitles(unique bool) {
+	e.uniqueListTitles = unique
+}
+
+// SetPreviewStore makes exports inline preview images kept in store as data URIs, and
+// imports move preview data URIs into store instead of the database
+func (e *ExportAPI) SetPreviewStore(store *favicon.Store) {
+	e.previewStore = store
 }
 
-// NewExportAPI creates a new export API handler
-func NewExportAPI(database *db.DB) *ExportAPI {
-	return &ExportAPI{db: database}
+// dropOversizedFavicons clears favicons over maxFaviconLength in data and returns how many it cleared
+func (e *ExportAPI) dropOversizedFavicons(data *models.ExportData) int {
+	if e.maxFaviconLength <= 0 {
+		return 0
+	}
+
+	dropped := 0
+	oversized := func(faviconURL *string) bool {
+		return faviconURL != nil && len(*faviconURL) > e.maxFaviconLength
+	}
+	for i := range data.Lists {
+		list := &data.Lists[i]
+		for j := range list.Items {
+			if oversized(list.Items[j].FaviconURL) {
+				list.Items[j].FaviconURL = nil
+				dropped++
+			}
+		}
+		for j := range list.Bookmarks {
+			if oversized(list.Bookmarks[j].FaviconURL) {
+				list.Bookmarks[j].FaviconURL = nil
+				dropped++
+			}
+		}
+	}
+	return dropped
 }
 
-// ImportRequest represents an import request
+// ImportRequest represents an import request. Merged items are only matched within
+// the same-titled list on the board being imported into, so lookalikes on the user's
+// other boards are left alone.
 type ImportRequest struct {
-	Data models.ExportData `json:"data"`
-	Mode string            `json:"mode"` // "merge" or "replace"
+	Data    models.ExportData `json:"data"`
+	Mode    string            `json:"mode"`               // "merge" or "replace"
+	MatchBy string            `json:"match_by,omitempty"` // "id" (default), "url", or "title"
+
+	// ConfirmToken confirms a replace import; it comes from an earlier call without one
+	ConfirmToken string `json:"confirm_token,omitempty"`
+}
+
+// ImportReplaceConfirmation is returned with 428 instead of importing when a replace
+// import has no confirmation token. It summarizes what the replace would delete and
+// create and carries the token that lets the same user repeat the call to actually
+// perform it.
+type ImportReplaceConfirmation struct {
+	Error                string    `json:"error"`
+	ConfirmationRequired bool      `json:"confirmation_required"`
+	ListsDeleted         int       `json:"lists_deleted"`
+	ItemsDeleted         int       `json:"items_deleted"`
+	ListsImported        int       `json:"lists_imported"`
+	ItemsImported        int       `json:"items_imported"`
+	ConfirmToken         string    `json:"confirm_token"`
+	ExpiresAt            time.Time `json:"expires_at"`
+}
+
+// importMatcher finds existing items within a target list when merging by URL or title
+type importMatcher struct {
+	byURL   map[string]*models.Item
+	byTitle map[string]*models.Item
+}
+
+// newImportMatcher indexes the given items by normalized URL and exact title
+func newImportMatcher(items []*models.Item) *importMatcher {
+	m := &importMatcher{
+		byURL:   make(map[string]*models.Item),
+		byTitle: make(map[string]*models.Item),
+	}
+	for _, item := range items {
+		m.add(item)
+	}
+	return m
+}
+
+// add indexes an item, keeping the first match for duplicate keys
+func (m *importMatcher) add(item *models.Item) {
+	if item.URL != nil {
+		key := urlutil.NormalizeForMatch(*item.URL)
+		if _, exists := m.byURL[key]; !exists && key != "" {
+			m.byURL[key] = item
+		}
+	}
+	if item.Title != nil && *item.Title != "" {
+		if _, exists := m.byTitle[*item.Title]; !exists {
+			m.byTitle[*item.Title] = item
+		}
+	}
+}
+
+// find returns the existing item matching the given title or URL, or nil
+func (m *importMatcher) find(matchBy string, title, rawURL *string) *models.Item {
+	switch matchBy {
+	case "url":
+		if rawURL == nil {
+			return nil
+		}
+		return m.byURL[urlutil.NormalizeForMatch(*rawURL)]
+	case "title":
+		if title == nil {
+			return nil
+		}
+		return m.byTitle[*title]
+	}
+	return nil
 }
 
 // HandleExport exports user data as JSON
@@ -35,18 +230,94 @@ func (e *ExportAPI) HandleExport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	boardIDStr := r.URL.Query().Get("board_id")
-	var lists []*models.List
-	var err error
-	var filename string
+	boardID := 0
+	if boardIDStr := r.URL.Query().Get("board_id"); boardIDStr != "" {
+		var err error
+		boardID, err = strconv.Atoi(boardIDStr)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid board ID")
+			return
+		}
+	}
+
+	e.writeExport(w, userID, boardID)
+}
 
-	if boardIDStr != "" {
-		boardID, err := strconv.Atoi(boardIDStr)
+// HandleCreateExportToken returns a signed, expiring token for downloading an export without a session
+func (e *ExportAPI) HandleCreateExportToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	boardID := 0
+	if boardIDStr := r.URL.Query().Get("board_id"); boardIDStr != "" {
+		var err error
+		boardID, err = strconv.Atoi(boardIDStr)
 		if err != nil {
 			respondError(w, http.StatusBadRequest, "Invalid board ID")
 			return
 		}
 
+		owns, err := e.db.VerifyBoardOwnership(boardID, userID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to verify board ownership")
+			return
+		}
+		if !owns {
+			respondError(w, http.StatusNotFound, "Board not found")
+			return
+		}
+	}
+
+	expiresAt := time.Now().Add(exportTokenTTL)
+	token := auth.SignToken(e.tokenKey, fmt.Sprintf("%d:%d", userID, boardID), expiresAt)
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"token":      token,
+		"expires_at": expiresAt.UTC(),
+	})
+}
+
+// HandleExportDownload streams an export authorized by a signed token instead of a
+// session. The token must be sent as "Authorization: Bearer <token>"; it is never read
+// from the URL, where access logs and proxies would record it.
+func (e *ExportAPI) HandleExportDownload(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	token = strings.TrimSpace(token)
+	if !ok || token == "" {
+		respondError(w, http.StatusUnauthorized, "Token is required in the Authorization header")
+		return
+	}
+
+	payload, err := auth.VerifyToken(e.tokenKey, token, time.Now())
+	if err != nil {
+		if errors.Is(err, auth.ErrExpiredToken) {
+			respondError(w, http.StatusUnauthorized, "Token expired")
+			return
+		}
+		respondError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	var userID, boardID int
+	if _, err := fmt.Sscanf(payload, "%d:%d", &userID, &boardID); err != nil {
+		respondError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	e.writeExport(w, userID, boardID)
+}
+
+// writeExport writes the export for a board, or all of the user's lists when boardID is 0
+func (e *ExportAPI) writeExport(w http.ResponseWriter, userID, boardID int) {
+	var lists []*models.List
+	var err error
+	var filename string
+	var boardTitle string
+
+	if boardID != 0 {
 		// Verify board ownership and get board title
 		board, err := e.db.GetBoardByID(boardID, userID)
 		if err != nil {
@@ -64,6 +335,7 @@ func (e *ExportAPI) HandleExport(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		filename = fmt.Sprintf("loom-export-%s-%s.json", board.Title, time.Now().Format("2006-01-02"))
+		boardTitle = board.Title
 	} else {
 		// Get all lists for the user (legacy behavior)
 		lists, err = e.db.GetLists(userID)
@@ -88,14 +360,19 @@ func (e *ExportAPI) HandleExport(w http.ResponseWriter, r *http.Request) {
 		exportItems := []models.ExportItem{}
 		exportBookmarks := []models.ExportBookmark{} // For backward compatibility
 		for _, item := range items {
+			faviconURL := inlineStoredImage(e.faviconStore, item.FaviconURL)
 			exportItems = append(exportItems, models.ExportItem{
-				ID:         item.ID,
-				Type:       item.Type,
-				Title:      item.Title,
-				URL:        item.URL,
-				Content:    item.Content,
-				FaviconURL: item.FaviconURL,
-				Position:   item.Position,
+				ID:              item.ID,
+				Type:            item.Type,
+				Title:           item.Title,
+				URL:             item.URL,
+				Content:         item.Content,
+				ContentFormat:   item.ContentFormat,
+				FaviconURL:      faviconURL,
+				PreviewImageURL: inlineStoredImage(e.previewStore, item.PreviewImageURL),
+				OpenInNewTab:    &item.OpenInNewTab,
+				Position:        item.Position,
+				CreatedAt:       &item.CreatedAt,
 			})
 
 			// Also populate legacy bookmarks field if it's a bookmark
@@ -112,7 +389,7 @@ func (e *ExportAPI) HandleExport(w http.ResponseWriter, r *http.Request) {
 					ID:         item.ID,
 					Title:      title,
 					URL:        url,
-					FaviconURL: item.FaviconURL,
+					FaviconURL: faviconURL,
 					Position:   item.Position,
 				})
 			}
@@ -124,6 +401,8 @@ func (e *ExportAPI) HandleExport(w http.ResponseWriter, r *http.Request) {
 			Color:     list.Color,
 			Position:  list.Position,
 			Collapsed: list.Collapsed,
+			SortMode:  list.SortMode,
+			CreatedAt: &list.CreatedAt,
 			Items:     exportItems,
 			Bookmarks: exportBookmarks,
 		})
@@ -132,6 +411,7 @@ func (e *ExportAPI) HandleExport(w http.ResponseWriter, r *http.Request) {
 	exportData := models.ExportData{
 		Version:    1,
 		ExportedAt: time.Now(),
+		BoardTitle: boardTitle,
 		Lists:      exportLists,
 	}
 
@@ -140,7 +420,45 @@ func (e *ExportAPI) HandleExport(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, exportData)
 }
 
-// HandleImport imports user data from JSON
+// exportTime returns an exported timestamp, or the zero time (meaning "now") when an older export omits it
+func exportTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// countImportItems counts the items an import would create, including legacy bookmarks
+func countImportItems(data models.ExportData) int {
+	count := 0
+	for _, list := range data.Lists {
+		if len(list.Items) > 0 {
+			count += len(list.Items)
+		} else {
+			count += len(list.Bookmarks)
+		}
+	}
+	return count
+}
+
+// countImportItemsByType counts the items of each type an import would create; legacy
+// bookmarks are bookmarks
+func countImportItemsByType(data models.ExportData) map[string]int {
+	counts := make(map[string]int)
+	for _, list := range data.Lists {
+		if len(list.Items) == 0 {
+			counts[db.ItemTypeBookmark] += len(list.Bookmarks)
+			continue
+		}
+		for _, item := range list.Items {
+			counts[item.Type]++
+		}
+	}
+	return counts
+}
+
+// HandleImport imports user data from JSON: a loom export by default, or another
+// service's export with ?format= (see importParsers)
 func (e *ExportAPI) HandleImport(w http.ResponseWriter, r *http.Request) {
 	userID, ok := getUserID(r.Context())
 	if !ok {
@@ -148,10 +466,49 @@ func (e *ExportAPI) HandleImport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	e.limitImportBody(w, r)
 	var req ImportRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid request body")
-		return
+	format := r.URL.Query().Get("format")
+	if format == "" || format == importFormatLoom {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			e.respondImportBodyError(w, err)
+			return
+		}
+	} else {
+		// Other services' exports are posted as-is; mode and match_by come from the query
+		parse, ok := importParsers[format]
+		if !ok {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Unsupported import format %q (must be one of: %s)", format, importFormatNames()))
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			e.respondImportBodyError(w, err)
+			return
+		}
+		data, err := parse(body)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid %s export: %v", format, err))
+			return
+		}
+
+		req = ImportRequest{
+			Data:         data,
+			Mode:         r.URL.Query().Get("mode"),
+			MatchBy:      r.URL.Query().Get("match_by"),
+			ConfirmToken: r.URL.Query().Get("confirm_token"),
+		}
+		if req.Mode == "" {
+			req.Mode = "merge"
+		}
+		if req.MatchBy == "" {
+			req.MatchBy = "url"
+		}
+		// IDs in a converted export are synthetic, so they must never match existing rows
+		if req.MatchBy == "id" {
+			respondError(w, http.StatusBadRequest, "match_by 'id' is only supported for loom exports")
+			return
+		}
 	}
 
 	// Validate mode
@@ -160,12 +517,43 @@ func (e *ExportAPI) HandleImport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate version
-	if req.Data.Version != 1 {
-		respondError(w, http.StatusBadRequest, "Unsupported export version")
+	// Validate match strategy
+	if req.MatchBy == "" {
+		req.MatchBy = "id"
+	}
+	if req.MatchBy != "id" && req.MatchBy != "url" && req.MatchBy != "title" {
+		respondError(w, http.StatusBadRequest, "Invalid match_by (must be 'id', 'url', or 'title')")
+		return
+	}
+
+	sanitizeImportText(&req.Data)
+	if !e.checkImportData(w, req.Data) {
+		return
+	}
+
+	added := countImportItemsByType(req.Data)
+	if req.Mode == "replace" {
+		// Replace deletes every existing item, so only the imported ones count
+		existing, err := e.db.CountItemsByType(userID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to count items")
+			return
+		}
+		for itemType, count := range existing {
+			added[itemType] -= count
+		}
+	}
+	if !e.itemLimits.checkItemLimits(w, e.db, userID, added) {
 		return
 	}
 
+	// Replace deletes everything first, so it only runs with a token from a previous dry run
+	if req.Mode == "replace" && !e.confirmReplace(w, userID, req) {
+		return
+	}
+
+	faviconsDropped := e.dropOversizedFavicons(&req.Data)
+
 	// Get or create default board for this user
 	defaultBoard, err := e.db.GetDefaultBoard(userID)
 	if err != nil {
@@ -189,95 +577,337 @@ func (e *ExportAPI) HandleImport(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if !e.importLists(w, userID, defaultBoard.ID, req.Data, req.Mode == "merge", req.MatchBy) {
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"message":          fmt.Sprintf("Successfully imported %d lists", len(req.Data.Lists)),
+		"favicons_dropped": faviconsDropped,
+	})
+}
+
+// replaceConfirmPayload binds a replace confirmation to the user and the exact data being imported
+func replaceConfirmPayload(userID int, data models.ExportData) (string, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d:%x", userID, sha256.Sum256(encoded)), nil
+}
+
+// confirmReplace reports whether req carries a valid token confirming its replace import.
+// Without a token it responds with an ImportReplaceConfirmation; with a bad one, an error.
+func (e *ExportAPI) confirmReplace(w http.ResponseWriter, userID int, req ImportRequest) bool {
+	payload, err := replaceConfirmPayload(userID, req.Data)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to prepare import")
+		return false
+	}
+
+	if req.ConfirmToken != "" {
+		confirmed, err := auth.VerifyToken(e.replaceKey, req.ConfirmToken, time.Now())
+		if err != nil {
+			if errors.Is(err, auth.ErrExpiredToken) {
+				respondError(w, http.StatusBadRequest, "Confirmation token expired")
+				return false
+			}
+			respondError(w, http.StatusBadRequest, "Invalid confirmation token")
+			return false
+		}
+		if confirmed != payload {
+			respondError(w, http.StatusBadRequest, "Confirmation token does not match this import")
+			return false
+		}
+		return true
+	}
+
+	lists, err := e.db.GetLists(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get existing lists")
+		return false
+	}
+	items, err := e.db.CountItems(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to count existing items")
+		return false
+	}
+
+	expiresAt := time.Now().Add(replaceConfirmTTL)
+	respondJSON(w, http.StatusPreconditionRequired, ImportReplaceConfirmation{
+		Error:                "Replace import not performed: repeat the request with confirm_token to delete existing data",
+		ConfirmationRequired: true,
+		ListsDeleted:         len(lists),
+		ItemsDeleted:         items,
+		ListsImported:        len(req.Data.Lists),
+		ItemsImported:        countImportItems(req.Data),
+		ConfirmToken:         auth.SignToken(e.replaceKey, payload, expiresAt),
+		ExpiresAt:            expiresAt.UTC(),
+	})
+	return false
+}
+
+// sanitizeImportText applies sanitizeText to every title and content field in data
+func sanitizeImportText(data *models.ExportData) {
+	data.BoardTitle = sanitizeText(data.BoardTitle)
+	for i := range data.Lists {
+		list := &data.Lists[i]
+		list.Title = sanitizeText(list.Title)
+		for j := range list.Items {
+			sanitizeTextFields(list.Items[j].Title, list.Items[j].Content)
+		}
+		for j := range list.Bookmarks {
+			list.Bookmarks[j].Title = sanitizeText(list.Bookmarks[j].Title)
+		}
+	}
+}
+
+// checkImportData rejects data that is the wrong version, over the import caps or
+// has invalid entries, responding with 400 and returning false. When list titles
+// must be unique, data repeating a list title is rejected with 409.
+func (e *ExportAPI) checkImportData(w http.ResponseWriter, data models.ExportData) bool {
+	// Validate version
+	if data.Version != 1 {
+		respondError(w, http.StatusBadRequest, "Unsupported export version")
+		return false
+	}
+
+	// Enforce size caps before any writes
+	if e.maxImportLists > 0 && len(data.Lists) > e.maxImportLists {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Import exceeds the maximum of %d lists", e.maxImportLists))
+		return false
+	}
+	if e.maxImportItems > 0 && countImportItems(data) > e.maxImportItems {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Import exceeds the maximum of %d items", e.maxImportItems))
+		return false
+	}
+
+	// Reject the whole import if any entry is invalid, so nothing is half-written
+	if errs := validateImportEntries(data); len(errs) > 0 {
+		respondJSON(w, http.StatusBadRequest, ImportErrorResponse{
+			Error:  fmt.Sprintf("Import contains %d invalid entries", len(errs)),
+			Errors: errs,
+		})
+		return false
+	}
+
+	if e.uniqueListTitles {
+		seen := make(map[string]bool, len(data.Lists))
+		for _, list := range data.Lists {
+			key := strings.ToLower(list.Title)
+			if seen[key] {
+				respondError(w, http.StatusConflict, fmt.Sprintf("Import has more than one list titled %q", list.Title))
+				return false
+			}
+			seen[key] = true
+		}
+	}
+
+	return true
+}
+
+// importLists writes data's lists and items onto a board, responding with an error
+// and returning false if a write fails. When merge is set, lists and items already on
+// the user's account are updated in place, matched as matchBy describes.
+func (e *ExportAPI) importLists(w http.ResponseWriter, userID, boardID int, data models.ExportData, merge bool, matchBy string) bool {
+	// Lists are matched by title on the board being imported into. Exported IDs only pick
+	// between same-titled lists, since an ID from another export can belong to any list.
+	// With unique list titles the match ignores case, as the title check does.
+	titleKey := func(title string) string {
+		if e.uniqueListTitles {
+			return strings.ToLower(title)
+		}
+		return title
+	}
+	listsByTitle := make(map[string]*models.List)
+	if merge {
+		lists, err := e.db.GetListsByBoard(userID, boardID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to get existing lists")
+			return false
+		}
+		for _, list := range lists {
+			if _, exists := listsByTitle[titleKey(list.Title)]; !exists {
+				listsByTitle[titleKey(list.Title)] = list
+			}
+		}
+	}
+
 	// Import lists and bookmarks
 	listIDMap := make(map[int]int) // old ID -> new ID
 
-	for _, exportList := range req.Data.Lists {
+	for _, exportList := range data.Lists {
 		// In merge mode, check if list exists
 		var newList *models.List
 		var err error
 
-		if req.Mode == "merge" {
-			// Try to get existing list by ID
-			existingList, err := e.db.GetList(exportList.ID, userID)
-			if err != nil {
-				respondError(w, http.StatusInternalServerError, "Database error")
-				return
+		if merge {
+			var existingList *models.List
+			if matchBy == "id" {
+				existingList, err = e.db.GetList(exportList.ID, userID)
+				if err != nil {
+					respondError(w, http.StatusInternalServerError, "Database error")
+					return false
+				}
+				if existingList != nil && (existingList.BoardID != boardID || existingList.Title != exportList.Title) {
+					existingList = nil
+				}
+			}
+			if existingList == nil {
+				existingList = listsByTitle[titleKey(exportList.Title)]
 			}
 
 			if existingList != nil {
-				// Update existing list
+				// Update existing list; older exports without a sort mode leave it as is
 				title := exportList.Title
 				color := exportList.Color
 				collapsed := exportList.Collapsed
-				if err := e.db.UpdateList(exportList.ID, userID, &title, &color, &collapsed); err != nil {
+				var sortMode *string
+				if exportList.SortMode != "" {
+					sortMode = &exportList.SortMode
+				}
+				if err := e.db.UpdateList(existingList.ID, userID, &title, &color, &collapsed, sortMode); err != nil {
 					respondError(w, http.StatusInternalServerError, "Failed to update list")
-					return
+					return false
 				}
 				newList = existingList
 				newList.Title = title
 				newList.Color = color
 				newList.Collapsed = collapsed
+				if sortMode != nil {
+					newList.SortMode = *sortMode
+				}
 			}
 		}
 
 		// Create new list if it doesn't exist
 		if newList == nil {
-			newList, err = e.db.CreateList(userID, defaultBoard.ID, exportList.Title, exportList.Color, exportList.Position)
+			newList, err = e.db.CreateListAt(userID, boardID, exportList.Title, exportList.Color, exportList.Position, exportList.Collapsed, exportTime(exportList.CreatedAt))
 			if err != nil {
 				respondError(w, http.StatusInternalServerError, "Failed to create list")
-				return
+				return false
+			}
+			if exportList.SortMode != "" && exportList.SortMode != newList.SortMode {
+				if err := e.db.UpdateList(newList.ID, userID, nil, nil, nil, &exportList.SortMode); err != nil {
+					respondError(w, http.StatusInternalServerError, "Failed to create list")
+					return false
+				}
+				newList.SortMode = exportList.SortMode
 			}
 		}
 
 		listIDMap[exportList.ID] = newList.ID
 
+		// Index the target list's items when matching by URL or title
+		var matcher *importMatcher
+		if merge && matchBy != "id" {
+			existingItems, err := e.db.GetItems(newList.ID)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "Failed to get existing items")
+				return false
+			}
+			matcher = newImportMatcher(existingItems)
+		}
+
 		// Import items
 		for _, exportItem := range exportList.Items {
-			if req.Mode == "merge" {
+			contentFormat := exportItem.ContentFormat
+			if !sanitize.IsValidFormat(contentFormat) {
+				contentFormat = sanitize.DefaultFormat
+			}
+			openInNewTab := exportItem.OpenInNewTab == nil || *exportItem.OpenInNewTab
+			if exportItem.Content != nil {
+				content := sanitize.Content(contentFormat, *exportItem.Content)
+				exportItem.Content = &content
+			}
+			exportItem.PreviewImageURL, err = storeImportedPreview(e.previewStore, exportItem.PreviewImageURL)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "Failed to store preview image")
+				return false
+			}
+
+			if merge {
 				// Try to get existing item
-				existingItem, err := e.db.GetItem(exportItem.ID)
-				if err != nil {
-					respondError(w, http.StatusInternalServerError, "Database error")
-					return
+				var existingItem *models.Item
+				if matcher != nil {
+					existingItem = matcher.find(matchBy, exportItem.Title, exportItem.URL)
+				} else {
+					existingItem, err = e.db.GetItem(exportItem.ID)
+					if err != nil {
+						respondError(w, http.StatusInternalServerError, "Database error")
+						return false
+					}
+					// Only items already in the resolved list can be updated by ID
+					if existingItem != nil && existingItem.ListID != newList.ID {
+						existingItem = nil
+					}
 				}
 
 				if existingItem != nil {
 					// Update existing item
-					if err := e.db.UpdateItem(exportItem.ID, exportItem.Title, exportItem.URL, exportItem.Content, &exportItem.FaviconURL); err != nil {
+					if err := e.db.UpdateItem(existingItem.ID, exportItem.Title, exportItem.URL, exportItem.Content, &exportItem.FaviconURL); err != nil {
 						respondError(w, http.StatusInternalServerError, "Failed to update item")
-						return
+						return false
+					}
+					fields := map[string]interface{}{}
+					if exportItem.OpenInNewTab != nil {
+						fields["open_in_new_tab"] = openInNewTab
+					}
+					if exportItem.PreviewImageURL != nil {
+						fields["preview_image_url"] = *exportItem.PreviewImageURL
+					}
+					if err := e.db.UpdateItemFields(existingItem.ID, fields); err != nil {
+						respondError(w, http.StatusInternalServerError, "Failed to update item")
+						return false
 					}
 					continue
 				}
 			}
 
 			// Create new item
-			_, err := e.db.CreateItem(newList.ID, exportItem.Type, exportItem.Title, exportItem.URL, exportItem.Content, exportItem.FaviconURL, "auto", nil, exportItem.Position)
+			item, err := e.db.CreateItemAt(newList.ID, exportItem.Type, exportItem.Title, exportItem.URL, exportItem.Content, exportItem.FaviconURL, "auto", nil, contentFormat, exportItem.Position, openInNewTab, exportTime(exportItem.CreatedAt))
 			if err != nil {
 				respondError(w, http.StatusInternalServerError, "Failed to create item")
-				return
+				return false
+			}
+			if exportItem.PreviewImageURL != nil {
+				if err := e.db.SetItemPreviewImage(item.ID, exportItem.PreviewImageURL); err != nil {
+					respondError(w, http.StatusInternalServerError, "Failed to create item")
+					return false
+				}
+				item.PreviewImageURL = exportItem.PreviewImageURL
+			}
+			if matcher != nil {
+				matcher.add(item)
 			}
 		}
 
 		// Backward compatibility: Import bookmarks if Items is empty
 		if len(exportList.Items) == 0 {
 			for _, exportBookmark := range exportList.Bookmarks {
-				if req.Mode == "merge" {
+				if merge {
 					// Try to get existing item (bookmarks are now items)
-					existingItem, err := e.db.GetItem(exportBookmark.ID)
-					if err != nil {
-						respondError(w, http.StatusInternalServerError, "Database error")
-						return
+					var existingItem *models.Item
+					if matcher != nil {
+						existingItem = matcher.find(matchBy, &exportBookmark.Title, &exportBookmark.URL)
+					} else {
+						existingItem, err = e.db.GetItem(exportBookmark.ID)
+						if err != nil {
+							respondError(w, http.StatusInternalServerError, "Database error")
+							return false
+						}
+						if existingItem != nil && existingItem.ListID != newList.ID {
+							existingItem = nil
+						}
 					}
 
 					if existingItem != nil && existingItem.Type == "bookmark" {
 						// Update existing bookmark
 						title := exportBookmark.Title
 						url := exportBookmark.URL
-						if err := e.db.UpdateItem(exportBookmark.ID, &title, &url, nil, &exportBookmark.FaviconURL); err != nil {
+						if err := e.db.UpdateItem(existingItem.ID, &title, &url, nil, &exportBookmark.FaviconURL); err != nil {
 							respondError(w, http.StatusInternalServerError, "Failed to update bookmark")
-							return
+							return false
 						}
 						continue
 					}
@@ -286,16 +916,268 @@ func (e *ExportAPI) HandleImport(w http.ResponseWriter, r *http.Request) {
 				// Create new bookmark as item
 				title := exportBookmark.Title
 				url := exportBookmark.URL
-				_, err := e.db.CreateItem(newList.ID, "bookmark", &title, &url, nil, exportBookmark.FaviconURL, "auto", nil, exportBookmark.Position)
+				item, err := e.db.CreateItem(newList.ID, "bookmark", &title, &url, nil, exportBookmark.FaviconURL, "auto", nil, sanitize.DefaultFormat, exportBookmark.Position, true)
 				if err != nil {
 					respondError(w, http.StatusInternalServerError, "Failed to create bookmark")
-					return
+					return false
+				}
+				if matcher != nil {
+					matcher.add(item)
 				}
 			}
 		}
 	}
 
-	respondJSON(w, http.StatusOK, map[string]string{
-		"message": fmt.Sprintf("Successfully imported %d lists", len(req.Data.Lists)),
-	})
+	return true
+}
+
+// importedBoardTitle names a board created by HandleImportBoard when neither the
+// request nor the file gives a title
+const importedBoardTitle = "Imported Board"
+
+// HandleImportBoard creates a new board from a loom export file and imports the file's
+// lists and items into it. The board is named by the title query parameter, else the
+// file's board_title, else importedBoardTitle.
+func (e *ExportAPI) HandleImportBoard(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	e.limitImportBody(w, r)
+	var data models.ExportData
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		e.respondImportBodyError(w, err)
+		return
+	}
+
+	sanitizeImportText(&data)
+	title := strings.TrimSpace(sanitizeText(r.URL.Query().Get("title")))
+	if title == "" {
+		title = strings.TrimSpace(data.BoardTitle)
+	}
+	if title == "" {
+		title = importedBoardTitle
+	}
+	if utf8.RuneCountInString(title) > 100 {
+		respondError(w, http.StatusBadRequest, "Title must be 100 characters or less")
+		return
+	}
+
+	if !e.checkImportData(w, data) {
+		return
+	}
+	if !e.itemLimits.checkItemLimits(w, e.db, userID, countImportItemsByType(data)) {
+		return
+	}
+	faviconsDropped := e.dropOversizedFavicons(&data)
+
+	board, err := e.db.CreateBoard(userID, title, false)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to create board")
+		return
+	}
+
+	// Nothing on a new board can match, so every list and item is created fresh
+	if !e.importLists(w, userID, board.ID, data, false, "") {
+		e.discardBoard(userID, board.ID)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, ImportBoardResponse{Board: board, FaviconsDropped: faviconsDropped})
+}
+
+// ImportBoardResponse is the board created by HandleImportBoard plus what the import dropped
+type ImportBoardResponse struct {
+	*models.Board
+	FaviconsDropped int `json:"favicons_dropped"`
+}
+
+// discardBoard deletes a board and its lists after a failed import, best effort
+func (e *ExportAPI) discardBoard(userID, boardID int) {
+	lists, err := e.db.GetListsByBoard(userID, boardID)
+	if err == nil {
+		for _, list := range lists {
+			e.db.DeleteList(list.ID, userID)
+		}
+	}
+	e.db.DeleteBoard(boardID, userID)
+}
+
+// ImportValidationReport describes structural problems found in an export file
+type ImportValidationReport struct {
+	Valid    bool     `json:"valid"`
+	Version  int      `json:"version"`
+	Lists    int      `json:"lists"`
+	Items    int      `json:"items"`
+	Problems []string `json:"problems"`
+}
+
+// HandleValidateImport checks that a loom export file is well-formed without writing anything
+func (e *ExportAPI) HandleValidateImport(w http.ResponseWriter, r *http.Request) {
+	if _, ok := getUserID(r.Context()); !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	e.limitImportBody(w, r)
+	var data models.ExportData
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		e.respondImportBodyError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, e.validateExportData(data))
+}
+
+// validateExportData reports every structural problem in data, including the import size caps
+func (e *ExportAPI) validateExportData(data models.ExportData) ImportValidationReport {
+	report := ImportValidationReport{
+		Version:  data.Version,
+		Lists:    len(data.Lists),
+		Items:    countImportItems(data),
+		Problems: []string{},
+	}
+	problemf := func(format string, args ...any) {
+		report.Problems = append(report.Problems, fmt.Sprintf(format, args...))
+	}
+
+	if data.Version != 1 {
+		problemf("Unsupported export version %d", data.Version)
+	}
+	if e.maxImportLists > 0 && report.Lists > e.maxImportLists {
+		problemf("Import exceeds the maximum of %d lists", e.maxImportLists)
+	}
+	if e.maxImportItems > 0 && report.Items > e.maxImportItems {
+		problemf("Import exceeds the maximum of %d items", e.maxImportItems)
+	}
+
+	for _, entryErr := range validateImportEntries(data) {
+		report.Problems = append(report.Problems, entryErr.problem())
+	}
+
+	report.Valid = len(report.Problems) == 0
+	return report
+}
+
+// ImportEntryError locates an invalid list or item in an import file. Indexes are
+// zero-based; ItemIndex is absent for list-level errors.
+type ImportEntryError struct {
+	Path      string `json:"path"`
+	ListIndex int    `json:"list_index"`
+	ItemIndex *int   `json:"item_index,omitempty"`
+	Reason    string `json:"reason"`
+
+	// kind is "item" or "bookmark" (legacy entries) when ItemIndex is set
+	kind string
+}
+
+// ImportErrorResponse is the 400 body for an import rejected by validateImportEntries
+type ImportErrorResponse struct {
+	Error  string             `json:"error"`
+	Errors []ImportEntryError `json:"errors"`
+}
+
+// problem formats the error as a validation report line, e.g. "List 1, item 2: invalid URL"
+func (e ImportEntryError) problem() string {
+	label := fmt.Sprintf("List %d", e.ListIndex+1)
+	if e.ItemIndex != nil {
+		label += fmt.Sprintf(", %s %d", e.kind, *e.ItemIndex+1)
+	}
+	return label + ": " + e.Reason
+}
+
+// validateImportEntries checks every list and item in data, returning one error per problem in file order
+func validateImportEntries(data models.ExportData) []ImportEntryError {
+	var errs []ImportEntryError
+	listError := func(i int, field, reason string) {
+		errs = append(errs, ImportEntryError{
+			Path:      fmt.Sprintf("lists[%d].%s", i, field),
+			ListIndex: i,
+			Reason:    reason,
+		})
+	}
+	entryError := func(i int, kind string, j int, field, reason string) {
+		errs = append(errs, ImportEntryError{
+			Path:      fmt.Sprintf("lists[%d].%ss[%d].%s", i, kind, j, field),
+			ListIndex: i,
+			ItemIndex: &j,
+			Reason:    reason,
+			kind:      kind,
+		})
+	}
+
+	for i, list := range data.Lists {
+		if strings.TrimSpace(list.Title) == "" {
+			listError(i, "title", "title is required")
+		}
+		if !isValidHexColor(list.Color) {
+			listError(i, "color", fmt.Sprintf("invalid color %q", list.Color))
+		}
+		if list.SortMode != "" && !db.IsValidListSortMode(list.SortMode) {
+			listError(i, "sort_mode", fmt.Sprintf("invalid sort mode %q", list.SortMode))
+		}
+
+		for j, item := range list.Items {
+			if !db.IsValidItemType(item.Type) {
+				entryError(i, "item", j, "type", fmt.Sprintf("invalid type %q (must be %s)", item.Type, db.ItemTypesDescription()))
+				continue
+			}
+			switch item.Type {
+			case db.ItemTypeBookmark:
+				if item.URL == nil || !isValidURL(strings.TrimSpace(*item.URL)) {
+					entryError(i, "item", j, "url", "invalid URL")
+				}
+			case db.ItemTypeNote:
+				if item.Content == nil || strings.TrimSpace(*item.Content) == "" {
+					entryError(i, "item", j, "content", "content is required for notes")
+				}
+			case db.ItemTypeSeparator:
+				if item.URL != nil && strings.TrimSpace(*item.URL) != "" {
+					entryError(i, "item", j, "url", "separators cannot have a URL")
+				}
+			}
+			if item.ContentFormat != "" && !sanitize.IsValidFormat(item.ContentFormat) {
+				entryError(i, "item", j, "content_format", fmt.Sprintf("invalid content format %q", item.ContentFormat))
+			}
+			if item.FaviconURL != nil && !isValidFaviconURL(*item.FaviconURL) {
+				entryError(i, "item", j, "favicon_url", "invalid favicon URL")
+			}
+			if item.PreviewImageURL != nil && !isImageDataURI(*item.PreviewImageURL) && !favicon.IsStoredURL(*item.PreviewImageURL, StoredPreviewPath) {
+				entryError(i, "item", j, "preview_image_url", "invalid preview image")
+			}
+		}
+
+		// Legacy bookmarks are only imported when a list has no items
+		if len(list.Items) > 0 {
+			continue
+		}
+		for j, bookmark := range list.Bookmarks {
+			if !isValidURL(strings.TrimSpace(bookmark.URL)) {
+				entryError(i, "bookmark", j, "url", "invalid URL")
+			}
+			if bookmark.FaviconURL != nil && !isValidFaviconURL(*bookmark.FaviconURL) {
+				entryError(i, "bookmark", j, "favicon_url", "invalid favicon URL")
+			}
+		}
+	}
+
+	return errs
+}
+
+// isValidFaviconURL reports whether s is a favicon reference loom can display: a base64
+// image data URI, a stored icon or built-in icon path, or an http(s) URL
+func isValidFaviconURL(s string) bool {
+	return strings.HasPrefix(s, "/static/") || favicon.IsStoredIconURL(s) || isImageDataURI(s) || isValidURL(s)
+}
+
+// isImageDataURI reports whether s is a base64 image data URI
+func isImageDataURI(s string) bool {
+	mediaType, encoded, ok := strings.Cut(strings.TrimPrefix(s, "data:"), ";base64,")
+	if !ok || !strings.HasPrefix(s, "data:") || !strings.HasPrefix(mediaType, "image/") {
+		return false
+	}
+	_, err := base64.StdEncoding.DecodeString(encoded)
+	return err == nil
 }
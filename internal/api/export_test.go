@@ -0,0 +1,1104 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crueber/loom/internal/db"
+	"github.com/crueber/loom/internal/favicon"
+	"github.com/crueber/loom/internal/models"
+)
+
+func TestHandleImport_MergeMatchByURLUpdatesExistingItem(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	board, err := database.GetDefaultBoard(userID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	list, err := database.CreateList(userID, board.ID, "Reading", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	oldTitle := "Old Title"
+	existingURL := "https://Example.com/article/"
+	existing, err := database.CreateItem(list.ID, "bookmark", &oldTitle, &existingURL, nil, nil, "auto", nil, "markdown", 0, true)
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	newTitle := "New Title"
+	importedURL := "https://example.com/article"
+	rec := performImportRequest(t, exportAPI, userID, ImportRequest{
+		Mode:    "merge",
+		MatchBy: "url",
+		Data: models.ExportData{
+			Version: 1,
+			Lists: []models.ExportList{{
+				ID:    9999,
+				Title: "Reading",
+				Color: "#ffffff",
+				Items: []models.ExportItem{{
+					ID:    8888,
+					Type:  "bookmark",
+					Title: &newTitle,
+					URL:   &importedURL,
+				}},
+			}},
+		},
+	})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	items, err := database.GetItems(list.ID)
+	if err != nil {
+		t.Fatalf("get items: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("len(items) = %d, want 1", len(items))
+	}
+	if items[0].ID != existing.ID {
+		t.Fatalf("item ID = %d, want %d", items[0].ID, existing.ID)
+	}
+	if got := *items[0].Title; got != newTitle {
+		t.Fatalf("title = %q, want %q", got, newTitle)
+	}
+}
+
+func TestHandleImport_MergeMatchByIDStaysOnTargetBoard(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	board, err := database.GetDefaultBoard(userID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	reading, err := database.CreateList(userID, board.ID, "Reading", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	otherBoard, err := database.CreateBoard(userID, "Work", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	work, err := database.CreateList(userID, otherBoard.ID, "Work", "#000000", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	workTitle := "Work Item"
+	workURL := "https://work.example.com"
+	workItem, err := database.CreateItem(work.ID, "bookmark", &workTitle, &workURL, nil, nil, "auto", nil, "markdown", 0, true)
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	// The exported IDs happen to be those of the list and item on the other board
+	importedTitle := "Imported"
+	importedURL := "https://example.com/imported"
+	rec := performImportRequest(t, exportAPI, userID, ImportRequest{
+		Mode:    "merge",
+		MatchBy: "id",
+		Data: models.ExportData{
+			Version: 1,
+			Lists: []models.ExportList{{
+				ID:    work.ID,
+				Title: "Reading",
+				Color: "#ffffff",
+				Items: []models.ExportItem{{
+					ID:    workItem.ID,
+					Type:  "bookmark",
+					Title: &importedTitle,
+					URL:   &importedURL,
+				}},
+			}},
+		},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	stillWork, err := database.GetList(work.ID, userID)
+	if err != nil {
+		t.Fatalf("get list: %v", err)
+	}
+	if stillWork.Title != "Work" {
+		t.Fatalf("other board's list title = %q, want it untouched", stillWork.Title)
+	}
+	workItems, err := database.GetItems(work.ID)
+	if err != nil {
+		t.Fatalf("get items: %v", err)
+	}
+	if len(workItems) != 1 || *workItems[0].Title != workTitle {
+		t.Fatalf("other board's items = %+v, want the original item untouched", workItems)
+	}
+
+	items, err := database.GetItems(reading.ID)
+	if err != nil {
+		t.Fatalf("get items: %v", err)
+	}
+	if len(items) != 1 || *items[0].Title != importedTitle {
+		t.Fatalf("items in Reading = %+v, want the imported item", items)
+	}
+	lists, err := database.GetListsByBoard(userID, board.ID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	if len(lists) != 1 {
+		t.Fatalf("len(lists) = %d, want the import merged into Reading", len(lists))
+	}
+}
+
+func TestHandleImport_MergeMatchByTitleStaysOnTargetBoard(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	board, err := database.GetDefaultBoard(userID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	otherBoard, err := database.CreateBoard(userID, "Work", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	work, err := database.CreateList(userID, otherBoard.ID, "Reading", "#000000", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	title := "Shared"
+	workURL := "https://work.example.com"
+	if _, err := database.CreateItem(work.ID, "bookmark", &title, &workURL, nil, nil, "auto", nil, "markdown", 0, true); err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	// The same list and item titles exist only on the other board
+	importedURL := "https://example.com/imported"
+	rec := performImportRequest(t, exportAPI, userID, ImportRequest{
+		Mode:    "merge",
+		MatchBy: "title",
+		Data: models.ExportData{
+			Version: 1,
+			Lists: []models.ExportList{{
+				ID:    1,
+				Title: "Reading",
+				Color: "#ffffff",
+				Items: []models.ExportItem{{ID: 1, Type: "bookmark", Title: &title, URL: &importedURL}},
+			}},
+		},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	workItems, err := database.GetItems(work.ID)
+	if err != nil {
+		t.Fatalf("get items: %v", err)
+	}
+	if len(workItems) != 1 || *workItems[0].URL != workURL {
+		t.Fatalf("other board's items = %+v, want the original item untouched", workItems)
+	}
+	lists, err := database.GetListsByBoard(userID, board.ID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	if len(lists) != 1 || lists[0].Title != "Reading" {
+		t.Fatalf("lists = %+v, want a new Reading list on the target board", lists)
+	}
+	items, err := database.GetItems(lists[0].ID)
+	if err != nil {
+		t.Fatalf("get items: %v", err)
+	}
+	if len(items) != 1 || *items[0].URL != importedURL {
+		t.Fatalf("items = %+v, want the imported item", items)
+	}
+}
+
+func TestHandleImport_InvalidMatchByRejected(t *testing.T) {
+	exportAPI, _, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	rec := performImportRequest(t, exportAPI, userID, ImportRequest{
+		Mode:    "merge",
+		MatchBy: "color",
+		Data:    models.ExportData{Version: 1},
+	})
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusBadRequest, rec.Body.String())
+	}
+}
+
+func TestHandleImport_ItemLimitRejectedBeforeWrites(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	board, err := database.GetDefaultBoard(userID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	if _, err := database.CreateList(userID, board.ID, "Keep Me", "#ffffff", 0, false); err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+
+	content := "note"
+	items := make([]models.ExportItem, 4)
+	for i := range items {
+		items[i] = models.ExportItem{Type: "note", Content: &content}
+	}
+	rec := performImportRequest(t, exportAPI, userID, ImportRequest{
+		Mode: "replace",
+		Data: models.ExportData{
+			Version: 1,
+			Lists:   []models.ExportList{{Title: "Too Big", Items: items}},
+		},
+	})
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusBadRequest, rec.Body.String())
+	}
+
+	lists, err := database.GetLists(userID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	if len(lists) != 1 || lists[0].Title != "Keep Me" {
+		t.Fatalf("lists = %+v, want the existing list untouched", lists)
+	}
+}
+
+func TestHandleImport_InvalidEntriesReportedBeforeWrites(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	goodURL := "https://example.com"
+	badURL := "not a url"
+	rec := performImportRequest(t, exportAPI, userID, ImportRequest{
+		Mode: "merge",
+		Data: models.ExportData{
+			Version: 1,
+			Lists: []models.ExportList{
+				{Title: "Fine", Color: "#3D6D95", Items: []models.ExportItem{{Type: "bookmark", URL: &goodURL}}},
+				{Title: "Broken", Color: "#3D6D95", Items: []models.ExportItem{
+					{Type: "bookmark", URL: &goodURL},
+					{Type: "bookmark", URL: &badURL},
+				}},
+			},
+		},
+	})
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusBadRequest, rec.Body.String())
+	}
+
+	var resp ImportErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Errors) != 1 {
+		t.Fatalf("errors = %+v, want one", resp.Errors)
+	}
+	got := resp.Errors[0]
+	if got.Path != "lists[1].items[1].url" || got.ListIndex != 1 || got.ItemIndex == nil || *got.ItemIndex != 1 || got.Reason != "invalid URL" {
+		t.Fatalf("error = %+v, want lists[1].items[1].url invalid URL", got)
+	}
+
+	lists, err := database.GetLists(userID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	if len(lists) != 0 {
+		t.Fatalf("lists = %+v, want nothing imported", lists)
+	}
+}
+
+func TestHandleExportDownload_TokenOnlyInHeader(t *testing.T) {
+	exportAPI, _, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/export/token", nil)
+	req = req.WithContext(setUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	exportAPI.HandleCreateExportToken(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("token status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Token == "" {
+		t.Fatalf("token body = %s (err %v), want a token", rec.Body.String(), err)
+	}
+
+	download := func(target, authorization string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		exportAPI.HandleExportDownload(rec, req)
+		return rec.Code
+	}
+
+	if code := download("/api/export/download?token="+body.Token, ""); code != http.StatusUnauthorized {
+		t.Fatalf("query token status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := download("/api/export/download", "Bearer tampered"); code != http.StatusUnauthorized {
+		t.Fatalf("bad token status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := download("/api/export/download", "Bearer "+body.Token); code != http.StatusOK {
+		t.Fatalf("header token status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestHandleImport_AcceptsFaviconReferences(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	url := "https://example.com"
+	icons := []string{
+		"https://example.com/favicon.ico",
+		favicon.StoredIconPath + strings.Repeat("ab", 32),
+		"data:image/png;base64,aWNvbg==",
+	}
+	var items []models.ExportItem
+	for i := range icons {
+		items = append(items, models.ExportItem{Type: "bookmark", URL: &url, FaviconURL: &icons[i], Position: i})
+	}
+	rec := performImportRequest(t, exportAPI, userID, ImportRequest{
+		Mode: "merge",
+		Data: models.ExportData{
+			Version: 1,
+			Lists:   []models.ExportList{{Title: "Icons", Color: "#3D6D95", Items: items}},
+		},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	lists, err := database.GetLists(userID)
+	if err != nil || len(lists) != 1 {
+		t.Fatalf("lists = %+v (err %v), want the imported list", lists, err)
+	}
+	stored, err := database.GetItems(lists[0].ID)
+	if err != nil || len(stored) != len(icons) {
+		t.Fatalf("items = %+v (err %v), want every bookmark", stored, err)
+	}
+	for i, item := range stored {
+		if item.FaviconURL == nil || *item.FaviconURL != icons[i] {
+			t.Fatalf("favicon %d = %v, want %q", i, item.FaviconURL, icons[i])
+		}
+	}
+
+	bad := "javascript:alert(1)"
+	rec = performImportRequest(t, exportAPI, userID, ImportRequest{
+		Mode: "merge",
+		Data: models.ExportData{
+			Version: 1,
+			Lists:   []models.ExportList{{Title: "Bad", Color: "#3D6D95", Items: []models.ExportItem{{Type: "bookmark", URL: &url, FaviconURL: &bad}}}},
+		},
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unsafe favicon status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleExport_InlinesStoredFavicons(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	store, err := favicon.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	exportAPI.SetFaviconStore(store)
+	hash, err := store.Put([]byte("icon"), "image/png")
+	if err != nil {
+		t.Fatalf("store icon: %v", err)
+	}
+
+	board, err := database.GetDefaultBoard(userID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	list, err := database.CreateList(userID, board.ID, "Icons", "#3D6D95", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	url := "https://example.com"
+	icon := favicon.StoredIconPath + hash
+	if _, err := database.CreateItem(list.ID, "bookmark", nil, &url, nil, &icon, "auto", nil, "markdown", 0, true); err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/export", nil)
+	req = req.WithContext(setUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	exportAPI.HandleExport(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var data models.ExportData
+	if err := json.Unmarshal(rec.Body.Bytes(), &data); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	want := "data:image/png;base64,aWNvbg=="
+	if len(data.Lists) != 1 || len(data.Lists[0].Items) != 1 || data.Lists[0].Items[0].FaviconURL == nil || *data.Lists[0].Items[0].FaviconURL != want {
+		t.Fatalf("export = %+v, want the stored icon inlined as %s", data.Lists, want)
+	}
+	if bookmark := data.Lists[0].Bookmarks[0]; bookmark.FaviconURL == nil || *bookmark.FaviconURL != want {
+		t.Fatalf("legacy bookmark favicon = %v, want %s", bookmark.FaviconURL, want)
+	}
+}
+
+func TestHandleExport_SortModeRoundTrips(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	board, err := database.GetDefaultBoard(userID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	list, err := database.CreateList(userID, board.ID, "Sorted", "#3D6D95", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	sortMode := "title"
+	if err := database.UpdateList(list.ID, userID, nil, nil, nil, &sortMode); err != nil {
+		t.Fatalf("update list: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/export", nil)
+	req = req.WithContext(setUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	exportAPI.HandleExport(rec, req)
+	var data models.ExportData
+	if err := json.Unmarshal(rec.Body.Bytes(), &data); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if len(data.Lists) != 1 || data.Lists[0].SortMode != "title" {
+		t.Fatalf("export = %+v, want the list's sort mode", data.Lists)
+	}
+
+	if rec := performConfirmedReplace(t, exportAPI, userID, ImportRequest{Mode: "replace", Data: data}); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	lists, err := database.GetLists(userID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	if len(lists) != 1 || lists[0].SortMode != "title" {
+		t.Fatalf("lists = %+v, want the sort mode restored", lists)
+	}
+
+	data.Lists[0].SortMode = "random"
+	if rec := performImportRequest(t, exportAPI, userID, ImportRequest{Mode: "merge", Data: data}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid sort mode status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleImport_StoresPreviewImages(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	store, err := favicon.NewStoreAt(t.TempDir(), StoredPreviewPath)
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	exportAPI.SetPreviewStore(store)
+
+	title := "Example"
+	url := "https://example.com"
+	preview := "data:image/png;base64,aW1hZ2U="
+	rec := performImportRequest(t, exportAPI, userID, ImportRequest{
+		Mode: "merge",
+		Data: models.ExportData{
+			Version: 1,
+			Lists: []models.ExportList{{
+				ID:    1,
+				Title: "Previews",
+				Color: "#ffffff",
+				Items: []models.ExportItem{{ID: 1, Type: "bookmark", Title: &title, URL: &url, PreviewImageURL: &preview}},
+			}},
+		},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	items, err := database.SearchItems(userID, "Example", nil)
+	if err != nil {
+		t.Fatalf("search items: %v", err)
+	}
+	if len(items) != 1 || items[0].PreviewImageURL == nil || !store.OwnsURL(*items[0].PreviewImageURL) {
+		t.Fatalf("imported items = %+v, want the preview moved into the store", items)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/export", nil)
+	req = req.WithContext(setUserID(req.Context(), userID))
+	rec = httptest.NewRecorder()
+	exportAPI.HandleExport(rec, req)
+	var data models.ExportData
+	if err := json.Unmarshal(rec.Body.Bytes(), &data); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	for _, list := range data.Lists {
+		if list.Title == "Previews" {
+			if len(list.Items) != 1 || list.Items[0].PreviewImageURL == nil || *list.Items[0].PreviewImageURL != preview {
+				t.Fatalf("exported items = %+v, want the stored preview inlined as %s", list.Items, preview)
+			}
+			return
+		}
+	}
+	t.Fatalf("export = %+v, want the Previews list", data.Lists)
+}
+
+func TestHandleImport_StripsControlCharacters(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	title := "Ti\x00tle"
+	url := "https://example.com"
+	content := "eggs\x00\x1b\nmilk"
+	rec := performImportRequest(t, exportAPI, userID, ImportRequest{
+		Mode: "merge",
+		Data: models.ExportData{
+			Version: 1,
+			Lists: []models.ExportList{{
+				ID:    1,
+				Title: "Sho\x00pping",
+				Color: "#ffffff",
+				Items: []models.ExportItem{
+					{ID: 1, Type: "bookmark", Title: &title, URL: &url},
+					{ID: 2, Type: "note", Content: &content, ContentFormat: "text"},
+				},
+			}},
+		},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	board, err := database.GetDefaultBoard(userID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	lists, err := database.GetListsByBoard(userID, board.ID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	for _, list := range lists {
+		if list.Title != "Shopping" {
+			continue
+		}
+		items, err := database.GetItems(list.ID)
+		if err != nil {
+			t.Fatalf("get items: %v", err)
+		}
+		if len(items) != 2 || *items[0].Title != "Title" || *items[1].Content != "eggs\nmilk" {
+			t.Fatalf("items = %+v, want control characters stripped", items)
+		}
+		return
+	}
+	t.Fatalf("lists = %+v, want Shopping with control characters stripped", lists)
+}
+
+func TestHandleImport_HonoursUniqueListTitles(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+	exportAPI.SetUniqueListTitles(true)
+
+	board, err := database.GetDefaultBoard(userID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	if _, err := database.CreateList(userID, board.ID, "Reading", "#ffffff", 0, false); err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+
+	rec := performImportRequest(t, exportAPI, userID, ImportRequest{
+		Mode: "merge",
+		Data: models.ExportData{
+			Version: 1,
+			Lists: []models.ExportList{
+				{ID: 1, Title: "Todo", Color: "#ffffff"},
+				{ID: 2, Title: "TODO", Color: "#ffffff"},
+			},
+		},
+	})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("repeated titles status = %d, want %d, body=%s", rec.Code, http.StatusConflict, rec.Body.String())
+	}
+
+	// A title differing only in case merges into the existing list
+	rec = performImportRequest(t, exportAPI, userID, ImportRequest{
+		Mode: "merge",
+		Data: models.ExportData{
+			Version: 1,
+			Lists:   []models.ExportList{{ID: 1, Title: "READING", Color: "#000000"}},
+		},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	lists, err := database.GetListsByBoard(userID, board.ID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	if len(lists) != 1 || lists[0].Title != "READING" {
+		t.Fatalf("lists = %+v, want the one list renamed", lists)
+	}
+}
+
+func TestHandleImportBoard_CreatesBoardFromFile(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	defaultBoard, err := database.GetDefaultBoard(userID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+
+	url := "https://example.com"
+	body, err := json.Marshal(models.ExportData{
+		Version:    1,
+		BoardTitle: "Research",
+		Lists: []models.ExportList{
+			{ID: 1, Title: "Papers", Color: "#3D6D95", Items: []models.ExportItem{{ID: 1, Type: "bookmark", URL: &url}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal request body: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/boards/import", bytes.NewReader(body))
+	req = req.WithContext(setUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	exportAPI.HandleImportBoard(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var board models.Board
+	if err := json.Unmarshal(rec.Body.Bytes(), &board); err != nil {
+		t.Fatalf("decode board: %v", err)
+	}
+	if board.ID == defaultBoard.ID || board.Title != "Research" {
+		t.Fatalf("board = %+v, want a new board named from the file", board)
+	}
+
+	lists, err := database.GetListsByBoard(userID, board.ID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	if len(lists) != 1 || lists[0].Title != "Papers" {
+		t.Fatalf("lists = %+v, want the imported list on the new board", lists)
+	}
+	if items, err := database.GetItems(lists[0].ID); err != nil || len(items) != 1 {
+		t.Fatalf("items = %+v (err %v), want the imported bookmark", items, err)
+	}
+	if defaultLists, err := database.GetListsByBoard(userID, defaultBoard.ID); err != nil || len(defaultLists) != 0 {
+		t.Fatalf("default board lists = %+v (err %v), want none", defaultLists, err)
+	}
+}
+
+func TestHandleImport_DropsOversizedFavicons(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+	exportAPI.SetMaxFaviconLength(40)
+
+	url := "https://example.com"
+	small := "data:image/png;base64,aWNvbg=="
+	large := "data:image/png;base64," + strings.Repeat("QUFB", 10)
+	rec := performImportRequest(t, exportAPI, userID, ImportRequest{
+		Mode: "merge",
+		Data: models.ExportData{
+			Version: 1,
+			Lists: []models.ExportList{{Title: "Icons", Color: "#3D6D95", Items: []models.ExportItem{
+				{Type: "bookmark", URL: &url, FaviconURL: &small, Position: 0},
+				{Type: "bookmark", URL: &url, FaviconURL: &large, Position: 1},
+			}}},
+		},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var resp struct {
+		FaviconsDropped int `json:"favicons_dropped"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.FaviconsDropped != 1 {
+		t.Fatalf("favicons_dropped = %d, want 1", resp.FaviconsDropped)
+	}
+
+	lists, err := database.GetLists(userID)
+	if err != nil || len(lists) != 1 {
+		t.Fatalf("lists = %+v (err %v), want the imported list", lists, err)
+	}
+	items, err := database.GetItems(lists[0].ID)
+	if err != nil || len(items) != 2 {
+		t.Fatalf("items = %+v (err %v), want both bookmarks", items, err)
+	}
+	if items[0].FaviconURL == nil || *items[0].FaviconURL != small {
+		t.Fatalf("small favicon = %v, want it kept", items[0].FaviconURL)
+	}
+	if items[1].FaviconURL != nil {
+		t.Fatalf("large favicon = %q, want it dropped", *items[1].FaviconURL)
+	}
+}
+
+func TestHandleImport_ReplaceRequiresConfirmation(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	board, err := database.GetDefaultBoard(userID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	existing, err := database.CreateList(userID, board.ID, "Keep Me", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	content := "old"
+	if _, err := database.CreateItem(existing.ID, "note", nil, nil, &content, nil, "auto", nil, "text", 0, false); err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	request := ImportRequest{
+		Mode: "replace",
+		Data: models.ExportData{
+			Version: 1,
+			Lists:   []models.ExportList{{Title: "New", Color: "#ffffff"}},
+		},
+	}
+	keptExisting := func() {
+		t.Helper()
+		lists, err := database.GetLists(userID)
+		if err != nil {
+			t.Fatalf("get lists: %v", err)
+		}
+		if len(lists) != 1 || lists[0].Title != "Keep Me" {
+			t.Fatalf("lists = %+v, want the existing list untouched", lists)
+		}
+	}
+
+	rec := performImportRequest(t, exportAPI, userID, request)
+	if rec.Code != http.StatusPreconditionRequired {
+		t.Fatalf("dry run status = %d, want %d, body=%s", rec.Code, http.StatusPreconditionRequired, rec.Body.String())
+	}
+	var confirmation ImportReplaceConfirmation
+	if err := json.Unmarshal(rec.Body.Bytes(), &confirmation); err != nil {
+		t.Fatalf("unmarshal confirmation: %v", err)
+	}
+	if !confirmation.ConfirmationRequired || confirmation.ConfirmToken == "" || confirmation.Error == "" ||
+		confirmation.ListsDeleted != 1 || confirmation.ItemsDeleted != 1 ||
+		confirmation.ListsImported != 1 || confirmation.ItemsImported != 0 {
+		t.Fatalf("confirmation = %+v, want a token and 1 list and 1 item deleted, 1 list imported", confirmation)
+	}
+	keptExisting()
+
+	other, err := database.CreateUser("other", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	changed := request
+	changed.Data.Lists = []models.ExportList{{Title: "Different", Color: "#ffffff"}}
+	changed.ConfirmToken = confirmation.ConfirmToken
+	request.ConfirmToken = confirmation.ConfirmToken
+
+	if rec := performImportRequest(t, exportAPI, other.ID, request); rec.Code != http.StatusBadRequest {
+		t.Fatalf("other user's status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := performImportRequest(t, exportAPI, userID, changed); rec.Code != http.StatusBadRequest {
+		t.Fatalf("different data status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	forged := request
+	forged.ConfirmToken = "forged"
+	if rec := performImportRequest(t, exportAPI, userID, forged); rec.Code != http.StatusBadRequest {
+		t.Fatalf("forged token status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	keptExisting()
+
+	if rec := performImportRequest(t, exportAPI, userID, request); rec.Code != http.StatusOK {
+		t.Fatalf("confirmed status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	lists, err := database.GetLists(userID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	if len(lists) != 1 || lists[0].Title != "New" {
+		t.Fatalf("lists = %+v, want only the imported list", lists)
+	}
+}
+
+func TestHandleImport_PreservesCreatedAt(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	listCreated := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	itemCreated := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+	content := "kept"
+	rec := performConfirmedReplace(t, exportAPI, userID, ImportRequest{
+		Mode: "replace",
+		Data: models.ExportData{
+			Version: 1,
+			Lists: []models.ExportList{{
+				Title:     "Old",
+				Color:     "#ffffff",
+				CreatedAt: &listCreated,
+				Items: []models.ExportItem{
+					{Type: "note", Content: &content, CreatedAt: &itemCreated},
+					{Type: "note", Content: &content, Position: 1},
+				},
+			}},
+		},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	lists, err := database.GetLists(userID)
+	if err != nil || len(lists) != 1 {
+		t.Fatalf("get lists = %v, %v, want 1 list", lists, err)
+	}
+	if !lists[0].CreatedAt.Equal(listCreated) {
+		t.Fatalf("list created_at = %v, want %v", lists[0].CreatedAt, listCreated)
+	}
+
+	items, err := database.GetItems(lists[0].ID)
+	if err != nil || len(items) != 2 {
+		t.Fatalf("get items = %v, %v, want 2 items", items, err)
+	}
+	if !items[0].CreatedAt.Equal(itemCreated) {
+		t.Fatalf("item created_at = %v, want %v", items[0].CreatedAt, itemCreated)
+	}
+	if time.Since(items[1].CreatedAt) > time.Hour {
+		t.Fatalf("item without created_at got %v, want about now", items[1].CreatedAt)
+	}
+}
+
+func TestHandleImport_PocketFormatGroupsByTag(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	body := `{"list": {
+		"2": {"given_url": "https://example.com/b", "given_title": "", "resolved_title": "Resolved B", "time_added": "1700000000", "tags": {"work": {"tag": "work"}}},
+		"1": {"given_url": "https://example.com/a", "given_title": "A", "time_added": "1600000000"},
+		"3": {"given_url": "javascript:alert(1)", "given_title": "Bad"}
+	}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/import?format=pocket", bytes.NewReader([]byte(body)))
+	req = req.WithContext(setUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	exportAPI.HandleImport(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	board, err := database.GetDefaultBoard(userID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	lists, err := database.GetListsByBoard(userID, board.ID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	titles := make(map[string]int)
+	for _, list := range lists {
+		items, err := database.GetItems(list.ID)
+		if err != nil {
+			t.Fatalf("get items: %v", err)
+		}
+		titles[list.Title] = len(items)
+		if list.Title == "work" && *items[0].Title != "Resolved B" {
+			t.Fatalf("work item title = %q, want %q", *items[0].Title, "Resolved B")
+		}
+	}
+	if titles["Pocket"] != 1 || titles["work"] != 1 {
+		t.Fatalf("items per list = %v, want Pocket:1 work:1", titles)
+	}
+}
+
+func TestHandleImport_PocketFormatAcceptsEmptyAccount(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	// Pocket exports an account with nothing saved as an empty array
+	req := httptest.NewRequest(http.MethodPost, "/api/import?format=pocket", bytes.NewReader([]byte(`{"status": 2, "list": []}`)))
+	req = req.WithContext(setUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	exportAPI.HandleImport(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	board, err := database.GetDefaultBoard(userID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	if lists, err := database.GetListsByBoard(userID, board.ID); err != nil || len(lists) != 0 {
+		t.Fatalf("lists = %v, %v, want none imported", lists, err)
+	}
+}
+
+func TestHandleImport_RejectsOversizedBody(t *testing.T) {
+	exportAPI, _, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+	exportAPI.SetMaxImportBytes(64)
+
+	body := `[{"given_url": "https://example.com/` + strings.Repeat("a", 64) + `"}]`
+	for _, tt := range []struct {
+		name    string
+		target  string
+		handler http.HandlerFunc
+	}{
+		{name: "format import", target: "/api/import?format=pocket", handler: exportAPI.HandleImport},
+		{name: "loom import", target: "/api/import", handler: exportAPI.HandleImport},
+		{name: "board import", target: "/api/boards/import", handler: exportAPI.HandleImportBoard},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(body))
+		req = req.WithContext(setUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		tt.handler(rec, req)
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("%s: status = %d, want %d, body=%s", tt.name, rec.Code, http.StatusRequestEntityTooLarge, rec.Body.String())
+		}
+	}
+}
+
+func TestHandleImport_UnknownFormatRejected(t *testing.T) {
+	exportAPI, _, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/import?format=delicious", bytes.NewReader([]byte(`[]`)))
+	req = req.WithContext(setUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	exportAPI.HandleImport(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleValidateImport_ReportsProblemsWithoutWriting(t *testing.T) {
+	exportAPI, database, userID, cleanup := newExportAPITestFixture(t)
+	defer cleanup()
+
+	badURL := "ftp://example.com"
+	goodURL := "https://example.com"
+	badFavicon := "javascript:alert(1)"
+	goodFavicon := "data:image/png;base64,aGVsbG8="
+	body, err := json.Marshal(models.ExportData{
+		Version: 1,
+		Lists: []models.ExportList{{
+			ID:    1,
+			Title: "Reading",
+			Color: "#ffffff",
+			Items: []models.ExportItem{
+				{ID: 1, Type: "bookmark", URL: &goodURL, FaviconURL: &goodFavicon},
+				{ID: 2, Type: "bookmark", URL: &badURL},
+				{ID: 3, Type: "bookmark", URL: &goodURL, FaviconURL: &badFavicon},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("marshal request body: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/import/validate", bytes.NewReader(body))
+	req = req.WithContext(setUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	exportAPI.HandleValidateImport(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var report ImportValidationReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if report.Valid {
+		t.Fatalf("report.Valid = true, want false")
+	}
+	if report.Lists != 1 || report.Items != 3 {
+		t.Fatalf("counts = %d lists, %d items, want 1 and 3", report.Lists, report.Items)
+	}
+	if len(report.Problems) != 2 {
+		t.Fatalf("problems = %v, want 2 entries", report.Problems)
+	}
+
+	lists, err := database.GetLists(userID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	if len(lists) != 0 {
+		t.Fatalf("len(lists) = %d, want 0", len(lists))
+	}
+}
+
+func newExportAPITestFixture(t *testing.T) (*ExportAPI, *db.DB, int, func()) {
+	t.Helper()
+
+	dbPath := filepath.Join(t.TempDir(), "bookmarks.db")
+	database, err := db.New(dbPath)
+	if err != nil {
+		t.Fatalf("create test db: %v", err)
+	}
+
+	user, err := database.CreateUser("test-user", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	cleanup := func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("close db: %v", err)
+		}
+	}
+
+	return NewExportAPI(database, []byte("test-signing-key"), 2, 3), database, user.ID, cleanup
+}
+
+// performConfirmedReplace runs a replace import through both steps: the dry run, then
+// the same request with the confirmation token it returned
+func performConfirmedReplace(t *testing.T, exportAPI *ExportAPI, userID int, payload ImportRequest) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := performImportRequest(t, exportAPI, userID, payload)
+	var confirmation ImportReplaceConfirmation
+	if err := json.Unmarshal(rec.Body.Bytes(), &confirmation); err != nil || rec.Code != http.StatusPreconditionRequired || !confirmation.ConfirmationRequired {
+		t.Fatalf("dry run = %d %s, want a confirmation", rec.Code, rec.Body.String())
+	}
+
+	payload.ConfirmToken = confirmation.ConfirmToken
+	return performImportRequest(t, exportAPI, userID, payload)
+}
+
+func performImportRequest(t *testing.T, exportAPI *ExportAPI, userID int, payload ImportRequest) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("marshal request body: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/import", bytes.NewReader(body))
+	req = req.WithContext(setUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+
+	exportAPI.HandleImport(rec, req)
+
+	return rec
+}
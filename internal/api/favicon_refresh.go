@@ -0,0 +1,170 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/crueber/loom/internal/db"
+	"github.com/crueber/loom/internal/favicon"
+	"github.com/crueber/loom/internal/models"
+	"github.com/crueber/loom/internal/urlutil"
+)
+
+// maxStaleRefreshBatch bounds how many favicons one board refresh re-fetches;
+// the rest are picked up by later refreshes
+const maxStaleRefreshBatch = 50
+
+// FaviconRefresher re-fetches favicons older than a configured age in the background.
+// A nil refresher, or one with a zero age, never refreshes anything.
+type FaviconRefresher struct {
+	db      *db.DB
+	fetcher *favicon.Fetcher
+	maxAge  time.Duration
+	auto    bool
+
+	// fetchQuota caps the favicons refreshed for each user per day
+	fetchQuota DailyQuota
+
+	mu       sync.Mutex
+	inFlight map[int]bool // item IDs being re-fetched
+	wg       sync.WaitGroup
+}
+
+// NewFaviconRefresher creates a refresher treating favicons fetched more than maxAge ago as stale
+func NewFaviconRefresher(database *db.DB, fetcher *favicon.Fetcher, maxAge time.Duration) *FaviconRefresher {
+	return &FaviconRefresher{
+		db:       database,
+		fetcher:  fetcher,
+		maxAge:   maxAge,
+		inFlight: make(map[int]bool),
+	}
+}
+
+// SetAuto makes board loads and item edits refresh stale favicons without being asked
+func (f *FaviconRefresher) SetAuto(auto bool) {
+	f.auto = auto
+}
+
+// SetFetchQuota counts refreshed favicons against their owner's fetch quota; a user
+// with none left keeps their stale icons until it resets
+func (f *FaviconRefresher) SetFetchQuota(quota DailyQuota) {
+	f.fetchQuota = quota
+}
+
+// Auto reports whether stale favicons are refreshed on every board load and item edit
+func (f *FaviconRefresher) Auto() bool {
+	return f != nil && f.auto
+}
+
+// enabled reports whether the refresher does anything
+func (f *FaviconRefresher) enabled() bool {
+	return f != nil && f.maxAge > 0 && !f.fetcher.Disabled()
+}
+
+// RefreshBoard re-fetches the board's stale favicons in the background and returns immediately
+func (f *FaviconRefresher) RefreshBoard(userID, boardID int) {
+	if !f.enabled() {
+		return
+	}
+
+	f.background(userID, func() []*models.Item {
+		items, err := f.db.GetStaleFaviconBookmarks(userID, boardID, f.maxAge, maxStaleRefreshBatch)
+		if err != nil {
+			log.Printf("Failed to find stale favicons for board %d: %v", boardID, err)
+			return nil
+		}
+		return items
+	})
+}
+
+// RefreshItem re-fetches one of userID's bookmarks' favicon in the background if it is stale
+func (f *FaviconRefresher) RefreshItem(userID int, item *models.Item) {
+	if !f.enabled() {
+		return
+	}
+
+	f.background(userID, func() []*models.Item {
+		stale, err := f.db.IsFaviconStale(item.ID, f.maxAge)
+		if err != nil {
+			log.Printf("Failed to check favicon age for item %d: %v", item.ID, err)
+			return nil
+		}
+		if !stale {
+			return nil
+		}
+		return []*models.Item{item}
+	})
+}
+
+// Wait blocks until every background refresh started so far has finished
+func (f *FaviconRefresher) Wait() {
+	if f != nil {
+		f.wg.Wait()
+	}
+}
+
+// background re-fetches the items of userID's that find returns on a new goroutine,
+// skipping any another refresh is already handling
+func (f *FaviconRefresher) background(userID int, find func() []*models.Item) {
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+
+		items := find()
+		f.mu.Lock()
+		var claimed []*models.Item
+		for _, item := range items {
+			if !f.inFlight[item.ID] {
+				f.inFlight[item.ID] = true
+				claimed = append(claimed, item)
+			}
+		}
+		f.mu.Unlock()
+
+		// Once the fetcher's circuit breaker opens or the user's fetch quota runs out
+		// the rest of the pass is skipped; those items stay stale and are picked up
+		// by a later refresh
+		paused := false
+		for _, item := range claimed {
+			if !paused {
+				paused = !f.refresh(userID, item)
+			}
+
+			f.mu.Lock()
+			delete(f.inFlight, item.ID)
+			f.mu.Unlock()
+		}
+	}()
+}
+
+// refresh re-fetches one item's favicon, keeping the old icon if the fetch fails. It
+// returns false without recording anything when fetching is paused by the circuit
+// breaker or userID's fetch quota is used up.
+func (f *FaviconRefresher) refresh(userID int, item *models.Item) bool {
+	if item.URL == nil {
+		return true
+	}
+	if fetchesIcon(item.IconSource) && !f.fetchQuota.allowFetch(nil, userID) {
+		return false
+	}
+
+	domain, _ := urlutil.Domain(*item.URL)
+	customIconURL := item.CustomIconURL
+	if item.IconSource == "auto" {
+		customIconURL = nil
+	}
+
+	faviconURL, err := f.fetcher.FetchIcon(item.IconSource, customIconURL, domain)
+	if errors.Is(err, favicon.ErrCircuitOpen) {
+		return false
+	}
+	if err != nil {
+		faviconURL = nil
+	}
+	if err := f.db.RecordFaviconFetch(item.ID, faviconURL); err != nil {
+		log.Printf("Failed to store refreshed favicon for item %d: %v", item.ID, err)
+	}
+	return true
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/crueber/loom/internal/favicon"
+	"github.com/crueber/loom/internal/urlutil"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// maxFaviconBatchURLs caps how many URLs one POST /api/favicons may resolve
+	maxFaviconBatchURLs = 50
+	// faviconBatchWorkers bounds the lookups a single request runs at once; the
+	// fetcher's own limit still applies across all requests
+	faviconBatchWorkers = 4
+)
+
+// FaviconsAPI handles favicon lookups that aren't tied to a saved item
+type FaviconsAPI struct {
+	fetcher    *favicon.Fetcher
+	store      *favicon.Store
+	fetchQuota DailyQuota
+}
+
+// NewFaviconsAPI creates a new favicons API handler
+func NewFaviconsAPI(fetcher *favicon.Fetcher) *FaviconsAPI {
+	return &FaviconsAPI{fetcher: fetcher}
+}
+
+// SetStore enables GET /api/favicons/{hash} for icons kept in store
+func (api *FaviconsAPI) SetStore(store *favicon.Store) {
+	api.store = store
+}
+
+// SetFetchQuota counts each domain a batch lookup fetches against quota
+func (api *FaviconsAPI) SetFetchQuota(quota DailyQuota) {
+	api.fetchQuota = quota
+}
+
+// BatchFaviconsRequest represents a request to resolve favicons for several URLs
+type BatchFaviconsRequest struct {
+	URLs []string `json:"urls"`
+}
+
+// HandleBatchFavicons resolves favicons for up to maxFaviconBatchURLs URLs and returns
+// a map of URL to data URI, or null when the URL is invalid, blocked or has no icon.
+// Each domain fetched counts toward the fetch quota; domains past it are left null,
+// and a lookup with none left is refused with 429.
+func (api *FaviconsAPI) HandleBatchFavicons(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	var req BatchFaviconsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.URLs) == 0 {
+		respondError(w, http.StatusBadRequest, "No URLs provided")
+		return
+	}
+	if len(req.URLs) > maxFaviconBatchURLs {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("At most %d URLs may be resolved at once", maxFaviconBatchURLs))
+		return
+	}
+
+	// Group URLs by domain so each domain is fetched once; unusable URLs stay null
+	result := make(map[string]*string, len(req.URLs))
+	urlsByDomain := make(map[string][]string)
+	for _, rawURL := range req.URLs {
+		result[rawURL] = nil
+		if !isValidURL(rawURL) || validateTitleFetchTarget(rawURL) != nil {
+			continue
+		}
+		domain, err := urlutil.Domain(rawURL)
+		if err != nil {
+			continue
+		}
+		urlsByDomain[domain] = append(urlsByDomain[domain], rawURL)
+	}
+
+	var allowed []string
+	for domain := range urlsByDomain {
+		if !api.fetchQuota.allowFetch(w, userID) {
+			break
+		}
+		allowed = append(allowed, domain)
+	}
+	if len(allowed) == 0 && len(urlsByDomain) > 0 {
+		respondError(w, http.StatusTooManyRequests, "Daily fetch quota exceeded")
+		return
+	}
+
+	domains := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range min(faviconBatchWorkers, len(allowed)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for domain := range domains {
+				icon, err := api.fetcher.FetchFromDomain(domain)
+				if err != nil {
+					icon = nil
+				}
+				mu.Lock()
+				for _, rawURL := range urlsByDomain[domain] {
+					result[rawURL] = icon
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, domain := range allowed {
+		domains <- domain
+	}
+	close(domains)
+	wg.Wait()
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// inlineStoredImage returns imageURL with an image kept in store replaced by its data
+// URI, so the result doesn't depend on this server. Other URLs, and stored images that
+// can't be read, are returned unchanged.
+func inlineStoredImage(store *favicon.Store, imageURL *string) *string {
+	if store == nil || imageURL == nil || !store.OwnsURL(*imageURL) {
+		return imageURL
+	}
+	dataURI, err := store.DataURI(*imageURL)
+	if err != nil {
+		log.Printf("Failed to inline stored image %s: %v", *imageURL, err)
+		return imageURL
+	}
+	return &dataURI
+}
+
+// HandleGetFavicon serves a stored icon by its content hash. The hash changes whenever
+// the icon does, so responses may be cached indefinitely.
+func (api *FaviconsAPI) HandleGetFavicon(w http.ResponseWriter, r *http.Request) {
+	serveStoredImage(w, api.store, chi.URLParam(r, "hash"), "Favicon")
+}
+
+// serveStoredImage writes the image with hash from store (which may be nil), naming it
+// kind in error messages
+func serveStoredImage(w http.ResponseWriter, store *favicon.Store, hash, kind string) {
+	if store == nil {
+		respondError(w, http.StatusNotFound, kind+" not found")
+		return
+	}
+
+	imageBytes, contentType, err := store.Get(hash)
+	if errors.Is(err, favicon.ErrIconNotFound) {
+		respondError(w, http.StatusNotFound, kind+" not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to read %s: %v", strings.ToLower(kind), err)
+		respondError(w, http.StatusInternalServerError, "Failed to read "+strings.ToLower(kind))
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(imageBytes)))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	// Images are fetched from arbitrary hosts, so an SVG must not run scripts on this origin
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	w.WriteHeader(http.StatusOK)
+	w.Write(imageBytes)
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crueber/loom/internal/favicon"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandleBatchFavicons_RejectsUnsafeURLs(t *testing.T) {
+	faviconsAPI := NewFaviconsAPI(favicon.New())
+
+	rec := performBatchFavicons(t, faviconsAPI, `{"urls":["http://127.0.0.1/admin","http://localhost:8080","not a url"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var result map[string]*string
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if len(result) != 3 {
+		t.Fatalf("result = %v, want an entry per URL", result)
+	}
+	for rawURL, icon := range result {
+		if icon != nil {
+			t.Fatalf("icon for %q = %q, want null", rawURL, *icon)
+		}
+	}
+}
+
+func TestHandleBatchFavicons_EnforcesBatchSize(t *testing.T) {
+	faviconsAPI := NewFaviconsAPI(favicon.New())
+
+	if rec := performBatchFavicons(t, faviconsAPI, `{"urls":[]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("empty batch status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	urls := make([]string, maxFaviconBatchURLs+1)
+	for i := range urls {
+		urls[i] = fmt.Sprintf(`"https://example%d.com"`, i)
+	}
+	body := `{"urls":[` + strings.Join(urls, ",") + `]}`
+	if rec := performBatchFavicons(t, faviconsAPI, body); rec.Code != http.StatusBadRequest {
+		t.Fatalf("oversized batch status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleGetFavicon(t *testing.T) {
+	store, err := favicon.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	hash, err := store.Put([]byte("<svg></svg>"), "image/svg+xml")
+	if err != nil {
+		t.Fatalf("put icon: %v", err)
+	}
+	faviconsAPI := NewFaviconsAPI(favicon.New())
+
+	get := func(hash string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/favicons/"+hash, nil)
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("hash", hash)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+		rec := httptest.NewRecorder()
+		faviconsAPI.HandleGetFavicon(rec, req)
+		return rec
+	}
+
+	if rec := get(hash); rec.Code != http.StatusNotFound {
+		t.Fatalf("status without a store = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	faviconsAPI.SetStore(store)
+	rec := get(hash)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if rec.Body.String() != "<svg></svg>" || rec.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Fatalf("response = %q (%s), want the stored SVG", rec.Body.String(), rec.Header().Get("Content-Type"))
+	}
+	if cache := rec.Header().Get("Cache-Control"); !strings.Contains(cache, "immutable") {
+		t.Fatalf("Cache-Control = %q, want a long-lived immutable cache", cache)
+	}
+	if csp := rec.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "sandbox") {
+		t.Fatalf("Content-Security-Policy = %q, want icons sandboxed", csp)
+	}
+
+	if rec := get(strings.Repeat("0", len(hash))); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown hash status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func performBatchFavicons(t *testing.T, faviconsAPI *FaviconsAPI, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/favicons", bytes.NewBufferString(body))
+	req = req.WithContext(setUserID(req.Context(), 1))
+	rec := httptest.NewRecorder()
+
+	faviconsAPI.HandleBatchFavicons(rec, req)
+
+	return rec
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crueber/loom/internal/models"
+	"github.com/crueber/loom/internal/sanitize"
+)
+
+// importFormatLoom is loom's own export format, handled by HandleImport directly
+const importFormatLoom = "loom"
+
+// importListColor is the color given to lists created from other services' exports
+const importListColor = "#3D6D95"
+
+// importParser converts another service's export into loom's export format so it
+// can go through the regular import path
+type importParser func(body []byte) (models.ExportData, error)
+
+// importParsers maps ?format= values to their parsers. Adding a service (e.g.
+// Raindrop) only needs a parser registered here.
+var importParsers = map[string]importParser{
+	"pocket": parsePocketExport,
+}
+
+// importFormatNames returns the accepted ?format= values for error messages
+func importFormatNames() string {
+	names := []string{importFormatLoom}
+	for name := range importParsers {
+		names = append(names, name)
+	}
+	slices.Sort(names[1:])
+	return strings.Join(names, ", ")
+}
+
+// pocketItem is one saved item in a Pocket export
+type pocketItem struct {
+	GivenURL      string          `json:"given_url"`
+	GivenTitle    string          `json:"given_title"`
+	ResolvedURL   string          `json:"resolved_url"`
+	ResolvedTitle string          `json:"resolved_title"`
+	TimeAdded     json.RawMessage `json:"time_added"`
+	Tags          json.RawMessage `json:"tags"`
+}
+
+// parsePocketExport reads Pocket's JSON export, either the API shape
+// ({"list": {"<id>": item}}) or a bare array of items. Each item goes into a list
+// named after its first tag (alphabetically); untagged items go into "Pocket".
+func parsePocketExport(body []byte) (models.ExportData, error) {
+	var items []pocketItem
+
+	var wrapped struct {
+		List json.RawMessage `json:"list"`
+	}
+	if err := json.Unmarshal(body, &wrapped); err == nil && len(wrapped.List) > 0 {
+		// An account with nothing saved exports "list" as an empty array, not an object
+		var byID map[string]pocketItem
+		if err := json.Unmarshal(wrapped.List, &byID); err != nil {
+			var empty []pocketItem
+			if err := json.Unmarshal(wrapped.List, &empty); err != nil || len(empty) > 0 {
+				return models.ExportData{}, fmt.Errorf("expected a Pocket export object or array of items")
+			}
+		}
+
+		// Map order is random; keep the export stable by item ID
+		ids := make([]string, 0, len(byID))
+		for id := range byID {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			a, errA := strconv.Atoi(ids[i])
+			b, errB := strconv.Atoi(ids[j])
+			if errA == nil && errB == nil {
+				return a < b
+			}
+			return ids[i] < ids[j]
+		})
+		for _, id := range ids {
+			items = append(items, byID[id])
+		}
+	} else if err := json.Unmarshal(body, &items); err != nil {
+		return models.ExportData{}, fmt.Errorf("expected a Pocket export object or array of items")
+	}
+
+	var lists []models.ExportList
+	listIndex := make(map[string]int)
+	for _, item := range items {
+		rawURL := strings.TrimSpace(item.GivenURL)
+		if rawURL == "" {
+			rawURL = strings.TrimSpace(item.ResolvedURL)
+		}
+		if !isValidURL(rawURL) {
+			continue
+		}
+
+		title := strings.TrimSpace(item.GivenTitle)
+		if title == "" {
+			title = strings.TrimSpace(item.ResolvedTitle)
+		}
+		if title == "" {
+			title = rawURL
+		}
+		title = truncateRunes(title, bookmarkTitleMaxLength)
+
+		listTitle := "Pocket"
+		if tags := pocketTags(item.Tags); len(tags) > 0 {
+			listTitle = truncateRunes(tags[0], 100)
+		}
+		idx, exists := listIndex[listTitle]
+		if !exists {
+			idx = len(lists)
+			listIndex[listTitle] = idx
+			lists = append(lists, models.ExportList{
+				ID:       idx + 1,
+				Title:    listTitle,
+				Color:    importListColor,
+				Position: idx,
+			})
+		}
+
+		list := &lists[idx]
+		list.Items = append(list.Items, models.ExportItem{
+			Type:          "bookmark",
+			Title:         &title,
+			URL:           &rawURL,
+			ContentFormat: sanitize.DefaultFormat,
+			Position:      len(list.Items),
+			CreatedAt:     pocketTime(item.TimeAdded),
+		})
+	}
+
+	return models.ExportData{Version: 1, ExportedAt: time.Now(), Lists: lists}, nil
+}
+
+// pocketTags reads an item's tags, which Pocket sends as an object keyed by tag
+// name; a plain list of names or a comma-separated string is accepted too
+func pocketTags(raw json.RawMessage) []string {
+	var tags []string
+
+	var byName map[string]json.RawMessage
+	var list []string
+	var joined string
+	switch {
+	case json.Unmarshal(raw, &byName) == nil:
+		for name := range byName {
+			tags = append(tags, name)
+		}
+	case json.Unmarshal(raw, &list) == nil:
+		tags = list
+	case json.Unmarshal(raw, &joined) == nil:
+		tags = strings.Split(joined, ",")
+	}
+
+	cleaned := tags[:0]
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			cleaned = append(cleaned, tag)
+		}
+	}
+	slices.Sort(cleaned)
+	return cleaned
+}
+
+// pocketTime reads a Unix timestamp sent as a string or number; nil means unknown
+func pocketTime(raw json.RawMessage) *time.Time {
+	var seconds int64
+	var text string
+	if json.Unmarshal(raw, &seconds) != nil {
+		if json.Unmarshal(raw, &text) != nil {
+			return nil
+		}
+		parsed, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil
+		}
+		seconds = parsed
+	}
+	if seconds <= 0 {
+		return nil
+	}
+	t := time.Unix(seconds, 0).UTC()
+	return &t
+}
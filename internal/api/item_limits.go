@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/crueber/loom/internal/db"
+)
+
+// ItemLimits caps how many bookmarks and notes each user may keep. Zero means
+// unlimited, and users IsAdmin reports as admins are exempt.
+type ItemLimits struct {
+	MaxBookmarks int
+	MaxNotes     int
+	IsAdmin      func(userID int) (bool, error)
+}
+
+// ItemUsage is a user's bookmark and note counts with the caps that apply to them;
+// a null cap means unlimited
+type ItemUsage struct {
+	Bookmarks    int  `json:"bookmarks"`
+	MaxBookmarks *int `json:"max_bookmarks"`
+	Notes        int  `json:"notes"`
+	MaxNotes     *int `json:"max_notes"`
+}
+
+// usage counts the user's items and works out which caps apply to them
+func (l ItemLimits) usage(database *db.DB, userID int) (ItemUsage, error) {
+	counts, err := database.CountItemsByType(userID)
+	if err != nil {
+		return ItemUsage{}, err
+	}
+	usage := ItemUsage{Bookmarks: counts[db.ItemTypeBookmark], Notes: counts[db.ItemTypeNote]}
+
+	if l.MaxBookmarks <= 0 && l.MaxNotes <= 0 {
+		return usage, nil
+	}
+	if l.IsAdmin != nil {
+		isAdmin, err := l.IsAdmin(userID)
+		if err != nil {
+			return ItemUsage{}, err
+		}
+		if isAdmin {
+			return usage, nil
+		}
+	}
+
+	if l.MaxBookmarks > 0 {
+		usage.MaxBookmarks = &l.MaxBookmarks
+	}
+	if l.MaxNotes > 0 {
+		usage.MaxNotes = &l.MaxNotes
+	}
+	return usage, nil
+}
+
+// checkItemLimit responds with 409 and returns false if adding count items of itemType
+// would take the user past their cap. Types without a cap always pass.
+func (l ItemLimits) checkItemLimit(w http.ResponseWriter, database *db.DB, userID int, itemType string, count int) bool {
+	return l.checkItemLimits(w, database, userID, map[string]int{itemType: count})
+}
+
+// checkItemLimits is checkItemLimit for several types at once; added maps each item type
+// to how many items of it are being added, which may be negative when items are replaced
+func (l ItemLimits) checkItemLimits(w http.ResponseWriter, database *db.DB, userID int, added map[string]int) bool {
+	addsBookmarks := added[db.ItemTypeBookmark] > 0 && l.MaxBookmarks > 0
+	addsNotes := added[db.ItemTypeNote] > 0 && l.MaxNotes > 0
+	if !addsBookmarks && !addsNotes {
+		return true
+	}
+
+	usage, err := l.usage(database, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to count items")
+		return false
+	}
+
+	if addsBookmarks && usage.MaxBookmarks != nil && usage.Bookmarks+added[db.ItemTypeBookmark] > *usage.MaxBookmarks {
+		respondError(w, http.StatusConflict, fmt.Sprintf("Bookmark limit of %d reached", *usage.MaxBookmarks))
+		return false
+	}
+	if addsNotes && usage.MaxNotes != nil && usage.Notes+added[db.ItemTypeNote] > *usage.MaxNotes {
+		respondError(w, http.StatusConflict, fmt.Sprintf("Note limit of %d reached", *usage.MaxNotes))
+		return false
+	}
+	return true
+}
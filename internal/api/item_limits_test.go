@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/crueber/loom/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestItemLimits_CapsCreationAndReportsUsage(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	isAdmin := false
+	limits := ItemLimits{MaxBookmarks: 1, IsAdmin: func(int) (bool, error) { return isAdmin, nil }}
+	itemsAPI.SetItemLimits(limits)
+
+	createBookmark := func() *httptest.ResponseRecorder {
+		t.Helper()
+		return performCreateItemRequest(t, itemsAPI, userID, map[string]any{
+			"list_id":     listID,
+			"type":        "bookmark",
+			"title":       "Example",
+			"url":         "https://example.com",
+			"icon_source": "loom",
+		})
+	}
+
+	if rec := createBookmark(); rec.Code != http.StatusCreated {
+		t.Fatalf("first bookmark status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if rec := createBookmark(); rec.Code != http.StatusConflict {
+		t.Fatalf("bookmark past cap status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	rec := performCreateItemRequest(t, itemsAPI, userID, map[string]any{
+		"list_id": listID,
+		"type":    "note",
+		"content": "Notes are not capped",
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("note status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	authAPI := NewAuthAPI(itemsAPI.db, nil, nil, false, false, false, "", nil)
+	authAPI.SetItemLimits(limits)
+	getSettings := func() ItemUsage {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/user/settings", nil)
+		rec := httptest.NewRecorder()
+		authAPI.HandleGetSettings(rec, req.WithContext(setUserID(req.Context(), userID)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("settings status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var settings UserSettingsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &settings); err != nil {
+			t.Fatalf("unmarshal settings: %v", err)
+		}
+		if settings.ItemUsage == nil {
+			t.Fatalf("settings = %s, want item usage", rec.Body.String())
+		}
+		return *settings.ItemUsage
+	}
+
+	usage := getSettings()
+	if usage.Bookmarks != 1 || usage.Notes != 1 || usage.MaxBookmarks == nil || *usage.MaxBookmarks != 1 || usage.MaxNotes != nil {
+		t.Fatalf("usage = %+v, want 1 of 1 bookmarks and 1 uncapped note", usage)
+	}
+
+	isAdmin = true
+	if rec := createBookmark(); rec.Code != http.StatusCreated {
+		t.Fatalf("admin bookmark status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if usage := getSettings(); usage.Bookmarks != 2 || usage.MaxBookmarks != nil {
+		t.Fatalf("admin usage = %+v, want 2 uncapped bookmarks", usage)
+	}
+}
+
+func TestItemLimits_CapsListCopiesAndImports(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	limits := ItemLimits{MaxNotes: 2}
+	if rec := performCreateItemRequest(t, itemsAPI, userID, map[string]any{"list_id": listID, "type": "note", "content": "First"}); rec.Code != http.StatusCreated {
+		t.Fatalf("note status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	listsAPI := NewListsAPI(itemsAPI.db, false, false)
+	listsAPI.SetItemLimits(limits)
+	duplicate := func() *httptest.ResponseRecorder {
+		t.Helper()
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("id", strconv.Itoa(listID))
+		req := httptest.NewRequest(http.MethodPost, "/api/lists/"+strconv.Itoa(listID)+"/duplicate", nil)
+		req = req.WithContext(context.WithValue(setUserID(req.Context(), userID), chi.RouteCtxKey, routeCtx))
+		rec := httptest.NewRecorder()
+		listsAPI.HandleDuplicateList(rec, req)
+		return rec
+	}
+	if rec := duplicate(); rec.Code != http.StatusCreated {
+		t.Fatalf("duplicate within cap status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if rec := duplicate(); rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate past cap status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	exportAPI := NewExportAPI(itemsAPI.db, []byte("test-signing-key"), 0, 0)
+	exportAPI.SetItemLimits(limits)
+	content := "Imported"
+	rec := performImportRequest(t, exportAPI, userID, ImportRequest{
+		Mode: "merge",
+		Data: models.ExportData{
+			Version: 1,
+			Lists: []models.ExportList{{
+				ID:    1,
+				Title: "Imported",
+				Color: "#ffffff",
+				Items: []models.ExportItem{{ID: 1, Type: "note", Content: &content}},
+			}},
+		},
+	})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("import past cap status = %d, want %d, body=%s", rec.Code, http.StatusConflict, rec.Body.String())
+	}
+
+	counts, err := itemsAPI.db.CountItemsByType(userID)
+	if err != nil {
+		t.Fatalf("count items: %v", err)
+	}
+	if counts["note"] != 2 {
+		t.Fatalf("notes = %d, want the cap of 2 kept", counts["note"])
+	}
+}
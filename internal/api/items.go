@@ -14,11 +14,16 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/crueber/loom/internal/db"
 	"github.com/crueber/loom/internal/favicon"
 	"github.com/crueber/loom/internal/models"
+	"github.com/crueber/loom/internal/sanitize"
+	"github.com/crueber/loom/internal/urlutil"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -26,37 +31,87 @@ const (
 	bookmarkTitleMaxLength = 200
 	titleFetchTimeout      = 2 * time.Second
 	titleFetchMaxBytes     = 1024 * 1024 // 1MiB
+	maxItemsPerIDLookup    = 500
+	maxItemsPerPinBatch    = 500
+
+	// Recent items (GET /api/items/recent) defaults and caps
+	recentItemsDefaultRange = 7 * 24 * time.Hour
+	recentItemsMaxRange     = 366 * 24 * time.Hour
+	recentItemsMaxResults   = 500
+
+	// All items (GET /api/items/all) page size default and cap
+	allItemsDefaultLimit = 1000
+	allItemsMaxLimit     = 5000
 )
 
 var (
 	htmlTitlePattern     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
 	htmlTagPattern       = regexp.MustCompile(`(?is)<[^>]+>`)
 	bookmarkTitleFetcher = fetchHTMLTitle
+	bookmarkPageFetcher  = fetchHTMLPage
 )
 
 // ItemsAPI handles item endpoints (unified bookmarks and notes)
 type ItemsAPI struct {
-	db             *db.DB
-	faviconFetcher *favicon.Fetcher
+	db                 *db.DB
+	faviconFetcher     *favicon.Fetcher
+	autoTitleByDefault bool
+	faviconRefresher   *FaviconRefresher
+
+	// previewImageMaxBytes caps captured og:image previews; zero disables capture
+	previewImageMaxBytes int
+	// previewStore keeps captured preview images; nil disables capture
+	previewStore *favicon.Store
+	previewSlots chan struct{}
+	previewWG    sync.WaitGroup
+
+	// itemLimits caps each user's bookmark and note counts
+	itemLimits ItemLimits
+	// fetchQuota caps the pages, favicons and preview images each user's items fetch per day
+	fetchQuota DailyQuota
 }
 
-// NewItemsAPI creates a new items API handler
-func NewItemsAPI(database *db.DB, faviconFetcher *favicon.Fetcher) *ItemsAPI {
+// NewItemsAPI creates a new items API handler.
+// autoTitleByDefault controls whether bookmarks created with a blank title get one fetched
+// from the page when the request does not set auto_title.
+func NewItemsAPI(database *db.DB, faviconFetcher *favicon.Fetcher, autoTitleByDefault bool) *ItemsAPI {
 	return &ItemsAPI{
-		db:             database,
-		faviconFetcher: faviconFetcher,
+		db:                 database,
+		faviconFetcher:     faviconFetcher,
+		autoTitleByDefault: autoTitleByDefault,
+		previewSlots:       make(chan struct{}, maxConcurrentPreviewCaptures),
 	}
 }
 
+// SetFaviconRefresher lets item edits re-fetch stale favicons in the background when
+// the refresher is in auto mode
+func (api *ItemsAPI) SetFaviconRefresher(refresher *FaviconRefresher) {
+	api.faviconRefresher = refresher
+}
+
+// SetItemLimits caps how many bookmarks and notes each non-admin user may create
+func (api *ItemsAPI) SetItemLimits(limits ItemLimits) {
+	api.itemLimits = limits
+}
+
+// SetFetchQuota counts the titles, favicons and preview images fetched for each user's
+// items against quota. Once it is used up items are still saved, just without them.
+func (api *ItemsAPI) SetFetchQuota(quota DailyQuota) {
+	api.fetchQuota = quota
+}
+
 // CreateItemRequest represents a request to create an item
 type CreateItemRequest struct {
 	ListID        int     `json:"list_id"`
-	Type          string  `json:"type"` // "bookmark" or "note"
+	Type          string  `json:"type"` // "bookmark", "note" or "separator"
 	Title         *string `json:"title,omitempty"`
 	URL           *string `json:"url,omitempty"`
 	Content       *string `json:"content,omitempty"`
+	ContentFormat string  `json:"content_format,omitempty"`  // "text", "markdown", "html"
 	IconSource    string  `json:"icon_source,omitempty"`     // "auto", "custom", "service"
 	CustomIconURL *string `json:"custom_icon_url,omitempty"` // Custom icon URL or service slug
+	AutoTitle     *bool   `json:"auto_title,omitempty"`      // Fetch a title for blank bookmark titles; defaults to the server setting
+	OpenInNewTab  *bool   `json:"open_in_new_tab,omitempty"` // Defaults to true
 }
 
 // UpdateItemRequest represents a request to update an item
@@ -64,8 +119,17 @@ type UpdateItemRequest struct {
 	Title         *string `json:"title,omitempty"`
 	URL           *string `json:"url,omitempty"`
 	Content       *string `json:"content,omitempty"`
+	ContentFormat *string `json:"content_format,omitempty"`  // "text", "markdown", "html"
 	IconSource    *string `json:"icon_source,omitempty"`     // "auto", "custom", "service"
 	CustomIconURL *string `json:"custom_icon_url,omitempty"` // Custom icon URL or service slug
+	OpenInNewTab  *bool   `json:"open_in_new_tab,omitempty"`
+	IsPinned      *bool   `json:"is_pinned,omitempty"`
+}
+
+// PinItemsRequest represents a request to pin or unpin several items
+type PinItemsRequest struct {
+	ItemIDs []int `json:"item_ids"`
+	Pinned  bool  `json:"pinned"`
 }
 
 // ReorderItemsRequest represents a request to reorder items
@@ -115,7 +179,195 @@ func (api *ItemsAPI) HandleGetItems(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, items)
 }
 
-// HandleCreateItem creates a new item (bookmark or note)
+// HandleGetItemsByIDs returns the requested items (?ids=1,2,3), omitting any the user does not own
+func (api *ItemsAPI) HandleGetItemsByIDs(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	idsParam := strings.TrimSpace(r.URL.Query().Get("ids"))
+	if idsParam == "" {
+		respondError(w, http.StatusBadRequest, "ids query parameter is required")
+		return
+	}
+
+	seen := make(map[int]bool)
+	var ids []int
+	for _, part := range strings.Split(idsParam, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id <= 0 {
+			respondError(w, http.StatusBadRequest, "Invalid item ID")
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) > maxItemsPerIDLookup {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("At most %d item IDs may be requested", maxItemsPerIDLookup))
+		return
+	}
+
+	items, err := api.db.GetItemsByIDs(ids, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get items")
+		return
+	}
+
+	if items == nil {
+		items = []*models.Item{}
+	}
+
+	respondJSON(w, http.StatusOK, items)
+}
+
+// HandleGetRecentItems returns items created in a date range (?since=&until=, RFC3339), newest first.
+// until defaults to now and since to a week before until.
+func (api *ItemsAPI) HandleGetRecentItems(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	until := time.Now()
+	if raw := r.URL.Query().Get("until"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "until must be an RFC3339 timestamp")
+			return
+		}
+		until = parsed
+	}
+
+	since := until.Add(-recentItemsDefaultRange)
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "since must be an RFC3339 timestamp")
+			return
+		}
+		since = parsed
+	}
+
+	if since.After(until) {
+		respondError(w, http.StatusBadRequest, "since must not be after until")
+		return
+	}
+	if until.Sub(since) > recentItemsMaxRange {
+		respondError(w, http.StatusBadRequest, "Date range must be 366 days or less")
+		return
+	}
+
+	items, err := api.db.GetItemsByDateRange(userID, since, until, recentItemsMaxResults)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get items")
+		return
+	}
+
+	if items == nil {
+		items = []*models.Item{}
+	}
+
+	respondJSON(w, http.StatusOK, items)
+}
+
+// HandleGetAllItems returns a page of the user's items across all boards (?limit=&offset=),
+// flattened with board_title and list_title for client-side search
+func (api *ItemsAPI) HandleGetAllItems(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	limit, offset, err := parsePagination(r, allItemsDefaultLimit, allItemsMaxLimit)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	total, err := api.db.CountItems(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to count items")
+		return
+	}
+
+	items, err := api.db.GetAllItemsWithBoard(userID, limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get items")
+		return
+	}
+
+	if items == nil {
+		items = []*models.ItemWithBoard{}
+	}
+
+	respondPaginated(w, items, limit, offset, total)
+}
+
+// HandleItemExists reports whether the user has already bookmarked a URL (?url=), matching
+// equivalent spellings, and where each matching bookmark lives
+func (api *ItemsAPI) HandleItemExists(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	rawURL := strings.TrimSpace(r.URL.Query().Get("url"))
+	if rawURL == "" {
+		respondError(w, http.StatusBadRequest, "url query parameter is required")
+		return
+	}
+
+	locations, err := api.db.FindItemsByURL(userID, urlutil.NormalizeForMatch(rawURL))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to find items")
+		return
+	}
+
+	if locations == nil {
+		locations = []*models.ItemLocation{}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"exists": len(locations) > 0,
+		"items":  locations,
+	})
+}
+
+// HandleGetItemStats returns the user's item totals per type, e.g. {"bookmark": 312, "note": 27}
+func (api *ItemsAPI) HandleGetItemStats(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	counts, err := api.db.CountItemsByType(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to count items")
+		return
+	}
+
+	// Always report every type, even when the user has none of it
+	stats := make(map[string]int, len(db.ItemTypes))
+	for _, itemType := range db.ItemTypes {
+		stats[itemType] = 0
+	}
+	for itemType, count := range counts {
+		stats[itemType] = count
+	}
+
+	respondJSON(w, http.StatusOK, stats)
+}
+
+// HandleCreateItem creates a new item (bookmark, note or separator)
 func (api *ItemsAPI) HandleCreateItem(w http.ResponseWriter, r *http.Request) {
 	userID, ok := getUserID(r.Context())
 	if !ok {
@@ -128,10 +380,11 @@ func (api *ItemsAPI) HandleCreateItem(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	sanitizeTextFields(req.Title, req.Content)
 
 	// Validate type
-	if req.Type != "bookmark" && req.Type != "note" {
-		respondError(w, http.StatusBadRequest, "Type must be 'bookmark' or 'note'")
+	if !db.IsValidItemType(req.Type) {
+		respondError(w, http.StatusBadRequest, "Type must be "+db.ItemTypesDescription())
 		return
 	}
 
@@ -146,21 +399,36 @@ func (api *ItemsAPI) HandleCreateItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !api.itemLimits.checkItemLimit(w, api.db, userID, req.Type, 1) {
+		return
+	}
+
 	// Set default icon source if not provided
 	iconSource := req.IconSource
 	if iconSource == "" {
 		iconSource = "auto"
 	}
 
+	// Set default content format if not provided
+	contentFormat := req.ContentFormat
+	if contentFormat == "" {
+		contentFormat = sanitize.DefaultFormat
+	}
+	if !sanitize.IsValidFormat(contentFormat) {
+		respondError(w, http.StatusBadRequest, "Content format must be 'text', 'markdown', or 'html'")
+		return
+	}
+
 	// Type-specific validation
 	var faviconURL *string
+	var page []byte // the bookmark's HTML when fetched for its title
 	if req.Type == "bookmark" {
 		var normalizedTitle string
 		if req.Title != nil {
 			normalizedTitle = strings.TrimSpace(*req.Title)
 		}
 
-		if normalizedTitle != "" && len(normalizedTitle) > bookmarkTitleMaxLength {
+		if normalizedTitle != "" && utf8.RuneCountInString(normalizedTitle) > bookmarkTitleMaxLength {
 			respondError(w, http.StatusBadRequest, "Title must be less than 200 characters")
 			return
 		}
@@ -175,21 +443,54 @@ func (api *ItemsAPI) HandleCreateItem(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if normalizedTitle == "" {
-			normalizedTitle = autoTitleForBookmarkURL(*req.URL)
+		autoTitle := api.autoTitleByDefault
+		if req.AutoTitle != nil {
+			autoTitle = *req.AutoTitle
+		}
+		if normalizedTitle == "" && autoTitle {
+			if api.fetchQuota.allowFetch(w, userID) {
+				normalizedTitle, page = api.autoTitle(*req.URL)
+			} else {
+				normalizedTitle = fallbackBookmarkTitle(*req.URL)
+			}
 		}
 		req.Title = &normalizedTitle
 
 		// Fetch favicon based on icon source
-		domain := extractDomainFromURL(*req.URL)
-		faviconURL, _ = api.faviconFetcher.FetchIcon(iconSource, req.CustomIconURL, domain)
+		if !fetchesIcon(iconSource) || api.fetchQuota.allowFetch(w, userID) {
+			domain, _ := urlutil.Domain(*req.URL)
+			faviconURL, _ = api.faviconFetcher.FetchIcon(iconSource, req.CustomIconURL, domain)
+		}
 	} else if req.Type == "note" {
 		// Validate note fields
 		if req.Content == nil || strings.TrimSpace(*req.Content) == "" {
 			respondError(w, http.StatusBadRequest, "Content is required for notes")
 			return
 		}
-		*req.Content = strings.TrimSpace(*req.Content)
+		*req.Content = sanitize.Content(contentFormat, *req.Content)
+	} else if req.Type == "separator" {
+		// Separators are non-clickable dividers with at most a short label
+		if req.URL != nil && strings.TrimSpace(*req.URL) != "" {
+			respondError(w, http.StatusBadRequest, "Separators cannot have a URL")
+			return
+		}
+		if req.Content != nil && strings.TrimSpace(*req.Content) != "" {
+			respondError(w, http.StatusBadRequest, "Separators cannot have content")
+			return
+		}
+		req.URL, req.Content, req.CustomIconURL = nil, nil, nil
+
+		if req.Title != nil {
+			label := strings.TrimSpace(*req.Title)
+			if utf8.RuneCountInString(label) > bookmarkTitleMaxLength {
+				respondError(w, http.StatusBadRequest, "Title must be less than 200 characters")
+				return
+			}
+			req.Title = nil
+			if label != "" {
+				req.Title = &label
+			}
+		}
 	}
 
 	// Get next position efficiently
@@ -199,12 +500,18 @@ func (api *ItemsAPI) HandleCreateItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	openInNewTab := true
+	if req.OpenInNewTab != nil {
+		openInNewTab = *req.OpenInNewTab
+	}
+
 	// Create item
-	item, err := api.db.CreateItem(req.ListID, req.Type, req.Title, req.URL, req.Content, faviconURL, iconSource, req.CustomIconURL, position)
+	item, err := api.db.CreateItem(req.ListID, req.Type, req.Title, req.URL, req.Content, faviconURL, iconSource, req.CustomIconURL, contentFormat, position, openInNewTab)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to create item")
 		return
 	}
+	api.capturePreviewImage(userID, item, page)
 
 	respondJSON(w, http.StatusCreated, item)
 }
@@ -250,6 +557,7 @@ func (api *ItemsAPI) HandleUpdateItem(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	sanitizeTextFields(req.Title, req.Content)
 
 	// DEBUG: Log the request
 	log.Printf("UpdateItem request for item %d: IconSource=%v, CustomIconURL=%v", itemID, req.IconSource, req.CustomIconURL)
@@ -264,7 +572,7 @@ func (api *ItemsAPI) HandleUpdateItem(w http.ResponseWriter, r *http.Request) {
 				respondError(w, http.StatusBadRequest, "Title cannot be empty")
 				return
 			}
-			if len(*req.Title) > 200 {
+			if utf8.RuneCountInString(*req.Title) > 200 {
 				respondError(w, http.StatusBadRequest, "Title must be less than 200 characters")
 				return
 			}
@@ -284,6 +592,10 @@ func (api *ItemsAPI) HandleUpdateItem(w http.ResponseWriter, r *http.Request) {
 			updates["url"] = req.URL
 		}
 
+		if req.OpenInNewTab != nil {
+			updates["open_in_new_tab"] = *req.OpenInNewTab
+		}
+
 		// Handle icon source and custom icon URL changes
 		iconSourceChanged := req.IconSource != nil
 		customIconURLChanged := req.CustomIconURL != nil
@@ -322,9 +634,9 @@ func (api *ItemsAPI) HandleUpdateItem(w http.ResponseWriter, r *http.Request) {
 				customIconURL = item.CustomIconURL
 			}
 
-			// Fetch new favicon
-			if urlForFavicon != "" {
-				domain := extractDomainFromURL(urlForFavicon)
+			// Fetch new favicon; over the fetch quota the old one is kept
+			if urlForFavicon != "" && (!fetchesIcon(iconSource) || api.fetchQuota.allowFetch(w, userID)) {
+				domain, _ := urlutil.Domain(urlForFavicon)
 				faviconURL, err := api.faviconFetcher.FetchIcon(iconSource, customIconURL, domain)
 				if err != nil {
 					// Clear favicon_url if fetch fails
@@ -335,12 +647,44 @@ func (api *ItemsAPI) HandleUpdateItem(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	} else if item.Type == "note" {
-		if req.Content != nil {
-			*req.Content = strings.TrimSpace(*req.Content)
-			updates["content"] = req.Content
+		contentFormat := item.ContentFormat
+		if req.ContentFormat != nil {
+			if !sanitize.IsValidFormat(*req.ContentFormat) {
+				respondError(w, http.StatusBadRequest, "Content format must be 'text', 'markdown', or 'html'")
+				return
+			}
+			contentFormat = *req.ContentFormat
+			updates["content_format"] = contentFormat
+		}
+
+		// Re-normalize stored content when only the format changes
+		content := req.Content
+		if content == nil && req.ContentFormat != nil && item.Content != nil {
+			content = item.Content
+		}
+		if content != nil {
+			normalized := sanitize.Content(contentFormat, *content)
+			updates["content"] = &normalized
+		}
+	} else if item.Type == "separator" {
+		if req.Title != nil {
+			label := strings.TrimSpace(*req.Title)
+			if utf8.RuneCountInString(label) > bookmarkTitleMaxLength {
+				respondError(w, http.StatusBadRequest, "Title must be less than 200 characters")
+				return
+			}
+			if label == "" {
+				updates["title"] = nil
+			} else {
+				updates["title"] = &label
+			}
 		}
 	}
 
+	if req.IsPinned != nil {
+		updates["is_pinned"] = *req.IsPinned
+	}
+
 	// Update item
 	if err := api.db.UpdateItemFields(itemID, updates); err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to update item")
@@ -354,6 +698,11 @@ func (api *ItemsAPI) HandleUpdateItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// An edit that didn't already re-fetch the icon may still find it stale
+	if _, refetched := updates["favicon_url"]; !refetched && api.faviconRefresher.Auto() {
+		api.faviconRefresher.RefreshItem(userID, updatedItem)
+	}
+
 	respondJSON(w, http.StatusOK, updatedItem)
 }
 
@@ -391,6 +740,217 @@ func (api *ItemsAPI) HandleDeleteItem(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// maxAppendBookmarks caps how many bookmarks a single append to a named list may add
+const maxAppendBookmarks = 100
+
+// AppendBookmarksRequest represents bookmarks to append to a list found or created by title
+type AppendBookmarksRequest struct {
+	Color string `json:"color,omitempty"` // Used only when the list is created; defaults to importListColor
+	Items []struct {
+		Title *string `json:"title,omitempty"` // Fetched from the page when blank and auto-title is on
+		URL   string  `json:"url"`
+	} `json:"items"`
+}
+
+// HandleAppendToNamedList appends bookmarks to the board's list with the given title,
+// creating the list if the board has none, and responds with the list and the new items
+func (api *ItemsAPI) HandleAppendToNamedList(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	boardID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid board ID")
+		return
+	}
+
+	listTitle := chi.URLParam(r, "title")
+	if unescaped, err := url.PathUnescape(listTitle); err == nil {
+		listTitle = unescaped
+	}
+	listTitle = strings.TrimSpace(sanitizeText(listTitle))
+	if listTitle == "" {
+		respondError(w, http.StatusBadRequest, "List title is required")
+		return
+	}
+	if utf8.RuneCountInString(listTitle) > 100 {
+		respondError(w, http.StatusBadRequest, "List title must be less than 100 characters")
+		return
+	}
+
+	var req AppendBookmarksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Color == "" {
+		req.Color = importListColor
+	}
+	if !isValidHexColor(req.Color) {
+		respondError(w, http.StatusBadRequest, "Invalid color")
+		return
+	}
+	if len(req.Items) == 0 {
+		respondError(w, http.StatusBadRequest, "At least one item is required")
+		return
+	}
+	if len(req.Items) > maxAppendBookmarks {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("At most %d items may be added at once", maxAppendBookmarks))
+		return
+	}
+
+	// Verify board ownership before fetching any titles or favicons
+	owns, err := api.db.VerifyBoardOwnership(boardID, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to verify board ownership")
+		return
+	}
+	if !owns {
+		respondError(w, http.StatusNotFound, "Board not found")
+		return
+	}
+
+	bookmarks := make([]db.NewBookmark, 0, len(req.Items))
+	for i, entry := range req.Items {
+		rawURL := strings.TrimSpace(entry.URL)
+		if !isValidURL(rawURL) {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Item %d: invalid URL", i+1))
+			return
+		}
+
+		var title string
+		if entry.Title != nil {
+			title = strings.TrimSpace(sanitizeText(*entry.Title))
+		}
+		if utf8.RuneCountInString(title) > bookmarkTitleMaxLength {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Item %d: title must be less than 200 characters", i+1))
+			return
+		}
+
+		bookmarks = append(bookmarks, db.NewBookmark{Title: title, URL: rawURL})
+	}
+
+	if !api.itemLimits.checkItemLimit(w, api.db, userID, db.ItemTypeBookmark, len(bookmarks)) {
+		return
+	}
+
+	// Network lookups happen before the transaction so it stays short; once the fetch
+	// quota runs out the remaining bookmarks are added without them
+	for i := range bookmarks {
+		if bookmarks[i].Title == "" && api.autoTitleByDefault {
+			if api.fetchQuota.allowFetch(w, userID) {
+				bookmarks[i].Title = autoTitleForBookmarkURL(bookmarks[i].URL)
+			} else {
+				bookmarks[i].Title = fallbackBookmarkTitle(bookmarks[i].URL)
+			}
+		}
+		if api.fetchQuota.allowFetch(w, userID) {
+			domain, _ := urlutil.Domain(bookmarks[i].URL)
+			bookmarks[i].FaviconURL, _ = api.faviconFetcher.FetchIcon("auto", nil, domain)
+		}
+	}
+
+	list, items, err := api.db.AppendBookmarksToNamedList(userID, boardID, listTitle, req.Color, bookmarks)
+	if err != nil {
+		if err.Error() == "board not found" {
+			respondError(w, http.StatusNotFound, "Board not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to add items")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, map[string]any{
+		"list":  list,
+		"items": items,
+	})
+}
+
+// HandlePinItems pins or unpins several items in one transaction and returns them
+func (api *ItemsAPI) HandlePinItems(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	var req PinItemsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.ItemIDs) == 0 {
+		respondError(w, http.StatusBadRequest, "At least one item is required")
+		return
+	}
+	if len(req.ItemIDs) > maxItemsPerPinBatch {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("At most %d items may be pinned at once", maxItemsPerPinBatch))
+		return
+	}
+	seen := make(map[int]bool, len(req.ItemIDs))
+	for _, id := range req.ItemIDs {
+		if seen[id] {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Item %d appears more than once", id))
+			return
+		}
+		seen[id] = true
+	}
+
+	items, err := api.db.SetItemsPinned(userID, req.ItemIDs, req.Pinned)
+	if err != nil {
+		if err.Error() == "items not found" {
+			respondError(w, http.StatusNotFound, "Items not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to update items")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, items)
+}
+
+// HandleMoveItemToTop moves an item to the top of its list
+func (api *ItemsAPI) HandleMoveItemToTop(w http.ResponseWriter, r *http.Request) {
+	api.moveItemToEdge(w, r, true)
+}
+
+// HandleMoveItemToBottom moves an item to the bottom of its list
+func (api *ItemsAPI) HandleMoveItemToBottom(w http.ResponseWriter, r *http.Request) {
+	api.moveItemToEdge(w, r, false)
+}
+
+// moveItemToEdge moves an item to either end of its list and responds with the reordered items
+func (api *ItemsAPI) moveItemToEdge(w http.ResponseWriter, r *http.Request, toTop bool) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	itemID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid item ID")
+		return
+	}
+
+	items, err := api.db.MoveItemToEdge(itemID, userID, toTop)
+	if err != nil {
+		if err.Error() == "item not found" {
+			respondError(w, http.StatusNotFound, "Item not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to move item")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, items)
+}
+
 // HandleReorderItems reorders items
 func (api *ItemsAPI) HandleReorderItems(w http.ResponseWriter, r *http.Request) {
 	userID, ok := getUserID(r.Context())
@@ -454,35 +1014,6 @@ func (api *ItemsAPI) HandleReorderItems(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// extractDomainFromURL extracts the domain from a URL string
-func extractDomainFromURL(rawURL string) string {
-	// Add scheme if missing
-	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
-		rawURL = "https://" + rawURL
-	}
-
-	// Simple extraction without net/url to avoid import
-	// Find the position after the scheme
-	schemeEnd := strings.Index(rawURL, "://")
-	if schemeEnd == -1 {
-		return ""
-	}
-
-	// Start after "://"
-	domainStart := schemeEnd + 3
-
-	// Find the end of the domain (first /, :, or end of string)
-	domainEnd := len(rawURL)
-	for i := domainStart; i < len(rawURL); i++ {
-		if rawURL[i] == '/' || rawURL[i] == ':' {
-			domainEnd = i
-			break
-		}
-	}
-
-	return rawURL[domainStart:domainEnd]
-}
-
 func autoTitleForBookmarkURL(rawURL string) string {
 	title, err := bookmarkTitleFetcher(rawURL)
 	if err == nil {
@@ -495,10 +1026,67 @@ func autoTitleForBookmarkURL(rawURL string) string {
 	return fallbackBookmarkTitle(rawURL)
 }
 
+// autoTitle returns autoTitleForBookmarkURL's title for rawURL. When preview images are
+// captured it fetches the page itself and also returns it, so the preview capture can
+// reuse it instead of requesting the page again.
+func (api *ItemsAPI) autoTitle(rawURL string) (string, []byte) {
+	if !api.previewsEnabled() {
+		return autoTitleForBookmarkURL(rawURL), nil
+	}
+
+	page, err := bookmarkPageFetcher(rawURL)
+	if err != nil {
+		return fallbackBookmarkTitle(rawURL), nil
+	}
+	if title, err := htmlPageTitle(page); err == nil {
+		if title = normalizeBookmarkTitle(title); title != "" {
+			return title, page
+		}
+	}
+	return fallbackBookmarkTitle(rawURL), page
+}
+
 func fetchHTMLTitle(rawURL string) (string, error) {
-	if err := validateTitleFetchTarget(rawURL); err != nil {
+	body, err := fetchHTMLPage(rawURL)
+	if err != nil {
 		return "", err
 	}
+	return htmlPageTitle(body)
+}
+
+// htmlPageTitle returns the raw contents of page's title element
+func htmlPageTitle(page []byte) (string, error) {
+	matches := htmlTitlePattern.FindSubmatch(page)
+	if len(matches) < 2 {
+		return "", fmt.Errorf("title element not found")
+	}
+
+	return string(matches[1]), nil
+}
+
+// fetchHTMLPage returns up to titleFetchMaxBytes of an HTML page, refusing to contact
+// local or private addresses
+func fetchHTMLPage(rawURL string) ([]byte, error) {
+	resp, err := getPublicURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	if contentType != "" && !strings.Contains(contentType, "text/html") && !strings.Contains(contentType, "application/xhtml+xml") {
+		return nil, fmt.Errorf("unsupported content type: %s", contentType)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, titleFetchMaxBytes))
+}
+
+// getPublicURL GETs rawURL without following redirects, refusing to contact local or
+// private addresses, and fails on any non-2xx status. The caller closes the body.
+func getPublicURL(rawURL string) (*http.Response, error) {
+	if err := validateTitleFetchTarget(rawURL); err != nil {
+		return nil, err
+	}
 
 	dialer := &net.Dialer{Timeout: titleFetchTimeout}
 
@@ -516,34 +1104,42 @@ func fetchHTMLTitle(rawURL string) (string, error) {
 
 	resp, err := client.Get(rawURL)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
 	}
 
-	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
-	if contentType != "" && !strings.Contains(contentType, "text/html") && !strings.Contains(contentType, "application/xhtml+xml") {
-		return "", fmt.Errorf("unsupported content type: %s", contentType)
-	}
+	return resp, nil
+}
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, titleFetchMaxBytes))
-	if err != nil {
-		return "", err
-	}
+// sanitizeText removes NUL and other control characters, which break rendering and
+// full-text search, keeping tabs and line breaks. Invalid UTF-8 is dropped too.
+func sanitizeText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return r
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(s, ""))
+}
 
-	matches := htmlTitlePattern.FindSubmatch(body)
-	if len(matches) < 2 {
-		return "", fmt.Errorf("title element not found")
+// sanitizeTextFields applies sanitizeText in place to each non-nil field
+func sanitizeTextFields(fields ...*string) {
+	for _, field := range fields {
+		if field != nil {
+			*field = sanitizeText(*field)
+		}
 	}
-
-	return string(matches[1]), nil
 }
 
 func normalizeBookmarkTitle(rawTitle string) string {
-	normalized := html.UnescapeString(rawTitle)
+	normalized := sanitizeText(html.UnescapeString(rawTitle))
 	normalized = htmlTagPattern.ReplaceAllString(normalized, "")
 	normalized = strings.Join(strings.Fields(normalized), " ")
 	normalized = strings.TrimSpace(normalized)
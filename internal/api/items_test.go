@@ -2,17 +2,24 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/crueber/loom/internal/db"
 	"github.com/crueber/loom/internal/favicon"
 	"github.com/crueber/loom/internal/models"
+	"github.com/go-chi/chi/v5"
 )
 
 func TestHandleCreateItem_BookmarkEmptyTitleAssignsAutoTitle(t *testing.T) {
@@ -86,6 +93,40 @@ func TestHandleCreateItem_BookmarkOmittedTitleAssignsAutoTitle(t *testing.T) {
 	}
 }
 
+func TestHandleCreateItem_AutoTitleDisabledKeepsBlankTitle(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	originalFetcher := bookmarkTitleFetcher
+	bookmarkTitleFetcher = func(rawURL string) (string, error) {
+		t.Fatalf("title fetcher called for %q with auto_title disabled", rawURL)
+		return "", nil
+	}
+	defer func() {
+		bookmarkTitleFetcher = originalFetcher
+	}()
+
+	rec := performCreateItemRequest(t, itemsAPI, userID, map[string]any{
+		"list_id":     listID,
+		"type":        "bookmark",
+		"url":         "https://example.com/path",
+		"icon_source": "loom",
+		"auto_title":  false,
+	})
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	var item models.Item
+	if err := json.Unmarshal(rec.Body.Bytes(), &item); err != nil {
+		t.Fatalf("unmarshal created item: %v", err)
+	}
+	if item.Title != nil && *item.Title != "" {
+		t.Fatalf("title = %q, want blank", *item.Title)
+	}
+}
+
 func TestHandleCreateItem_BookmarkNonEmptyTitlePreserved(t *testing.T) {
 	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
 	defer cleanup()
@@ -128,6 +169,43 @@ func TestHandleCreateItem_BookmarkNonEmptyTitlePreserved(t *testing.T) {
 	}
 }
 
+func TestHandleCreateItem_TitleLengthCountsCharacters(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	tests := []struct {
+		name     string
+		title    string
+		wantCode int
+	}{
+		{name: "200 emoji", title: strings.Repeat("🔖", bookmarkTitleMaxLength), wantCode: http.StatusCreated},
+		{name: "200 CJK characters", title: strings.Repeat("書", bookmarkTitleMaxLength), wantCode: http.StatusCreated},
+		{name: "201 emoji", title: strings.Repeat("🔖", bookmarkTitleMaxLength+1), wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := performCreateItemRequest(t, itemsAPI, userID, map[string]any{
+			"list_id":     listID,
+			"type":        "bookmark",
+			"title":       tt.title,
+			"url":         "https://example.com/path",
+			"icon_source": "loom",
+		})
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d, body=%s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+		}
+		if rec.Code != http.StatusCreated {
+			continue
+		}
+		var item models.Item
+		if err := json.Unmarshal(rec.Body.Bytes(), &item); err != nil {
+			t.Fatalf("unmarshal created item: %v", err)
+		}
+		if item.Title == nil || *item.Title != tt.title {
+			t.Fatalf("%s: title = %v, want it stored unchanged", tt.name, item.Title)
+		}
+	}
+}
+
 func TestHandleCreateItem_BookmarkTitleExtractionFailureFallsBack(t *testing.T) {
 	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
 	defer cleanup()
@@ -203,6 +281,185 @@ func TestHandleCreateItem_BookmarkInvalidURLReturnsSameValidationError(t *testin
 	}
 }
 
+func TestHandleGetItemsByIDs_OmitsItemsOwnedByOtherUsers(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	content := "mine"
+	mine, err := itemsAPI.db.CreateItem(listID, "note", nil, nil, &content, nil, "auto", nil, "markdown", 0, true)
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	other, err := itemsAPI.db.CreateUser("other-user", "hash")
+	if err != nil {
+		t.Fatalf("create other user: %v", err)
+	}
+	otherBoard, err := itemsAPI.db.CreateBoard(other.ID, "Other Board", true)
+	if err != nil {
+		t.Fatalf("create other board: %v", err)
+	}
+	otherList, err := itemsAPI.db.CreateList(other.ID, otherBoard.ID, "Other List", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create other list: %v", err)
+	}
+	theirs, err := itemsAPI.db.CreateItem(otherList.ID, "note", nil, nil, &content, nil, "auto", nil, "markdown", 0, true)
+	if err != nil {
+		t.Fatalf("create other item: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/items?ids=%d,%d", mine.ID, theirs.ID), nil)
+	req = req.WithContext(setUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+
+	itemsAPI.HandleGetItemsByIDs(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var items []models.Item
+	if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+		t.Fatalf("unmarshal items: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != mine.ID {
+		t.Fatalf("items = %+v, want only item %d", items, mine.ID)
+	}
+}
+
+func TestHandleGetRecentItems(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	content := "saved"
+	item, err := itemsAPI.db.CreateItem(listID, "note", nil, nil, &content, nil, "auto", nil, "markdown", 0, true)
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	now := time.Now().UTC()
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantItems int
+	}{
+		{name: "default range includes new item", query: "", wantCode: http.StatusOK, wantItems: 1},
+		{name: "range before item", query: "?since=2000-01-01T00:00:00Z&until=2000-01-02T00:00:00Z", wantCode: http.StatusOK, wantItems: 0},
+		{name: "explicit range", query: "?since=" + now.Add(-time.Hour).Format(time.RFC3339) + "&until=" + now.Add(time.Hour).Format(time.RFC3339), wantCode: http.StatusOK, wantItems: 1},
+		{name: "invalid timestamp", query: "?since=yesterday", wantCode: http.StatusBadRequest},
+		{name: "since after until", query: "?since=2000-01-02T00:00:00Z&until=2000-01-01T00:00:00Z", wantCode: http.StatusBadRequest},
+		{name: "range too long", query: "?since=2000-01-01T00:00:00Z&until=2002-01-01T00:00:00Z", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/items/recent"+tt.query, nil)
+			req = req.WithContext(setUserID(req.Context(), userID))
+			rec := httptest.NewRecorder()
+
+			itemsAPI.HandleGetRecentItems(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d, body=%s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var items []models.Item
+			if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+				t.Fatalf("unmarshal items: %v", err)
+			}
+			if len(items) != tt.wantItems {
+				t.Fatalf("len(items) = %d, want %d", len(items), tt.wantItems)
+			}
+			if tt.wantItems > 0 && items[0].ID != item.ID {
+				t.Fatalf("items[0].ID = %d, want %d", items[0].ID, item.ID)
+			}
+		})
+	}
+}
+
+func TestHandleGetItemStats(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	content := "note"
+	for range 2 {
+		if _, err := itemsAPI.db.CreateItem(listID, "note", nil, nil, &content, nil, "auto", nil, "markdown", 0, true); err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats/items", nil)
+	req = req.WithContext(setUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+
+	itemsAPI.HandleGetItemStats(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var stats map[string]int
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("unmarshal stats: %v", err)
+	}
+	if stats["note"] != 2 || stats["bookmark"] != 0 || stats["separator"] != 0 || len(stats) != 3 {
+		t.Fatalf("stats = %v, want 2 notes, 0 bookmarks and 0 separators", stats)
+	}
+}
+
+func TestHandleMoveItemToEdge(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	var ids []int
+	for i := range 3 {
+		content := fmt.Sprintf("note %d", i)
+		item, err := itemsAPI.db.CreateItem(listID, "note", nil, nil, &content, nil, "auto", nil, "markdown", i, true)
+		if err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+		ids = append(ids, item.ID)
+	}
+
+	move := func(handler http.HandlerFunc, itemID int) []int {
+		t.Helper()
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("id", strconv.Itoa(itemID))
+		ctx := context.WithValue(setUserID(context.Background(), userID), chi.RouteCtxKey, routeCtx)
+		req := httptest.NewRequest(http.MethodPost, "/api/items/"+strconv.Itoa(itemID)+"/move", nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+
+		handler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var items []models.Item
+		if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+			t.Fatalf("unmarshal items: %v", err)
+		}
+		var order []int
+		for i, item := range items {
+			if item.Position != i {
+				t.Fatalf("item %d position = %d, want %d", item.ID, item.Position, i)
+			}
+			order = append(order, item.ID)
+		}
+		return order
+	}
+
+	if got, want := move(itemsAPI.HandleMoveItemToTop, ids[2]), []int{ids[2], ids[0], ids[1]}; !slices.Equal(got, want) {
+		t.Fatalf("after move-to-top order = %v, want %v", got, want)
+	}
+	if got, want := move(itemsAPI.HandleMoveItemToBottom, ids[2]), []int{ids[0], ids[1], ids[2]}; !slices.Equal(got, want) {
+		t.Fatalf("after move-to-bottom order = %v, want %v", got, want)
+	}
+}
+
 func TestNormalizeBookmarkTitle(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -258,12 +515,12 @@ func newItemsAPITestFixture(t *testing.T) (*ItemsAPI, int, int, func()) {
 		t.Fatalf("create board: %v", err)
 	}
 
-	list, err := database.CreateList(user.ID, board.ID, "Test List", "#ffffff", 0)
+	list, err := database.CreateList(user.ID, board.ID, "Test List", "#ffffff", 0, false)
 	if err != nil {
 		t.Fatalf("create list: %v", err)
 	}
 
-	itemsAPI := NewItemsAPI(database, favicon.New())
+	itemsAPI := NewItemsAPI(database, favicon.New(), true)
 
 	cleanup := func() {
 		if err := database.Close(); err != nil {
@@ -290,3 +547,531 @@ func performCreateItemRequest(t *testing.T, itemsAPI *ItemsAPI, userID int, payl
 
 	return rec
 }
+
+func TestHandleCreateItem_OpenInNewTabDefaultsToTrue(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		name    string
+		payload map[string]any
+		want    bool
+	}{
+		{name: "omitted", payload: map[string]any{}, want: true},
+		{name: "false", payload: map[string]any{"open_in_new_tab": false}, want: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.payload["list_id"] = listID
+			tc.payload["type"] = "bookmark"
+			tc.payload["title"] = "Intranet"
+			tc.payload["url"] = "https://intranet.example.com"
+			tc.payload["icon_source"] = "loom"
+
+			rec := performCreateItemRequest(t, itemsAPI, userID, tc.payload)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+			}
+
+			var item models.Item
+			if err := json.Unmarshal(rec.Body.Bytes(), &item); err != nil {
+				t.Fatalf("unmarshal created item: %v", err)
+			}
+			if item.OpenInNewTab != tc.want {
+				t.Fatalf("open_in_new_tab = %v, want %v", item.OpenInNewTab, tc.want)
+			}
+		})
+	}
+}
+
+func TestHandleCreateItem_RejectsUnknownType(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	rec := performCreateItemRequest(t, itemsAPI, userID, map[string]any{
+		"list_id": listID,
+		"type":    "folder",
+		"title":   "Nope",
+	})
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusBadRequest, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "Type must be 'bookmark', 'note' or 'separator'") {
+		t.Fatalf("body = %s, want allowed types listed", rec.Body.String())
+	}
+}
+
+func TestHandleCreateItem_Separator(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	rec := performCreateItemRequest(t, itemsAPI, userID, map[string]any{
+		"list_id": listID,
+		"type":    "separator",
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	var item models.Item
+	if err := json.Unmarshal(rec.Body.Bytes(), &item); err != nil {
+		t.Fatalf("unmarshal created item: %v", err)
+	}
+	if item.Type != "separator" || item.Title != nil || item.URL != nil || item.Content != nil {
+		t.Fatalf("item = %+v, want bare separator", item)
+	}
+
+	rec = performCreateItemRequest(t, itemsAPI, userID, map[string]any{
+		"list_id": listID,
+		"type":    "separator",
+		"url":     "https://example.com",
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d for separator with URL", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleGetAllItems_ScopedAndPaginated(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	content := "note"
+	for i := 0; i < 3; i++ {
+		if _, err := itemsAPI.db.CreateItem(listID, "note", nil, nil, &content, nil, "auto", nil, "markdown", i, true); err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+	}
+
+	other, err := itemsAPI.db.CreateUser("other-user", "hash")
+	if err != nil {
+		t.Fatalf("create other user: %v", err)
+	}
+	otherBoard, err := itemsAPI.db.CreateBoard(other.ID, "Other Board", true)
+	if err != nil {
+		t.Fatalf("create other board: %v", err)
+	}
+	otherList, err := itemsAPI.db.CreateList(other.ID, otherBoard.ID, "Other List", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create other list: %v", err)
+	}
+	if _, err := itemsAPI.db.CreateItem(otherList.ID, "note", nil, nil, &content, nil, "auto", nil, "markdown", 0, true); err != nil {
+		t.Fatalf("create other item: %v", err)
+	}
+
+	getPage := func(query string) []models.ItemWithBoard {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/items/all"+query, nil)
+		req = req.WithContext(setUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+
+		itemsAPI.HandleGetAllItems(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var page struct {
+			Data       []models.ItemWithBoard `json:"data"`
+			Pagination Pagination             `json:"pagination"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatalf("unmarshal items: %v", err)
+		}
+		if page.Pagination.Total != 3 {
+			t.Fatalf("pagination = %+v, want total 3", page.Pagination)
+		}
+		return page.Data
+	}
+
+	all := getPage("")
+	if len(all) != 3 {
+		t.Fatalf("len(items) = %d, want 3", len(all))
+	}
+	if all[0].BoardTitle != "Test Board" || all[0].ListTitle != "Test List" {
+		t.Fatalf("item = %+v, want board and list titles", all[0])
+	}
+
+	page := getPage("?limit=2&offset=2")
+	if len(page) != 1 || page[0].ID != all[2].ID {
+		t.Fatalf("page = %+v, want only item %d", page, all[2].ID)
+	}
+}
+
+func TestItemMutationsTouchBoard(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	list, err := itemsAPI.db.GetList(listID, userID)
+	if err != nil {
+		t.Fatalf("get list: %v", err)
+	}
+	stale := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	resetAndCheck := func(action string, mutate func() error) {
+		t.Helper()
+		if _, err := itemsAPI.db.Exec("UPDATE boards SET updated_at = ? WHERE id = ?", stale, list.BoardID); err != nil {
+			t.Fatalf("reset updated_at: %v", err)
+		}
+		if err := mutate(); err != nil {
+			t.Fatalf("%s: %v", action, err)
+		}
+		board, err := itemsAPI.db.GetBoardByID(list.BoardID, userID)
+		if err != nil {
+			t.Fatalf("get board: %v", err)
+		}
+		if !board.UpdatedAt.After(stale) {
+			t.Fatalf("%s: board updated_at = %v, want touched", action, board.UpdatedAt)
+		}
+	}
+
+	content := "note"
+	var itemID int
+	resetAndCheck("create", func() error {
+		item, err := itemsAPI.db.CreateItem(listID, "note", nil, nil, &content, nil, "auto", nil, "markdown", 0, true)
+		if item != nil {
+			itemID = item.ID
+		}
+		return err
+	})
+	resetAndCheck("update", func() error {
+		return itemsAPI.db.UpdateItemFields(itemID, map[string]interface{}{"content": "edited"})
+	})
+	resetAndCheck("move to top", func() error {
+		_, err := itemsAPI.db.MoveItemToEdge(itemID, userID, true)
+		return err
+	})
+	resetAndCheck("delete", func() error {
+		return itemsAPI.db.DeleteItem(itemID)
+	})
+}
+
+func TestHandleAppendToNamedList(t *testing.T) {
+	itemsAPI, _, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+	itemsAPI.faviconFetcher.SetDisabled(true)
+
+	originalFetcher := bookmarkTitleFetcher
+	bookmarkTitleFetcher = func(rawURL string) (string, error) {
+		return "Fetched", nil
+	}
+	defer func() {
+		bookmarkTitleFetcher = originalFetcher
+	}()
+
+	board, err := itemsAPI.db.GetDefaultBoard(userID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+
+	appendItems := func(title string, payload map[string]any) (*httptest.ResponseRecorder, models.List, []models.Item) {
+		t.Helper()
+		body, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("marshal request body: %v", err)
+		}
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("id", strconv.Itoa(board.ID))
+		routeCtx.URLParams.Add("title", title)
+		ctx := context.WithValue(setUserID(context.Background(), userID), chi.RouteCtxKey, routeCtx)
+		req := httptest.NewRequest(http.MethodPost, "/api/boards/"+strconv.Itoa(board.ID)+"/lists/"+url.PathEscape(title)+"/items", bytes.NewReader(body)).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		itemsAPI.HandleAppendToNamedList(rec, req)
+
+		var resp struct {
+			List  models.List   `json:"list"`
+			Items []models.Item `json:"items"`
+		}
+		if rec.Code == http.StatusCreated {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("unmarshal response: %v", err)
+			}
+		}
+		return rec, resp.List, resp.Items
+	}
+
+	rec, created, items := appendItems("Reading%20List", map[string]any{
+		"items": []map[string]any{
+			{"url": "https://example.com/a"},
+			{"url": "https://example.com/b", "title": "B"},
+		},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if created.Title != "Reading List" || created.BoardID != board.ID {
+		t.Fatalf("list = %q on board %d, want %q on board %d", created.Title, created.BoardID, "Reading List", board.ID)
+	}
+	if len(items) != 2 || *items[0].Title != "Fetched" || *items[1].Title != "B" || items[1].Position != 1 {
+		t.Fatalf("items = %+v, want Fetched and B at positions 0 and 1", items)
+	}
+
+	// A second call with different casing appends to the same list
+	rec, existing, items := appendItems("reading list", map[string]any{
+		"items": []map[string]any{{"url": "https://example.com/c"}},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if existing.ID != created.ID {
+		t.Fatalf("list ID = %d, want existing list %d", existing.ID, created.ID)
+	}
+	if len(items) != 1 || items[0].Position != 2 {
+		t.Fatalf("items = %+v, want one item at position 2", items)
+	}
+
+	// Invalid URLs reject the whole batch
+	rec, _, _ = appendItems("Other", map[string]any{
+		"items": []map[string]any{{"url": "https://example.com/d"}, {"url": "not a url"}},
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	lists, err := itemsAPI.db.GetListsByBoard(userID, board.ID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	for _, list := range lists {
+		if list.Title == "Other" {
+			t.Fatalf("list %q was created for a rejected batch", list.Title)
+		}
+	}
+}
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "embedded NUL", input: "a\x00b", want: "ab"},
+		{name: "C0 controls", input: "\x01bell\x07 esc\x1b[0m", want: "bell esc[0m"},
+		{name: "DEL and C1", input: "x\x7fy\u0085z", want: "xyz"},
+		{name: "tabs and newlines kept", input: "line 1\n\tline 2\r\n", want: "line 1\n\tline 2\r\n"},
+		{name: "invalid UTF-8 dropped", input: "ok\xff", want: "ok"},
+		{name: "unicode kept", input: "café ✓", want: "café ✓"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeText(tt.input); got != tt.want {
+				t.Fatalf("sanitizeText(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleCreateItem_StripsControlCharacters(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	rec := performCreateItemRequest(t, itemsAPI, userID, map[string]any{
+		"list_id":        listID,
+		"type":           "note",
+		"title":          "Sho\x00pping",
+		"content":        "eggs\x00\x1b\nmilk\tx2",
+		"content_format": "text",
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	var item models.Item
+	if err := json.Unmarshal(rec.Body.Bytes(), &item); err != nil {
+		t.Fatalf("unmarshal created item: %v", err)
+	}
+	if item.Content == nil || *item.Content != "eggs\nmilk\tx2" {
+		t.Fatalf("content = %v, want control characters stripped", item.Content)
+	}
+
+	// A note made only of control characters is empty once sanitized
+	rec = performCreateItemRequest(t, itemsAPI, userID, map[string]any{
+		"list_id": listID,
+		"type":    "note",
+		"content": "\x00\x00",
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleItemExists(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	saved := "https://Example.com/article/#comments"
+	item, err := itemsAPI.db.CreateItem(listID, "bookmark", nil, &saved, nil, nil, "auto", nil, "markdown", 0, true)
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	list, err := itemsAPI.db.GetList(listID, userID)
+	if err != nil {
+		t.Fatalf("get list: %v", err)
+	}
+
+	// Another user's identical bookmark must not be reported
+	other, err := itemsAPI.db.CreateUser("other", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	otherBoard, err := itemsAPI.db.GetDefaultBoard(other.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	otherList, err := itemsAPI.db.CreateList(other.ID, otherBoard.ID, "Other", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	if _, err := itemsAPI.db.CreateItem(otherList.ID, "bookmark", nil, &saved, nil, nil, "auto", nil, "markdown", 0, true); err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	check := func(rawURL string) (bool, []models.ItemLocation) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/items/exists?url="+url.QueryEscape(rawURL), nil)
+		req = req.WithContext(setUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		itemsAPI.HandleItemExists(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var body struct {
+			Exists bool                  `json:"exists"`
+			Items  []models.ItemLocation `json:"items"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		return body.Exists, body.Items
+	}
+
+	exists, items := check("https://example.com/article")
+	want := models.ItemLocation{ID: item.ID, ListID: listID, BoardID: list.BoardID}
+	if !exists || len(items) != 1 || items[0] != want {
+		t.Fatalf("exists = %v, items = %+v, want true with %+v", exists, items, want)
+	}
+
+	if exists, items := check("https://example.com/other"); exists || len(items) != 0 {
+		t.Fatalf("exists = %v, items = %+v, want false with no items", exists, items)
+	}
+
+	// URLs whose normalized form isn't a prefix of the stored one are still found
+	for _, stored := range []string{"https://example.com/?ref=x", "https://example.com/path/?q=1", "https://example.com/a b"} {
+		if _, err := itemsAPI.db.CreateItem(listID, "bookmark", nil, &stored, nil, nil, "auto", nil, "markdown", 0, true); err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+		if exists, items := check(stored); !exists || len(items) != 1 {
+			t.Fatalf("%s: exists = %v, items = %+v, want the stored bookmark", stored, exists, items)
+		}
+	}
+
+	// Editing the URL moves the bookmark to its new address
+	moved := "https://EXAMPLE.com/moved/"
+	if err := itemsAPI.db.UpdateItemFields(item.ID, map[string]interface{}{"url": &moved}); err != nil {
+		t.Fatalf("update item: %v", err)
+	}
+	if exists, _ := check("https://example.com/article"); exists {
+		t.Fatalf("old URL still reported after the edit")
+	}
+	if exists, items := check("https://example.com/moved"); !exists || len(items) != 1 || items[0] != want {
+		t.Fatalf("exists = %v, items = %+v, want true with %+v", exists, items, want)
+	}
+}
+
+func TestHandlePinItems(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	var ids []int
+	for _, title := range []string{"One", "Two"} {
+		rec := performCreateItemRequest(t, itemsAPI, userID, map[string]any{
+			"list_id":     listID,
+			"type":        "bookmark",
+			"title":       title,
+			"url":         "https://example.com/" + title,
+			"icon_source": "loom",
+		})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+		var item models.Item
+		if err := json.Unmarshal(rec.Body.Bytes(), &item); err != nil {
+			t.Fatalf("unmarshal created item: %v", err)
+		}
+		ids = append(ids, item.ID)
+	}
+
+	// pin posts body to the pin-batch handler
+	pin := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/items/pin-batch", strings.NewReader(body))
+		req = req.WithContext(setUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		itemsAPI.HandlePinItems(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{name: "unknown item", body: fmt.Sprintf(`{"item_ids":[%d,999999],"pinned":true}`, ids[0]), wantCode: http.StatusNotFound},
+		{name: "duplicate id", body: fmt.Sprintf(`{"item_ids":[%d,%d],"pinned":true}`, ids[0], ids[0]), wantCode: http.StatusBadRequest},
+		{name: "empty", body: `{"item_ids":[],"pinned":true}`, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := pin(tt.body); rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d, body=%s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+		}
+	}
+	if item, err := itemsAPI.db.GetItem(ids[0]); err != nil || item.IsPinned {
+		t.Fatalf("item = %+v (err %v), want it left unpinned", item, err)
+	}
+
+	rec := pin(fmt.Sprintf(`{"item_ids":[%d,%d],"pinned":true}`, ids[0], ids[1]))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var pinned []models.Item
+	if err := json.Unmarshal(rec.Body.Bytes(), &pinned); err != nil {
+		t.Fatalf("unmarshal items: %v", err)
+	}
+	if len(pinned) != 2 || !pinned[0].IsPinned || !pinned[1].IsPinned {
+		t.Fatalf("items = %+v, want both pinned", pinned)
+	}
+
+	if rec := pin(fmt.Sprintf(`{"item_ids":[%d],"pinned":false}`, ids[1])); rec.Code != http.StatusOK {
+		t.Fatalf("unpin status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if item, err := itemsAPI.db.GetItem(ids[1]); err != nil || item.IsPinned {
+		t.Fatalf("item = %+v (err %v), want it unpinned", item, err)
+	}
+}
+
+func TestHandleGetItemsByIDs_NullContentFormat(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	content := "legacy"
+	item, err := itemsAPI.db.CreateItem(listID, "note", nil, nil, &content, nil, "auto", nil, "markdown", 0, true)
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	if _, err := itemsAPI.db.Exec("UPDATE items SET content_format = NULL WHERE id = ?", item.ID); err != nil {
+		t.Fatalf("clear content format: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/items?ids=%d", item.ID), nil)
+	req = req.WithContext(setUserID(req.Context(), userID))
+	rec := httptest.NewRecorder()
+	itemsAPI.HandleGetItemsByIDs(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var items []models.Item
+	if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+		t.Fatalf("unmarshal items: %v", err)
+	}
+	if len(items) != 1 || items[0].ContentFormat != "markdown" {
+		t.Fatalf("items = %+v, want the item read with the default format", items)
+	}
+}
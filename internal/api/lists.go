@@ -2,10 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/crueber/loom/internal/db"
 	"github.com/crueber/loom/internal/models"
@@ -14,26 +16,112 @@ import (
 
 // ListsAPI handles list endpoints
 type ListsAPI struct {
-	db *db.DB
+	db                 *db.DB
+	uniqueListTitles   bool
+	collapsedByDefault bool
+
+	// itemLimits caps each user's bookmark and note counts
+	itemLimits ItemLimits
+}
+
+// NewListsAPI creates a new lists API handler.
+// When uniqueListTitles is set, duplicate list titles within a board are rejected.
+// collapsedByDefault sets the initial state of lists created without an explicit collapsed value.
+func NewListsAPI(database *db.DB, uniqueListTitles, collapsedByDefault bool) *ListsAPI {
+	return &ListsAPI{
+		db:                 database,
+		uniqueListTitles:   uniqueListTitles,
+		collapsedByDefault: collapsedByDefault,
+	}
+}
+
+// SetItemLimits caps how many bookmarks and notes each non-admin user may create by
+// copying lists
+func (l *ListsAPI) SetItemLimits(limits ItemLimits) {
+	l.itemLimits = limits
+}
+
+// checkListCopyLimit responds with 409 and returns false if copying the list's items
+// would take the user past their item caps
+func (l *ListsAPI) checkListCopyLimit(w http.ResponseWriter, listID, userID int) bool {
+	counts, err := l.db.CountListItemsByType(listID, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to count items")
+		return false
+	}
+	return l.itemLimits.checkItemLimits(w, l.db, userID, counts)
 }
 
-// NewListsAPI creates a new lists API handler
-func NewListsAPI(database *db.DB) *ListsAPI {
-	return &ListsAPI{db: database}
+// checkListCopyTitle responds and returns false if unique list titles are enforced
+// and copying or moving the list onto boardID would repeat a title already there.
+// A boardID of zero means the list's own board. A copy leaves the original in
+// place, so its own title counts as taken.
+func (l *ListsAPI) checkListCopyTitle(w http.ResponseWriter, listID, userID, boardID int, copy bool) bool {
+	if !l.uniqueListTitles {
+		return true
+	}
+	list, err := l.db.GetList(listID, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database error")
+		return false
+	}
+	if list == nil {
+		respondError(w, http.StatusNotFound, "List not found")
+		return false
+	}
+	if boardID == 0 {
+		boardID = list.BoardID
+	}
+	excludeListID := listID
+	if copy {
+		excludeListID = 0
+	}
+	exists, err := l.db.ListTitleExists(boardID, userID, list.Title, excludeListID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database error")
+		return false
+	}
+	if exists {
+		respondError(w, http.StatusConflict, "A list with this title already exists on this board")
+		return false
+	}
+	return true
 }
 
 // CreateListRequest represents a request to create a list
 type CreateListRequest struct {
-	Title   string `json:"title"`
-	Color   string `json:"color"`
-	BoardID int    `json:"board_id"`
+	Title     string `json:"title"`
+	Color     string `json:"color"`
+	BoardID   int    `json:"board_id"`
+	Collapsed *bool  `json:"collapsed,omitempty"` // defaults to the server's configured default
+}
+
+// maxBatchLists caps how many lists a single batch create may add
+const maxBatchLists = 100
+
+// BatchCreateListsRequest represents a request to create several lists on a board
+type BatchCreateListsRequest struct {
+	Lists []struct {
+		Title     string `json:"title"`
+		Color     string `json:"color"`
+		Collapsed *bool  `json:"collapsed,omitempty"`
+	} `json:"lists"`
 }
 
+// AdoptListsRequest represents a request to move several lists onto a board
+type AdoptListsRequest struct {
+	ListIDs []int `json:"list_ids"`
+}
+
+// RenameListsRequest maps list IDs to their new titles
+type RenameListsRequest map[string]string
+
 // UpdateListRequest represents a request to update a list
 type UpdateListRequest struct {
 	Title     *string `json:"title,omitempty"`
 	Color     *string `json:"color,omitempty"`
 	Collapsed *bool   `json:"collapsed,omitempty"`
+	SortMode  *string `json:"sort_mode,omitempty"` // "manual", "title" or "created"
 }
 
 // ReorderListsRequest represents a request to reorder lists
@@ -86,12 +174,12 @@ func (l *ListsAPI) HandleCreateList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate input
-	req.Title = strings.TrimSpace(req.Title)
+	req.Title = strings.TrimSpace(sanitizeText(req.Title))
 	if req.Title == "" {
 		respondError(w, http.StatusBadRequest, "Title is required")
 		return
 	}
-	if len(req.Title) > 100 {
+	if utf8.RuneCountInString(req.Title) > 100 {
 		respondError(w, http.StatusBadRequest, "Title must be less than 100 characters")
 		return
 	}
@@ -118,6 +206,19 @@ func (l *ListsAPI) HandleCreateList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject duplicate titles within the board if enabled
+	if l.uniqueListTitles {
+		exists, err := l.db.ListTitleExists(req.BoardID, userID, req.Title, 0)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if exists {
+			respondError(w, http.StatusConflict, "A list with this title already exists on this board")
+			return
+		}
+	}
+
 	// Get current max position for this board
 	lists, err := l.db.GetListsByBoard(userID, req.BoardID)
 	if err != nil {
@@ -130,8 +231,13 @@ func (l *ListsAPI) HandleCreateList(w http.ResponseWriter, r *http.Request) {
 		position = lists[len(lists)-1].Position + 1
 	}
 
+	collapsed := l.collapsedByDefault
+	if req.Collapsed != nil {
+		collapsed = *req.Collapsed
+	}
+
 	// Create list
-	list, err := l.db.CreateList(userID, req.BoardID, req.Title, req.Color, position)
+	list, err := l.db.CreateList(userID, req.BoardID, req.Title, req.Color, position, collapsed)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to create list")
 		return
@@ -140,6 +246,208 @@ func (l *ListsAPI) HandleCreateList(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusCreated, list)
 }
 
+// HandleBatchCreateLists appends several lists to a board in one transaction
+func (l *ListsAPI) HandleBatchCreateLists(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	boardID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid board ID")
+		return
+	}
+
+	var req BatchCreateListsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.Lists) == 0 {
+		respondError(w, http.StatusBadRequest, "At least one list is required")
+		return
+	}
+	if len(req.Lists) > maxBatchLists {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("At most %d lists may be created at once", maxBatchLists))
+		return
+	}
+
+	// Verify board ownership
+	owns, err := l.db.VerifyBoardOwnership(boardID, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to verify board ownership")
+		return
+	}
+	if !owns {
+		respondError(w, http.StatusNotFound, "Board not found")
+		return
+	}
+
+	newLists := make([]db.NewList, 0, len(req.Lists))
+	seenTitles := make(map[string]bool)
+	for i, entry := range req.Lists {
+		title := strings.TrimSpace(sanitizeText(entry.Title))
+		if title == "" {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("List %d: title is required", i+1))
+			return
+		}
+		if utf8.RuneCountInString(title) > 100 {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("List %d: title must be less than 100 characters", i+1))
+			return
+		}
+		if !isValidHexColor(entry.Color) {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("List %d: invalid color", i+1))
+			return
+		}
+
+		// Reject duplicate titles within the board (and within the batch) if enabled
+		if l.uniqueListTitles {
+			key := strings.ToLower(title)
+			exists, err := l.db.ListTitleExists(boardID, userID, title, 0)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "Database error")
+				return
+			}
+			if exists || seenTitles[key] {
+				respondError(w, http.StatusConflict, fmt.Sprintf("List %d: a list with this title already exists on this board", i+1))
+				return
+			}
+			seenTitles[key] = true
+		}
+
+		collapsed := l.collapsedByDefault
+		if entry.Collapsed != nil {
+			collapsed = *entry.Collapsed
+		}
+
+		newLists = append(newLists, db.NewList{Title: title, Color: entry.Color, Collapsed: collapsed})
+	}
+
+	lists, err := l.db.CreateLists(userID, boardID, newLists)
+	if err != nil {
+		if err.Error() == "board not found" {
+			respondError(w, http.StatusNotFound, "Board not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to create lists")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, lists)
+}
+
+// HandleAdoptLists moves several lists onto a board in one transaction, appending them in order
+func (l *ListsAPI) HandleAdoptLists(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	boardID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid board ID")
+		return
+	}
+
+	var req AdoptListsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.ListIDs) == 0 {
+		respondError(w, http.StatusBadRequest, "At least one list is required")
+		return
+	}
+	if len(req.ListIDs) > maxBatchLists {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("At most %d lists may be moved at once", maxBatchLists))
+		return
+	}
+	seen := make(map[int]bool, len(req.ListIDs))
+	for _, id := range req.ListIDs {
+		if seen[id] {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("List %d appears more than once", id))
+			return
+		}
+		seen[id] = true
+	}
+
+	lists, err := l.db.AdoptLists(userID, boardID, req.ListIDs)
+	if err != nil {
+		if err.Error() == "lists not found" {
+			respondError(w, http.StatusNotFound, "Board or lists not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to move lists")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, lists)
+}
+
+// HandleRenameLists renames several lists in one transaction and returns them by ID;
+// either every list is renamed or none is
+func (l *ListsAPI) HandleRenameLists(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	var req RenameListsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req) == 0 {
+		respondError(w, http.StatusBadRequest, "At least one list is required")
+		return
+	}
+	if len(req) > maxBatchLists {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("At most %d lists may be renamed at once", maxBatchLists))
+		return
+	}
+
+	titles := make(map[int]string, len(req))
+	for key, title := range req {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid list ID %q", key))
+			return
+		}
+		title = strings.TrimSpace(sanitizeText(title))
+		if title == "" {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("List %d: title cannot be empty", id))
+			return
+		}
+		if utf8.RuneCountInString(title) > 100 {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("List %d: title must be less than 100 characters", id))
+			return
+		}
+		titles[id] = title
+	}
+
+	lists, err := l.db.RenameLists(userID, titles, l.uniqueListTitles)
+	if err != nil {
+		switch err.Error() {
+		case "lists not found":
+			respondError(w, http.StatusNotFound, "Lists not found")
+		case "list title already exists":
+			respondError(w, http.StatusConflict, "A list with this title already exists on this board")
+		default:
+			respondError(w, http.StatusInternalServerError, "Failed to rename lists")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, lists)
+}
+
 // HandleUpdateList updates a list
 func (l *ListsAPI) HandleUpdateList(w http.ResponseWriter, r *http.Request) {
 	userID, ok := getUserID(r.Context())
@@ -173,12 +481,12 @@ func (l *ListsAPI) HandleUpdateList(w http.ResponseWriter, r *http.Request) {
 
 	// Validate input
 	if req.Title != nil {
-		*req.Title = strings.TrimSpace(*req.Title)
+		*req.Title = strings.TrimSpace(sanitizeText(*req.Title))
 		if *req.Title == "" {
 			respondError(w, http.StatusBadRequest, "Title cannot be empty")
 			return
 		}
-		if len(*req.Title) > 100 {
+		if utf8.RuneCountInString(*req.Title) > 100 {
 			respondError(w, http.StatusBadRequest, "Title must be less than 100 characters")
 			return
 		}
@@ -189,8 +497,40 @@ func (l *ListsAPI) HandleUpdateList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.SortMode != nil && !db.IsValidListSortMode(*req.SortMode) {
+		respondError(w, http.StatusBadRequest, "Sort mode must be 'manual', 'title', or 'created'")
+		return
+	}
+
+	// Reject duplicate titles within the board if enabled
+	if l.uniqueListTitles && req.Title != nil {
+		list, err := l.db.GetList(listID, userID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if list == nil {
+			respondError(w, http.StatusNotFound, "List not found")
+			return
+		}
+		exists, err := l.db.ListTitleExists(list.BoardID, userID, *req.Title, listID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if exists {
+			respondError(w, http.StatusConflict, "A list with this title already exists on this board")
+			return
+		}
+	}
+
 	// Update list
-	if err := l.db.UpdateList(listID, userID, req.Title, req.Color, req.Collapsed); err != nil {
+	if err := l.db.UpdateList(listID, userID, req.Title, req.Color, req.Collapsed, req.SortMode); err != nil {
+		// The list may have been deleted since the ownership check
+		if err.Error() == "list not found" {
+			respondError(w, http.StatusNotFound, "List not found")
+			return
+		}
 		respondError(w, http.StatusInternalServerError, "Failed to update list")
 		return
 	}
@@ -201,6 +541,10 @@ func (l *ListsAPI) HandleUpdateList(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "Failed to get list")
 		return
 	}
+	if list == nil {
+		respondError(w, http.StatusNotFound, "List not found")
+		return
+	}
 
 	respondJSON(w, http.StatusOK, list)
 }
@@ -266,10 +610,68 @@ func (l *ListsAPI) HandleReorderLists(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// MoveListRequest represents a request to move a single list within its board.
+// Exactly one of Direction ("up" or "down") or Position (target index) is required.
+type MoveListRequest struct {
+	Direction string `json:"direction,omitempty"`
+	Position  *int   `json:"position,omitempty"`
+}
+
+// HandleMoveList nudges a list up or down (or to an index) within its board and returns the board's lists
+func (l *ListsAPI) HandleMoveList(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid list ID")
+		return
+	}
+
+	var req MoveListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if (req.Direction == "") == (req.Position == nil) {
+		respondError(w, http.StatusBadRequest, "Provide either direction or position")
+		return
+	}
+	if req.Direction != "" && req.Direction != db.ListMoveUp && req.Direction != db.ListMoveDown {
+		respondError(w, http.StatusBadRequest, "Direction must be 'up' or 'down'")
+		return
+	}
+	position := 0
+	if req.Position != nil {
+		if *req.Position < 0 {
+			respondError(w, http.StatusBadRequest, "Position must be non-negative")
+			return
+		}
+		position = *req.Position
+	}
+
+	lists, err := l.db.MoveListWithinBoard(listID, userID, req.Direction, position)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, "List not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to move list")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, lists)
+}
+
 // CopyOrMoveListRequest represents a request to copy or move a list to another board
 type CopyOrMoveListRequest struct {
 	TargetBoardID int  `json:"target_board_id"`
-	Copy          bool `json:"copy"` // true for copy, false for move
+	Copy          bool `json:"copy"`               // true for copy, false for move
+	Position      *int `json:"position,omitempty"` // optional index in the target board (appends if omitted)
 }
 
 // HandleCopyOrMoveList copies or moves a list to another board
@@ -292,16 +694,63 @@ func (l *ListsAPI) HandleCopyOrMoveList(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if req.Copy && !l.checkListCopyLimit(w, listID, userID) {
+		return
+	}
+	if !l.checkListCopyTitle(w, listID, userID, req.TargetBoardID, req.Copy) {
+		return
+	}
+
 	// Call database method to copy or move the list
-	resultList, err := l.db.MoveOrCopyListToBoard(listID, userID, req.TargetBoardID, req.Copy)
+	resultList, err := l.db.MoveOrCopyListToBoard(listID, userID, req.TargetBoardID, req.Copy, req.Position)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			respondError(w, http.StatusNotFound, err.Error())
 			return
 		}
+		if strings.Contains(err.Error(), "out of range") {
+			respondError(w, http.StatusBadRequest, "Position is out of range for the target board")
+			return
+		}
 		respondError(w, http.StatusInternalServerError, "Failed to copy/move list")
 		return
 	}
 
 	respondJSON(w, http.StatusOK, resultList)
 }
+
+// HandleDuplicateList copies a list and its items into the same board, directly after the original
+func (l *ListsAPI) HandleDuplicateList(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	listID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid list ID")
+		return
+	}
+
+	if !l.checkListCopyLimit(w, listID, userID) {
+		return
+	}
+	// A duplicate keeps its title on the same board, so it is refused when list
+	// titles must be unique
+	if !l.checkListCopyTitle(w, listID, userID, 0, true) {
+		return
+	}
+
+	list, err := l.db.DuplicateList(listID, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, "List not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to duplicate list")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, list)
+}
@@ -0,0 +1,471 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/crueber/loom/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandleBatchCreateLists_AppendsInOrder(t *testing.T) {
+	database := newBoardsTestDB(t)
+	listsAPI := NewListsAPI(database, true, false)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.GetDefaultBoard(user.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	if _, err := database.CreateList(user.ID, board.ID, "Existing", "#ffffff", 0, false); err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+
+	rec := performBatchCreateLists(t, listsAPI, board.ID, user.ID, `{"lists":[{"title":"Todo","color":"#111111"},{"title":"Done","color":"#222222"}]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	var created []models.List
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("unmarshal lists: %v", err)
+	}
+	if len(created) != 2 || created[0].Title != "Todo" || created[0].Position != 1 || created[1].Title != "Done" || created[1].Position != 2 {
+		t.Fatalf("created = %+v, want Todo at 1 and Done at 2", created)
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{name: "invalid color", body: `{"lists":[{"title":"Ok","color":"#333333"},{"title":"Bad","color":"red"}]}`, wantCode: http.StatusBadRequest},
+		{name: "empty batch", body: `{"lists":[]}`, wantCode: http.StatusBadRequest},
+		{name: "duplicate of existing title", body: `{"lists":[{"title":"existing","color":"#333333"}]}`, wantCode: http.StatusConflict},
+		{name: "duplicate within batch", body: `{"lists":[{"title":"New","color":"#333333"},{"title":"NEW","color":"#333333"}]}`, wantCode: http.StatusConflict},
+		{name: "101 CJK characters", body: `{"lists":[{"title":"` + strings.Repeat("表", 101) + `","color":"#333333"}]}`, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := performBatchCreateLists(t, listsAPI, board.ID, user.ID, tt.body); rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d", tt.name, rec.Code, tt.wantCode)
+		}
+	}
+
+	lists, err := database.GetListsByBoard(user.ID, board.ID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	if len(lists) != 3 {
+		t.Fatalf("len(lists) = %d, want 3 (rejected batches must not write)", len(lists))
+	}
+
+	// Titles are limited in characters, not bytes: 100 CJK characters are 300 bytes
+	rec = performBatchCreateLists(t, listsAPI, board.ID, user.ID, `{"lists":[{"title":"`+strings.Repeat("表", 100)+`","color":"#333333"}]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("100 CJK characters: status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	rec = performBatchCreateLists(t, listsAPI, board.ID, user.ID, `{"lists":[{"title":"Ar\u0000chi\u001bve","color":"#333333"}]}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("control characters: status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if created[0].Title != "Archive" {
+		t.Fatalf("title = %q, want control characters stripped", created[0].Title)
+	}
+}
+
+func performBatchCreateLists(t *testing.T, listsAPI *ListsAPI, boardID, userID int, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", strconv.Itoa(boardID))
+
+	ctx := context.WithValue(context.Background(), chi.RouteCtxKey, routeCtx)
+	ctx = setUserID(ctx, userID)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/boards/"+strconv.Itoa(boardID)+"/lists/batch", bytes.NewBufferString(body)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	listsAPI.HandleBatchCreateLists(rec, req)
+
+	return rec
+}
+
+func TestHandleMoveList(t *testing.T) {
+	database := newBoardsTestDB(t)
+	listsAPI := NewListsAPI(database, true, false)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.GetDefaultBoard(user.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	ids := map[string]int{}
+	for i, title := range []string{"A", "B", "C"} {
+		list, err := database.CreateList(user.ID, board.ID, title, "#ffffff", i, false)
+		if err != nil {
+			t.Fatalf("create list: %v", err)
+		}
+		ids[title] = list.ID
+	}
+
+	tests := []struct {
+		name      string
+		listID    int
+		body      string
+		wantCode  int
+		wantOrder string
+	}{
+		{name: "up", listID: ids["C"], body: `{"direction":"up"}`, wantCode: http.StatusOK, wantOrder: "ACB"},
+		{name: "up at top is a no-op", listID: ids["A"], body: `{"direction":"up"}`, wantCode: http.StatusOK, wantOrder: "ACB"},
+		{name: "down", listID: ids["A"], body: `{"direction":"down"}`, wantCode: http.StatusOK, wantOrder: "CAB"},
+		{name: "position clamps", listID: ids["C"], body: `{"position":10}`, wantCode: http.StatusOK, wantOrder: "ABC"},
+		{name: "both fields", listID: ids["A"], body: `{"direction":"up","position":1}`, wantCode: http.StatusBadRequest},
+		{name: "bad direction", listID: ids["A"], body: `{"direction":"left"}`, wantCode: http.StatusBadRequest},
+		{name: "missing list", listID: 9999, body: `{"direction":"up"}`, wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := performListAction(t, listsAPI.HandleMoveList, tt.listID, user.ID, tt.body)
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d, body=%s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+		}
+		if tt.wantOrder == "" {
+			continue
+		}
+
+		var lists []models.List
+		if err := json.Unmarshal(rec.Body.Bytes(), &lists); err != nil {
+			t.Fatalf("%s: unmarshal lists: %v", tt.name, err)
+		}
+		order := ""
+		for i, list := range lists {
+			if list.Position != i {
+				t.Fatalf("%s: list %s at position %d, want %d", tt.name, list.Title, list.Position, i)
+			}
+			order += list.Title
+		}
+		if order != tt.wantOrder {
+			t.Fatalf("%s: order = %s, want %s", tt.name, order, tt.wantOrder)
+		}
+	}
+}
+
+func TestHandleUpdateList_SortModeOrdersItems(t *testing.T) {
+	database := newBoardsTestDB(t)
+	listsAPI := NewListsAPI(database, true, false)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.GetDefaultBoard(user.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	list, err := database.CreateList(user.ID, board.ID, "Links", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	if list.SortMode != "manual" {
+		t.Fatalf("new list sort_mode = %q, want manual", list.SortMode)
+	}
+
+	// Positions put the titles out of alphabetical order
+	for i, title := range []string{"banana", "Cherry", "apple"} {
+		url := "https://example.com/" + title
+		if _, err := database.CreateItem(list.ID, "bookmark", &title, &url, nil, nil, "auto", nil, "text", i, true); err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+	}
+	itemOrder := func() string {
+		t.Helper()
+		items, err := database.GetItems(list.ID)
+		if err != nil {
+			t.Fatalf("get items: %v", err)
+		}
+		order := ""
+		for _, item := range items {
+			order += (*item.Title)[:1]
+		}
+		return order
+	}
+
+	if rec := performListAction(t, listsAPI.HandleUpdateList, list.ID, user.ID, `{"sort_mode":"shuffle"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad sort_mode status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec := performListAction(t, listsAPI.HandleUpdateList, list.ID, user.ID, `{"sort_mode":"title"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var updated models.List
+	if err := json.Unmarshal(rec.Body.Bytes(), &updated); err != nil {
+		t.Fatalf("unmarshal list: %v", err)
+	}
+	if updated.SortMode != "title" {
+		t.Fatalf("sort_mode = %q, want title", updated.SortMode)
+	}
+	if order := itemOrder(); order != "abC" {
+		t.Fatalf("title order = %s, want abC", order)
+	}
+
+	if rec := performListAction(t, listsAPI.HandleUpdateList, list.ID, user.ID, `{"sort_mode":"manual"}`); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if order := itemOrder(); order != "bCa" {
+		t.Fatalf("manual order = %s, want bCa", order)
+	}
+}
+
+func TestListCopies_HonourUniqueTitles(t *testing.T) {
+	database := newBoardsTestDB(t)
+	listsAPI := NewListsAPI(database, true, false)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.GetDefaultBoard(user.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	other, err := database.CreateBoard(user.ID, "Work", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	list, err := database.CreateList(user.ID, board.ID, "Links", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	if _, err := database.CreateList(user.ID, other.ID, "LINKS", "#ffffff", 0, false); err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	empty, err := database.CreateBoard(user.ID, "Empty", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		listID   int
+		body     string
+		wantCode int
+	}{
+		{name: "duplicate", handler: listsAPI.HandleDuplicateList, listID: list.ID, wantCode: http.StatusConflict},
+		{name: "copy onto a board with the title", handler: listsAPI.HandleCopyOrMoveList, listID: list.ID, body: fmt.Sprintf(`{"target_board_id":%d,"copy":true}`, other.ID), wantCode: http.StatusConflict},
+		{name: "move onto a board with the title", handler: listsAPI.HandleCopyOrMoveList, listID: list.ID, body: fmt.Sprintf(`{"target_board_id":%d}`, other.ID), wantCode: http.StatusConflict},
+		{name: "copy onto the same board", handler: listsAPI.HandleCopyOrMoveList, listID: list.ID, body: fmt.Sprintf(`{"target_board_id":%d,"copy":true}`, board.ID), wantCode: http.StatusConflict},
+		{name: "copy onto a free board", handler: listsAPI.HandleCopyOrMoveList, listID: list.ID, body: fmt.Sprintf(`{"target_board_id":%d,"copy":true}`, empty.ID), wantCode: http.StatusOK},
+		{name: "duplicate missing list", handler: listsAPI.HandleDuplicateList, listID: 9999, wantCode: http.StatusNotFound},
+		{name: "update missing list", handler: listsAPI.HandleUpdateList, listID: 9999, body: `{"title":"Other"}`, wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rec := performListAction(t, tt.handler, tt.listID, user.ID, tt.body); rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d, body=%s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+		}
+	}
+
+	title, link, iconURL := "Example", "https://example.com/", "https://icons.example.com/e.png"
+	item, err := database.CreateItem(list.ID, "bookmark", &title, &link, nil, nil, "custom", &iconURL, "markdown", 0, false)
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	if _, err := database.SetItemsPinned(user.ID, []int{item.ID}, true); err != nil {
+		t.Fatalf("pin item: %v", err)
+	}
+
+	// Without the setting a duplicate keeps its title
+	rec := performListAction(t, NewListsAPI(database, false, false).HandleDuplicateList, list.ID, user.ID, "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	// The copied items keep their per-item settings
+	var duplicate models.List
+	if err := json.Unmarshal(rec.Body.Bytes(), &duplicate); err != nil {
+		t.Fatalf("unmarshal list: %v", err)
+	}
+	items, err := database.GetItems(duplicate.ID)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("duplicate items = %v, %v, want 1 item", items, err)
+	}
+	if item := items[0]; item.OpenInNewTab || !item.IsPinned || item.IconSource != "custom" || item.CustomIconURL == nil || *item.CustomIconURL != iconURL {
+		t.Fatalf("duplicate item = %+v, want open_in_new_tab, is_pinned, icon_source and custom_icon_url copied", item)
+	}
+}
+
+func performListAction(t *testing.T, handler http.HandlerFunc, listID, userID int, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", strconv.Itoa(listID))
+
+	ctx := context.WithValue(context.Background(), chi.RouteCtxKey, routeCtx)
+	ctx = setUserID(ctx, userID)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/lists/"+strconv.Itoa(listID), bytes.NewBufferString(body)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	handler(rec, req)
+
+	return rec
+}
+
+func TestHandleAdoptLists(t *testing.T) {
+	database := newBoardsTestDB(t)
+	listsAPI := NewListsAPI(database, true, false)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	other, err := database.CreateUser("other", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	source, err := database.GetDefaultBoard(user.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	target, err := database.CreateBoard(user.ID, "Target", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	if _, err := database.CreateList(user.ID, target.ID, "Existing", "#ffffff", 0, false); err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	ids := map[string]int{}
+	for i, title := range []string{"A", "B", "C"} {
+		list, err := database.CreateList(user.ID, source.ID, title, "#ffffff", i, false)
+		if err != nil {
+			t.Fatalf("create list: %v", err)
+		}
+		ids[title] = list.ID
+	}
+	otherBoard, err := database.GetDefaultBoard(other.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	foreign, err := database.CreateList(other.ID, otherBoard.ID, "Foreign", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		boardID  int
+		body     string
+		wantCode int
+	}{
+		{name: "foreign list", boardID: target.ID, body: fmt.Sprintf(`{"list_ids":[%d,%d]}`, ids["A"], foreign.ID), wantCode: http.StatusNotFound},
+		{name: "foreign board", boardID: otherBoard.ID, body: fmt.Sprintf(`{"list_ids":[%d]}`, ids["A"]), wantCode: http.StatusNotFound},
+		{name: "duplicate id", boardID: target.ID, body: fmt.Sprintf(`{"list_ids":[%d,%d]}`, ids["A"], ids["A"]), wantCode: http.StatusBadRequest},
+		{name: "empty", boardID: target.ID, body: `{"list_ids":[]}`, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := performListAction(t, listsAPI.HandleAdoptLists, tt.boardID, user.ID, tt.body); rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d, body=%s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+		}
+	}
+	if list, err := database.GetList(ids["A"], user.ID); err != nil || list.BoardID != source.ID {
+		t.Fatalf("list A = %+v (err %v), want it left on the source board", list, err)
+	}
+
+	rec := performListAction(t, listsAPI.HandleAdoptLists, target.ID, user.ID, fmt.Sprintf(`{"list_ids":[%d,%d]}`, ids["C"], ids["A"]))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var moved []models.List
+	if err := json.Unmarshal(rec.Body.Bytes(), &moved); err != nil {
+		t.Fatalf("unmarshal lists: %v", err)
+	}
+	if len(moved) != 2 || moved[0].Title != "C" || moved[0].Position != 1 || moved[1].Title != "A" || moved[1].Position != 2 {
+		t.Fatalf("moved = %+v, want C at 1 and A at 2", moved)
+	}
+	for _, list := range moved {
+		if list.BoardID != target.ID {
+			t.Fatalf("list %s on board %d, want %d", list.Title, list.BoardID, target.ID)
+		}
+	}
+}
+
+func TestHandleRenameLists(t *testing.T) {
+	database := newBoardsTestDB(t)
+	listsAPI := NewListsAPI(database, true, false)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	other, err := database.CreateUser("other", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	board, err := database.GetDefaultBoard(user.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	ids := map[string]int{}
+	for i, title := range []string{"A", "B", "C"} {
+		list, err := database.CreateList(user.ID, board.ID, title, "#ffffff", i, false)
+		if err != nil {
+			t.Fatalf("create list: %v", err)
+		}
+		ids[title] = list.ID
+	}
+	otherBoard, err := database.GetDefaultBoard(other.ID)
+	if err != nil {
+		t.Fatalf("get default board: %v", err)
+	}
+	foreign, err := database.CreateList(other.ID, otherBoard.ID, "Foreign", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{name: "foreign list", body: fmt.Sprintf(`{"%d":"X","%d":"Y"}`, ids["A"], foreign.ID), wantCode: http.StatusNotFound},
+		{name: "empty title", body: fmt.Sprintf(`{"%d":"X","%d":"  "}`, ids["A"], ids["B"]), wantCode: http.StatusBadRequest},
+		{name: "invalid id", body: `{"abc":"X"}`, wantCode: http.StatusBadRequest},
+		{name: "duplicate title", body: fmt.Sprintf(`{"%d":"c"}`, ids["A"]), wantCode: http.StatusConflict},
+		{name: "empty", body: `{}`, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := performListAction(t, listsAPI.HandleRenameLists, 0, user.ID, tt.body); rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d, body=%s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+		}
+	}
+	if list, err := database.GetList(ids["A"], user.ID); err != nil || list.Title != "A" {
+		t.Fatalf("list A = %+v (err %v), want it left unrenamed", list, err)
+	}
+
+	// Swapping titles within the batch doesn't trip the unique title check
+	rec := performListAction(t, listsAPI.HandleRenameLists, 0, user.ID, fmt.Sprintf(`{"%d":"B","%d":" A "}`, ids["A"], ids["B"]))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var renamed []models.List
+	if err := json.Unmarshal(rec.Body.Bytes(), &renamed); err != nil {
+		t.Fatalf("unmarshal lists: %v", err)
+	}
+	if len(renamed) != 2 || renamed[0].ID != ids["A"] || renamed[0].Title != "B" || renamed[1].ID != ids["B"] || renamed[1].Title != "A" {
+		t.Fatalf("renamed = %+v, want lists A and B with swapped titles", renamed)
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// oauthErrorTemplate is the page shown when an OAuth login fails. The browser
+// lands on these paths directly, so a JSON body would be unhelpful.
+var oauthErrorTemplate = template.Must(template.New("oauth_error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Sign-in failed - Loom</title>
+<style>
+body { font-family: system-ui, sans-serif; display: flex; align-items: center; justify-content: center; min-height: 100vh; margin: 0; background: #f5f5f5; color: #222; }
+main { background: #fff; padding: 2rem; border-radius: 8px; box-shadow: 0 2px 8px rgba(0,0,0,0.1); max-width: 28rem; text-align: center; }
+h1 { font-size: 1.25rem; margin-top: 0; }
+a { display: inline-block; margin-top: 1rem; padding: 0.5rem 1rem; border-radius: 4px; background: #3b82f6; color: #fff; text-decoration: none; }
+</style>
+</head>
+<body>
+<main>
+<h1>Sign-in failed</h1>
+<p>{{.Message}}</p>
+<a href="{{.RetryURL}}">Try again</a>
+</main>
+</body>
+</html>
+`))
+
+// renderOAuthError writes the OAuth error page with a link back to the login flow
+func renderOAuthError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	oauthErrorTemplate.Execute(w, struct {
+		Message  string
+		RetryURL string
+	}{
+		Message:  message,
+		RetryURL: "login", // Relative to /auth/, so it also works under a base path
+	})
+}
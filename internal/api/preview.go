@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/crueber/loom/internal/db"
+	"github.com/crueber/loom/internal/favicon"
+	"github.com/crueber/loom/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// StoredPreviewPath is the URL path stored preview images are served under
+const StoredPreviewPath = "/api/previews/"
+
+// maxConcurrentPreviewCaptures bounds the preview images captured at once
+const maxConcurrentPreviewCaptures = 4
+
+var (
+	ogImageTagPattern      = regexp.MustCompile(`(?is)<meta\s[^>]*(?:property|name)\s*=\s*["']og:image(?::url|:secure_url)?["'][^>]*>`)
+	htmlContentAttrPattern = regexp.MustCompile(`(?is)\bcontent\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	previewImageDownloader = downloadPreviewImage
+)
+
+// SetPreviewStore keeps captured preview images in store and serves them from
+// GET /api/previews/{hash}
+func (api *ItemsAPI) SetPreviewStore(store *favicon.Store) {
+	api.previewStore = store
+}
+
+// SetPreviewImages makes bookmark creation capture the page's og:image, when at most
+// maxBytes bytes, into the preview store; zero turns preview capture off
+func (api *ItemsAPI) SetPreviewImages(maxBytes int) {
+	api.previewImageMaxBytes = maxBytes
+}
+
+// previewsEnabled reports whether new bookmarks get preview images
+func (api *ItemsAPI) previewsEnabled() bool {
+	return api.previewStore != nil && api.previewImageMaxBytes > 0
+}
+
+// WaitForPreviews blocks until every preview capture started so far has finished
+func (api *ItemsAPI) WaitForPreviews() {
+	api.previewWG.Wait()
+}
+
+// HandleGetPreviewImage serves a stored preview image by its content hash
+func (api *ItemsAPI) HandleGetPreviewImage(w http.ResponseWriter, r *http.Request) {
+	serveStoredImage(w, api.previewStore, chi.URLParam(r, "hash"), "Preview image")
+}
+
+// capturePreviewImage stores a new bookmark's preview image in the background when
+// enabled, like favicon refreshes. page is the bookmark's HTML when it was already
+// fetched for the title, so the page isn't requested twice; nil fetches it. A page
+// without a usable og:image, or userID running out of fetch quota, just leaves the
+// bookmark without a preview.
+func (api *ItemsAPI) capturePreviewImage(userID int, item *models.Item, page []byte) {
+	if !api.previewsEnabled() || item.Type != db.ItemTypeBookmark || item.URL == nil {
+		return
+	}
+
+	itemID, pageURL := item.ID, *item.URL
+	api.previewWG.Add(1)
+	go func() {
+		defer api.previewWG.Done()
+		api.previewSlots <- struct{}{}
+		defer func() { <-api.previewSlots }()
+
+		if page == nil {
+			if !api.fetchQuota.allowFetch(nil, userID) {
+				return
+			}
+			var err error
+			if page, err = bookmarkPageFetcher(pageURL); err != nil {
+				return
+			}
+		}
+		imageURL, err := findOGImageURL(page, pageURL)
+		if err != nil || !api.fetchQuota.allowFetch(nil, userID) {
+			return
+		}
+		imageBytes, contentType, err := previewImageDownloader(imageURL, api.previewImageMaxBytes)
+		if err != nil {
+			return
+		}
+
+		hash, err := api.previewStore.Put(imageBytes, contentType)
+		if err != nil {
+			log.Printf("Failed to store preview image for item %d: %v", itemID, err)
+			return
+		}
+		previewURL := api.previewStore.URL(hash)
+		if err := api.db.SetItemPreviewImage(itemID, &previewURL); err != nil {
+			log.Printf("Failed to store preview image for item %d: %v", itemID, err)
+		}
+	}()
+}
+
+// downloadPreviewImage fetches the image at imageURL and returns its bytes and content
+// type, failing if it isn't an image or is larger than maxBytes
+func downloadPreviewImage(imageURL string, maxBytes int) ([]byte, string, error) {
+	resp, err := getPublicURL(imageURL)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "image/") {
+		return nil, "", fmt.Errorf("preview is not an image")
+	}
+
+	// Read one byte past the cap so oversized images are detected rather than truncated
+	imageBytes, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBytes)+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(imageBytes) > maxBytes {
+		return nil, "", fmt.Errorf("preview image exceeds %d bytes", maxBytes)
+	}
+	if len(imageBytes) == 0 {
+		return nil, "", fmt.Errorf("preview image is empty")
+	}
+
+	return imageBytes, mediaType, nil
+}
+
+// storeImportedPreview moves an imported preview data URI into store and returns its
+// stored URL. Other values, or every value when store is nil, are returned unchanged.
+func storeImportedPreview(store *favicon.Store, preview *string) (*string, error) {
+	if store == nil || preview == nil || !isImageDataURI(*preview) {
+		return preview, nil
+	}
+	mediaType, encoded, _ := strings.Cut(strings.TrimPrefix(*preview, "data:"), ";base64,")
+	imageBytes, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := store.Put(imageBytes, mediaType)
+	if err != nil {
+		return nil, err
+	}
+	storedURL := store.URL(hash)
+	return &storedURL, nil
+}
+
+// findOGImageURL returns the absolute http(s) URL of the first og:image in page,
+// resolving relative URLs against pageURL
+func findOGImageURL(page []byte, pageURL string) (string, error) {
+	tag := ogImageTagPattern.Find(page)
+	if tag == nil {
+		return "", fmt.Errorf("og:image not found")
+	}
+
+	matches := htmlContentAttrPattern.FindSubmatch(tag)
+	if matches == nil {
+		return "", fmt.Errorf("og:image has no content")
+	}
+	content := string(matches[1]) + string(matches[2])
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	imageURL, err := base.Parse(strings.TrimSpace(html.UnescapeString(content)))
+	if err != nil {
+		return "", err
+	}
+	if imageURL.Scheme != "http" && imageURL.Scheme != "https" {
+		return "", fmt.Errorf("unsupported og:image scheme %q", imageURL.Scheme)
+	}
+
+	return imageURL.String(), nil
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crueber/loom/internal/favicon"
+	"github.com/crueber/loom/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestFindOGImageURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    string
+		want    string
+		wantErr bool
+	}{
+		{name: "property first", page: `<meta property="og:image" content="https://cdn.example.com/a.png">`, want: "https://cdn.example.com/a.png"},
+		{name: "content first", page: `<META content='/img/b.jpg' property='og:image' />`, want: "https://example.com/img/b.jpg"},
+		{name: "escaped and relative", page: `<meta name="og:image:url" content="c.png?w=1&amp;h=2">`, want: "https://example.com/posts/c.png?w=1&h=2"},
+		{name: "missing", page: `<meta property="og:title" content="Title">`, wantErr: true},
+		{name: "non-http scheme", page: `<meta property="og:image" content="javascript:alert(1)">`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := findOGImageURL([]byte(tt.page), "https://example.com/posts/1")
+		if tt.wantErr {
+			if err == nil {
+				t.Fatalf("%s: url = %q, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Fatalf("%s: url = %q (err %v), want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestHandleCreateItem_CapturesPreviewImageWhenEnabled(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	store, err := favicon.NewStoreAt(t.TempDir(), StoredPreviewPath)
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	itemsAPI.SetPreviewStore(store)
+
+	var pageFetches int
+	originalPageFetcher, originalDownloader := bookmarkPageFetcher, previewImageDownloader
+	bookmarkPageFetcher = func(rawURL string) ([]byte, error) {
+		pageFetches++
+		return []byte(`<title>Fetched</title><meta property="og:image" content="/preview.png">`), nil
+	}
+	var gotImageURL string
+	var gotMaxBytes int
+	previewImageDownloader = func(imageURL string, maxBytes int) ([]byte, string, error) {
+		gotImageURL, gotMaxBytes = imageURL, maxBytes
+		return []byte("png bytes"), "image/png", nil
+	}
+	defer func() {
+		bookmarkPageFetcher, previewImageDownloader = originalPageFetcher, originalDownloader
+	}()
+
+	create := func(fields map[string]any) models.Item {
+		t.Helper()
+		fields["list_id"], fields["type"], fields["url"], fields["icon_source"] = listID, "bookmark", "https://example.com/post", "loom"
+		rec := performCreateItemRequest(t, itemsAPI, userID, fields)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+		var item models.Item
+		if err := json.Unmarshal(rec.Body.Bytes(), &item); err != nil {
+			t.Fatalf("unmarshal created item: %v", err)
+		}
+		itemsAPI.WaitForPreviews()
+		return item
+	}
+	storedPreview := func(itemID int) *string {
+		t.Helper()
+		stored, err := itemsAPI.db.GetItem(itemID)
+		if err != nil {
+			t.Fatalf("get item: %v", err)
+		}
+		return stored.PreviewImageURL
+	}
+
+	if item := create(map[string]any{"title": "Example"}); storedPreview(item.ID) != nil || pageFetches != 0 {
+		t.Fatalf("preview = %v after %d page fetches with capture disabled, want none", storedPreview(item.ID), pageFetches)
+	}
+
+	itemsAPI.SetPreviewImages(1024)
+
+	// The page fetched for the auto title is reused for the preview
+	item := create(map[string]any{"title": "", "auto_title": true})
+	if *item.Title != "Fetched" || pageFetches != 1 {
+		t.Fatalf("title = %q after %d page fetches, want the page title from one fetch", *item.Title, pageFetches)
+	}
+	if item.PreviewImageURL != nil {
+		t.Fatalf("response preview = %q, want it captured in the background", *item.PreviewImageURL)
+	}
+	preview := storedPreview(item.ID)
+	if preview == nil || !store.OwnsURL(*preview) || gotImageURL != "https://example.com/preview.png" || gotMaxBytes != 1024 {
+		t.Fatalf("preview = %v from %q (max bytes %d), want a stored image capped at 1024", preview, gotImageURL, gotMaxBytes)
+	}
+	imageBytes, contentType, err := store.Get(strings.TrimPrefix(*preview, StoredPreviewPath))
+	if err != nil || string(imageBytes) != "png bytes" || contentType != "image/png" {
+		t.Fatalf("stored image = %q %q (err %v), want the downloaded image", imageBytes, contentType, err)
+	}
+
+	// With an explicit title the capture fetches the page itself
+	item = create(map[string]any{"title": "Mine"})
+	if pageFetches != 2 || storedPreview(item.ID) == nil {
+		t.Fatalf("preview = %v after %d page fetches, want one more fetch", storedPreview(item.ID), pageFetches)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, *preview, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("hash", strings.TrimPrefix(*preview, StoredPreviewPath))
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	itemsAPI.HandleGetPreviewImage(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "png bytes" || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("GET preview = %d %q, want the stored image", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/crueber/loom/internal/ratelimit"
+)
+
+// DailyQuota caps how many times each user may do something per day. A nil Quota is
+// unlimited, and users IsAdmin reports as admins are exempt.
+type DailyQuota struct {
+	Quota   *ratelimit.Quota
+	IsAdmin func(userID int) (bool, error)
+}
+
+// take counts one use by userID and reports whether it may go ahead. When w is set the
+// uses left today are reported in header, and a refusal also sets Retry-After to the
+// reset. Admins and unlimited quotas are never counted.
+func (q DailyQuota) take(w http.ResponseWriter, header string, userID int) (bool, error) {
+	if q.Quota == nil {
+		return true, nil
+	}
+	if q.IsAdmin != nil {
+		isAdmin, err := q.IsAdmin(userID)
+		if err != nil {
+			return false, err
+		}
+		if isAdmin {
+			return true, nil
+		}
+	}
+
+	allowed, remaining, reset := q.Quota.Take("user:" + strconv.Itoa(userID))
+	if w != nil {
+		w.Header().Set(header, strconv.Itoa(remaining))
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(reset).Seconds()))))
+		}
+	}
+	return allowed, nil
+}
+
+// Middleware counts the authenticated requests for which counts returns true. Counted
+// responses carry X-Quota-Remaining, and once the quota is used up the caller gets 429
+// with Retry-After set to the next reset. It must run after AuthMiddleware.
+func (q DailyQuota) Middleware(counts func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := getUserID(r.Context())
+			if !ok || !counts(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed, err := q.take(w, "X-Quota-Remaining", userID)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "Database error")
+				return
+			}
+			if !allowed {
+				respondError(w, http.StatusTooManyRequests, "Daily quota exceeded")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allowFetch counts one outbound fetch (a page, favicon or preview image) made for
+// userID and reports whether it may go ahead. The fetches left today are reported in
+// X-Fetch-Quota-Remaining; w is nil for fetches made after the response.
+func (q DailyQuota) allowFetch(w http.ResponseWriter, userID int) bool {
+	allowed, err := q.take(w, "X-Fetch-Quota-Remaining", userID)
+	if err != nil {
+		// Failing to check for an admin shouldn't leave everyone unlimited
+		log.Printf("Failed to check fetch quota for user %d: %v", userID, err)
+		allowed, _ = DailyQuota{Quota: q.Quota}.take(w, "X-Fetch-Quota-Remaining", userID)
+	}
+	return allowed
+}
+
+// fetchesIcon reports whether resolving an icon from iconSource contacts another server;
+// the built-in loom icon is served locally and never counts toward the fetch quota
+func fetchesIcon(iconSource string) bool {
+	return iconSource != "loom"
+}
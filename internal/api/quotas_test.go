@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crueber/loom/internal/models"
+	"github.com/crueber/loom/internal/ratelimit"
+)
+
+func TestDailyQuotaMiddleware(t *testing.T) {
+	quota := DailyQuota{
+		Quota:   ratelimit.NewQuota(1),
+		IsAdmin: func(userID int) (bool, error) { return userID == 2, nil },
+	}
+	handler := quota.Middleware(func(r *http.Request) bool { return r.Method == http.MethodPost })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }),
+	)
+	perform := func(method string, userID int) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/api/lists", nil)
+		req = req.WithContext(setUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := perform(http.MethodPost, 1); rec.Code != http.StatusNoContent || rec.Header().Get("X-Quota-Remaining") != "0" {
+		t.Fatalf("first write = %d (remaining %q), want it allowed with none left", rec.Code, rec.Header().Get("X-Quota-Remaining"))
+	}
+
+	rec := perform(http.MethodPost, 1)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("second write = %d (Retry-After %q), want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	var body ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" {
+		t.Fatalf("429 body = %q, want a JSON error", rec.Body.String())
+	}
+
+	if rec := perform(http.MethodGet, 1); rec.Code != http.StatusNoContent {
+		t.Fatalf("uncounted read = %d, want it allowed", rec.Code)
+	}
+	for range 3 {
+		if rec := perform(http.MethodPost, 2); rec.Code != http.StatusNoContent {
+			t.Fatalf("admin write = %d, want admins exempt", rec.Code)
+		}
+	}
+}
+
+func TestHandleCreateItem_CountsFetchesAgainstQuota(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+	itemsAPI.faviconFetcher.SetDisabled(true)
+
+	// The title and favicon of the first bookmark use up the quota
+	itemsAPI.SetFetchQuota(DailyQuota{Quota: ratelimit.NewQuota(2)})
+	titleFetches := 0
+	originalFetcher := bookmarkTitleFetcher
+	bookmarkTitleFetcher = func(rawURL string) (string, error) {
+		titleFetches++
+		return "Fetched Title", nil
+	}
+	defer func() {
+		bookmarkTitleFetcher = originalFetcher
+	}()
+
+	create := func() (models.Item, string) {
+		t.Helper()
+		rec := performCreateItemRequest(t, itemsAPI, userID, map[string]any{
+			"list_id": listID,
+			"type":    "bookmark",
+			"url":     "https://example.com/page",
+		})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+		var item models.Item
+		if err := json.Unmarshal(rec.Body.Bytes(), &item); err != nil {
+			t.Fatalf("unmarshal item: %v", err)
+		}
+		return item, rec.Header().Get("X-Fetch-Quota-Remaining")
+	}
+
+	item, remaining := create()
+	if *item.Title != "Fetched Title" || remaining != "0" {
+		t.Fatalf("first bookmark = %q (remaining %q), want the fetched title and no fetches left", *item.Title, remaining)
+	}
+
+	// Over quota the bookmark is still saved, just without fetching anything
+	item, _ = create()
+	if titleFetches != 1 || *item.Title != "example.com" {
+		t.Fatalf("second bookmark title = %q after %d title fetches, want the fallback and no new fetch", *item.Title, titleFetches)
+	}
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/crueber/loom/internal/db"
+	"github.com/crueber/loom/internal/models"
+	"github.com/crueber/loom/internal/sanitize"
+	"github.com/crueber/loom/internal/urlutil"
+	"github.com/go-chi/chi/v5"
+)
+
+// ReadLaterRequest represents a request to add a URL to the read-later queue
+type ReadLaterRequest struct {
+	URL   string  `json:"url"`
+	Title *string `json:"title,omitempty"` // Fetched from the page when blank
+}
+
+// HandleGetReadLater returns the user's read-later queue, oldest first
+func (api *ItemsAPI) HandleGetReadLater(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	list, err := api.db.GetReadLaterList(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get read-later list")
+		return
+	}
+
+	items, err := api.db.GetReadLaterItems(list.ID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get items")
+		return
+	}
+
+	if items == nil {
+		items = []*models.Item{}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"list":  list,
+		"items": items,
+	})
+}
+
+// HandleAddReadLater appends a bookmark to the end of the user's read-later queue
+func (api *ItemsAPI) HandleAddReadLater(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	var req ReadLaterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	req.URL = strings.TrimSpace(req.URL)
+	if req.URL == "" {
+		respondError(w, http.StatusBadRequest, "URL is required")
+		return
+	}
+	if !isValidURL(req.URL) {
+		respondError(w, http.StatusBadRequest, "Invalid URL")
+		return
+	}
+
+	if !api.itemLimits.checkItemLimit(w, api.db, userID, db.ItemTypeBookmark, 1) {
+		return
+	}
+
+	var title string
+	var page []byte // the page when fetched for its title
+	if req.Title != nil {
+		title = strings.TrimSpace(sanitizeText(*req.Title))
+	}
+	if utf8.RuneCountInString(title) > bookmarkTitleMaxLength {
+		respondError(w, http.StatusBadRequest, "Title must be less than 200 characters")
+		return
+	}
+	if title == "" {
+		if api.fetchQuota.allowFetch(w, userID) {
+			title, page = api.autoTitle(req.URL)
+		} else {
+			title = fallbackBookmarkTitle(req.URL)
+		}
+	}
+
+	list, err := api.db.GetReadLaterList(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get read-later list")
+		return
+	}
+
+	var faviconURL *string
+	if api.fetchQuota.allowFetch(w, userID) {
+		domain, _ := urlutil.Domain(req.URL)
+		faviconURL, _ = api.faviconFetcher.FetchIcon("auto", nil, domain)
+	}
+
+	position, err := api.db.GetNextItemPosition(list.ID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get next position")
+		return
+	}
+
+	item, err := api.db.CreateItem(list.ID, db.ItemTypeBookmark, &title, &req.URL, nil, faviconURL, "auto", nil, sanitize.DefaultFormat, position, true)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to create item")
+		return
+	}
+	api.capturePreviewImage(userID, item, page)
+
+	respondJSON(w, http.StatusCreated, item)
+}
+
+// HandleReadLaterDone removes an item from the user's read-later queue
+func (api *ItemsAPI) HandleReadLaterDone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	itemID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid item ID")
+		return
+	}
+
+	list, err := api.db.GetReadLaterList(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get read-later list")
+		return
+	}
+
+	// Only items in the queue can be marked done
+	item, err := api.db.GetItem(itemID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if item == nil || item.ListID != list.ID {
+		respondError(w, http.StatusNotFound, "Item not found")
+		return
+	}
+
+	if err := api.db.DeleteItem(itemID); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to delete item")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
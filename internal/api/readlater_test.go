@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/crueber/loom/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestReadLaterQueue(t *testing.T) {
+	itemsAPI, _, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+	itemsAPI.faviconFetcher.SetDisabled(true)
+
+	originalFetcher := bookmarkTitleFetcher
+	bookmarkTitleFetcher = func(rawURL string) (string, error) {
+		return "Fetched " + rawURL, nil
+	}
+	defer func() {
+		bookmarkTitleFetcher = originalFetcher
+	}()
+
+	add := func(payload map[string]any) models.Item {
+		t.Helper()
+		body, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("marshal request body: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/readlater", bytes.NewReader(body))
+		req = req.WithContext(setUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		itemsAPI.HandleAddReadLater(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+		var item models.Item
+		if err := json.Unmarshal(rec.Body.Bytes(), &item); err != nil {
+			t.Fatalf("unmarshal created item: %v", err)
+		}
+		return item
+	}
+	queue := func() []int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/readlater", nil)
+		req = req.WithContext(setUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		itemsAPI.HandleGetReadLater(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var resp struct {
+			Items []models.Item `json:"items"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal queue: %v", err)
+		}
+		var ids []int
+		for _, item := range resp.Items {
+			ids = append(ids, item.ID)
+		}
+		return ids
+	}
+	done := func(itemID int) int {
+		t.Helper()
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("id", strconv.Itoa(itemID))
+		ctx := context.WithValue(setUserID(context.Background(), userID), chi.RouteCtxKey, routeCtx)
+		req := httptest.NewRequest(http.MethodPost, "/api/readlater/"+strconv.Itoa(itemID)+"/done", nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		itemsAPI.HandleReadLaterDone(rec, req)
+		return rec.Code
+	}
+
+	first := add(map[string]any{"url": "https://example.com/one"})
+	if first.Title == nil || *first.Title != "Fetched https://example.com/one" {
+		t.Fatalf("title = %v, want auto-fetched title", first.Title)
+	}
+	second := add(map[string]any{"url": "https://example.com/two", "title": "Two"})
+
+	if got := queue(); len(got) != 2 || got[0] != first.ID || got[1] != second.ID {
+		t.Fatalf("queue = %v, want [%d %d]", got, first.ID, second.ID)
+	}
+
+	// Both items land in the same reserved list
+	if first.ListID != second.ListID {
+		t.Fatalf("list IDs = %d and %d, want the same read-later list", first.ListID, second.ListID)
+	}
+
+	if code := done(first.ID); code != http.StatusNoContent {
+		t.Fatalf("done status = %d, want %d", code, http.StatusNoContent)
+	}
+	if got := queue(); len(got) != 1 || got[0] != second.ID {
+		t.Fatalf("queue after done = %v, want [%d]", got, second.ID)
+	}
+
+	// Items outside the queue cannot be marked done
+	if code := done(first.ID); code != http.StatusNotFound {
+		t.Fatalf("done on removed item status = %d, want %d", code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/crueber/loom/internal/models"
+)
+
+// maxSearchQueryLength caps the characters in a search query
+const maxSearchQueryLength = 200
+
+// HandleSearch finds the user's items whose title, URL or content contains ?q=,
+// optionally only on ?board_id=, annotated with the list and board each lives in.
+// At most db.SearchLimit items are returned, newest first.
+func (api *ItemsAPI) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		respondError(w, http.StatusBadRequest, "q query parameter is required")
+		return
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryLength {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Search query must be %d characters or less", maxSearchQueryLength))
+		return
+	}
+
+	var boardID *int
+	if raw := r.URL.Query().Get("board_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid board ID")
+			return
+		}
+		boardID = &id
+	}
+
+	items, err := api.db.SearchItems(userID, query, boardID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to search items")
+		return
+	}
+
+	if items == nil {
+		items = []*models.ItemWithBoard{}
+	}
+
+	respondJSON(w, http.StatusOK, items)
+}
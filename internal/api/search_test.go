@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/crueber/loom/internal/models"
+)
+
+func TestHandleSearch(t *testing.T) {
+	itemsAPI, listID, userID, cleanup := newItemsAPITestFixture(t)
+	defer cleanup()
+
+	list, err := itemsAPI.db.GetList(listID, userID)
+	if err != nil {
+		t.Fatalf("get list: %v", err)
+	}
+	otherBoard, err := itemsAPI.db.CreateBoard(userID, "Work", false)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	workList, err := itemsAPI.db.CreateList(userID, otherBoard.ID, "Tools", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+
+	create := func(listID int, itemType string, title, link, content *string) {
+		t.Helper()
+		if _, err := itemsAPI.db.CreateItem(listID, itemType, title, link, content, nil, "auto", nil, "markdown", 0, true); err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+	}
+	str := func(s string) *string { return &s }
+	create(listID, "bookmark", str("Go Documentation"), str("https://go.dev/doc"), nil)
+	create(listID, "note", nil, nil, str("Remember to read the golang spec"))
+	create(workList.ID, "bookmark", str("Issue tracker"), str("https://example.com/golang/issues"), nil)
+	create(listID, "bookmark", str("100% coverage"), str("https://example.com/coverage"), nil)
+
+	// Another user's matching item must never show up
+	other, err := itemsAPI.db.CreateUser("other", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	otherDefault, err := itemsAPI.db.CreateBoard(other.ID, "Other", true)
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	otherList, err := itemsAPI.db.CreateList(other.ID, otherDefault.ID, "Private", "#ffffff", 0, false)
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	create(otherList.ID, "bookmark", str("Golang secrets"), str("https://secret.example.com"), nil)
+
+	search := func(params url.Values) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/search?"+params.Encode(), nil)
+		req = req.WithContext(setUserID(req.Context(), userID))
+		rec := httptest.NewRecorder()
+		itemsAPI.HandleSearch(rec, req)
+		return rec
+	}
+	results := func(params url.Values) []models.ItemWithBoard {
+		t.Helper()
+		rec := search(params)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%v: status = %d, want %d, body=%s", params, rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var items []models.ItemWithBoard
+		if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+			t.Fatalf("unmarshal results: %v", err)
+		}
+		return items
+	}
+
+	items := results(url.Values{"q": {"GOLANG"}})
+	if len(items) != 2 {
+		t.Fatalf("len(results) = %d, want the note and the work bookmark", len(items))
+	}
+	for _, item := range items {
+		if item.ListID == workList.ID && (item.BoardID != otherBoard.ID || item.BoardTitle != "Work" || item.ListTitle != "Tools") {
+			t.Fatalf("result = %+v, want it annotated with its board and list", item)
+		}
+	}
+
+	items = results(url.Values{"q": {"golang"}, "board_id": {strconv.Itoa(list.BoardID)}})
+	if len(items) != 1 || items[0].Type != "note" {
+		t.Fatalf("board results = %+v, want only the note", items)
+	}
+
+	if items := results(url.Values{"q": {"%"}}); len(items) != 1 || *items[0].Title != "100% coverage" {
+		t.Fatalf("wildcard results = %+v, want only the literal match", items)
+	}
+
+	rec := search(url.Values{"q": {"nothing matches this"}})
+	if rec.Code != http.StatusOK || rec.Body.String() != "[]\n" {
+		t.Fatalf("empty search = %d %q, want an empty array", rec.Code, rec.Body.String())
+	}
+
+	for _, params := range []url.Values{{"q": {"  "}}, {"q": {"go"}, "board_id": {"abc"}}} {
+		if rec := search(params); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%v: status = %d, want %d", params, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/crueber/loom/internal/models"
+	"github.com/crueber/loom/internal/sanitize"
+)
+
+// defaultNewUserTemplate is the welcome content seeded when no template path is configured
+//
+//go:embed templates/new_user.json
+var defaultNewUserTemplate []byte
+
+// LoadNewUserTemplate reads the loom-format export used to seed new users' boards from
+// path, or the built-in welcome template when path is empty. A missing file returns a
+// nil template so seeding is skipped; an invalid one is an error.
+func LoadNewUserTemplate(path string) (*models.ExportData, error) {
+	raw := defaultNewUserTemplate
+	if path != "" {
+		var err error
+		raw, err = os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Printf("New user template %s not found; new users will not be seeded", path)
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read new user template: %w", err)
+		}
+	}
+
+	var template models.ExportData
+	if err := json.Unmarshal(raw, &template); err != nil {
+		return nil, fmt.Errorf("failed to parse new user template: %w", err)
+	}
+	if template.Version != 1 {
+		return nil, fmt.Errorf("unsupported new user template version %d", template.Version)
+	}
+	if errs := validateImportEntries(template); len(errs) > 0 {
+		return nil, fmt.Errorf("new user template has %d invalid entries, first: %s", len(errs), errs[0].problem())
+	}
+
+	// Sanitize once here so seeding can write the content as-is
+	for i := range template.Lists {
+		for j := range template.Lists[i].Items {
+			item := &template.Lists[i].Items[j]
+			if !sanitize.IsValidFormat(item.ContentFormat) {
+				item.ContentFormat = sanitize.DefaultFormat
+			}
+			if item.Content != nil {
+				content := sanitize.Content(item.ContentFormat, *item.Content)
+				item.Content = &content
+			}
+		}
+	}
+
+	return &template, nil
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crueber/loom/internal/oauth"
+)
+
+func TestLoadNewUserTemplate(t *testing.T) {
+	template, err := LoadNewUserTemplate("")
+	if err != nil || template == nil || len(template.Lists) == 0 {
+		t.Fatalf("built-in template = %v, %v, want a valid template with lists", template, err)
+	}
+
+	if template, err := LoadNewUserTemplate(filepath.Join(t.TempDir(), "missing.json")); err != nil || template != nil {
+		t.Fatalf("missing template = %v, %v, want nil without an error", template, err)
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"version":1,"lists":[{"title":"","items":[]}]}`), 0o600); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+	if _, err := LoadNewUserTemplate(invalid); err == nil {
+		t.Fatalf("invalid template loaded without an error")
+	}
+}
+
+func TestProvisionUser_SeedsNewUserBoard(t *testing.T) {
+	database := newBoardsTestDB(t)
+	authAPI := NewAuthAPI(database, nil, nil, false, false, true, "", nil)
+
+	template, err := LoadNewUserTemplate("")
+	if err != nil {
+		t.Fatalf("load template: %v", err)
+	}
+	authAPI.SetNewUserTemplate(template)
+
+	user, err := authAPI.provisionUser(&oauth.UserInfo{Email: "new@example.com", Sub: "new"})
+	if err != nil {
+		t.Fatalf("provisionUser() error = %v", err)
+	}
+
+	lists, err := database.GetLists(user.ID)
+	if err != nil {
+		t.Fatalf("get lists: %v", err)
+	}
+	if len(lists) != len(template.Lists) || lists[0].Title != template.Lists[0].Title {
+		t.Fatalf("lists = %+v, want the template's %d lists", lists, len(template.Lists))
+	}
+	items, err := database.GetItems(lists[0].ID)
+	if err != nil {
+		t.Fatalf("get items: %v", err)
+	}
+	if len(items) != len(template.Lists[0].Items) {
+		t.Fatalf("items = %d, want %d", len(items), len(template.Lists[0].Items))
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// sessionIDPrefixLength is how much of a session ID the sessions API reveals and accepts
+const sessionIDPrefixLength = 12
+
+// SessionResponse describes one of the user's sessions; ID is a prefix of the real session ID
+type SessionResponse struct {
+	ID         string     `json:"id"`
+	UserAgent  *string    `json:"user_agent,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastActive *time.Time `json:"last_active,omitempty"`
+	Current    bool       `json:"current"` // The session making this request
+}
+
+// HandleGetSessions lists the user's active sessions, most recently active first
+func (a *AuthAPI) HandleGetSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessions, err := a.db.GetUserSessions(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get sessions")
+		return
+	}
+
+	currentID := a.sessionManager.SessionID(r)
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			ID:         session.ID[:min(len(session.ID), sessionIDPrefixLength)],
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			ExpiresAt:  session.ExpiresAt,
+			LastActive: session.LastActive,
+			Current:    session.ID == currentID,
+		})
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// HandleRevokeSession signs out one of the user's sessions, identified by the ID prefix
+// HandleGetSessions returns. Revoking the current session also clears its cookie.
+func (a *AuthAPI) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	prefix := chi.URLParam(r, "id")
+	if len(prefix) != sessionIDPrefixLength {
+		respondError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	sessions, err := a.db.GetUserSessions(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get sessions")
+		return
+	}
+
+	var sessionID string
+	for _, session := range sessions {
+		if strings.HasPrefix(session.ID, prefix) {
+			sessionID = session.ID
+			break
+		}
+	}
+	if sessionID == "" {
+		respondError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+
+	if err := a.db.DeleteSession(sessionID); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to revoke session")
+		return
+	}
+	if sessionID == a.sessionManager.SessionID(r) {
+		if err := a.sessionManager.DestroySession(w, r); err != nil {
+			a.logger.Error("failed to clear revoked session cookie", "error", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
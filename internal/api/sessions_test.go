@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crueber/loom/internal/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestSessionsListAndRevoke(t *testing.T) {
+	database := newBoardsTestDB(t)
+	key := []byte("0123456789abcdef0123456789abcdef")
+	sessionManager := auth.NewSessionManager(key, key, 3600, false, nil)
+	sessionManager.SetStore(database)
+	authAPI := NewAuthAPI(database, sessionManager, nil, false, false, false, "", nil)
+
+	user, err := database.CreateUser("owner", "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// login returns the cookies of a new session created from the given browser
+	login := func(userAgent string) []*http.Cookie {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/login", nil)
+		req.Header.Set("User-Agent", userAgent)
+		rec := httptest.NewRecorder()
+		if err := sessionManager.CreateSession(rec, req, user.ID); err != nil {
+			t.Fatalf("create session: %v", err)
+		}
+		return rec.Result().Cookies()
+	}
+	laptop, phone := login("laptop"), login("phone")
+
+	// call runs handler behind AuthMiddleware with the session's cookies
+	call := func(handler http.HandlerFunc, method, sessionPrefix string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/api/user/sessions", nil)
+		if sessionPrefix != "" {
+			routeCtx := chi.NewRouteContext()
+			routeCtx.URLParams.Add("id", sessionPrefix)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+		}
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		authAPI.AuthMiddleware(handler).ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := call(authAPI.HandleGetSessions, http.MethodGet, "", laptop)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list status = %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var sessions []SessionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &sessions); err != nil {
+		t.Fatalf("unmarshal sessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("len(sessions) = %d, want 2", len(sessions))
+	}
+	var phoneID string
+	for _, session := range sessions {
+		if len(session.ID) != sessionIDPrefixLength || session.UserAgent == nil {
+			t.Fatalf("session = %+v, want a %d-character ID prefix and a user agent", session, sessionIDPrefixLength)
+		}
+		if session.Current != (*session.UserAgent == "laptop") {
+			t.Fatalf("session %s current = %v, want only the laptop session current", *session.UserAgent, session.Current)
+		}
+		if *session.UserAgent == "phone" {
+			phoneID = session.ID
+		}
+	}
+
+	if rec := call(authAPI.HandleRevokeSession, http.MethodDelete, "000000000000", laptop); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown session status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := call(authAPI.HandleRevokeSession, http.MethodDelete, phoneID, laptop); rec.Code != http.StatusNoContent {
+		t.Fatalf("revoke status = %d, want %d, body=%s", rec.Code, http.StatusNoContent, rec.Body.String())
+	}
+
+	if rec := call(authAPI.HandleGetSessions, http.MethodGet, "", phone); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked session status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := call(authAPI.HandleGetSessions, http.MethodGet, "", laptop); rec.Code != http.StatusOK {
+		t.Fatalf("remaining session status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/crueber/loom/internal/db"
+	"github.com/crueber/loom/internal/favicon"
+	"github.com/crueber/loom/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// startPageTemplate renders a board as a standalone page for use as a browser
+// homepage. It has no scripts and no external resources, so it works offline.
+var startPageTemplate = template.Must(template.New("startpage").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0; padding: 1.5rem; background: #f5f5f5; color: #222; }
+h1 { font-size: 1.5rem; margin: 0 0 1rem; }
+main { display: grid; grid-template-columns: repeat(auto-fill, minmax(16rem, 1fr)); gap: 1rem; align-items: start; }
+section { background: #fff; border-radius: 8px; border-top: 4px solid; box-shadow: 0 2px 8px rgba(0,0,0,0.1); padding: 0.75rem 1rem; }
+h2 { font-size: 1rem; margin: 0 0 0.5rem; }
+ul { list-style: none; margin: 0; padding: 0; }
+li { margin: 0.35rem 0; }
+a { display: flex; align-items: center; gap: 0.5rem; color: inherit; text-decoration: none; }
+a:hover { text-decoration: underline; }
+img { width: 16px; height: 16px; flex: none; }
+.note { white-space: pre-wrap; font-size: 0.9rem; color: #555; }
+.separator { border-top: 1px solid #ddd; padding-top: 0.25rem; font-size: 0.8rem; color: #888; }
+@media (prefers-color-scheme: dark) {
+body { background: #1a1a1a; color: #eee; }
+section { background: #262626; box-shadow: none; }
+.note { color: #bbb; }
+.separator { border-color: #444; }
+}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<main>
+{{- range .Lists}}
+<section style="border-top-color: {{.Color}}">
+<h2>{{.Title}}</h2>
+<ul>
+{{- range .Items}}
+{{- if eq .Type "bookmark"}}
+<li><a href="{{.URL}}">{{if .Icon}}<img src="{{.Icon}}" alt="">{{end}}{{.Title}}</a></li>
+{{- else if eq .Type "note"}}
+<li class="note">{{.Content}}</li>
+{{- else}}
+<li class="separator">{{.Title}}</li>
+{{- end}}
+{{- end}}
+</ul>
+</section>
+{{- end}}
+</main>
+</body>
+</html>
+`))
+
+// startPageList is a list as rendered on the start page
+type startPageList struct {
+	Title string
+	Color string
+	Items []startPageItem
+}
+
+// startPageItem is an item as rendered on the start page
+type startPageItem struct {
+	Type    string
+	Title   string
+	URL     string
+	Content string
+	Icon    template.URL // Embedded favicon data URI or stored icon URL, empty when there is none
+}
+
+// GetBoardStartPage renders one of the user's boards as a self-contained HTML page
+// with its lists and bookmarks, for saving and opening as a browser start page.
+// Icons kept in faviconStore (which may be nil) are embedded as data URIs; any that
+// can't be are linked on this server under basePath ("" when served at the root).
+func GetBoardStartPage(database *db.DB, faviconStore *favicon.Store, basePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := getUserID(r.Context())
+		if !ok {
+			respondError(w, http.StatusUnauthorized, "Not authenticated")
+			return
+		}
+
+		boardID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid board ID")
+			return
+		}
+
+		board, err := database.GetBoardByIDContext(r.Context(), boardID, userID)
+		if err != nil {
+			respondQueryError(w, err, "Failed to get board")
+			return
+		}
+		if board == nil {
+			respondError(w, http.StatusNotFound, "Board not found")
+			return
+		}
+
+		lists, err := database.GetListsByBoardContext(r.Context(), userID, boardID)
+		if err != nil {
+			respondQueryError(w, err, "Failed to get lists")
+			return
+		}
+
+		var items []*models.Item
+		if len(lists) > 0 {
+			items, err = database.GetItemsByBoardContext(r.Context(), userID, boardID)
+			if err != nil {
+				respondQueryError(w, err, "Failed to get items")
+				return
+			}
+		}
+		itemsByList := groupItemsByList(items)
+
+		page := struct {
+			Title string
+			Lists []startPageList
+		}{Title: board.Title}
+		for _, list := range lists {
+			pageList := startPageList{Title: list.Title, Color: list.Color}
+			for _, item := range itemsByList[list.ID] {
+				pageList.Items = append(pageList.Items, newStartPageItem(item, faviconStore, basePath))
+			}
+			page.Lists = append(page.Lists, pageList)
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", "loom-"+board.Title+".html"))
+		if err := startPageTemplate.Execute(w, page); err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to render start page")
+		}
+	}
+}
+
+// newStartPageItem converts an item for rendering. Only embedded image data URIs
+// are kept as icons since the page must work offline, so stored icons are inlined.
+// A stored icon that can't be inlined is linked instead, prefixed with basePath.
+func newStartPageItem(item *models.Item, faviconStore *favicon.Store, basePath string) startPageItem {
+	pageItem := startPageItem{Type: item.Type}
+	if item.Title != nil {
+		pageItem.Title = *item.Title
+	}
+	if item.URL != nil {
+		pageItem.URL = *item.URL
+	}
+	if item.Content != nil {
+		pageItem.Content = *item.Content
+	}
+	if pageItem.Type == db.ItemTypeBookmark && pageItem.Title == "" {
+		pageItem.Title = pageItem.URL
+	}
+	// data: URIs are rejected by html/template unless marked safe
+	if icon := inlineStoredImage(faviconStore, item.FaviconURL); icon != nil {
+		switch {
+		case strings.HasPrefix(*icon, "data:image/"):
+			pageItem.Icon = template.URL(*icon)
+		case favicon.IsStoredIconURL(*icon):
+			pageItem.Icon = template.URL(basePath + *icon)
+		}
+	}
+	return pageItem
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -10,26 +11,50 @@ import (
 )
 
 const (
-	sessionName = "loom-session"
-	sessionKey  = "user_id"
+	sessionName    = "loom-session"
+	sessionKey     = "user_id"
+	lastActiveKey  = "last_active"
+	createdAtKey   = "created_at"
+	sessionIDKey   = "session_id"
+	maxTouchPeriod = time.Minute
 )
 
+// SessionStore keeps a server-side record of each session so it can be listed and revoked
+type SessionStore interface {
+	CreateSession(id string, userID int, userAgent string, expiresAt time.Time) error
+	SessionExists(id string, userID int) (bool, error)
+	TouchSession(id string) error
+	DeleteSession(id string) error
+}
+
 // SessionManager handles user sessions
 type SessionManager struct {
 	store        *sessions.CookieStore
 	maxAge       int
 	secureCookie bool
+	logger       *slog.Logger
+
+	// idleTimeout expires sessions that have not been used for this long (0 disables it)
+	idleTimeout time.Duration
+
+	// records keeps sessions server-side when set; cookies without a record are rejected
+	records SessionStore
 }
 
 // NewSessionManager creates a new session manager
-func NewSessionManager(authKey, encryptionKey []byte, maxAge int, secureCookie bool) *SessionManager {
+// A nil logger falls back to slog.Default()
+func NewSessionManager(authKey, encryptionKey []byte, maxAge int, secureCookie bool, logger *slog.Logger) *SessionManager {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
 	store := sessions.NewCookieStore(authKey, encryptionKey)
 
 	store.Options = &sessions.Options{
 		Path:     "/",
 		MaxAge:   maxAge,
 		HttpOnly: true,
-		Secure:   secureCookie, // Set to true in production with HTTPS
+		Secure:   secureCookie,         // Set to true in production with HTTPS
 		SameSite: http.SameSiteLaxMode, // Lax allows cookies on OAuth redirects
 	}
 
@@ -37,31 +62,75 @@ func NewSessionManager(authKey, encryptionKey []byte, maxAge int, secureCookie b
 		store:        store,
 		maxAge:       maxAge,
 		secureCookie: secureCookie,
+		logger:       logger,
 	}
 }
 
+// SetIdleTimeout makes sessions expire after the given period without an authenticated
+// request, in addition to the absolute max age. Zero, the default, disables it.
+func (sm *SessionManager) SetIdleTimeout(timeout time.Duration) {
+	sm.idleTimeout = max(timeout, 0)
+}
+
+// SetCookiePath limits the session cookie to path, for serving the app under a
+// subpath behind a reverse proxy. The default is "/".
+func (sm *SessionManager) SetCookiePath(path string) {
+	sm.store.Options.Path = path
+}
+
+// SetStore records every new session in store and only accepts sessions it still holds,
+// so deleting a record revokes that session. Sessions created before a store was set
+// carry no ID and must log in again.
+func (sm *SessionManager) SetStore(store SessionStore) {
+	sm.records = store
+}
+
 // CreateSession creates a new session for the user
 func (sm *SessionManager) CreateSession(w http.ResponseWriter, r *http.Request, userID int) error {
 	session, err := sm.store.Get(r, sessionName)
 	if err != nil {
-		println("DEBUG: Failed to get session:", err.Error())
+		sm.logger.Debug("existing session invalid, creating new one", "error", err)
 		// Create a new session if the existing one is invalid
 		session, err = sm.store.New(r, sessionName)
 		if err != nil {
-			println("DEBUG: Failed to create new session:", err.Error())
+			sm.logger.Error("failed to create new session", "error", err)
 			return err
 		}
-		println("DEBUG: Created new session successfully")
+	}
+
+	if sm.records != nil {
+		// Logging in again replaces the request's previous session record
+		if oldID, ok := session.Values[sessionIDKey].(string); ok {
+			if err := sm.records.DeleteSession(oldID); err != nil {
+				sm.logger.Error("failed to delete replaced session", "error", err)
+			}
+		}
+
+		id, err := GenerateSessionID()
+		if err != nil {
+			sm.logger.Error("failed to generate session id", "error", err)
+			return err
+		}
+		expiresAt := SessionExpiry(sm.maxAge)
+		if sm.maxAge <= 0 {
+			// Browser-session cookies have no fixed lifetime; keep their record for a year
+			expiresAt = time.Now().AddDate(1, 0, 0)
+		}
+		if err := sm.records.CreateSession(id, userID, r.UserAgent(), expiresAt); err != nil {
+			sm.logger.Error("failed to record session", "error", err)
+			return err
+		}
+		session.Values[sessionIDKey] = id
 	}
 
 	session.Values[sessionKey] = userID
-	println("DEBUG: About to save session for user ID:", userID)
-	err = session.Save(r, w)
-	if err != nil {
-		println("DEBUG: Failed to save session:", err.Error())
+	session.Values[createdAtKey] = time.Now().Unix()
+	session.Values[lastActiveKey] = time.Now().Unix()
+	if err := session.Save(r, w); err != nil {
+		sm.logger.Error("failed to save session", "error", err)
 		return err
 	}
-	println("DEBUG: Session saved successfully")
+	sm.logger.Debug("session saved", "user_id", userID)
 	return nil
 }
 
@@ -77,9 +146,86 @@ func (sm *SessionManager) GetUserID(r *http.Request) (int, bool) {
 		return 0, false
 	}
 
+	// Sessions created before idle tracking have no last_active; Touch adds it.
+	// Touch also re-issues the cookie, so the absolute max age is checked against
+	// the creation time rather than left to the cookie's expiry.
+	if sm.idleTimeout > 0 {
+		if createdAt, ok := session.Values[createdAtKey].(int64); ok && sm.maxAge > 0 && time.Since(time.Unix(createdAt, 0)) > time.Duration(sm.maxAge)*time.Second {
+			return 0, false
+		}
+		if lastActive, ok := session.Values[lastActiveKey].(int64); ok && time.Since(time.Unix(lastActive, 0)) > sm.idleTimeout {
+			return 0, false
+		}
+	}
+
+	// A session whose record is gone has been revoked or has expired
+	if sm.records != nil {
+		id, ok := session.Values[sessionIDKey].(string)
+		if !ok {
+			return 0, false
+		}
+		exists, err := sm.records.SessionExists(id, userID)
+		if err != nil {
+			sm.logger.Error("failed to look up session", "error", err)
+			return 0, false
+		}
+		if !exists {
+			return 0, false
+		}
+	}
+
 	return userID, true
 }
 
+// SessionID returns the ID of the request's recorded session, or "" if it has none
+func (sm *SessionManager) SessionID(r *http.Request) string {
+	session, err := sm.store.Get(r, sessionName)
+	if err != nil {
+		return ""
+	}
+	id, _ := session.Values[sessionIDKey].(string)
+	return id
+}
+
+// Touch records activity on the request's session so the idle timeout restarts
+// and the session store's last-active time moves on. The cookie is rewritten at
+// most once per touch period (a tenth of the idle timeout, capped at a minute)
+// rather than on every request.
+func (sm *SessionManager) Touch(w http.ResponseWriter, r *http.Request) {
+	if sm.idleTimeout <= 0 && sm.records == nil {
+		return
+	}
+
+	session, err := sm.store.Get(r, sessionName)
+	if err != nil {
+		return
+	}
+	if _, ok := session.Values[sessionKey].(int); !ok {
+		return
+	}
+
+	if sm.records != nil {
+		if id, ok := session.Values[sessionIDKey].(string); ok {
+			if err := sm.records.TouchSession(id); err != nil {
+				sm.logger.Error("failed to record session activity", "error", err)
+			}
+		}
+	}
+	if sm.idleTimeout <= 0 {
+		return
+	}
+
+	now := time.Now()
+	if lastActive, ok := session.Values[lastActiveKey].(int64); ok && now.Sub(time.Unix(lastActive, 0)) < min(sm.idleTimeout/10, maxTouchPeriod) {
+		return
+	}
+
+	session.Values[lastActiveKey] = now.Unix()
+	if err := session.Save(r, w); err != nil {
+		sm.logger.Error("failed to save session", "error", err)
+	}
+}
+
 // DestroySession destroys the user's session
 func (sm *SessionManager) DestroySession(w http.ResponseWriter, r *http.Request) error {
 	session, err := sm.store.Get(r, sessionName)
@@ -87,6 +233,12 @@ func (sm *SessionManager) DestroySession(w http.ResponseWriter, r *http.Request)
 		return nil // Session doesn't exist, nothing to destroy
 	}
 
+	if id, ok := session.Values[sessionIDKey].(string); ok && sm.records != nil {
+		if err := sm.records.DeleteSession(id); err != nil {
+			sm.logger.Error("failed to delete session record", "error", err)
+		}
+	}
+
 	session.Options.MaxAge = -1
 	return session.Save(r, w)
 }
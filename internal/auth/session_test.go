@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSessionIdleTimeout(t *testing.T) {
+	sm := NewSessionManager([]byte("0123456789abcdef0123456789abcdef"), []byte("0123456789abcdef0123456789abcdef"), 3600, false, nil)
+	sm.SetIdleTimeout(time.Hour)
+
+	// sessionRequest returns a request carrying a session for user 7 last active at lastActive
+	sessionRequest := func(lastActive time.Time) *http.Request {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		session, err := sm.store.New(req, sessionName)
+		if err != nil {
+			t.Fatalf("new session: %v", err)
+		}
+		session.Values[sessionKey] = 7
+		session.Values[lastActiveKey] = lastActive.Unix()
+		if err := session.Save(req, rec); err != nil {
+			t.Fatalf("save session: %v", err)
+		}
+
+		next := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, cookie := range rec.Result().Cookies() {
+			next.AddCookie(cookie)
+		}
+		return next
+	}
+
+	if _, ok := sm.GetUserID(sessionRequest(time.Now().Add(-time.Minute))); !ok {
+		t.Fatalf("GetUserID() ok = false for a recently active session, want true")
+	}
+	if _, ok := sm.GetUserID(sessionRequest(time.Now().Add(-2 * time.Hour))); ok {
+		t.Fatalf("GetUserID() ok = true for an idle session, want false")
+	}
+
+	// Touch refreshes a session that is older than the touch period
+	req := sessionRequest(time.Now().Add(-30 * time.Minute))
+	rec := httptest.NewRecorder()
+	sm.Touch(rec, req)
+	if len(rec.Result().Cookies()) == 0 {
+		t.Fatalf("Touch() did not rewrite the session cookie")
+	}
+
+	// and leaves a just-touched session alone
+	rec = httptest.NewRecorder()
+	sm.Touch(rec, sessionRequest(time.Now()))
+	if len(rec.Result().Cookies()) != 0 {
+		t.Fatalf("Touch() rewrote a session that was just active")
+	}
+}
+
+func TestSessionCookiePath(t *testing.T) {
+	sm := NewSessionManager([]byte("0123456789abcdef0123456789abcdef"), []byte("0123456789abcdef0123456789abcdef"), 3600, false, nil)
+	sm.SetCookiePath("/loom")
+
+	rec := httptest.NewRecorder()
+	if err := sm.CreateSession(rec, httptest.NewRequest(http.MethodPost, "/loom/api/login", nil), 7); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Path != "/loom" {
+		t.Fatalf("cookies = %v, want one session cookie with path /loom", cookies)
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerifyToken(t *testing.T) {
+	key := DeriveKey([]byte("master"), "test")
+	now := time.Now()
+
+	token := SignToken(key, "42:7", now.Add(time.Minute))
+
+	payload, err := VerifyToken(key, token, now)
+	if err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	if payload != "42:7" {
+		t.Fatalf("payload = %q, want %q", payload, "42:7")
+	}
+
+	if _, err := VerifyToken(key, token, now.Add(2*time.Minute)); !errors.Is(err, ErrExpiredToken) {
+		t.Fatalf("VerifyToken() after expiry error = %v, want %v", err, ErrExpiredToken)
+	}
+
+	otherKey := DeriveKey([]byte("master"), "other")
+	if _, err := VerifyToken(otherKey, token, now); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("VerifyToken() with other key error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	if _, err := VerifyToken(key, token+"x", now); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("VerifyToken() with tampered signature error = %v, want %v", err, ErrInvalidToken)
+	}
+}
@@ -0,0 +1,263 @@
+// Package configfile reads flat configuration files whose keys mirror the
+// server's environment variables. It understands the subset of YAML and TOML
+// needed for that: one "key: value" (YAML) or "key = value" (TOML) pair per
+// line, quoted or bare scalars, and lists, which are joined with commas the
+// same way list-valued environment variables are written. Nested mappings
+// and TOML tables are rejected.
+package configfile
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Load reads a .yaml, .yml or .toml file and returns its values keyed by
+// upper-cased name, ready to be used as environment variable values
+func Load(path string) (map[string]string, error) {
+	var parse func(lines []string) (map[string]string, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		parse = parseYAML
+	case ".toml":
+		parse = parseTOML
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q (use .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values, err := parse(lines)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return values, nil
+}
+
+// parseYAML parses flat "key: value" lines, allowing a key with no value to be
+// followed by a block list of "- item" lines
+func parseYAML(lines []string) (map[string]string, error) {
+	values := make(map[string]string)
+	var listKey string
+	var listItems []string
+
+	flushList := func() {
+		if listKey != "" {
+			values[listKey] = strings.Join(listItems, ",")
+			listKey, listItems = "", nil
+		}
+	}
+
+	for i, raw := range lines {
+		line := strings.TrimRight(stripComment(raw), " \t")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if listKey == "" {
+				return nil, fmt.Errorf("line %d: list item without a key", i+1)
+			}
+			item, err := parseScalar(strings.TrimSpace(strings.TrimPrefix(trimmed, "-")))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			listItems = append(listItems, item)
+			continue
+		}
+		flushList()
+
+		if line != trimmed {
+			return nil, fmt.Errorf("line %d: nested mappings are not supported", i+1)
+		}
+
+		key, value, found := strings.Cut(trimmed, ":")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", i+1)
+		}
+		key, err := normalizeKey(key, values)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		value = strings.TrimSpace(value)
+		if value == "" {
+			// Either an empty value or the start of a block list
+			listKey = key
+			values[key] = ""
+			continue
+		}
+
+		parsed, err := parseValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		values[key] = parsed
+	}
+	flushList()
+
+	return values, nil
+}
+
+// parseTOML parses flat "key = value" lines
+func parseTOML(lines []string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	for i, raw := range lines {
+		line := strings.TrimSpace(stripComment(raw))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("line %d: tables are not supported", i+1)
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected \"key = value\"", i+1)
+		}
+		key, err := normalizeKey(key, values)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil, fmt.Errorf("line %d: missing value", i+1)
+		}
+
+		parsed, err := parseValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		values[key] = parsed
+	}
+
+	return values, nil
+}
+
+// normalizeKey validates a key, upper-cases it and rejects duplicates
+func normalizeKey(key string, seen map[string]string) (string, error) {
+	key = strings.TrimSpace(key)
+	if !keyPattern.MatchString(key) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	key = strings.ToUpper(key)
+	if _, exists := seen[key]; exists {
+		return "", fmt.Errorf("duplicate key %q", key)
+	}
+	return key, nil
+}
+
+// parseValue parses a scalar or a single-line [a, b] list, which is joined with commas
+func parseValue(value string) (string, error) {
+	if !strings.HasPrefix(value, "[") {
+		return parseScalar(value)
+	}
+	if !strings.HasSuffix(value, "]") {
+		return "", fmt.Errorf("unterminated list")
+	}
+
+	var items []string
+	for _, part := range splitOutsideQuotes(value[1:len(value)-1], ',') {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		item, err := parseScalar(part)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, item)
+	}
+	return strings.Join(items, ","), nil
+}
+
+// parseScalar unquotes a double-quoted (escape-processing) or single-quoted
+// (literal) string; anything else is returned as written
+func parseScalar(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted string %s", value)
+		}
+		return unquoted, nil
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	}
+	if value[0] == '"' || value[0] == '\'' {
+		return "", fmt.Errorf("unterminated string %s", value)
+	}
+	return value, nil
+}
+
+// stripComment removes a # comment that is not inside quotes. As in YAML and TOML, a #
+// only starts a comment at the start of the line or after whitespace, so unquoted
+// values such as URL fragments keep theirs.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitOutsideQuotes splits s on sep, ignoring separators inside single or double quotes
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
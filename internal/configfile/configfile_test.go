@@ -0,0 +1,88 @@
+package configfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "yaml scalars and lists",
+			file: "loom.yaml",
+			content: `# Loom configuration
+port: 9090
+database_path: "/data/loom.db"   # trailing comment
+OAUTH2_ISSUER_URL: https://auth.example.com/app/
+base_path: /loom#section	# tab comment
+content_security_policy: 'default-src ''self'''
+admin_users:
+  - alice
+  - "bob@example.com"
+favicon_blocked_hosts: [google.com, 'gstatic.com']
+`,
+			want: map[string]string{
+				"PORT":                    "9090",
+				"DATABASE_PATH":           "/data/loom.db",
+				"OAUTH2_ISSUER_URL":       "https://auth.example.com/app/",
+				"BASE_PATH":               "/loom#section",
+				"CONTENT_SECURITY_POLICY": "default-src 'self'",
+				"ADMIN_USERS":             "alice,bob@example.com",
+				"FAVICON_BLOCKED_HOSTS":   "google.com,gstatic.com",
+			},
+		},
+		{
+			name: "toml scalars and lists",
+			file: "loom.toml",
+			content: `port = 9090
+secure_cookie = true
+session_key = "0123#not-a-comment"
+admin_users = ["alice", "bob"] # trailing comment
+favicon_blocked_hosts = [cdn.example.com#1]
+`,
+			want: map[string]string{
+				"PORT":                  "9090",
+				"SECURE_COOKIE":         "true",
+				"SESSION_KEY":           "0123#not-a-comment",
+				"ADMIN_USERS":           "alice,bob",
+				"FAVICON_BLOCKED_HOSTS": "cdn.example.com#1",
+			},
+		},
+		{name: "yaml nested mapping rejected", file: "loom.yml", content: "server:\n  port: 8080\n", wantErr: true},
+		{name: "toml table rejected", file: "loom.toml", content: "[server]\nport = 8080\n", wantErr: true},
+		{name: "duplicate key rejected", file: "loom.toml", content: "port = 1\nPORT = 2\n", wantErr: true},
+		{name: "unterminated string rejected", file: "loom.toml", content: "port = \"8080\n", wantErr: true},
+		{name: "unknown extension rejected", file: "loom.json", content: "{}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("write config: %v", err)
+			}
+
+			got, err := Load(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Load() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Load() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
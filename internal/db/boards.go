@@ -1,20 +1,64 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	"github.com/crueber/loom/internal/models"
 )
 
-// GetBoards retrieves all boards for a user, sorted by most recently updated with default board first
+// Board sort modes accepted by GetBoardsSorted
+const (
+	BoardSortRecent   = "recent"
+	BoardSortAlpha    = "alpha"
+	BoardSortPosition = "position"
+)
+
+// boardSortOrders maps each sort mode to its ORDER BY clause (default board always first).
+// Boards have no manual position, so "position" is their creation order.
+var boardSortOrders = map[string]string{
+	BoardSortRecent:   "is_default DESC, updated_at DESC",
+	BoardSortAlpha:    "is_default DESC, title COLLATE NOCASE",
+	BoardSortPosition: "is_default DESC, created_at ASC, id ASC",
+}
+
+// IsValidBoardSort reports whether sort is a supported board sort mode
+func IsValidBoardSort(sort string) bool {
+	_, ok := boardSortOrders[sort]
+	return ok
+}
+
+// GetBoards retrieves all unarchived boards for a user, sorted by most recently updated with default board first
 func (db *DB) GetBoards(userID int) ([]*models.Board, error) {
-	rows, err := db.Query(`
-		SELECT id, user_id, title, is_default, updated_at, created_at
+	return db.GetBoardsSorted(userID, BoardSortRecent, false)
+}
+
+// GetBoardsContext is like GetBoards but honors ctx and the configured query timeout
+func (db *DB) GetBoardsContext(ctx context.Context, userID int) ([]*models.Board, error) {
+	return db.GetBoardsSortedContext(ctx, userID, BoardSortRecent, false)
+}
+
+// GetBoardsSorted retrieves a user's boards using the given sort mode, optionally including archived boards
+func (db *DB) GetBoardsSorted(userID int, sort string, includeArchived bool) ([]*models.Board, error) {
+	return db.GetBoardsSortedContext(context.Background(), userID, sort, includeArchived)
+}
+
+// GetBoardsSortedContext is like GetBoardsSorted but honors ctx and the configured query timeout
+func (db *DB) GetBoardsSortedContext(ctx context.Context, userID int, sort string, includeArchived bool) ([]*models.Board, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	orderBy, ok := boardSortOrders[sort]
+	if !ok {
+		return nil, fmt.Errorf("invalid board sort: %s", sort)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, user_id, title, is_default, public_read, archived, updated_at, created_at
 		FROM boards
-		WHERE user_id = ?
-		ORDER BY is_default DESC, updated_at DESC
-	`, userID)
+		WHERE user_id = ? AND (? OR archived = 0)
+		ORDER BY `+orderBy, userID, includeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get boards: %w", err)
 	}
@@ -24,13 +68,17 @@ func (db *DB) GetBoards(userID int) ([]*models.Board, error) {
 	for rows.Next() {
 		var board models.Board
 		var isDefault int
-		if err := rows.Scan(&board.ID, &board.UserID, &board.Title, &isDefault, &board.UpdatedAt, &board.CreatedAt); err != nil {
+		if err := rows.Scan(&board.ID, &board.UserID, &board.Title, &isDefault, &board.PublicRead, &board.Archived, &board.UpdatedAt, &board.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan board: %w", err)
 		}
 		board.IsDefault = isDefault == 1
 		boards = append(boards, &board)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read boards: %w", err)
+	}
+
 	// If no boards exist, create a default board
 	if len(boards) == 0 {
 		defaultBoard, err := db.GetDefaultBoard(userID)
@@ -45,13 +93,21 @@ func (db *DB) GetBoards(userID int) ([]*models.Board, error) {
 
 // GetBoardByID retrieves a board by ID
 func (db *DB) GetBoardByID(boardID, userID int) (*models.Board, error) {
+	return db.GetBoardByIDContext(context.Background(), boardID, userID)
+}
+
+// GetBoardByIDContext is like GetBoardByID but honors ctx and the configured query timeout
+func (db *DB) GetBoardByIDContext(ctx context.Context, boardID, userID int) (*models.Board, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
 	var board models.Board
 	var isDefault int
-	err := db.QueryRow(`
-		SELECT id, user_id, title, is_default, updated_at, created_at
+	err := db.QueryRowContext(ctx, `
+		SELECT id, user_id, title, is_default, public_read, archived, updated_at, created_at
 		FROM boards
 		WHERE id = ? AND user_id = ?
-	`, boardID, userID).Scan(&board.ID, &board.UserID, &board.Title, &isDefault, &board.UpdatedAt, &board.CreatedAt)
+	`, boardID, userID).Scan(&board.ID, &board.UserID, &board.Title, &isDefault, &board.PublicRead, &board.Archived, &board.UpdatedAt, &board.CreatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -69,10 +125,10 @@ func (db *DB) GetDefaultBoard(userID int) (*models.Board, error) {
 	var board models.Board
 	var isDefault int
 	err := db.QueryRow(`
-		SELECT id, user_id, title, is_default, updated_at, created_at
+		SELECT id, user_id, title, is_default, public_read, archived, updated_at, created_at
 		FROM boards
 		WHERE user_id = ? AND is_default = 1
-	`, userID).Scan(&board.ID, &board.UserID, &board.Title, &isDefault, &board.UpdatedAt, &board.CreatedAt)
+	`, userID).Scan(&board.ID, &board.UserID, &board.Title, &isDefault, &board.PublicRead, &board.Archived, &board.UpdatedAt, &board.CreatedAt)
 
 	if err == sql.ErrNoRows {
 		// Create default board
@@ -100,6 +156,62 @@ func (db *DB) GetDefaultBoard(userID int) (*models.Board, error) {
 	return &board, nil
 }
 
+// RepairDefaultBoards makes sure every user who has boards has exactly one default:
+// extra defaults are demoted, keeping the oldest, and users with none get their oldest
+// board promoted. Unarchived boards are preferred over archived ones either way. Users without any boards are left alone; GetDefaultBoard creates one
+// on demand. It returns how many boards were promoted and demoted.
+func (db *DB) RepairDefaultBoards() (promoted, demoted int, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Demote first so promotions can never collide with the one-default-per-user index
+	result, err := tx.Exec(`
+		UPDATE boards SET is_default = 0
+		WHERE is_default = 1 AND id NOT IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY archived, created_at, id) AS rn
+				FROM boards WHERE is_default = 1
+			) WHERE rn = 1
+		)
+	`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to demote extra default boards: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	demoted = int(rows)
+
+	result, err = tx.Exec(`
+		UPDATE boards SET is_default = 1
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT id, user_id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY archived, created_at, id) AS rn
+				FROM boards
+			) b
+			WHERE rn = 1 AND NOT EXISTS (SELECT 1 FROM boards d WHERE d.user_id = b.user_id AND d.is_default = 1)
+		)
+	`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to promote default boards: %w", err)
+	}
+	rows, err = result.RowsAffected()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	promoted = int(rows)
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return promoted, demoted, nil
+}
+
 // CreateBoard creates a new board
 func (db *DB) CreateBoard(userID int, title string, isDefault bool) (*models.Board, error) {
 	isDefaultInt := 0
@@ -122,6 +234,35 @@ func (db *DB) CreateBoard(userID int, title string, isDefault bool) (*models.Boa
 	return db.GetBoardByID(int(id), userID)
 }
 
+// SeedBoard adds template's lists and items to a new user's board. A nil template
+// seeds nothing; content is written as given, so callers validate it first.
+func (db *DB) SeedBoard(userID, boardID int, template *models.ExportData) error {
+	if template == nil {
+		return nil
+	}
+
+	for _, templateList := range template.Lists {
+		list, err := db.CreateList(userID, boardID, templateList.Title, templateList.Color, templateList.Position, templateList.Collapsed)
+		if err != nil {
+			return err
+		}
+		if templateList.SortMode != "" {
+			if err := db.UpdateList(list.ID, userID, nil, nil, nil, &templateList.SortMode); err != nil {
+				return err
+			}
+		}
+
+		for _, templateItem := range templateList.Items {
+			openInNewTab := templateItem.OpenInNewTab == nil || *templateItem.OpenInNewTab
+			if _, err := db.CreateItem(list.ID, templateItem.Type, templateItem.Title, templateItem.URL, templateItem.Content, templateItem.FaviconURL, "auto", nil, templateItem.ContentFormat, templateItem.Position, openInNewTab); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 // UpdateBoard updates a board's title
 func (db *DB) UpdateBoard(boardID, userID int, title string) error {
 	result, err := db.Exec(`
@@ -178,6 +319,91 @@ func (db *DB) DeleteBoard(boardID, userID int) error {
 	return nil
 }
 
+// SetBoardPublicRead sets whether a board can be read without authentication
+func (db *DB) SetBoardPublicRead(boardID, userID int, publicRead bool) error {
+	result, err := db.Exec(`
+		UPDATE boards
+		SET public_read = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ?
+	`, publicRead, boardID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update board: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("board not found")
+	}
+
+	return nil
+}
+
+// SetBoardArchived archives or restores a board (the default board cannot be archived)
+func (db *DB) SetBoardArchived(boardID, userID int, archived bool) error {
+	var isDefault int
+	err := db.QueryRow("SELECT is_default FROM boards WHERE id = ? AND user_id = ?", boardID, userID).Scan(&isDefault)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("board not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check board: %w", err)
+	}
+
+	if archived && isDefault == 1 {
+		return fmt.Errorf("cannot archive default board")
+	}
+
+	_, err = db.Exec(`
+		UPDATE boards
+		SET archived = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ?
+	`, archived, boardID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update board: %w", err)
+	}
+
+	return nil
+}
+
+// GetPublicBoardContext retrieves a board by ID regardless of owner, but only if it is flagged public_read.
+// Returns nil if the board does not exist or is private.
+func (db *DB) GetPublicBoardContext(ctx context.Context, boardID int) (*models.Board, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	var board models.Board
+	var isDefault int
+	err := db.QueryRowContext(ctx, `
+		SELECT id, user_id, title, is_default, public_read, archived, updated_at, created_at
+		FROM boards
+		WHERE id = ? AND public_read = 1
+	`, boardID).Scan(&board.ID, &board.UserID, &board.Title, &isDefault, &board.PublicRead, &board.Archived, &board.UpdatedAt, &board.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public board: %w", err)
+	}
+
+	board.IsDefault = isDefault == 1
+	return &board, nil
+}
+
+// CountBoardLists returns how many lists the user has on a board
+func (db *DB) CountBoardLists(boardID, userID int) (int, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM lists WHERE board_id = ? AND user_id = ?", boardID, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count board lists: %w", err)
+	}
+	return count, nil
+}
+
 // VerifyBoardOwnership checks if a board belongs to a user
 func (db *DB) VerifyBoardOwnership(boardID, userID int) (bool, error) {
 	var exists bool
@@ -196,3 +422,12 @@ func (db *DB) TouchBoard(boardID int) error {
 	}
 	return nil
 }
+
+// TouchBoardByList updates the updated_at timestamp for the board that owns a list
+func (db *DB) TouchBoardByList(listID int) error {
+	_, err := db.Exec("UPDATE boards SET updated_at = CURRENT_TIMESTAMP WHERE id = (SELECT board_id FROM lists WHERE id = ?)", listID)
+	if err != nil {
+		return fmt.Errorf("failed to touch board: %w", err)
+	}
+	return nil
+}
@@ -3,7 +3,6 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"strings"
 
 	"github.com/crueber/loom/internal/models"
 )
@@ -134,61 +133,6 @@ func (db *DB) DeleteBookmark(id int) error {
 	return nil
 }
 
-// UpdateBookmarkPositions updates positions for multiple bookmarks
-func (db *DB) UpdateBookmarkPositions(positions map[int]struct {
-	Position int
-	ListID   int
-}) error {
-	if len(positions) == 0 {
-		return nil
-	}
-
-	tx, err := db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	// Build bulk UPDATE with CASE statements for efficiency
-	var ids []interface{}
-	var positionCases, listIDCases strings.Builder
-	positionCases.WriteString("CASE id ")
-	listIDCases.WriteString("CASE id ")
-
-	for bookmarkID, pos := range positions {
-		ids = append(ids, bookmarkID)
-		positionCases.WriteString(fmt.Sprintf("WHEN %d THEN %d ", bookmarkID, pos.Position))
-		listIDCases.WriteString(fmt.Sprintf("WHEN %d THEN %d ", bookmarkID, pos.ListID))
-	}
-
-	positionCases.WriteString("END")
-	listIDCases.WriteString("END")
-
-	// Build placeholders for IN clause
-	placeholders := make([]string, len(ids))
-	for i := range ids {
-		placeholders[i] = "?"
-	}
-
-	query := fmt.Sprintf(`
-		UPDATE bookmarks
-		SET position = %s,
-		    list_id = %s
-		WHERE id IN (%s)
-	`, positionCases.String(), listIDCases.String(), strings.Join(placeholders, ","))
-
-	_, err = tx.Exec(query, ids...)
-	if err != nil {
-		return fmt.Errorf("failed to update bookmark positions: %w", err)
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
 // VerifyBookmarkOwnership checks if a bookmark belongs to a user (through the list)
 func (db *DB) VerifyBookmarkOwnership(bookmarkID, userID int) (bool, error) {
 	var exists bool
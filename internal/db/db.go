@@ -1,9 +1,11 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -12,19 +14,94 @@ import (
 // DB wraps the SQL database connection
 type DB struct {
 	*sql.DB
+
+	// queryTimeout bounds context-aware queries; zero means no limit beyond the caller's context
+	queryTimeout time.Duration
+
+	// options tune one-time data migrations
+	options Options
+}
+
+// Options tune how New runs data migrations
+type Options struct {
+	// FaviconMigrationConcurrency bounds parallel icon fetches in the v4 favicon migration
+	FaviconMigrationConcurrency int
+	// FaviconMigrationTimeout bounds each icon fetch in the v4 favicon migration
+	FaviconMigrationTimeout time.Duration
+	// DropBookmarksBackup drops the pre-v3 bookmarks table instead of keeping it as
+	// bookmarks_backup_v3, and lets migration v19 drop an existing backup
+	DropBookmarksBackup bool
+}
+
+// DefaultOptions returns the options New uses
+func DefaultOptions() Options {
+	return Options{
+		FaviconMigrationConcurrency: 8,
+		FaviconMigrationTimeout:     2 * time.Second,
+	}
+}
+
+// Defaults for ResolvePath
+const (
+	DefaultDataDir  = "./data"
+	DefaultFilename = "bookmarks.db"
+)
+
+// ResolvePath returns the absolute database path. An explicit path wins; otherwise the
+// database is filename inside dataDir. The containing directory is created if needed and
+// checked for writability so a bad location fails at startup rather than on first write.
+func ResolvePath(explicitPath, dataDir, filename string) (string, error) {
+	path := explicitPath
+	if path == "" {
+		if filename != filepath.Base(filename) || filename == "." || filename == ".." {
+			return "", fmt.Errorf("database filename must not contain a directory: %q", filename)
+		}
+		path = filepath.Join(dataDir, filename)
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve database path: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create data directory: %w", err)
+	}
+	probe, err := os.CreateTemp(dir, ".write-test-*")
+	if err != nil {
+		return "", fmt.Errorf("data directory %s is not writable: %w", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	return path, nil
 }
 
-// New creates a new database connection and runs migrations
+// New creates a new database connection and runs migrations with DefaultOptions
 func New(dbPath string) (*DB, error) {
+	return NewWithOptions(dbPath, DefaultOptions())
+}
+
+// NewWithOptions is like New but runs data migrations with the given options.
+// Zero-valued fields fall back to DefaultOptions.
+func NewWithOptions(dbPath string, options Options) (*DB, error) {
+	defaults := DefaultOptions()
+	if options.FaviconMigrationConcurrency <= 0 {
+		options.FaviconMigrationConcurrency = defaults.FaviconMigrationConcurrency
+	}
+	if options.FaviconMigrationTimeout <= 0 {
+		options.FaviconMigrationTimeout = defaults.FaviconMigrationTimeout
+	}
+
 	// Ensure the directory exists
-	dir := dbPath[:len(dbPath)-len("/bookmarks.db")]
-	if dir != "" {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return nil, fmt.Errorf("failed to create database directory: %w", err)
-		}
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", dbPath)
+	// Foreign keys are a per-connection setting, so set them in the DSN to cover
+	// every pooled connection rather than only the first
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=foreign_keys(1)")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -35,21 +112,16 @@ func New(dbPath string) (*DB, error) {
 	}
 
 	// Configure connection pool
-	db.SetMaxOpenConns(25)                  // Limit concurrent connections
-	db.SetMaxIdleConns(5)                   // Keep some connections warm
-	db.SetConnMaxLifetime(5 * time.Minute)  // Recycle connections periodically
-
-	// Enable foreign keys
-	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
-	}
+	db.SetMaxOpenConns(25)                 // Limit concurrent connections
+	db.SetMaxIdleConns(5)                  // Keep some connections warm
+	db.SetConnMaxLifetime(5 * time.Minute) // Recycle connections periodically
 
 	// Enable WAL mode for better concurrency
 	if _, err := db.Exec("PRAGMA journal_mode = WAL"); err != nil {
 		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
 	}
 
-	wrapped := &DB{db}
+	wrapped := &DB{DB: db, options: options}
 
 	// Run migrations
 	if err := wrapped.migrate(); err != nil {
@@ -59,7 +131,42 @@ func New(dbPath string) (*DB, error) {
 	return wrapped, nil
 }
 
+// SetQueryTimeout sets the maximum duration of context-aware queries (zero disables the limit)
+func (db *DB) SetQueryTimeout(timeout time.Duration) {
+	db.queryTimeout = timeout
+}
+
+// queryContext derives a context for a single query, applying the configured timeout
+func (db *DB) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.queryTimeout > 0 {
+		return context.WithTimeout(ctx, db.queryTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
 // Close closes the database connection
 func (db *DB) Close() error {
 	return db.DB.Close()
 }
+
+// BackupTo writes a consistent snapshot of the database to path using VACUUM INTO.
+// The snapshot includes committed WAL content and can be taken while writes continue.
+// path must not exist or must be an empty file.
+func (db *DB) BackupTo(path string) error {
+	if _, err := db.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// sqlTimestampLayout matches how SQLite's CURRENT_TIMESTAMP stores times (UTC)
+const sqlTimestampLayout = "2006-01-02 15:04:05"
+
+// sqlTimestamp formats t for comparison with or storage alongside CURRENT_TIMESTAMP values.
+// A zero t yields nil so that COALESCE(?, CURRENT_TIMESTAMP) falls back to now.
+func sqlTimestamp(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(sqlTimestampLayout)
+}
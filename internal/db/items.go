@@ -1,13 +1,67 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/crueber/loom/internal/models"
+	"github.com/crueber/loom/internal/urlutil"
 )
 
+// Item types. ItemTypes is the single list of accepted types: handler validation
+// and IsValidItemType are derived from it.
+const (
+	ItemTypeBookmark  = "bookmark"
+	ItemTypeNote      = "note"
+	ItemTypeSeparator = "separator"
+)
+
+// ItemTypes lists every accepted item type. Adding a type here also needs a new
+// migration that rebuilds the items table with the type added to its CHECK constraint;
+// shipped migrations are never edited
+var ItemTypes = []string{ItemTypeBookmark, ItemTypeNote, ItemTypeSeparator}
+
+// faviconFetchedSQL is the last_favicon_fetch value for a write that binds the new
+// favicon: the current time when an icon was stored, otherwise NULL so it counts as stale
+const faviconFetchedSQL = "CASE WHEN ? IS NOT NULL THEN CURRENT_TIMESTAMP END"
+
+// normalizedURL returns the normalized_url stored alongside an item's url (see
+// urlutil.NormalizeForMatch), which FindItemsByURL matches on; nil when url is nil
+func normalizedURL(url *string) *string {
+	if url == nil {
+		return nil
+	}
+	normalized := urlutil.NormalizeForMatch(*url)
+	return &normalized
+}
+
+// IsValidItemType reports whether itemType is one of ItemTypes
+func IsValidItemType(itemType string) bool {
+	return slices.Contains(ItemTypes, itemType)
+}
+
+// ItemTypesDescription returns the accepted types for error messages, e.g. "'bookmark', 'note' or 'separator'"
+func ItemTypesDescription() string {
+	quoted := quotedItemTypes()
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
+
+// quotedItemTypes returns ItemTypes as single-quoted SQL string literals
+func quotedItemTypes() []string {
+	quoted := make([]string, len(ItemTypes))
+	for i, t := range ItemTypes {
+		quoted[i] = "'" + t + "'"
+	}
+	return quoted
+}
+
 // GetNextItemPosition returns the next position for a new item in a list
 func (db *DB) GetNextItemPosition(listID int) (int, error) {
 	var position int
@@ -21,11 +75,20 @@ func (db *DB) GetNextItemPosition(listID int) (int, error) {
 	return position, nil
 }
 
-// CreateItem creates a new item (bookmark or note)
-func (db *DB) CreateItem(listID int, itemType string, title, url, content *string, faviconURL *string, iconSource string, customIconURL *string, position int) (*models.Item, error) {
+// CreateItem creates a new item (bookmark, note or separator)
+func (db *DB) CreateItem(listID int, itemType string, title, url, content *string, faviconURL *string, iconSource string, customIconURL *string, contentFormat string, position int, openInNewTab bool) (*models.Item, error) {
+	return db.CreateItemAt(listID, itemType, title, url, content, faviconURL, iconSource, customIconURL, contentFormat, position, openInNewTab, time.Time{})
+}
+
+// CreateItemAt is like CreateItem but records createdAt as the creation time (zero means now)
+func (db *DB) CreateItemAt(listID int, itemType string, title, url, content *string, faviconURL *string, iconSource string, customIconURL *string, contentFormat string, position int, openInNewTab bool, createdAt time.Time) (*models.Item, error) {
+	if !IsValidItemType(itemType) {
+		return nil, fmt.Errorf("invalid item type: %s", itemType)
+	}
+
 	result, err := db.Exec(
-		"INSERT INTO items (list_id, type, title, url, content, favicon_url, icon_source, custom_icon_url, position) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
-		listID, itemType, title, url, content, faviconURL, iconSource, customIconURL, position,
+		"INSERT INTO items (list_id, type, title, url, normalized_url, content, favicon_url, icon_source, custom_icon_url, content_format, position, open_in_new_tab, created_at, last_favicon_fetch) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP), "+faviconFetchedSQL+")",
+		listID, itemType, title, url, normalizedURL(url), content, faviconURL, iconSource, customIconURL, contentFormat, position, openInNewTab, sqlTimestamp(createdAt), faviconURL,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create item: %w", err)
@@ -36,16 +99,118 @@ func (db *DB) CreateItem(listID int, itemType string, title, url, content *strin
 		return nil, fmt.Errorf("failed to get item ID: %w", err)
 	}
 
+	// Touch the board so "recently updated" ordering reflects item changes
+	db.TouchBoardByList(listID)
+
 	return db.GetItem(int(id))
 }
 
+// NewBookmark describes a bookmark to append with AppendBookmarksToNamedList
+type NewBookmark struct {
+	Title      string
+	URL        string
+	FaviconURL *string
+}
+
+// AppendBookmarksToNamedList finds the board's list with the given title (case-insensitive),
+// creating it at the end of the board with color if missing, and appends the bookmarks to it
+// in one transaction. It returns the list and the created items in order.
+func (db *DB) AppendBookmarksToNamedList(userID, boardID int, title, color string, bookmarks []NewBookmark) (*models.List, []*models.Item, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var boardExists bool
+	err = tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM boards WHERE id = ? AND user_id = ?)",
+		boardID, userID,
+	).Scan(&boardExists)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to verify board ownership: %w", err)
+	}
+	if !boardExists {
+		return nil, nil, fmt.Errorf("board not found")
+	}
+
+	var listID int
+	err = tx.QueryRow(
+		"SELECT id FROM lists WHERE board_id = ? AND user_id = ? AND title = ? COLLATE NOCASE ORDER BY position LIMIT 1",
+		boardID, userID, title,
+	).Scan(&listID)
+	if err == sql.ErrNoRows {
+		result, err := tx.Exec(`
+			INSERT INTO lists (user_id, board_id, title, color, position, collapsed)
+			SELECT ?, ?, ?, ?, COALESCE(MAX(position), -1) + 1, 0 FROM lists WHERE board_id = ? AND user_id = ?
+		`, userID, boardID, title, color, boardID, userID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create list: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get list ID: %w", err)
+		}
+		listID = int(id)
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to find list: %w", err)
+	}
+
+	var position int
+	err = tx.QueryRow("SELECT COALESCE(MAX(position), -1) + 1 FROM items WHERE list_id = ?", listID).Scan(&position)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get next item position: %w", err)
+	}
+
+	ids := make([]int, 0, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		result, err := tx.Exec(
+			"INSERT INTO items (list_id, type, title, url, normalized_url, favicon_url, position, last_favicon_fetch) VALUES (?, ?, ?, ?, ?, ?, ?, "+faviconFetchedSQL+")",
+			listID, ItemTypeBookmark, bookmark.Title, bookmark.URL, urlutil.NormalizeForMatch(bookmark.URL), bookmark.FaviconURL, position+i, bookmark.FaviconURL,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create item: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get item ID: %w", err)
+		}
+		ids = append(ids, int(id))
+	}
+
+	if _, err := tx.Exec("UPDATE boards SET updated_at = CURRENT_TIMESTAMP WHERE id = ?", boardID); err != nil {
+		return nil, nil, fmt.Errorf("failed to touch board: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	list, err := db.GetList(listID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items := make([]*models.Item, 0, len(ids))
+	for _, id := range ids {
+		item, err := db.GetItem(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, item)
+	}
+
+	return list, items, nil
+}
+
 // GetItem retrieves an item by ID
 func (db *DB) GetItem(id int) (*models.Item, error) {
 	var item models.Item
+	var contentFormat sql.NullString
 	err := db.QueryRow(
-		"SELECT id, list_id, type, title, url, content, favicon_url, icon_source, custom_icon_url, position, created_at FROM items WHERE id = ?",
+		"SELECT id, list_id, type, title, url, content, content_format, favicon_url, icon_source, custom_icon_url, preview_image_url, open_in_new_tab, is_pinned, position, created_at FROM items WHERE id = ?",
 		id,
-	).Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &item.FaviconURL, &item.IconSource, &item.CustomIconURL, &item.Position, &item.CreatedAt)
+	).Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &contentFormat, &item.FaviconURL, &item.IconSource, &item.CustomIconURL, &item.PreviewImageURL, &item.OpenInNewTab, &item.IsPinned, &item.Position, &item.CreatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -53,14 +218,36 @@ func (db *DB) GetItem(id int) (*models.Item, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get item: %w", err)
 	}
+	item.ContentFormat = contentFormatOrDefault(contentFormat)
 
 	return &item, nil
 }
 
-// GetItems retrieves all items for a list
+// defaultContentFormat is the content_format column default
+const defaultContentFormat = "markdown"
+
+// contentFormatOrDefault reads the nullable content_format column, treating NULL as the default
+func contentFormatOrDefault(format sql.NullString) string {
+	if !format.Valid {
+		return defaultContentFormat
+	}
+	return format.String
+}
+
+// itemSortOrderSQL orders items (i) as their list (l) sort_mode asks, falling back to
+// position for manual lists and ties. Titleless bookmarks sort by URL.
+const itemSortOrderSQL = `CASE l.sort_mode WHEN 'title' THEN lower(COALESCE(NULLIF(i.title, ''), i.url, '')) END,
+		 CASE l.sort_mode WHEN 'created' THEN i.created_at END,
+		 i.position, i.id`
+
+// GetItems retrieves all items for a list in the list's sort order
 func (db *DB) GetItems(listID int) ([]*models.Item, error) {
 	rows, err := db.Query(
-		"SELECT id, list_id, type, title, url, content, favicon_url, icon_source, custom_icon_url, position, created_at FROM items WHERE list_id = ? ORDER BY position",
+		`SELECT i.id, i.list_id, i.type, i.title, i.url, i.content, i.content_format, i.favicon_url, i.icon_source, i.custom_icon_url, i.preview_image_url, i.open_in_new_tab, i.is_pinned, i.position, i.created_at
+		 FROM items i
+		 INNER JOIN lists l ON i.list_id = l.id
+		 WHERE i.list_id = ?
+		 ORDER BY `+itemSortOrderSQL,
 		listID,
 	)
 	if err != nil {
@@ -71,23 +258,65 @@ func (db *DB) GetItems(listID int) ([]*models.Item, error) {
 	var items []*models.Item
 	for rows.Next() {
 		var item models.Item
-		if err := rows.Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &item.FaviconURL, &item.IconSource, &item.CustomIconURL, &item.Position, &item.CreatedAt); err != nil {
+		var contentFormat sql.NullString
+		if err := rows.Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &contentFormat, &item.FaviconURL, &item.IconSource, &item.CustomIconURL, &item.PreviewImageURL, &item.OpenInNewTab, &item.IsPinned, &item.Position, &item.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan item: %w", err)
 		}
+		item.ContentFormat = contentFormatOrDefault(contentFormat)
 		items = append(items, &item)
 	}
 
 	return items, nil
 }
 
+// FindItemsByURL returns the locations of the user's bookmarks whose URL normalizes
+// (see urlutil.NormalizeForMatch) to normalized, using the stored normalized_url
+func (db *DB) FindItemsByURL(userID int, normalized string) ([]*models.ItemLocation, error) {
+	rows, err := db.Query(
+		`SELECT i.id, i.list_id, l.board_id
+		 FROM items i
+		 INNER JOIN lists l ON i.list_id = l.id
+		 WHERE i.normalized_url = ? AND l.user_id = ? AND i.type = ?
+		 ORDER BY i.id`,
+		normalized, userID, ItemTypeBookmark,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find items by URL: %w", err)
+	}
+	defer rows.Close()
+
+	var locations []*models.ItemLocation
+	for rows.Next() {
+		var location models.ItemLocation
+		if err := rows.Scan(&location.ID, &location.ListID, &location.BoardID); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		locations = append(locations, &location)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read items: %w", err)
+	}
+
+	return locations, nil
+}
+
 // GetAllItems retrieves all items for a user (across all lists)
 func (db *DB) GetAllItems(userID int) ([]*models.Item, error) {
-	rows, err := db.Query(
-		`SELECT i.id, i.list_id, i.type, i.title, i.url, i.content, i.favicon_url, i.position, i.created_at
+	return db.GetAllItemsContext(context.Background(), userID)
+}
+
+// GetAllItemsContext is like GetAllItems but honors ctx and the configured query timeout
+func (db *DB) GetAllItemsContext(ctx context.Context, userID int) ([]*models.Item, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT i.id, i.list_id, i.type, i.title, i.url, i.content, i.content_format, i.favicon_url, i.open_in_new_tab, i.is_pinned, i.position, i.created_at
 		 FROM items i
 		 INNER JOIN lists l ON i.list_id = l.id
 		 WHERE l.user_id = ?
-		 ORDER BY i.list_id, i.position`,
+		 ORDER BY i.list_id, `+itemSortOrderSQL,
 		userID,
 	)
 	if err != nil {
@@ -98,23 +327,37 @@ func (db *DB) GetAllItems(userID int) ([]*models.Item, error) {
 	var items []*models.Item
 	for rows.Next() {
 		var item models.Item
-		if err := rows.Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &item.FaviconURL, &item.Position, &item.CreatedAt); err != nil {
+		var contentFormat sql.NullString
+		if err := rows.Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &contentFormat, &item.FaviconURL, &item.OpenInNewTab, &item.IsPinned, &item.Position, &item.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan item: %w", err)
 		}
+		item.ContentFormat = contentFormatOrDefault(contentFormat)
 		items = append(items, &item)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read items: %w", err)
+	}
+
 	return items, nil
 }
 
 // GetItemsByBoard retrieves all items for a specific board
 func (db *DB) GetItemsByBoard(userID, boardID int) ([]*models.Item, error) {
-	rows, err := db.Query(
-		`SELECT i.id, i.list_id, i.type, i.title, i.url, i.content, i.favicon_url, i.icon_source, i.custom_icon_url, i.position, i.created_at
+	return db.GetItemsByBoardContext(context.Background(), userID, boardID)
+}
+
+// GetItemsByBoardContext is like GetItemsByBoard but honors ctx and the configured query timeout
+func (db *DB) GetItemsByBoardContext(ctx context.Context, userID, boardID int) ([]*models.Item, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT i.id, i.list_id, i.type, i.title, i.url, i.content, i.content_format, i.favicon_url, i.icon_source, i.custom_icon_url, i.preview_image_url, i.open_in_new_tab, i.is_pinned, i.position, i.created_at
 		 FROM items i
 		 INNER JOIN lists l ON i.list_id = l.id
 		 WHERE l.user_id = ? AND l.board_id = ?
-		 ORDER BY i.list_id, i.position`,
+		 ORDER BY i.list_id, `+itemSortOrderSQL,
 		userID, boardID,
 	)
 	if err != nil {
@@ -125,12 +368,439 @@ func (db *DB) GetItemsByBoard(userID, boardID int) ([]*models.Item, error) {
 	var items []*models.Item
 	for rows.Next() {
 		var item models.Item
-		if err := rows.Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &item.FaviconURL, &item.IconSource, &item.CustomIconURL, &item.Position, &item.CreatedAt); err != nil {
+		var contentFormat sql.NullString
+		if err := rows.Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &contentFormat, &item.FaviconURL, &item.IconSource, &item.CustomIconURL, &item.PreviewImageURL, &item.OpenInNewTab, &item.IsPinned, &item.Position, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		item.ContentFormat = contentFormatOrDefault(contentFormat)
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read items: %w", err)
+	}
+
+	return items, nil
+}
+
+// GetOrphanedItems retrieves items whose list no longer exists. Items record no owner of
+// their own, so these cannot be traced back to a user.
+func (db *DB) GetOrphanedItems() ([]*models.OrphanedItem, error) {
+	rows, err := db.Query(`
+		SELECT i.id, i.list_id, i.type, i.title
+		FROM items i
+		LEFT JOIN lists l ON i.list_id = l.id
+		WHERE l.id IS NULL
+		ORDER BY i.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orphaned items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.OrphanedItem
+	for rows.Next() {
+		var item models.OrphanedItem
+		if err := rows.Scan(&item.ID, &item.ListID, &item.Type, &item.Title); err != nil {
 			return nil, fmt.Errorf("failed to scan item: %w", err)
 		}
 		items = append(items, &item)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read items: %w", err)
+	}
+
+	return items, nil
+}
+
+// SearchLimit caps how many items SearchItems returns
+const SearchLimit = 100
+
+// likeEscaper escapes LIKE wildcards so a search matches them literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// SearchItems finds up to SearchLimit of a user's items whose title, URL or content
+// contains query (case-insensitive for ASCII), optionally only on one board. Each item
+// is annotated with its list and board, most recently created first.
+func (db *DB) SearchItems(userID int, query string, boardID *int) ([]*models.ItemWithBoard, error) {
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	sqlQuery := `SELECT i.id, i.list_id, i.type, i.title, i.url, i.content, i.content_format, i.favicon_url, i.icon_source, i.custom_icon_url, i.preview_image_url, i.open_in_new_tab, i.is_pinned, i.position, i.created_at,
+		        b.id, b.title, l.title
+		 FROM items i
+		 INNER JOIN lists l ON i.list_id = l.id
+		 INNER JOIN boards b ON l.board_id = b.id
+		 WHERE l.user_id = ? AND b.user_id = ?
+		   AND (i.title LIKE ? ESCAPE '\' OR i.url LIKE ? ESCAPE '\' OR i.content LIKE ? ESCAPE '\')`
+	args := []any{userID, userID, pattern, pattern, pattern}
+	if boardID != nil {
+		sqlQuery += " AND b.id = ?"
+		args = append(args, *boardID)
+	}
+	sqlQuery += " ORDER BY i.created_at DESC, i.id DESC LIMIT ?"
+	args = append(args, SearchLimit)
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.ItemWithBoard
+	for rows.Next() {
+		var item models.ItemWithBoard
+		var contentFormat sql.NullString
+		if err := rows.Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &contentFormat, &item.FaviconURL, &item.IconSource, &item.CustomIconURL, &item.PreviewImageURL, &item.OpenInNewTab, &item.IsPinned, &item.Position, &item.CreatedAt,
+			&item.BoardID, &item.BoardTitle, &item.ListTitle); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		item.ContentFormat = contentFormatOrDefault(contentFormat)
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read items: %w", err)
+	}
+
+	return items, nil
+}
+
+// GetAllItemsWithBoard retrieves a page of a user's items across every board, each annotated
+// with its list and board titles, ordered by board, list position and item position
+func (db *DB) GetAllItemsWithBoard(userID, limit, offset int) ([]*models.ItemWithBoard, error) {
+	rows, err := db.Query(
+		`SELECT i.id, i.list_id, i.type, i.title, i.url, i.content, i.content_format, i.favicon_url, i.icon_source, i.custom_icon_url, i.preview_image_url, i.open_in_new_tab, i.is_pinned, i.position, i.created_at,
+		        b.id, b.title, l.title
+		 FROM items i
+		 INNER JOIN lists l ON i.list_id = l.id
+		 INNER JOIN boards b ON l.board_id = b.id
+		 WHERE l.user_id = ? AND b.user_id = ?
+		 ORDER BY b.is_default DESC, b.id, l.position, i.position, i.id
+		 LIMIT ? OFFSET ?`,
+		userID, userID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get items with board: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.ItemWithBoard
+	for rows.Next() {
+		var item models.ItemWithBoard
+		var contentFormat sql.NullString
+		if err := rows.Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &contentFormat, &item.FaviconURL, &item.IconSource, &item.CustomIconURL, &item.PreviewImageURL, &item.OpenInNewTab, &item.IsPinned, &item.Position, &item.CreatedAt,
+			&item.BoardID, &item.BoardTitle, &item.ListTitle); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		item.ContentFormat = contentFormatOrDefault(contentFormat)
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read items: %w", err)
+	}
+
+	return items, nil
+}
+
+// GetItemsByDateRange retrieves up to limit of a user's items created between since and until (inclusive), newest first
+func (db *DB) GetItemsByDateRange(userID int, since, until time.Time, limit int) ([]*models.Item, error) {
+	rows, err := db.Query(
+		`SELECT i.id, i.list_id, i.type, i.title, i.url, i.content, i.content_format, i.favicon_url, i.icon_source, i.custom_icon_url, i.preview_image_url, i.open_in_new_tab, i.is_pinned, i.position, i.created_at
+		 FROM items i
+		 INNER JOIN lists l ON i.list_id = l.id
+		 WHERE l.user_id = ? AND datetime(i.created_at) BETWEEN ? AND ?
+		 ORDER BY i.created_at DESC, i.id DESC
+		 LIMIT ?`,
+		userID, sqlTimestamp(since), sqlTimestamp(until), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get items by date range: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.Item
+	for rows.Next() {
+		var item models.Item
+		var contentFormat sql.NullString
+		if err := rows.Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &contentFormat, &item.FaviconURL, &item.IconSource, &item.CustomIconURL, &item.PreviewImageURL, &item.OpenInNewTab, &item.IsPinned, &item.Position, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		item.ContentFormat = contentFormatOrDefault(contentFormat)
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read items: %w", err)
+	}
+
+	return items, nil
+}
+
+// CountItems returns how many items a user has across all boards
+func (db *DB) CountItems(userID int) (int, error) {
+	var count int
+	err := db.QueryRow(
+		`SELECT COUNT(*)
+		 FROM items i
+		 INNER JOIN lists l ON i.list_id = l.id
+		 WHERE l.user_id = ?`,
+		userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count items: %w", err)
+	}
+	return count, nil
+}
+
+// CountItemsByType returns how many items of each type a user has
+func (db *DB) CountItemsByType(userID int) (map[string]int, error) {
+	return db.countItemsByType(
+		`SELECT i.type, COUNT(*)
+		 FROM items i
+		 INNER JOIN lists l ON i.list_id = l.id
+		 WHERE l.user_id = ?
+		 GROUP BY i.type`,
+		userID,
+	)
+}
+
+// CountListItemsByType returns how many items of each type are in a list the user
+// owns; a list they don't own has none
+func (db *DB) CountListItemsByType(listID, userID int) (map[string]int, error) {
+	return db.countItemsByType(
+		`SELECT i.type, COUNT(*)
+		 FROM items i
+		 INNER JOIN lists l ON i.list_id = l.id
+		 WHERE l.id = ? AND l.user_id = ?
+		 GROUP BY i.type`,
+		listID, userID,
+	)
+}
+
+// countItemsByType runs a query returning (type, count) rows and collects them
+func (db *DB) countItemsByType(query string, args ...any) (map[string]int, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count items: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var itemType string
+		var count int
+		if err := rows.Scan(&itemType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan item count: %w", err)
+		}
+		counts[itemType] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read item counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// GetBookmarksNeedingFavicons retrieves bookmarks across all users whose favicon is
+// missing or still a remote http(s) URL rather than an embedded data URI
+func (db *DB) GetBookmarksNeedingFavicons() ([]*models.Item, error) {
+	rows, err := db.Query(
+		`SELECT id, list_id, type, title, url, content, content_format, favicon_url, icon_source, custom_icon_url, preview_image_url, open_in_new_tab, is_pinned, position, created_at
+		 FROM items
+		 WHERE type = 'bookmark' AND url IS NOT NULL
+		   AND (favicon_url IS NULL OR favicon_url LIKE 'http://%' OR favicon_url LIKE 'https://%')
+		 ORDER BY id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bookmarks needing favicons: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.Item
+	for rows.Next() {
+		var item models.Item
+		var contentFormat sql.NullString
+		if err := rows.Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &contentFormat, &item.FaviconURL, &item.IconSource, &item.CustomIconURL, &item.PreviewImageURL, &item.OpenInNewTab, &item.IsPinned, &item.Position, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		item.ContentFormat = contentFormatOrDefault(contentFormat)
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+// staleFaviconModifier is the datetime() modifier for the cutoff before which a favicon is stale
+func staleFaviconModifier(maxAge time.Duration) string {
+	return fmt.Sprintf("-%d seconds", int64(maxAge/time.Second))
+}
+
+// GetStaleFaviconBookmarks retrieves up to limit of a board's bookmarks whose favicon was
+// last fetched more than maxAge ago or never, least recently fetched first
+func (db *DB) GetStaleFaviconBookmarks(userID, boardID int, maxAge time.Duration, limit int) ([]*models.Item, error) {
+	rows, err := db.Query(
+		`SELECT i.id, i.list_id, i.type, i.title, i.url, i.content, i.content_format, i.favicon_url, i.icon_source, i.custom_icon_url, i.preview_image_url, i.open_in_new_tab, i.is_pinned, i.position, i.created_at
+		 FROM items i
+		 INNER JOIN lists l ON i.list_id = l.id
+		 WHERE l.user_id = ? AND l.board_id = ? AND i.type = 'bookmark' AND i.url IS NOT NULL
+		   AND (i.last_favicon_fetch IS NULL OR i.last_favicon_fetch < datetime('now', ?))
+		 ORDER BY i.last_favicon_fetch IS NOT NULL, i.last_favicon_fetch, i.id
+		 LIMIT ?`,
+		userID, boardID, staleFaviconModifier(maxAge), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stale favicon bookmarks: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.Item
+	for rows.Next() {
+		var item models.Item
+		var contentFormat sql.NullString
+		if err := rows.Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &contentFormat, &item.FaviconURL, &item.IconSource, &item.CustomIconURL, &item.PreviewImageURL, &item.OpenInNewTab, &item.IsPinned, &item.Position, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		item.ContentFormat = contentFormatOrDefault(contentFormat)
+		items = append(items, &item)
+	}
+
+	return items, rows.Err()
+}
+
+// IsFaviconStale reports whether a bookmark's favicon was last fetched more than maxAge ago or never
+func (db *DB) IsFaviconStale(itemID int, maxAge time.Duration) (bool, error) {
+	var stale bool
+	err := db.QueryRow(
+		`SELECT EXISTS(
+			SELECT 1 FROM items
+			WHERE id = ? AND type = 'bookmark' AND url IS NOT NULL
+			  AND (last_favicon_fetch IS NULL OR last_favicon_fetch < datetime('now', ?))
+		)`,
+		itemID, staleFaviconModifier(maxAge),
+	).Scan(&stale)
+	if err != nil {
+		return false, fmt.Errorf("failed to check favicon age: %w", err)
+	}
+	return stale, nil
+}
+
+// RecordFaviconFetch marks an item's favicon as fetched now, storing faviconURL unless it is nil
+// (a failed fetch keeps the old icon). The board isn't touched since the user changed nothing.
+func (db *DB) RecordFaviconFetch(itemID int, faviconURL *string) error {
+	if _, err := db.Exec(
+		"UPDATE items SET favicon_url = COALESCE(?, favicon_url), last_favicon_fetch = CURRENT_TIMESTAMP WHERE id = ?",
+		faviconURL, itemID,
+	); err != nil {
+		return fmt.Errorf("failed to record favicon fetch: %w", err)
+	}
+	return nil
+}
+
+// SetItemPreviewImage stores an item's preview image, or clears it when previewImageURL is nil
+func (db *DB) SetItemPreviewImage(itemID int, previewImageURL *string) error {
+	if _, err := db.Exec("UPDATE items SET preview_image_url = ? WHERE id = ?", previewImageURL, itemID); err != nil {
+		return fmt.Errorf("failed to store preview image: %w", err)
+	}
+	return nil
+}
+
+// SetItemsPinned pins or unpins several of the user's items in one transaction and
+// returns them, failing with "items not found" unless the user owns every one
+func (db *DB) SetItemsPinned(userID int, itemIDs []int, pinned bool) ([]*models.Item, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	placeholders := make([]string, len(itemIDs))
+	itemArgs := make([]interface{}, len(itemIDs))
+	for i, id := range itemIDs {
+		placeholders[i] = "?"
+		itemArgs[i] = id
+	}
+	inClause := strings.Join(placeholders, ",")
+
+	// Verify all items belong to the user in a single query
+	var itemCount int
+	err = tx.QueryRow(
+		fmt.Sprintf("SELECT COUNT(DISTINCT i.id) FROM items i JOIN lists l ON i.list_id = l.id WHERE i.id IN (%s) AND l.user_id = ?", inClause),
+		append(itemArgs, userID)...,
+	).Scan(&itemCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify ownership: %w", err)
+	}
+	if itemCount != len(itemIDs) {
+		return nil, fmt.Errorf("items not found")
+	}
+
+	if _, err := tx.Exec(
+		fmt.Sprintf("UPDATE items SET is_pinned = ? WHERE id IN (%s)", inClause),
+		append([]interface{}{pinned}, itemArgs...)...,
+	); err != nil {
+		return nil, fmt.Errorf("failed to update pinned state: %w", err)
+	}
+
+	if _, err := tx.Exec(
+		fmt.Sprintf("UPDATE boards SET updated_at = CURRENT_TIMESTAMP WHERE id IN (SELECT l.board_id FROM lists l JOIN items i ON i.list_id = l.id WHERE i.id IN (%s))", inClause),
+		itemArgs...,
+	); err != nil {
+		return nil, fmt.Errorf("failed to touch boards: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return db.GetItemsByIDs(itemIDs, userID)
+}
+
+// GetItemsByIDs retrieves the items with the given IDs that belong to a user, silently omitting the rest
+func (db *DB) GetItemsByIDs(ids []int, userID int) ([]*models.Item, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]any, 0, len(ids)+1)
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+	args = append(args, userID)
+
+	query := fmt.Sprintf(
+		`SELECT i.id, i.list_id, i.type, i.title, i.url, i.content, i.content_format, i.favicon_url, i.icon_source, i.custom_icon_url, i.preview_image_url, i.open_in_new_tab, i.is_pinned, i.position, i.created_at
+		 FROM items i
+		 INNER JOIN lists l ON i.list_id = l.id
+		 WHERE i.id IN (%s) AND l.user_id = ?
+		 ORDER BY i.list_id, i.position`,
+		strings.Join(placeholders, ","),
+	)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get items by IDs: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.Item
+	for rows.Next() {
+		var item models.Item
+		var contentFormat sql.NullString
+		if err := rows.Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &contentFormat, &item.FaviconURL, &item.IconSource, &item.CustomIconURL, &item.PreviewImageURL, &item.OpenInNewTab, &item.IsPinned, &item.Position, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		item.ContentFormat = contentFormatOrDefault(contentFormat)
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read items: %w", err)
+	}
+
 	return items, nil
 }
 
@@ -145,16 +815,16 @@ func (db *DB) UpdateItem(id int, title, url, content *string, faviconURL **strin
 		args = append(args, *title)
 	}
 	if url != nil {
-		updates = append(updates, "url = ?")
-		args = append(args, *url)
+		updates = append(updates, "url = ?", "normalized_url = ?")
+		args = append(args, *url, normalizedURL(url))
 	}
 	if content != nil {
 		updates = append(updates, "content = ?")
 		args = append(args, *content)
 	}
 	if faviconURL != nil {
-		updates = append(updates, "favicon_url = ?")
-		args = append(args, *faviconURL)
+		updates = append(updates, "favicon_url = ?", "last_favicon_fetch = "+faviconFetchedSQL)
+		args = append(args, *faviconURL, *faviconURL)
 	}
 
 	if len(updates) == 0 {
@@ -183,6 +853,8 @@ func (db *DB) UpdateItem(id int, title, url, content *string, faviconURL **strin
 		return fmt.Errorf("item not found")
 	}
 
+	db.touchBoardByItem(id)
+
 	return nil
 }
 
@@ -198,12 +870,16 @@ func (db *DB) UpdateItemFields(id int, fields map[string]interface{}) error {
 
 	// Allowed fields for update
 	allowedFields := map[string]bool{
-		"title":           true,
-		"url":             true,
-		"content":         true,
-		"favicon_url":     true,
-		"icon_source":     true,
-		"custom_icon_url": true,
+		"title":             true,
+		"url":               true,
+		"content":           true,
+		"content_format":    true,
+		"favicon_url":       true,
+		"icon_source":       true,
+		"custom_icon_url":   true,
+		"preview_image_url": true,
+		"open_in_new_tab":   true,
+		"is_pinned":         true,
 	}
 
 	for field, value := range fields {
@@ -212,6 +888,21 @@ func (db *DB) UpdateItemFields(id int, fields map[string]interface{}) error {
 		}
 		updates = append(updates, field+" = ?")
 		args = append(args, value)
+		if field == "favicon_url" {
+			updates = append(updates, "last_favicon_fetch = "+faviconFetchedSQL)
+			args = append(args, value)
+		}
+		if field == "url" {
+			var url *string
+			switch v := value.(type) {
+			case string:
+				url = &v
+			case *string:
+				url = v
+			}
+			updates = append(updates, "normalized_url = ?")
+			args = append(args, normalizedURL(url))
+		}
 	}
 
 	if len(updates) == 0 {
@@ -240,11 +931,17 @@ func (db *DB) UpdateItemFields(id int, fields map[string]interface{}) error {
 		return fmt.Errorf("item not found")
 	}
 
+	db.touchBoardByItem(id)
+
 	return nil
 }
 
 // DeleteItem deletes an item
 func (db *DB) DeleteItem(id int) error {
+	// Look up the list first; the board can only be found while the item exists
+	var listID int
+	db.QueryRow("SELECT list_id FROM items WHERE id = ?", id).Scan(&listID)
+
 	result, err := db.Exec("DELETE FROM items WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete item: %w", err)
@@ -259,9 +956,16 @@ func (db *DB) DeleteItem(id int) error {
 		return fmt.Errorf("item not found")
 	}
 
+	db.TouchBoardByList(listID)
+
 	return nil
 }
 
+// touchBoardByItem updates the updated_at timestamp for the board that owns an item
+func (db *DB) touchBoardByItem(itemID int) {
+	db.Exec("UPDATE boards SET updated_at = CURRENT_TIMESTAMP WHERE id = (SELECT l.board_id FROM lists l JOIN items i ON i.list_id = l.id WHERE i.id = ?)", itemID)
+}
+
 // UpdateItemPositions updates positions for multiple items
 func (db *DB) UpdateItemPositions(positions map[int]struct {
 	Position int
@@ -317,6 +1021,67 @@ func (db *DB) UpdateItemPositions(positions map[int]struct {
 	return nil
 }
 
+// MoveItemToEdge moves an item to the top (or bottom) of its list, renumbering the
+// list's positions in one transaction, and returns the list's items in their new order
+func (db *DB) MoveItemToEdge(itemID, userID int, toTop bool) ([]*models.Item, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var listID int
+	err = tx.QueryRow(
+		`SELECT i.list_id FROM items i
+		 INNER JOIN lists l ON i.list_id = l.id
+		 WHERE i.id = ? AND l.user_id = ?`,
+		itemID, userID,
+	).Scan(&listID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("item not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	rows, err := tx.Query("SELECT id FROM items WHERE list_id = ? AND id != ? ORDER BY position, id", listID, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get items: %w", err)
+	}
+	var others []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		others = append(others, id)
+	}
+	rows.Close()
+
+	ordered := append([]int{itemID}, others...)
+	if !toTop {
+		ordered = append(others, itemID)
+	}
+
+	for position, id := range ordered {
+		if _, err := tx.Exec("UPDATE items SET position = ? WHERE id = ?", position, id); err != nil {
+			return nil, fmt.Errorf("failed to update item position: %w", err)
+		}
+	}
+
+	// Touch the board so "recently updated" ordering reflects the move
+	if _, err := tx.Exec("UPDATE boards SET updated_at = CURRENT_TIMESTAMP WHERE id = (SELECT board_id FROM lists WHERE id = ?)", listID); err != nil {
+		return nil, fmt.Errorf("failed to touch board: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return db.GetItems(listID)
+}
+
 // VerifyItemOwnership checks if an item belongs to a user (through the list)
 func (db *DB) VerifyItemOwnership(itemID, userID int) (bool, error) {
 	var exists bool
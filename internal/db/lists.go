@@ -1,17 +1,26 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/crueber/loom/internal/models"
 )
 
 // CreateList creates a new list
-func (db *DB) CreateList(userID int, boardID int, title, color string, position int) (*models.List, error) {
+func (db *DB) CreateList(userID int, boardID int, title, color string, position int, collapsed bool) (*models.List, error) {
+	return db.CreateListAt(userID, boardID, title, color, position, collapsed, time.Time{})
+}
+
+// CreateListAt is like CreateList but records createdAt as the creation time (zero means now)
+func (db *DB) CreateListAt(userID int, boardID int, title, color string, position int, collapsed bool, createdAt time.Time) (*models.List, error) {
 	result, err := db.Exec(
-		"INSERT INTO lists (user_id, board_id, title, color, position) VALUES (?, ?, ?, ?, ?)",
-		userID, boardID, title, color, position,
+		"INSERT INTO lists (user_id, board_id, title, color, position, collapsed, created_at) VALUES (?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))",
+		userID, boardID, title, color, position, collapsed, sqlTimestamp(createdAt),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create list: %w", err)
@@ -28,13 +37,86 @@ func (db *DB) CreateList(userID int, boardID int, title, color string, position
 	return db.GetList(int(id), userID)
 }
 
+// NewList describes a list to create with CreateLists
+type NewList struct {
+	Title     string
+	Color     string
+	Collapsed bool
+}
+
+// CreateLists appends several lists to a board in one transaction, in the given order,
+// and returns them with their IDs and positions
+func (db *DB) CreateLists(userID, boardID int, lists []NewList) ([]*models.List, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var boardExists bool
+	err = tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM boards WHERE id = ? AND user_id = ?)",
+		boardID, userID,
+	).Scan(&boardExists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify board ownership: %w", err)
+	}
+	if !boardExists {
+		return nil, fmt.Errorf("board not found")
+	}
+
+	var position int
+	err = tx.QueryRow(
+		"SELECT COALESCE(MAX(position), -1) + 1 FROM lists WHERE board_id = ? AND user_id = ?",
+		boardID, userID,
+	).Scan(&position)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get next list position: %w", err)
+	}
+
+	ids := make([]int, 0, len(lists))
+	for i, list := range lists {
+		result, err := tx.Exec(
+			"INSERT INTO lists (user_id, board_id, title, color, position, collapsed) VALUES (?, ?, ?, ?, ?, ?)",
+			userID, boardID, list.Title, list.Color, position+i, list.Collapsed,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create list: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get list ID: %w", err)
+		}
+		ids = append(ids, int(id))
+	}
+
+	if _, err := tx.Exec("UPDATE boards SET updated_at = CURRENT_TIMESTAMP WHERE id = ?", boardID); err != nil {
+		return nil, fmt.Errorf("failed to touch board: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	created := make([]*models.List, 0, len(ids))
+	for _, id := range ids {
+		list, err := db.GetList(id, userID)
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, list)
+	}
+
+	return created, nil
+}
+
 // GetList retrieves a list by ID and user ID
 func (db *DB) GetList(id, userID int) (*models.List, error) {
 	var list models.List
 	err := db.QueryRow(
-		"SELECT id, user_id, board_id, title, color, position, collapsed, created_at FROM lists WHERE id = ? AND user_id = ?",
+		"SELECT id, user_id, board_id, title, color, position, collapsed, sort_mode, created_at FROM lists WHERE id = ? AND user_id = ?",
 		id, userID,
-	).Scan(&list.ID, &list.UserID, &list.BoardID, &list.Title, &list.Color, &list.Position, &list.Collapsed, &list.CreatedAt)
+	).Scan(&list.ID, &list.UserID, &list.BoardID, &list.Title, &list.Color, &list.Position, &list.Collapsed, &list.SortMode, &list.CreatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -48,8 +130,16 @@ func (db *DB) GetList(id, userID int) (*models.List, error) {
 
 // GetLists retrieves all lists for a user
 func (db *DB) GetLists(userID int) ([]*models.List, error) {
-	rows, err := db.Query(
-		"SELECT id, user_id, board_id, title, color, position, collapsed, created_at FROM lists WHERE user_id = ? ORDER BY position",
+	return db.GetListsContext(context.Background(), userID)
+}
+
+// GetListsContext is like GetLists but honors ctx and the configured query timeout
+func (db *DB) GetListsContext(ctx context.Context, userID int) ([]*models.List, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx,
+		"SELECT id, user_id, board_id, title, color, position, collapsed, sort_mode, created_at FROM lists WHERE user_id = ? ORDER BY position",
 		userID,
 	)
 	if err != nil {
@@ -60,19 +150,31 @@ func (db *DB) GetLists(userID int) ([]*models.List, error) {
 	var lists []*models.List
 	for rows.Next() {
 		var list models.List
-		if err := rows.Scan(&list.ID, &list.UserID, &list.BoardID, &list.Title, &list.Color, &list.Position, &list.Collapsed, &list.CreatedAt); err != nil {
+		if err := rows.Scan(&list.ID, &list.UserID, &list.BoardID, &list.Title, &list.Color, &list.Position, &list.Collapsed, &list.SortMode, &list.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan list: %w", err)
 		}
 		lists = append(lists, &list)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read lists: %w", err)
+	}
+
 	return lists, nil
 }
 
 // GetListsByBoard retrieves all lists for a specific board
 func (db *DB) GetListsByBoard(userID int, boardID int) ([]*models.List, error) {
-	rows, err := db.Query(
-		"SELECT id, user_id, board_id, title, color, position, collapsed, created_at FROM lists WHERE user_id = ? AND board_id = ? ORDER BY position",
+	return db.GetListsByBoardContext(context.Background(), userID, boardID)
+}
+
+// GetListsByBoardContext is like GetListsByBoard but honors ctx and the configured query timeout
+func (db *DB) GetListsByBoardContext(ctx context.Context, userID int, boardID int) ([]*models.List, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx,
+		"SELECT id, user_id, board_id, title, color, position, collapsed, sort_mode, created_at FROM lists WHERE user_id = ? AND board_id = ? ORDER BY position",
 		userID, boardID,
 	)
 	if err != nil {
@@ -83,17 +185,21 @@ func (db *DB) GetListsByBoard(userID int, boardID int) ([]*models.List, error) {
 	var lists []*models.List
 	for rows.Next() {
 		var list models.List
-		if err := rows.Scan(&list.ID, &list.UserID, &list.BoardID, &list.Title, &list.Color, &list.Position, &list.Collapsed, &list.CreatedAt); err != nil {
+		if err := rows.Scan(&list.ID, &list.UserID, &list.BoardID, &list.Title, &list.Color, &list.Position, &list.Collapsed, &list.SortMode, &list.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan list: %w", err)
 		}
 		lists = append(lists, &list)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read lists: %w", err)
+	}
+
 	return lists, nil
 }
 
 // UpdateList updates a list
-func (db *DB) UpdateList(id, userID int, title, color *string, collapsed *bool) error {
+func (db *DB) UpdateList(id, userID int, title, color *string, collapsed *bool, sortMode *string) error {
 	query := "UPDATE lists SET "
 	args := []any{}
 	updates := []string{}
@@ -110,6 +216,10 @@ func (db *DB) UpdateList(id, userID int, title, color *string, collapsed *bool)
 		updates = append(updates, "collapsed = ?")
 		args = append(args, *collapsed)
 	}
+	if sortMode != nil {
+		updates = append(updates, "sort_mode = ?")
+		args = append(args, *sortMode)
+	}
 
 	if len(updates) == 0 {
 		return nil
@@ -181,8 +291,167 @@ func (db *DB) UpdateListPositions(userID int, positions map[int]int) error {
 	return nil
 }
 
-// MoveOrCopyListToBoard moves or copies a list (with all its items) to another board
-func (db *DB) MoveOrCopyListToBoard(listID, userID, targetBoardID int, copy bool) (*models.List, error) {
+// List sort keys accepted by SortBoardLists
+const (
+	ListSortTitle   = "title"
+	ListSortCreated = "created"
+)
+
+// ListSortManual is the default lists.sort_mode: items keep their dragged positions.
+// A list's sort_mode can also be ListSortTitle or ListSortCreated to order its items
+// by title or oldest first.
+const ListSortManual = "manual"
+
+// IsValidListSortMode reports whether mode is a supported lists.sort_mode value
+func IsValidListSortMode(mode string) bool {
+	return mode == ListSortManual || mode == ListSortTitle || mode == ListSortCreated
+}
+
+// listSortOrders maps list sort keys to ORDER BY clauses
+var listSortOrders = map[string]string{
+	ListSortTitle:   "title COLLATE NOCASE, id",
+	ListSortCreated: "created_at, id",
+}
+
+// IsValidListSort reports whether by is a supported list sort key
+func IsValidListSort(by string) bool {
+	_, ok := listSortOrders[by]
+	return ok
+}
+
+// SortBoardLists renumbers a board's list positions in the given order and returns the reordered lists
+func (db *DB) SortBoardLists(boardID, userID int, by string) ([]*models.List, error) {
+	orderBy, ok := listSortOrders[by]
+	if !ok {
+		return nil, fmt.Errorf("invalid list sort: %s", by)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var boardExists bool
+	err = tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM boards WHERE id = ? AND user_id = ?)",
+		boardID, userID,
+	).Scan(&boardExists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify board ownership: %w", err)
+	}
+	if !boardExists {
+		return nil, fmt.Errorf("board not found")
+	}
+
+	rows, err := tx.Query(
+		"SELECT id FROM lists WHERE board_id = ? AND user_id = ? ORDER BY "+orderBy,
+		boardID, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lists: %w", err)
+	}
+	var listIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan list: %w", err)
+		}
+		listIDs = append(listIDs, id)
+	}
+	rows.Close()
+
+	for i, id := range listIDs {
+		if _, err := tx.Exec("UPDATE lists SET position = ? WHERE id = ? AND user_id = ?", i, id, userID); err != nil {
+			return nil, fmt.Errorf("failed to update list position: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return db.GetListsByBoard(userID, boardID)
+}
+
+// Directions accepted by MoveListWithinBoard
+const (
+	ListMoveUp   = "up"
+	ListMoveDown = "down"
+)
+
+// MoveListWithinBoard moves a list one step up or down among its board's lists, or to index
+// position when direction is empty (clamped to the board's bounds). It renumbers the board's
+// positions in one transaction and returns the board's lists in their new order.
+func (db *DB) MoveListWithinBoard(listID, userID int, direction string, position int) ([]*models.List, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var boardID int
+	err = tx.QueryRow("SELECT board_id FROM lists WHERE id = ? AND user_id = ?", listID, userID).Scan(&boardID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("list not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list: %w", err)
+	}
+
+	rows, err := tx.Query(
+		"SELECT id FROM lists WHERE board_id = ? AND user_id = ? ORDER BY position, id",
+		boardID, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lists: %w", err)
+	}
+	var others []int
+	current := 0
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan list: %w", err)
+		}
+		if id == listID {
+			current = len(others)
+			continue
+		}
+		others = append(others, id)
+	}
+	rows.Close()
+
+	switch direction {
+	case ListMoveUp:
+		position = current - 1
+	case ListMoveDown:
+		position = current + 1
+	case "":
+	default:
+		return nil, fmt.Errorf("invalid direction: %s", direction)
+	}
+	position = max(0, min(position, len(others)))
+	ordered := slices.Insert(others, position, listID)
+
+	for i, id := range ordered {
+		if _, err := tx.Exec("UPDATE lists SET position = ? WHERE id = ? AND user_id = ?", i, id, userID); err != nil {
+			return nil, fmt.Errorf("failed to update list position: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return db.GetListsByBoard(userID, boardID)
+}
+
+// MoveOrCopyListToBoard moves or copies a list (with all its items) to another board.
+// If position is nil the list is appended; otherwise it is inserted at that index and
+// the target board's lists are renumbered.
+func (db *DB) MoveOrCopyListToBoard(listID, userID, targetBoardID int, copy bool, position *int) (*models.List, error) {
 	tx, err := db.Begin()
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
@@ -192,9 +461,9 @@ func (db *DB) MoveOrCopyListToBoard(listID, userID, targetBoardID int, copy bool
 	// Verify list ownership and get list details
 	var list models.List
 	err = tx.QueryRow(
-		"SELECT id, user_id, board_id, title, color, position, collapsed, created_at FROM lists WHERE id = ? AND user_id = ?",
+		"SELECT id, user_id, board_id, title, color, position, collapsed, sort_mode, created_at FROM lists WHERE id = ? AND user_id = ?",
 		listID, userID,
-	).Scan(&list.ID, &list.UserID, &list.BoardID, &list.Title, &list.Color, &list.Position, &list.Collapsed, &list.CreatedAt)
+	).Scan(&list.ID, &list.UserID, &list.BoardID, &list.Title, &list.Color, &list.Position, &list.Collapsed, &list.SortMode, &list.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("list not found")
 	}
@@ -221,11 +490,41 @@ func (db *DB) MoveOrCopyListToBoard(listID, userID, targetBoardID int, copy bool
 
 	newPosition := maxPosition + 1
 
+	// Collect the target board's current order (without the list being moved) for positional inserts
+	var targetListIDs []int
+	if position != nil {
+		excludeID := 0
+		if !copy {
+			excludeID = listID
+		}
+		rows, err := tx.Query(
+			"SELECT id FROM lists WHERE board_id = ? AND user_id = ? AND id != ? ORDER BY position, id",
+			targetBoardID, userID, excludeID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get target lists: %w", err)
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan target list: %w", err)
+			}
+			targetListIDs = append(targetListIDs, id)
+		}
+		rows.Close()
+
+		if *position < 0 || *position > len(targetListIDs) {
+			return nil, fmt.Errorf("position out of range")
+		}
+		newPosition = *position
+	}
+
 	if copy {
 		// Create a copy of the list
 		result, err := tx.Exec(
-			"INSERT INTO lists (user_id, board_id, title, color, position, collapsed) VALUES (?, ?, ?, ?, ?, ?)",
-			userID, targetBoardID, list.Title+" (copy)", list.Color, newPosition, list.Collapsed,
+			"INSERT INTO lists (user_id, board_id, title, color, position, collapsed, sort_mode) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			userID, targetBoardID, list.Title+" (copy)", list.Color, newPosition, list.Collapsed, list.SortMode,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to copy list: %w", err)
@@ -238,7 +537,7 @@ func (db *DB) MoveOrCopyListToBoard(listID, userID, targetBoardID int, copy bool
 
 		// Copy all items from the original list to the new list
 		_, err = tx.Exec(
-			"INSERT INTO items (list_id, type, title, url, content, favicon_url, position) SELECT ?, type, title, url, content, favicon_url, position FROM items WHERE list_id = ?",
+			"INSERT INTO items (list_id, type, title, url, normalized_url, content, content_format, favicon_url, icon_source, custom_icon_url, open_in_new_tab, is_pinned, preview_image_url, position, last_favicon_fetch) SELECT ?, type, title, url, normalized_url, content, content_format, favicon_url, icon_source, custom_icon_url, open_in_new_tab, is_pinned, preview_image_url, position, last_favicon_fetch FROM items WHERE list_id = ?",
 			newListID, listID,
 		)
 		if err != nil {
@@ -263,6 +562,20 @@ func (db *DB) MoveOrCopyListToBoard(listID, userID, targetBoardID int, copy bool
 		list.Position = newPosition
 	}
 
+	// Shift subsequent lists so the positions in the target board stay contiguous
+	if position != nil {
+		ordered := make([]int, 0, len(targetListIDs)+1)
+		ordered = append(ordered, targetListIDs[:newPosition]...)
+		ordered = append(ordered, list.ID)
+		ordered = append(ordered, targetListIDs[newPosition:]...)
+
+		for i, id := range ordered {
+			if _, err := tx.Exec("UPDATE lists SET position = ? WHERE id = ? AND user_id = ?", i, id, userID); err != nil {
+				return nil, fmt.Errorf("failed to update list position: %w", err)
+			}
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -270,6 +583,308 @@ func (db *DB) MoveOrCopyListToBoard(listID, userID, targetBoardID int, copy bool
 	return &list, nil
 }
 
+// AdoptLists moves lists (with their items) onto a board in one transaction, appending
+// them in the given order. Returns "lists not found" unless the board and every list
+// belong to the user.
+func (db *DB) AdoptLists(userID, boardID int, listIDs []int) ([]*models.List, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	placeholders := make([]string, len(listIDs))
+	listArgs := make([]interface{}, len(listIDs))
+	for i, id := range listIDs {
+		placeholders[i] = "?"
+		listArgs[i] = id
+	}
+	inClause := strings.Join(placeholders, ",")
+
+	// Verify the board and all lists belong to the user in a single query
+	query := fmt.Sprintf(`
+		SELECT
+			EXISTS(SELECT 1 FROM boards WHERE id = ? AND user_id = ?) as board_exists,
+			(SELECT COUNT(DISTINCT id) FROM lists WHERE id IN (%s) AND user_id = ?) as list_count
+	`, inClause)
+	args := append([]interface{}{boardID, userID}, listArgs...)
+	args = append(args, userID)
+
+	var boardExists bool
+	var listCount int
+	if err := tx.QueryRow(query, args...).Scan(&boardExists, &listCount); err != nil {
+		return nil, fmt.Errorf("failed to verify ownership: %w", err)
+	}
+	if !boardExists || listCount != len(listIDs) {
+		return nil, fmt.Errorf("lists not found")
+	}
+
+	// Append after the board's remaining lists so adopted lists already on it move to the end
+	var position int
+	err = tx.QueryRow(
+		fmt.Sprintf("SELECT COALESCE(MAX(position), -1) + 1 FROM lists WHERE board_id = ? AND user_id = ? AND id NOT IN (%s)", inClause),
+		append([]interface{}{boardID, userID}, listArgs...)...,
+	).Scan(&position)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get next list position: %w", err)
+	}
+
+	for i, id := range listIDs {
+		if _, err := tx.Exec(
+			"UPDATE lists SET board_id = ?, position = ? WHERE id = ? AND user_id = ?",
+			boardID, position+i, id, userID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to move list: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE boards SET updated_at = CURRENT_TIMESTAMP WHERE id = ?", boardID); err != nil {
+		return nil, fmt.Errorf("failed to touch board: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	moved := make([]*models.List, 0, len(listIDs))
+	for _, id := range listIDs {
+		list, err := db.GetList(id, userID)
+		if err != nil {
+			return nil, err
+		}
+		moved = append(moved, list)
+	}
+
+	return moved, nil
+}
+
+// GetOrphanedLists retrieves every user's lists that are not on one of that user's boards,
+// which the board-scoped UI can never show
+func (db *DB) GetOrphanedLists() ([]*models.OrphanedList, error) {
+	rows, err := db.Query(`
+		SELECT l.id, l.user_id, l.board_id, l.title
+		FROM lists l
+		LEFT JOIN boards b ON l.board_id = b.id
+		WHERE b.id IS NULL OR b.user_id != l.user_id
+		ORDER BY l.user_id, l.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orphaned lists: %w", err)
+	}
+	defer rows.Close()
+
+	var lists []*models.OrphanedList
+	for rows.Next() {
+		var list models.OrphanedList
+		if err := rows.Scan(&list.ID, &list.UserID, &list.BoardID, &list.Title); err != nil {
+			return nil, fmt.Errorf("failed to scan list: %w", err)
+		}
+		lists = append(lists, &list)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read lists: %w", err)
+	}
+
+	return lists, nil
+}
+
+// ReassignOrphanedLists moves every orphaned list (see GetOrphanedLists), with its items,
+// to the end of its owner's default board in one transaction, creating the board if the
+// owner has none. It returns how many lists were moved for each user.
+func (db *DB) ReassignOrphanedLists() (map[int]int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT l.id, l.user_id
+		FROM lists l
+		LEFT JOIN boards b ON l.board_id = b.id
+		WHERE b.id IS NULL OR b.user_id != l.user_id
+		ORDER BY l.user_id, l.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orphaned lists: %w", err)
+	}
+	var userIDs []int
+	listIDsByUser := make(map[int][]int)
+	for rows.Next() {
+		var listID, userID int
+		if err := rows.Scan(&listID, &userID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan list: %w", err)
+		}
+		if _, seen := listIDsByUser[userID]; !seen {
+			userIDs = append(userIDs, userID)
+		}
+		listIDsByUser[userID] = append(listIDsByUser[userID], listID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read lists: %w", err)
+	}
+
+	moved := make(map[int]int, len(userIDs))
+	for _, userID := range userIDs {
+		var boardID int
+		err := tx.QueryRow("SELECT id FROM boards WHERE user_id = ? AND is_default = 1", userID).Scan(&boardID)
+		if err == sql.ErrNoRows {
+			result, err := tx.Exec(`
+				INSERT INTO boards (user_id, title, is_default, updated_at, created_at)
+				VALUES (?, 'Default', 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			`, userID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create default board: %w", err)
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get board ID: %w", err)
+			}
+			boardID = int(id)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get default board: %w", err)
+		}
+
+		var position int
+		err = tx.QueryRow(
+			"SELECT COALESCE(MAX(position), -1) + 1 FROM lists WHERE board_id = ? AND user_id = ?",
+			boardID, userID,
+		).Scan(&position)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next list position: %w", err)
+		}
+
+		for i, listID := range listIDsByUser[userID] {
+			if _, err := tx.Exec(
+				"UPDATE lists SET board_id = ?, position = ? WHERE id = ? AND user_id = ?",
+				boardID, position+i, listID, userID,
+			); err != nil {
+				return nil, fmt.Errorf("failed to move list: %w", err)
+			}
+		}
+
+		if _, err := tx.Exec("UPDATE boards SET updated_at = CURRENT_TIMESTAMP WHERE id = ?", boardID); err != nil {
+			return nil, fmt.Errorf("failed to touch board: %w", err)
+		}
+		moved[userID] = len(listIDsByUser[userID])
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return moved, nil
+}
+
+// RenameLists sets the titles of several of the user's lists in one transaction. When
+// uniqueTitles is set, no renamed list may share its title with another list on its board.
+func (db *DB) RenameLists(userID int, titles map[int]string, uniqueTitles bool) ([]*models.List, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	listIDs := make([]int, 0, len(titles))
+	for id := range titles {
+		listIDs = append(listIDs, id)
+	}
+	slices.Sort(listIDs)
+
+	placeholders := make([]string, len(listIDs))
+	listArgs := make([]interface{}, len(listIDs))
+	for i, id := range listIDs {
+		placeholders[i] = "?"
+		listArgs[i] = id
+	}
+	inClause := strings.Join(placeholders, ",")
+
+	// Verify all lists belong to the user in a single query
+	var listCount int
+	err = tx.QueryRow(
+		fmt.Sprintf("SELECT COUNT(*) FROM lists WHERE id IN (%s) AND user_id = ?", inClause),
+		append(listArgs, userID)...,
+	).Scan(&listCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify ownership: %w", err)
+	}
+	if listCount != len(listIDs) {
+		return nil, fmt.Errorf("lists not found")
+	}
+
+	for _, id := range listIDs {
+		if _, err := tx.Exec("UPDATE lists SET title = ? WHERE id = ? AND user_id = ?", titles[id], id, userID); err != nil {
+			return nil, fmt.Errorf("failed to rename list: %w", err)
+		}
+	}
+
+	// Check after all renames are applied so titles can be swapped within the batch
+	if uniqueTitles {
+		var duplicate bool
+		err = tx.QueryRow(fmt.Sprintf(`
+			SELECT EXISTS(
+				SELECT 1 FROM lists a
+				INNER JOIN lists b ON b.board_id = a.board_id AND b.user_id = a.user_id AND b.id != a.id AND b.title = a.title COLLATE NOCASE
+				WHERE a.id IN (%s) AND a.user_id = ?
+			)
+		`, inClause), append(listArgs, userID)...).Scan(&duplicate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check list titles: %w", err)
+		}
+		if duplicate {
+			return nil, fmt.Errorf("list title already exists")
+		}
+	}
+
+	if _, err := tx.Exec(
+		fmt.Sprintf("UPDATE boards SET updated_at = CURRENT_TIMESTAMP WHERE id IN (SELECT board_id FROM lists WHERE id IN (%s))", inClause),
+		listArgs...,
+	); err != nil {
+		return nil, fmt.Errorf("failed to touch boards: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	renamed := make([]*models.List, 0, len(listIDs))
+	for _, id := range listIDs {
+		list, err := db.GetList(id, userID)
+		if err != nil {
+			return nil, err
+		}
+		renamed = append(renamed, list)
+	}
+
+	return renamed, nil
+}
+
+// DuplicateList copies a list and its items into the same board, directly after the original
+func (db *DB) DuplicateList(listID, userID int) (*models.List, error) {
+	list, err := db.GetList(listID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		return nil, fmt.Errorf("list not found")
+	}
+
+	// Count the lists ordered before the original (inclusive) to find the slot right after it
+	var position int
+	err = db.QueryRow(
+		"SELECT COUNT(*) FROM lists WHERE board_id = ? AND user_id = ? AND (position < ? OR (position = ? AND id <= ?))",
+		list.BoardID, userID, list.Position, list.Position, list.ID,
+	).Scan(&position)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list position: %w", err)
+	}
+
+	return db.MoveOrCopyListToBoard(listID, userID, list.BoardID, true, &position)
+}
+
 // VerifyListOwnership checks if a list belongs to a user
 func (db *DB) VerifyListOwnership(listID, userID int) (bool, error) {
 	var exists bool
@@ -279,3 +894,17 @@ func (db *DB) VerifyListOwnership(listID, userID int) (bool, error) {
 	}
 	return exists, nil
 }
+
+// ListTitleExists checks if a user's board already has a list with the given title (case-insensitive),
+// ignoring excludeListID so a list can keep its own title on update
+func (db *DB) ListTitleExists(boardID, userID int, title string, excludeListID int) (bool, error) {
+	var exists bool
+	err := db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM lists WHERE board_id = ? AND user_id = ? AND title = ? COLLATE NOCASE AND id != ?)",
+		boardID, userID, title, excludeListID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check list title: %w", err)
+	}
+	return exists, nil
+}
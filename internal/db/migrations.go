@@ -8,7 +8,10 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"sync"
 	"time"
+
+	"github.com/crueber/loom/internal/urlutil"
 )
 
 // migrate runs all database migrations
@@ -29,6 +32,8 @@ func (db *DB) migrate() error {
 	migrations := []struct {
 		version int
 		sql     string
+		// deferred migrations are skipped and left unrecorded, so they run on a later start
+		deferred bool
 	}{
 		{
 			version: 1,
@@ -189,6 +194,178 @@ func (db *DB) migrate() error {
 				CREATE INDEX IF NOT EXISTS idx_items_list_position_v10 ON items(list_id, position);
 			`,
 		},
+		{
+			version: 11,
+			sql: `
+				-- Migration v11: Add content format for notes
+				-- Tells the frontend how to render note content ('text', 'markdown', 'html')
+				ALTER TABLE items ADD COLUMN content_format TEXT DEFAULT 'markdown';
+			`,
+		},
+		{
+			version: 12,
+			sql: `
+				-- Migration v12: Add public read flag to boards
+				-- Public boards can be read (GET only) without authentication
+				ALTER TABLE boards ADD COLUMN public_read INTEGER DEFAULT 0;
+			`,
+		},
+		{
+			version: 13,
+			sql: `
+				-- Migration v13: Add archived flag to boards
+				-- Archived boards are hidden from the board switcher but kept intact
+				ALTER TABLE boards ADD COLUMN archived INTEGER DEFAULT 0;
+			`,
+		},
+		{
+			version: 14,
+			sql: `
+				-- Migration v14: Add per-item link target preference
+				-- Bookmarks open in a new tab unless the user turns it off
+				ALTER TABLE items ADD COLUMN open_in_new_tab INTEGER NOT NULL DEFAULT 1;
+			`,
+		},
+		{
+			version: 15,
+			sql: `
+				-- Migration v15: Allow separator items
+				-- SQLite cannot alter a CHECK constraint, so the items table is rebuilt.
+				-- Every column is copied as-is, so the old table is dropped rather than kept.
+				CREATE TABLE items_new (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					list_id INTEGER NOT NULL,
+					type TEXT NOT NULL CHECK(type IN ('bookmark', 'note', 'separator')),
+					title TEXT,
+					url TEXT,
+					content TEXT,
+					favicon_url TEXT,
+					position INTEGER NOT NULL,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					icon_source TEXT DEFAULT 'auto',
+					custom_icon_url TEXT,
+					content_format TEXT DEFAULT 'markdown',
+					open_in_new_tab INTEGER NOT NULL DEFAULT 1,
+					FOREIGN KEY (list_id) REFERENCES lists(id) ON DELETE CASCADE
+				);
+
+				INSERT INTO items_new (id, list_id, type, title, url, content, favicon_url, position, created_at, icon_source, custom_icon_url, content_format, open_in_new_tab)
+				SELECT id, list_id, type, title, url, content, favicon_url, position, created_at, icon_source, custom_icon_url, content_format, open_in_new_tab
+				FROM items;
+
+				DROP TABLE items;
+				ALTER TABLE items_new RENAME TO items;
+
+				CREATE INDEX IF NOT EXISTS idx_items_list_id ON items(list_id);
+				CREATE INDEX IF NOT EXISTS idx_items_position ON items(list_id, position);
+				CREATE INDEX IF NOT EXISTS idx_items_type ON items(list_id, type);
+				CREATE INDEX IF NOT EXISTS idx_items_list_position_v10 ON items(list_id, position);
+			`,
+		},
+		{
+			version: 16,
+			sql: `
+				-- Migration v16: Add admin flag to users
+				-- Set from OAuth group membership on each login (ADMIN_USERS still applies too)
+				ALTER TABLE users ADD COLUMN is_admin INTEGER NOT NULL DEFAULT 0;
+			`,
+		},
+		{
+			version: 17,
+			sql: `
+				-- Migration v17: Add landing board preference to users
+				-- Cleared automatically when the board is deleted
+				ALTER TABLE users ADD COLUMN home_board_id INTEGER REFERENCES boards(id) ON DELETE SET NULL;
+			`,
+		},
+		{
+			version: 18,
+			sql: `
+				-- Migration v18: Mark each user's read-later queue list
+				-- At most one per user; it is created on first use
+				ALTER TABLE lists ADD COLUMN read_later INTEGER NOT NULL DEFAULT 0;
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_lists_read_later ON lists(user_id) WHERE read_later = 1;
+			`,
+		},
+		{
+			version: 19,
+			sql: `
+				-- Migration v19: Drop the bookmarks table kept aside by v3
+				DROP TABLE IF EXISTS bookmarks_backup_v3;
+			`,
+			// Kept until the operator opts in with Options.DropBookmarksBackup
+			deferred: !db.options.DropBookmarksBackup,
+		},
+		{
+			version: 20,
+			sql: `
+				-- Migration v20: Track when each item's favicon was last fetched
+				-- Existing icons count as fetched now so upgrading doesn't refetch them all at once
+				ALTER TABLE items ADD COLUMN last_favicon_fetch TIMESTAMP;
+				UPDATE items SET last_favicon_fetch = CURRENT_TIMESTAMP WHERE favicon_url IS NOT NULL;
+			`,
+		},
+		{
+			version: 21,
+			sql: `
+				-- Migration v21: Remember how each list's items are sorted
+				ALTER TABLE lists ADD COLUMN sort_mode TEXT NOT NULL DEFAULT 'manual' CHECK (sort_mode IN ('manual', 'title', 'created'));
+			`,
+		},
+		{
+			version: 22,
+			sql: `
+				-- Migration v22: Optional preview image (the page's og:image) for bookmarks
+				ALTER TABLE items ADD COLUMN preview_image_url TEXT;
+			`,
+		},
+		{
+			version: 23,
+			sql: `
+				-- Migration v23: Session metadata for listing and revoking sessions
+				ALTER TABLE sessions ADD COLUMN user_agent TEXT;
+				ALTER TABLE sessions ADD COLUMN last_active TIMESTAMP;
+			`,
+		},
+		{
+			version: 24,
+			sql: `
+				-- Migration v24: One account per OAuth identity
+				-- Pre-provisioned accounts have no subject yet; store that as NULL rather than ''
+				UPDATE users SET oauth_sub = NULL WHERE oauth_sub = '';
+
+				-- Keep the oldest account if an identity was ever recorded twice
+				UPDATE users SET oauth_sub = NULL
+				WHERE oauth_sub IS NOT NULL
+				  AND id NOT IN (SELECT MIN(id) FROM users WHERE oauth_sub IS NOT NULL GROUP BY oauth_provider, oauth_sub);
+
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_users_oauth_identity ON users(oauth_provider, oauth_sub) WHERE oauth_sub IS NOT NULL;
+			`,
+		},
+		{
+			version: 25,
+			sql: `
+				-- Migration v25: Pinned items
+				ALTER TABLE items ADD COLUMN is_pinned BOOLEAN NOT NULL DEFAULT 0;
+			`,
+		},
+		{
+			version: 26,
+			sql: `
+				-- Migration v26: Whether the identity provider verified the user's email
+				-- Unknown for existing accounts until their next OAuth login
+				ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT 0;
+			`,
+		},
+		{
+			version: 27,
+			sql: `
+				-- Migration v27: Normalized bookmark URLs for indexed duplicate lookups
+				-- Filled for existing items by migrateDataForNormalizedURLsV27
+				ALTER TABLE items ADD COLUMN normalized_url TEXT;
+				CREATE INDEX IF NOT EXISTS idx_items_normalized_url ON items(normalized_url);
+			`,
+		},
 	}
 
 	// Run each migration
@@ -200,7 +377,7 @@ func (db *DB) migrate() error {
 			return fmt.Errorf("failed to check migration version %d: %w", migration.version, err)
 		}
 
-		if exists {
+		if exists || migration.deferred {
 			continue
 		}
 
@@ -242,6 +419,12 @@ func (db *DB) migrate() error {
 				return fmt.Errorf("failed to migrate data for version %d: %w", migration.version, err)
 			}
 		}
+		if migration.version == 27 {
+			if err := db.migrateDataForNormalizedURLsV27(tx); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to migrate data for version %d: %w", migration.version, err)
+			}
+		}
 
 		// Record migration
 		if _, err := tx.Exec("INSERT INTO migrations (version) VALUES (?)", migration.version); err != nil {
@@ -372,12 +555,21 @@ func (db *DB) migrateDataForItemsV3(tx *sql.Tx) error {
 
 	log.Printf("  Data integrity verified: %d bookmarks successfully migrated", bookmarkCount)
 
-	// Drop the old bookmarks table
-	if _, err := tx.Exec("DROP TABLE bookmarks"); err != nil {
-		return fmt.Errorf("failed to drop bookmarks table: %w", err)
-	}
+	if db.options.DropBookmarksBackup {
+		// Drop the old bookmarks table
+		if _, err := tx.Exec("DROP TABLE bookmarks"); err != nil {
+			return fmt.Errorf("failed to drop bookmarks table: %w", err)
+		}
 
-	log.Println("  Dropped old bookmarks table")
+		log.Println("  Dropped old bookmarks table")
+	} else {
+		// Keep the old bookmarks table for recovery; migration v19 drops it later
+		if _, err := tx.Exec("ALTER TABLE bookmarks RENAME TO bookmarks_backup_v3"); err != nil {
+			return fmt.Errorf("failed to rename bookmarks table: %w", err)
+		}
+
+		log.Println("  Kept old bookmarks table as bookmarks_backup_v3")
+	}
 	log.Println("  Migration to items table complete")
 
 	return nil
@@ -418,70 +610,89 @@ func (db *DB) migrateDataForFaviconsV4(tx *sql.Tx) error {
 		return nil
 	}
 
-	log.Printf("  Found %d items with Google favicon URLs to convert", len(itemsToUpdate))
+	log.Printf("  Found %d items with Google favicon URLs to convert (%d at a time)", len(itemsToUpdate), db.options.FaviconMigrationConcurrency)
 
 	// Create HTTP client for fetching favicons
 	client := &http.Client{
-		Timeout: 2 * time.Second,
+		Timeout: db.options.FaviconMigrationTimeout,
+	}
+
+	// Fetch in parallel; each worker writes only its own slot, so no locking is needed.
+	// Items whose fetch fails keep an empty slot and their original URL.
+	dataURIs := make([]string, len(itemsToUpdate))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(db.options.FaviconMigrationConcurrency, len(itemsToUpdate)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				dataURIs[i] = fetchFaviconDataURI(client, itemsToUpdate[i].faviconURL, db.options.FaviconMigrationTimeout)
+			}
+		}()
+	}
+	for i := range itemsToUpdate {
+		jobs <- i
 	}
+	close(jobs)
+	wg.Wait()
 
 	converted := 0
 	failed := 0
 
-	for _, item := range itemsToUpdate {
-		// Fetch the favicon
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		req, err := http.NewRequestWithContext(ctx, "GET", item.faviconURL, nil)
-		if err != nil {
-			cancel()
+	for i, item := range itemsToUpdate {
+		if dataURIs[i] == "" {
 			failed++
 			continue
 		}
 
-		resp, err := client.Do(req)
-		if err != nil {
-			cancel()
-			failed++
-			continue
+		// Update the item
+		if _, err := tx.Exec("UPDATE items SET favicon_url = ? WHERE id = ?", dataURIs[i], item.id); err != nil {
+			return fmt.Errorf("failed to update item %d: %w", item.id, err)
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			resp.Body.Close()
-			cancel()
-			failed++
-			continue
-		}
+		converted++
+	}
 
-		// Get content type before reading body
-		contentType := resp.Header.Get("Content-Type")
-		if contentType == "" {
-			contentType = "image/png"
-		}
+	log.Printf("  Converted %d favicons successfully, %d failed", converted, failed)
+	return nil
+}
 
-		// Read favicon bytes
-		faviconBytes, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		cancel()
+// fetchFaviconDataURI fetches an icon and returns it as a Base64 data URI, or "" on failure
+func fetchFaviconDataURI(client *http.Client, faviconURL string, timeout time.Duration) string {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-		if err != nil || len(faviconBytes) < 100 {
-			failed++
-			continue
-		}
+	req, err := http.NewRequestWithContext(ctx, "GET", faviconURL, nil)
+	if err != nil {
+		return ""
+	}
 
-		// Encode to Base64
-		encoded := base64.StdEncoding.EncodeToString(faviconBytes)
-		dataURI := fmt.Sprintf("data:%s;base64,%s", contentType, encoded)
+	resp, err := client.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
 
-		// Update the item
-		if _, err := tx.Exec("UPDATE items SET favicon_url = ? WHERE id = ?", dataURI, item.id); err != nil {
-			return fmt.Errorf("failed to update item %d: %w", item.id, err)
-		}
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
 
-		converted++
+	// Get content type before reading body
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "image/png"
 	}
 
-	log.Printf("  Converted %d favicons successfully, %d failed", converted, failed)
-	return nil
+	// Read favicon bytes
+	faviconBytes, err := io.ReadAll(resp.Body)
+	if err != nil || len(faviconBytes) < 100 {
+		return ""
+	}
+
+	// Encode to Base64
+	encoded := base64.StdEncoding.EncodeToString(faviconBytes)
+	return fmt.Sprintf("data:%s;base64,%s", contentType, encoded)
 }
 
 // migrateDataForOAuth2V5 migrates existing user to OAuth2 authentication
@@ -509,6 +720,40 @@ func (db *DB) migrateDataForOAuth2V5(tx *sql.Tx) error {
 	return nil
 }
 
+// migrateDataForNormalizedURLsV27 fills normalized_url for every existing item with a URL
+func (db *DB) migrateDataForNormalizedURLsV27(tx *sql.Tx) error {
+	log.Println("  Normalizing item URLs...")
+
+	rows, err := tx.Query("SELECT id, url FROM items WHERE url IS NOT NULL")
+	if err != nil {
+		return fmt.Errorf("failed to query items: %w", err)
+	}
+
+	normalized := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var url string
+		if err := rows.Scan(&id, &url); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan item: %w", err)
+		}
+		normalized[id] = urlutil.NormalizeForMatch(url)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read items: %w", err)
+	}
+
+	for id, url := range normalized {
+		if _, err := tx.Exec("UPDATE items SET normalized_url = ? WHERE id = ?", url, id); err != nil {
+			return fmt.Errorf("failed to update item %d: %w", id, err)
+		}
+	}
+
+	log.Printf("  Normalized %d item URLs", len(normalized))
+	return nil
+}
+
 // CleanExpiredSessions removes expired sessions from the database
 func (db *DB) CleanExpiredSessions() error {
 	_, err := db.Exec("DELETE FROM sessions WHERE expires_at < CURRENT_TIMESTAMP")
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/crueber/loom/internal/models"
+)
+
+// Read-later queue list defaults, used when the list is first created
+const (
+	ReadLaterListTitle = "Read Later"
+	ReadLaterListColor = "#3D6D95"
+)
+
+// GetReadLaterList retrieves the user's read-later queue list, creating it at the end
+// of the default board if the user has none yet
+func (db *DB) GetReadLaterList(userID int) (*models.List, error) {
+	var listID int
+	err := db.QueryRow(
+		"SELECT id FROM lists WHERE user_id = ? AND read_later = 1",
+		userID,
+	).Scan(&listID)
+	if err == nil {
+		return db.GetList(listID, userID)
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get read-later list: %w", err)
+	}
+
+	board, err := db.GetDefaultBoard(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// INSERT OR IGNORE leaves a list created concurrently in place
+	_, err = db.Exec(`
+		INSERT OR IGNORE INTO lists (user_id, board_id, title, color, position, collapsed, read_later)
+		SELECT ?, ?, ?, ?, COALESCE(MAX(position), -1) + 1, 0, 1 FROM lists WHERE board_id = ? AND user_id = ?
+	`, userID, board.ID, ReadLaterListTitle, ReadLaterListColor, board.ID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create read-later list: %w", err)
+	}
+
+	err = db.QueryRow(
+		"SELECT id FROM lists WHERE user_id = ? AND read_later = 1",
+		userID,
+	).Scan(&listID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get read-later list: %w", err)
+	}
+
+	db.TouchBoard(board.ID)
+
+	return db.GetList(listID, userID)
+}
+
+// GetReadLaterItems retrieves the items in a read-later list, oldest first
+func (db *DB) GetReadLaterItems(listID int) ([]*models.Item, error) {
+	rows, err := db.Query(
+		"SELECT id, list_id, type, title, url, content, content_format, favicon_url, icon_source, custom_icon_url, preview_image_url, open_in_new_tab, is_pinned, position, created_at FROM items WHERE list_id = ? ORDER BY created_at, id",
+		listID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get read-later items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.Item
+	for rows.Next() {
+		var item models.Item
+		var contentFormat sql.NullString
+		if err := rows.Scan(&item.ID, &item.ListID, &item.Type, &item.Title, &item.URL, &item.Content, &contentFormat, &item.FaviconURL, &item.IconSource, &item.CustomIconURL, &item.PreviewImageURL, &item.OpenInNewTab, &item.IsPinned, &item.Position, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		item.ContentFormat = contentFormatOrDefault(contentFormat)
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read items: %w", err)
+	}
+
+	return items, nil
+}
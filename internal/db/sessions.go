@@ -0,0 +1,84 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/crueber/loom/internal/models"
+)
+
+// CreateSession records a new session for a user
+func (db *DB) CreateSession(id string, userID int, userAgent string, expiresAt time.Time) error {
+	var agent *string
+	if userAgent != "" {
+		agent = &userAgent
+	}
+
+	_, err := db.Exec(
+		"INSERT INTO sessions (id, user_id, user_agent, expires_at, last_active) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)",
+		id, userID, agent, sqlTimestamp(expiresAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// SessionExists reports whether a user's session is recorded and not yet expired
+func (db *DB) SessionExists(id string, userID int) (bool, error) {
+	var exists bool
+	err := db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM sessions WHERE id = ? AND user_id = ? AND expires_at >= CURRENT_TIMESTAMP)",
+		id, userID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+	return exists, nil
+}
+
+// TouchSession records activity on a session, at most once a minute
+func (db *DB) TouchSession(id string) error {
+	_, err := db.Exec(
+		"UPDATE sessions SET last_active = CURRENT_TIMESTAMP WHERE id = ? AND (last_active IS NULL OR last_active < datetime('now', '-1 minute'))",
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+	return nil
+}
+
+// DeleteSession removes a session, revoking it
+func (db *DB) DeleteSession(id string) error {
+	if _, err := db.Exec("DELETE FROM sessions WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// GetUserSessions returns a user's unexpired sessions, most recently active first
+func (db *DB) GetUserSessions(userID int) ([]*models.Session, error) {
+	rows, err := db.Query(
+		`SELECT id, user_id, user_agent, created_at, expires_at, last_active
+		 FROM sessions
+		 WHERE user_id = ? AND expires_at >= CURRENT_TIMESTAMP
+		 ORDER BY COALESCE(last_active, created_at) DESC, id`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.CreatedAt, &session.ExpiresAt, &session.LastActive); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, rows.Err()
+}
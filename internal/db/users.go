@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/crueber/loom/internal/models"
 )
@@ -34,9 +35,9 @@ func (db *DB) GetUserByID(id int) (*models.User, error) {
 	var theme sql.NullString
 	var oauthProvider, oauthSub sql.NullString
 	err := db.QueryRow(
-		"SELECT id, username, email, locale, theme, password_hash, oauth_provider, oauth_sub, created_at FROM users WHERE id = ?",
+		"SELECT id, username, email, locale, theme, password_hash, oauth_provider, oauth_sub, is_admin, email_verified, home_board_id, created_at FROM users WHERE id = ?",
 		id,
-	).Scan(&user.ID, &user.Username, &email, &locale, &theme, &user.PasswordHash, &oauthProvider, &oauthSub, &user.CreatedAt)
+	).Scan(&user.ID, &user.Username, &email, &locale, &theme, &user.PasswordHash, &oauthProvider, &oauthSub, &user.IsAdmin, &user.EmailVerified, &user.HomeBoardID, &user.CreatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -64,8 +65,9 @@ func (db *DB) GetUserByID(id int) (*models.User, error) {
 	return &user, nil
 }
 
-// EnsureStandaloneUser ensures that the default standalone user exists
-func (db *DB) EnsureStandaloneUser() error {
+// EnsureStandaloneUser ensures that the default standalone user exists, seeding its
+// default board from template when the user is first created (nil seeds nothing)
+func (db *DB) EnsureStandaloneUser(template *models.ExportData) error {
 	email := "user@standalone"
 	username := "standalone"
 
@@ -97,10 +99,13 @@ func (db *DB) EnsureStandaloneUser() error {
 	}
 
 	// Create default board for the user
-	_, err = db.CreateBoard(userID, "My Bookmarks", true)
+	board, err := db.CreateBoard(userID, "My Bookmarks", true)
 	if err != nil {
 		return fmt.Errorf("failed to create default board for standalone user: %w", err)
 	}
+	if err := db.SeedBoard(userID, board.ID, template); err != nil {
+		log.Printf("Failed to seed standalone user's board: %v", err)
+	}
 
 	log.Printf("Created default standalone user: %s", email)
 	return nil
@@ -114,9 +119,9 @@ func (db *DB) GetUserByUsername(username string) (*models.User, error) {
 	var theme sql.NullString
 	var oauthProvider, oauthSub sql.NullString
 	err := db.QueryRow(
-		"SELECT id, username, email, locale, theme, password_hash, oauth_provider, oauth_sub, created_at FROM users WHERE username = ?",
+		"SELECT id, username, email, locale, theme, password_hash, oauth_provider, oauth_sub, is_admin, email_verified, home_board_id, created_at FROM users WHERE username = ?",
 		username,
-	).Scan(&user.ID, &user.Username, &email, &locale, &theme, &user.PasswordHash, &oauthProvider, &oauthSub, &user.CreatedAt)
+	).Scan(&user.ID, &user.Username, &email, &locale, &theme, &user.PasswordHash, &oauthProvider, &oauthSub, &user.IsAdmin, &user.EmailVerified, &user.HomeBoardID, &user.CreatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -163,9 +168,79 @@ func (db *DB) DeleteUser(username string) error {
 	return nil
 }
 
+// MergeUserInto moves all of the source user's boards and lists (and so their items)
+// to the target user and then deletes the source, all in one transaction. The target
+// keeps its own default board and read-later list; the source's become ordinary ones.
+// It returns how many boards and lists were moved.
+func (db *DB) MergeUserInto(sourceID, targetID int) (boards, lists int, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Demote first so the moves can't collide with the one-per-user indexes
+	if _, err := tx.Exec(`
+		UPDATE boards SET is_default = 0
+		WHERE user_id = ? AND is_default = 1
+		  AND EXISTS (SELECT 1 FROM boards WHERE user_id = ? AND is_default = 1)
+	`, sourceID, targetID); err != nil {
+		return 0, 0, fmt.Errorf("failed to demote default board: %w", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE lists SET read_later = 0
+		WHERE user_id = ? AND read_later = 1
+		  AND EXISTS (SELECT 1 FROM lists WHERE user_id = ? AND read_later = 1)
+	`, sourceID, targetID); err != nil {
+		return 0, 0, fmt.Errorf("failed to demote read-later list: %w", err)
+	}
+
+	result, err := tx.Exec("UPDATE boards SET user_id = ? WHERE user_id = ?", targetID, sourceID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to move boards: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	boards = int(rows)
+
+	result, err = tx.Exec("UPDATE lists SET user_id = ? WHERE user_id = ?", targetID, sourceID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to move lists: %w", err)
+	}
+	rows, err = result.RowsAffected()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	lists = int(rows)
+
+	// Don't rely on the ON DELETE CASCADE: a surviving row would keep the session valid
+	if _, err := tx.Exec("DELETE FROM sessions WHERE user_id = ?", sourceID); err != nil {
+		return 0, 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	result, err = tx.Exec("DELETE FROM users WHERE id = ?", sourceID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to delete user: %w", err)
+	}
+	if rows, err = result.RowsAffected(); err != nil {
+		return 0, 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return 0, 0, fmt.Errorf("user not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return boards, lists, nil
+}
+
 // ListUsers returns all users
 func (db *DB) ListUsers() ([]*models.User, error) {
-	rows, err := db.Query("SELECT id, username, email, locale, theme, password_hash, oauth_provider, oauth_sub, created_at FROM users ORDER BY username")
+	rows, err := db.Query("SELECT id, username, email, locale, theme, password_hash, oauth_provider, oauth_sub, is_admin, email_verified, home_board_id, created_at FROM users ORDER BY username")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
@@ -178,7 +253,7 @@ func (db *DB) ListUsers() ([]*models.User, error) {
 		var locale sql.NullString
 		var theme sql.NullString
 		var oauthProvider, oauthSub sql.NullString
-		if err := rows.Scan(&user.ID, &user.Username, &email, &locale, &theme, &user.PasswordHash, &oauthProvider, &oauthSub, &user.CreatedAt); err != nil {
+		if err := rows.Scan(&user.ID, &user.Username, &email, &locale, &theme, &user.PasswordHash, &oauthProvider, &oauthSub, &user.IsAdmin, &user.EmailVerified, &user.HomeBoardID, &user.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
 		if email.Valid {
@@ -221,6 +296,145 @@ func (db *DB) UpdateUserPassword(username, passwordHash string) error {
 	return nil
 }
 
+// SetUserAdmin sets whether a user has admin access
+func (db *DB) SetUserAdmin(userID int, isAdmin bool) error {
+	result, err := db.Exec("UPDATE users SET is_admin = ? WHERE id = ?", isAdmin, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update admin flag: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// SetEmailVerified records whether the user's current email was verified by their
+// identity provider
+func (db *DB) SetEmailVerified(userID int, verified bool) error {
+	result, err := db.Exec("UPDATE users SET email_verified = ? WHERE id = ?", verified, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update email verification: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// SetHomeBoard sets the board a user lands on after login; a nil boardID clears it
+func (db *DB) SetHomeBoard(userID int, boardID *int) error {
+	if boardID != nil {
+		var owned bool
+		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM boards WHERE id = ? AND user_id = ?)", *boardID, userID).Scan(&owned)
+		if err != nil {
+			return fmt.Errorf("failed to verify board ownership: %w", err)
+		}
+		if !owned {
+			return fmt.Errorf("board not found")
+		}
+	}
+
+	result, err := db.Exec("UPDATE users SET home_board_id = ? WHERE id = ?", boardID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update home board: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// ResetUserSettings clears the user's locale and theme so the defaults apply again
+func (db *DB) ResetUserSettings(userID int) error {
+	result, err := db.Exec("UPDATE users SET locale = NULL, theme = NULL WHERE id = ?", userID)
+	if err != nil {
+		return fmt.Errorf("failed to reset user settings: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// GetHomeBoardID returns the user's landing board, or 0 when none is set or the
+// board no longer belongs to them
+func (db *DB) GetHomeBoardID(userID int) (int, error) {
+	var boardID int
+	err := db.QueryRow(`
+		SELECT b.id FROM users u
+		INNER JOIN boards b ON b.id = u.home_board_id AND b.user_id = u.id
+		WHERE u.id = ?
+	`, userID).Scan(&boardID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get home board: %w", err)
+	}
+	return boardID, nil
+}
+
+// UpdateUserEmail sets a user's email address, rejecting addresses already used by another account
+func (db *DB) UpdateUserEmail(userID int, email string) error {
+	var taken bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = ? COLLATE NOCASE AND id != ?)", email, userID).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("failed to check email: %w", err)
+	}
+	if taken {
+		return fmt.Errorf("email already in use")
+	}
+
+	// A changed address is unverified until an identity provider vouches for it again
+	result, err := db.Exec("UPDATE users SET email = ?, email_verified = 0 WHERE id = ?", email, userID)
+	if err != nil {
+		// The unique index still guards against a concurrent claim of the same address
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("email already in use")
+		}
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
 // GetUserByEmail retrieves a user by email address
 func (db *DB) GetUserByEmail(email string) (*models.User, error) {
 	var user models.User
@@ -228,9 +442,9 @@ func (db *DB) GetUserByEmail(email string) (*models.User, error) {
 	var theme sql.NullString
 	var oauthProvider, oauthSub sql.NullString
 	err := db.QueryRow(
-		"SELECT id, username, email, locale, theme, password_hash, oauth_provider, oauth_sub, created_at FROM users WHERE email = ?",
+		"SELECT id, username, email, locale, theme, password_hash, oauth_provider, oauth_sub, is_admin, email_verified, home_board_id, created_at FROM users WHERE email = ?",
 		email,
-	).Scan(&user.ID, &user.Username, &user.Email, &locale, &theme, &user.PasswordHash, &oauthProvider, &oauthSub, &user.CreatedAt)
+	).Scan(&user.ID, &user.Username, &user.Email, &locale, &theme, &user.PasswordHash, &oauthProvider, &oauthSub, &user.IsAdmin, &user.EmailVerified, &user.HomeBoardID, &user.CreatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
@@ -256,12 +470,81 @@ func (db *DB) GetUserByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-// CreateOAuthUser creates a new user from OAuth2 authentication
+// GetUserByOAuthSub retrieves the user linked to a provider's subject identifier;
+// returns nil if no user is linked to it
+func (db *DB) GetUserByOAuthSub(provider, sub string) (*models.User, error) {
+	var user models.User
+	var email sql.NullString
+	var locale sql.NullString
+	var theme sql.NullString
+	var oauthProvider, oauthSub sql.NullString
+	err := db.QueryRow(
+		"SELECT id, username, email, locale, theme, password_hash, oauth_provider, oauth_sub, is_admin, email_verified, home_board_id, created_at FROM users WHERE oauth_provider = ? AND oauth_sub = ?",
+		provider, sub,
+	).Scan(&user.ID, &user.Username, &email, &locale, &theme, &user.PasswordHash, &oauthProvider, &oauthSub, &user.IsAdmin, &user.EmailVerified, &user.HomeBoardID, &user.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if email.Valid {
+		user.Email = email.String
+	}
+	if locale.Valid {
+		user.Locale = locale.String
+	}
+	if theme.Valid {
+		user.Theme = theme.String
+	}
+	if oauthProvider.Valid {
+		user.OAuthProvider = &oauthProvider.String
+	}
+	if oauthSub.Valid {
+		user.OAuthSub = &oauthSub.String
+	}
+
+	return &user, nil
+}
+
+// LinkOAuthSub records the provider's subject identifier on an account provisioned for
+// OAuth that has none yet, so later logins find it by identity instead of email.
+// Password accounts are never linked.
+func (db *DB) LinkOAuthSub(userID int, provider, sub string) error {
+	result, err := db.Exec(
+		"UPDATE users SET oauth_provider = ?, oauth_sub = ? WHERE id = ? AND oauth_sub IS NULL AND oauth_provider IS NOT NULL AND password_hash = ''",
+		provider, sub, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to link OAuth identity: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("user not found or already linked")
+	}
+
+	return nil
+}
+
+// CreateOAuthUser creates a new user from OAuth2 authentication. An empty sub
+// (a pre-provisioned account) is stored as NULL so it can't collide.
 func (db *DB) CreateOAuthUser(email, provider, sub string) (*models.User, error) {
+	var oauthSub sql.NullString
+	if sub != "" {
+		oauthSub = sql.NullString{String: sub, Valid: true}
+	}
+
 	// Use email as username for new OAuth users (can be changed later if needed)
 	result, err := db.Exec(
 		"INSERT INTO users (username, email, oauth_provider, oauth_sub, password_hash) VALUES (?, ?, ?, ?, '')",
-		email, email, provider, sub,
+		email, email, provider, oauthSub,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OAuth user: %w", err)
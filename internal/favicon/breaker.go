@@ -0,0 +1,61 @@
+package favicon
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// DefaultBreakerThreshold is the number of consecutive unreachable-host failures
+	// that open the circuit breaker
+	DefaultBreakerThreshold = 5
+	// DefaultBreakerCooldown is how long an open breaker skips fetches
+	DefaultBreakerCooldown = time.Minute
+)
+
+// ErrCircuitOpen is returned without contacting any host while repeated connection
+// failures have opened the circuit breaker
+var ErrCircuitOpen = errors.New("icon fetching paused after repeated connection failures")
+
+// errHostUnreachable marks failures where no response was received at all; only these
+// count toward the breaker, so sites that merely lack an icon never trip it
+var errHostUnreachable = errors.New("failed to fetch icon")
+
+// SetCircuitBreaker makes the fetcher stop contacting icon hosts for cooldown after
+// threshold consecutive fetches fail to get any response, as on a server without
+// internet access. Once the cooldown ends one more failure reopens the breaker, and
+// any response closes it. A threshold of zero turns the breaker off.
+func (f *Fetcher) SetCircuitBreaker(threshold int, cooldown time.Duration) {
+	f.breakerMu.Lock()
+	defer f.breakerMu.Unlock()
+	f.breakerThreshold = max(threshold, 0)
+	f.breakerCooldown = cooldown
+	f.breakerFailures = 0
+	f.breakerOpenUntil = time.Time{}
+}
+
+// breakerOpen reports whether fetches are currently being skipped
+func (f *Fetcher) breakerOpen() bool {
+	f.breakerMu.Lock()
+	defer f.breakerMu.Unlock()
+	return f.breakerThreshold > 0 && f.now().Before(f.breakerOpenUntil)
+}
+
+// recordFetchResult updates the breaker with the outcome of a fetch
+func (f *Fetcher) recordFetchResult(err error) {
+	f.breakerMu.Lock()
+	defer f.breakerMu.Unlock()
+	if f.breakerThreshold <= 0 {
+		return
+	}
+
+	if !errors.Is(err, errHostUnreachable) {
+		f.breakerFailures = 0
+		return
+	}
+	// The count is kept while open so the first failure after the cooldown reopens it
+	f.breakerFailures++
+	if f.breakerFailures >= f.breakerThreshold {
+		f.breakerOpenUntil = f.now().Add(f.breakerCooldown)
+	}
+}
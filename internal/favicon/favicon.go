@@ -3,12 +3,17 @@ package favicon
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/crueber/loom/internal/urlutil"
 )
 
 const (
@@ -17,78 +22,188 @@ const (
 	simpleIconsService   = "https://cdn.simpleicons.org"
 	faviconSize          = "32"
 	requestTimeout       = 2 * time.Second
+
+	// DefaultRetryAttempts is the number of attempts made for each icon fetch
+	DefaultRetryAttempts = 2
+	retryBaseDelay       = 250 * time.Millisecond
+
+	// DefaultMaxConcurrent is the number of outbound icon requests allowed in flight at once
+	DefaultMaxConcurrent = 8
 )
 
+// ErrHostNotAllowed is returned when the host policy forbids contacting an icon host
+var ErrHostNotAllowed = errors.New("icon host not allowed")
+
 // Fetcher handles favicon fetching
 type Fetcher struct {
-	client *http.Client
+	client         *http.Client
+	retryAttempts  int
+	retryBaseDelay time.Duration
+
+	// sem bounds the number of outbound requests in flight across all callers
+	sem chan struct{}
+
+	// Host policy: when allowedHosts is non-empty only those hosts are contacted;
+	// blockedHosts are never contacted. Entries also match their subdomains.
+	allowedHosts []string
+	blockedHosts []string
+
+	// disabled turns every fetch into a no-op so the server makes no outbound requests
+	disabled bool
+
+	// transcodeSize re-encodes raster icons as square PNGs of this size (0 keeps them as fetched)
+	transcodeSize int
+
+	// store, when set, keeps fetched icons on disk and fetches return their StoredIconPath URL
+	store *Store
+
+	// Circuit breaker state; see SetCircuitBreaker
+	breakerMu        sync.Mutex
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	breakerFailures  int
+	breakerOpenUntil time.Time
+	now              func() time.Time
 }
 
-// New creates a new favicon fetcher
+// New creates a new favicon fetcher that honors the standard
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
 func New() *Fetcher {
+	fetcher, _ := NewWithProxy("")
+	return fetcher
+}
+
+// NewWithProxy creates a favicon fetcher that sends all requests through proxyURL.
+// An empty proxyURL falls back to the standard proxy environment variables.
+func NewWithProxy(proxyURL string) (*Fetcher, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+
+	if proxyURL != "" {
+		parsed, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch parsed.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("invalid proxy URL: unsupported scheme %q", parsed.Scheme)
+		}
+		if parsed.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL: missing host")
+		}
+		transport.Proxy = http.ProxyURL(parsed)
+	}
+
 	return &Fetcher{
 		client: &http.Client{
-			Timeout: requestTimeout,
+			Timeout:   requestTimeout,
+			Transport: transport,
 		},
-	}
+		retryAttempts:    DefaultRetryAttempts,
+		retryBaseDelay:   retryBaseDelay,
+		sem:              make(chan struct{}, DefaultMaxConcurrent),
+		breakerThreshold: DefaultBreakerThreshold,
+		breakerCooldown:  DefaultBreakerCooldown,
+		now:              time.Now,
+	}, nil
 }
 
-// FetchFaviconURL fetches the favicon for a given website URL and returns it as a Base64 data URI
-// Returns the data URI or nil if not available
-func (f *Fetcher) FetchFaviconURL(websiteURL string) *string {
-	domain, err := extractDomain(websiteURL)
-	if err != nil {
-		return nil
-	}
-
-	faviconURL := fmt.Sprintf("%s?domain=%s&sz=%s", googleFaviconService, domain, faviconSize)
+// SetMaxConcurrent sets how many outbound icon requests may be in flight at once (minimum 1).
+// Call it before the fetcher is shared; requests already waiting keep the old limit.
+func (f *Fetcher) SetMaxConcurrent(n int) {
+	f.sem = make(chan struct{}, max(n, 1))
+}
 
-	// Fetch the favicon bytes
-	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
-	defer cancel()
+// SetHostPolicy restricts which hosts the fetcher may contact.
+// An empty allowed list permits every host that is not blocked.
+func (f *Fetcher) SetHostPolicy(allowed, blocked []string) {
+	f.allowedHosts = normalizeHosts(allowed)
+	f.blockedHosts = normalizeHosts(blocked)
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", faviconURL, nil)
+// hostAllowed reports whether the host policy permits fetching rawURL
+func (f *Fetcher) hostAllowed(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
 	if err != nil {
-		return nil
+		return false
 	}
+	host := strings.ToLower(parsed.Hostname())
 
-	resp, err := f.client.Do(req)
-	if err != nil {
-		return nil
+	for _, blocked := range f.blockedHosts {
+		if hostMatches(host, blocked) {
+			return false
+		}
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil
+	if len(f.allowedHosts) == 0 {
+		return true
 	}
-
-	// Read the favicon bytes
-	faviconBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil
+	for _, allowed := range f.allowedHosts {
+		if hostMatches(host, allowed) {
+			return true
+		}
 	}
+	return false
+}
 
-	// Don't cache if the response is empty or suspiciously small
-	if len(faviconBytes) < 100 {
-		return nil
+// hostMatches reports whether host is pattern or one of its subdomains
+func hostMatches(host, pattern string) bool {
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+// normalizeHosts lowercases and trims host entries, dropping empty ones
+func normalizeHosts(hosts []string) []string {
+	var normalized []string
+	for _, host := range hosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host != "" {
+			normalized = append(normalized, host)
+		}
 	}
+	return normalized
+}
 
-	// Encode to Base64 and create data URI
-	encoded := base64.StdEncoding.EncodeToString(faviconBytes)
+// SetDisabled turns off all icon fetching; fetch methods then return nil without
+// touching the network and items are stored without a favicon
+func (f *Fetcher) SetDisabled(disabled bool) {
+	f.disabled = disabled
+}
 
-	// Determine content type from response header, default to png
-	contentType := resp.Header.Get("Content-Type")
-	if contentType == "" {
-		contentType = "image/png"
+// Disabled reports whether icon fetching has been turned off
+func (f *Fetcher) Disabled() bool {
+	return f.disabled
+}
+
+// SetRetryAttempts sets how many times each icon fetch is attempted (minimum 1)
+func (f *Fetcher) SetRetryAttempts(attempts int) {
+	f.retryAttempts = max(attempts, 1)
+}
+
+// SetStore makes fetches save icons in store and return their same-origin
+// StoredIconPath URL instead of a data URI; nil restores data URIs
+func (f *Fetcher) SetStore(store *Store) {
+	f.store = store
+}
+
+// FetchFaviconURL fetches the favicon for a given website URL and returns it as a Base64 data URI
+// Returns the data URI or nil if not available
+func (f *Fetcher) FetchFaviconURL(websiteURL string) *string {
+	domain, err := urlutil.Domain(websiteURL)
+	if err != nil {
+		return nil
 	}
 
-	dataURI := fmt.Sprintf("data:%s;base64,%s", contentType, encoded)
-	return &dataURI
+	icon, _ := f.FetchFromDomain(domain)
+	return icon
 }
 
-// FetchFromDomain fetches favicon from the website's domain using Google's service
+// FetchFromDomain fetches favicon from the website's domain using Google's service.
+// If the host policy forbids Google, the site's own /favicon.ico is fetched instead.
 func (f *Fetcher) FetchFromDomain(domain string) (*string, error) {
-	faviconURL := fmt.Sprintf("%s?domain=%s&sz=%s", googleFaviconService, domain, faviconSize)
+	faviconURL := fmt.Sprintf("%s?domain=%s&sz=%s", googleFaviconService, url.QueryEscape(domain), faviconSize)
+	if !f.hostAllowed(faviconURL) {
+		faviconURL = (&url.URL{Scheme: "https", Host: domain, Path: "/favicon.ico"}).String()
+	}
 	return f.fetchAndEncode(faviconURL)
 }
 
@@ -149,66 +264,110 @@ func (f *Fetcher) FetchIcon(iconSource string, customURL *string, domain string)
 	}
 }
 
-// fetchAndEncode fetches an icon from a URL and returns it as a Base64 data URI
+// fetchAndEncode fetches an icon from a URL and returns it as a Base64 data URI.
+// Transient failures (network errors, timeouts, 5xx) are retried with jittered backoff.
 func (f *Fetcher) fetchAndEncode(iconURL string) (*string, error) {
+	if f.disabled {
+		return nil, nil
+	}
+	if !f.hostAllowed(iconURL) {
+		return nil, ErrHostNotAllowed
+	}
+	if f.breakerOpen() {
+		return nil, ErrCircuitOpen
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < f.retryAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(f.retryDelay(attempt))
+		}
+
+		icon, retryable, err := f.fetchOnce(iconURL)
+		if err == nil {
+			f.recordFetchResult(nil)
+			return icon, nil
+		}
+		lastErr = err
+		if !retryable {
+			break
+		}
+	}
+	f.recordFetchResult(lastErr)
+	return nil, lastErr
+}
+
+// retryDelay returns the jittered backoff before the given retry attempt (1-based):
+// a random duration in [d/2, d) where d doubles with each attempt
+func (f *Fetcher) retryDelay(attempt int) time.Duration {
+	d := f.retryBaseDelay << (attempt - 1)
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int64N(int64(d/2)))
+}
+
+// fetchOnce performs a single fetch attempt, reporting whether a failure is worth retrying
+func (f *Fetcher) fetchOnce(iconURL string) (*string, bool, error) {
+	f.sem <- struct{}{}
+	defer func() { <-f.sem }()
+
 	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, "GET", iconURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	resp, err := f.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch icon: %w", err)
+		// Connection errors and timeouts are transient
+		return nil, true, fmt.Errorf("%w: %w", errHostUnreachable, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch icon: status %d", resp.StatusCode)
+		return nil, resp.StatusCode >= 500, fmt.Errorf("failed to fetch icon: status %d", resp.StatusCode)
 	}
 
 	// Read the icon bytes
 	iconBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read icon: %w", err)
+		return nil, true, fmt.Errorf("failed to read icon: %w", err)
 	}
 
 	// Don't cache if the response is empty or suspiciously small
 	if len(iconBytes) < 100 {
-		return nil, fmt.Errorf("icon too small: %d bytes", len(iconBytes))
+		return nil, false, fmt.Errorf("icon too small: %d bytes", len(iconBytes))
 	}
 
-	// Encode to Base64 and create data URI
-	encoded := base64.StdEncoding.EncodeToString(iconBytes)
-
 	// Determine content type from response header, default to png
 	contentType := resp.Header.Get("Content-Type")
 	if contentType == "" {
 		contentType = "image/png"
 	}
 
-	dataURI := fmt.Sprintf("data:%s;base64,%s", contentType, encoded)
-	return &dataURI, nil
-}
-
-// extractDomain extracts the domain from a URL
-func extractDomain(rawURL string) (string, error) {
-	// Add scheme if missing
-	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
-		rawURL = "https://" + rawURL
+	// Normalize raster icons to PNG when enabled; anything that can't be decoded is kept as-is
+	if f.transcodeSize > 0 {
+		if transcoded, err := transcodePNG(iconBytes, contentType, f.transcodeSize); err == nil {
+			iconBytes = transcoded
+			contentType = "image/png"
+		}
 	}
 
-	parsedURL, err := url.Parse(rawURL)
-	if err != nil {
-		return "", fmt.Errorf("invalid URL: %w", err)
+	if f.store != nil {
+		hash, err := f.store.Put(iconBytes, contentType)
+		if err != nil {
+			return nil, false, err
+		}
+		storedURL := f.store.URL(hash)
+		return &storedURL, false, nil
 	}
 
-	domain := parsedURL.Hostname()
-	if domain == "" {
-		return "", fmt.Errorf("no domain found in URL")
-	}
+	// Encode to Base64 and create data URI
+	encoded := base64.StdEncoding.EncodeToString(iconBytes)
 
-	return domain, nil
+	dataURI := fmt.Sprintf("data:%s;base64,%s", contentType, encoded)
+	return &dataURI, false, nil
 }
@@ -0,0 +1,321 @@
+package favicon
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchAndEncode_Retries(t *testing.T) {
+	icon := bytes.Repeat([]byte{0x89}, 128)
+
+	tests := []struct {
+		name         string
+		failStatus   int
+		failures     int32
+		wantRequests int32
+		wantIcon     bool
+	}{
+		{name: "server error retried", failStatus: http.StatusBadGateway, failures: 1, wantRequests: 2, wantIcon: true},
+		{name: "not found not retried", failStatus: http.StatusNotFound, failures: 1, wantRequests: 1},
+		{name: "gives up after max attempts", failStatus: http.StatusServiceUnavailable, failures: 10, wantRequests: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) <= tt.failures {
+					w.WriteHeader(tt.failStatus)
+					return
+				}
+				w.Header().Set("Content-Type", "image/png")
+				w.Write(icon)
+			}))
+			defer server.Close()
+
+			fetcher := New()
+			fetcher.SetRetryAttempts(3)
+			fetcher.retryBaseDelay = 0
+
+			got, err := fetcher.fetchAndEncode(server.URL)
+			if tt.wantIcon && (err != nil || got == nil) {
+				t.Fatalf("fetchAndEncode() = %v, %v, want icon", got, err)
+			}
+			if !tt.wantIcon && err == nil {
+				t.Fatalf("fetchAndEncode() error = nil, want error")
+			}
+			if n := requests.Load(); n != tt.wantRequests {
+				t.Fatalf("requests = %d, want %d", n, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestFetchAndEncode_MaxConcurrent(t *testing.T) {
+	icon := bytes.Repeat([]byte{0x89}, 128)
+
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write(icon)
+	}))
+	defer server.Close()
+
+	fetcher := New()
+	fetcher.SetMaxConcurrent(2)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := fetcher.fetchAndEncode(server.URL); err != nil {
+				t.Errorf("fetchAndEncode() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > 2 {
+		t.Fatalf("peak concurrent requests = %d, want at most 2", got)
+	}
+}
+
+func TestFetchIcon_DisabledMakesNoRequests(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("icon"))
+	}))
+	defer server.Close()
+
+	fetcher := New()
+	fetcher.SetDisabled(true)
+
+	customURL := server.URL
+	for _, source := range []string{"auto", "custom", "service"} {
+		got, err := fetcher.FetchIcon(source, &customURL, "example.com")
+		if got != nil || err != nil {
+			t.Fatalf("FetchIcon(%q) = %v, %v, want nil, nil", source, got, err)
+		}
+	}
+	if n := requests.Load(); n != 0 {
+		t.Fatalf("requests = %d, want 0", n)
+	}
+}
+
+func TestFetchAndEncode_CircuitBreaker(t *testing.T) {
+	// A listener that is closed straight away gives an address that refuses connections
+	server := httptest.NewServer(http.NotFoundHandler())
+	unreachable := server.URL + "/favicon.ico"
+	server.Close()
+
+	var requests atomic.Int32
+	missing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer missing.Close()
+
+	now := time.Now()
+	fetcher := New()
+	fetcher.now = func() time.Time { return now }
+	fetcher.SetRetryAttempts(1)
+	fetcher.SetCircuitBreaker(2, time.Minute)
+
+	// Missing icons are answered, so they never count toward the breaker
+	for range 3 {
+		if _, err := fetcher.fetchAndEncode(missing.URL); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("missing icon error = %v, want a not found error", err)
+		}
+	}
+
+	for range 2 {
+		if _, err := fetcher.fetchAndEncode(unreachable); !errors.Is(err, errHostUnreachable) {
+			t.Fatalf("unreachable error = %v, want a connection failure", err)
+		}
+	}
+	if icon, err := fetcher.fetchAndEncode(missing.URL); icon != nil || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("fetch while open = %v, %v, want ErrCircuitOpen", icon, err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Fatalf("requests = %d, want none made while the breaker is open", got)
+	}
+
+	// After the cooldown one failure reopens the breaker, and a response closes it
+	now = now.Add(time.Minute)
+	if _, err := fetcher.fetchAndEncode(unreachable); !errors.Is(err, errHostUnreachable) {
+		t.Fatalf("error after cooldown = %v, want a connection failure", err)
+	}
+	if _, err := fetcher.fetchAndEncode(missing.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error after reopening = %v, want ErrCircuitOpen", err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := fetcher.fetchAndEncode(missing.URL); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error after recovery = %v, want a not found error", err)
+	}
+	if _, err := fetcher.fetchAndEncode(unreachable); !errors.Is(err, errHostUnreachable) {
+		t.Fatalf("error after closing = %v, want the breaker closed", err)
+	}
+	if _, err := fetcher.fetchAndEncode(missing.URL); errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("single failure after closing opened the breaker")
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	fetcher := New()
+	fetcher.SetHostPolicy(nil, []string{"google.com"})
+
+	if fetcher.hostAllowed(googleFaviconService) {
+		t.Fatalf("hostAllowed(%q) = true, want false for blocked subdomain", googleFaviconService)
+	}
+	if !fetcher.hostAllowed("https://cdn.jsdelivr.net/icon.webp") {
+		t.Fatalf("hostAllowed(jsdelivr) = false, want true")
+	}
+	if !fetcher.hostAllowed("https://notgoogle.com/favicon.ico") {
+		t.Fatalf("hostAllowed(notgoogle.com) = false, want true")
+	}
+
+	fetcher.SetHostPolicy([]string{" CDN.jsdelivr.net ", ""}, nil)
+	if !fetcher.hostAllowed("https://cdn.jsdelivr.net/icon.webp") {
+		t.Fatalf("hostAllowed(jsdelivr) = false, want true when allowlisted")
+	}
+	if fetcher.hostAllowed("https://cdn.simpleicons.org/github") {
+		t.Fatalf("hostAllowed(simpleicons) = true, want false when not allowlisted")
+	}
+
+	if _, err := fetcher.fetchAndEncode("https://cdn.simpleicons.org/github"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Fatalf("fetchAndEncode() error = %v, want %v", err, ErrHostNotAllowed)
+	}
+}
+
+func TestFetchAndEncode_StoresIconsByHash(t *testing.T) {
+	icon := bytes.Repeat([]byte{0x89}, 128)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/x-icon")
+		w.Write(icon)
+	}))
+	defer server.Close()
+
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	fetcher := New()
+	fetcher.SetStore(store)
+
+	got, err := fetcher.fetchAndEncode(server.URL + "/favicon.ico")
+	if err != nil || got == nil {
+		t.Fatalf("fetchAndEncode() = %v, %v, want icon", got, err)
+	}
+	hash, ok := strings.CutPrefix(*got, StoredIconPath)
+	if !ok {
+		t.Fatalf("icon URL = %q, want it under %s", *got, StoredIconPath)
+	}
+
+	stored, contentType, err := store.Get(hash)
+	if err != nil || !bytes.Equal(stored, icon) || contentType != "image/x-icon" {
+		t.Fatalf("Get(%q) = %d bytes, %q, %v, want the fetched icon", hash, len(stored), contentType, err)
+	}
+	if again, err := fetcher.fetchAndEncode(server.URL + "/favicon.ico"); err != nil || *again != *got {
+		t.Fatalf("refetch = %v, %v, want the same URL %q", again, err, *got)
+	}
+
+	for _, bad := range []string{"", "../" + hash[3:], strings.ToUpper(hash)} {
+		if _, _, err := store.Get(bad); !errors.Is(err, ErrIconNotFound) {
+			t.Fatalf("Get(%q) error = %v, want ErrIconNotFound", bad, err)
+		}
+	}
+}
+
+func TestFetchAndEncode_TranscodeToPNG(t *testing.T) {
+	var jpegIcon bytes.Buffer
+	src := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.RGBA{R: 200, A: 255}), image.Point{}, draw.Src)
+	if err := jpeg.Encode(&jpegIcon, src, nil); err != nil {
+		t.Fatalf("encode jpeg: %v", err)
+	}
+	svgIcon := []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="32" height="32">` + strings.Repeat(" ", 100) + `</svg>`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/icon.svg" {
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write(svgIcon)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write(jpegIcon.Bytes())
+	}))
+	defer server.Close()
+
+	fetcher := New()
+	fetcher.SetTranscodeSize(16)
+
+	got, err := fetcher.fetchAndEncode(server.URL + "/icon.jpg")
+	if err != nil || got == nil {
+		t.Fatalf("fetchAndEncode() = %v, %v, want icon", got, err)
+	}
+	encoded, ok := strings.CutPrefix(*got, "data:image/png;base64,")
+	if !ok {
+		t.Fatalf("data URI = %.40q, want a PNG data URI", *got)
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("decode base64: %v", err)
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("decode png: %v", err)
+	}
+	if cfg.Width != 16 || cfg.Height != 16 {
+		t.Fatalf("size = %dx%d, want 16x16", cfg.Width, cfg.Height)
+	}
+
+	got, err = fetcher.fetchAndEncode(server.URL + "/icon.svg")
+	if err != nil || got == nil {
+		t.Fatalf("fetchAndEncode() = %v, %v, want icon", got, err)
+	}
+	if !strings.HasPrefix(*got, "data:image/svg+xml;base64,") {
+		t.Fatalf("data URI = %.40q, want the SVG kept as fetched", *got)
+	}
+}
+
+func TestTranscodePNG_RejectsOversizedImages(t *testing.T) {
+	// A uniform image compresses to a few kilobytes whatever its dimensions
+	var huge bytes.Buffer
+	if err := png.Encode(&huge, image.NewGray(image.Rect(0, 0, 2048, 1024))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	if _, err := transcodePNG(huge.Bytes(), "image/png", 16); err == nil {
+		t.Fatalf("transcodePNG() accepted a %d-byte 2048x1024 image, want it rejected before decoding", huge.Len())
+	}
+
+	var small bytes.Buffer
+	if err := png.Encode(&small, image.NewGray(image.Rect(0, 0, 1024, 1024))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	if _, err := transcodePNG(small.Bytes(), "image/png", 16); err != nil {
+		t.Fatalf("transcodePNG() error = %v for an image at the cap", err)
+	}
+}
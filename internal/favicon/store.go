@@ -0,0 +1,155 @@
+package favicon
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// StoredIconPath is the URL path stored icons are served under; an icon's URL is this
+// path followed by its content hash
+const StoredIconPath = "/api/favicons/"
+
+// ErrIconNotFound is returned when no stored icon has the requested hash
+var ErrIconNotFound = errors.New("icon not found")
+
+// Store keeps icon bytes on disk addressed by the SHA-256 of their content, so each
+// distinct icon is written once however many items use it. The content type is kept
+// in a sidecar file next to the icon.
+type Store struct {
+	dir     string
+	urlPath string
+}
+
+// NewStore creates a store of icons served under StoredIconPath in dir, creating the
+// directory if needed
+func NewStore(dir string) (*Store, error) {
+	return NewStoreAt(dir, StoredIconPath)
+}
+
+// NewStoreAt creates a store in dir whose images are served under urlPath, for images
+// other than favicons
+func NewStoreAt(dir, urlPath string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create image directory: %w", err)
+	}
+	return &Store{dir: dir, urlPath: urlPath}, nil
+}
+
+// URL returns the URL the image with hash is served at
+func (s *Store) URL(hash string) string {
+	return s.urlPath + hash
+}
+
+// OwnsURL reports whether u is the URL of an image in this store
+func (s *Store) OwnsURL(u string) bool {
+	return IsStoredURL(u, s.urlPath)
+}
+
+// Put stores an icon and returns its content hash
+func (s *Store) Put(iconBytes []byte, contentType string) (string, error) {
+	sum := sha256.Sum256(iconBytes)
+	hash := hex.EncodeToString(sum[:])
+
+	iconPath := filepath.Join(s.dir, hash)
+	if _, err := os.Stat(iconPath); err == nil {
+		return hash, nil
+	}
+
+	// The content type is written first so a readable icon always has one
+	if err := writeFileAtomic(iconPath+".type", []byte(contentType)); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(iconPath, iconBytes); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// Get returns a stored icon and its content type, or ErrIconNotFound
+func (s *Store) Get(hash string) ([]byte, string, error) {
+	if !validHash(hash) {
+		return nil, "", ErrIconNotFound
+	}
+
+	iconPath := filepath.Join(s.dir, hash)
+	iconBytes, err := os.ReadFile(iconPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", ErrIconNotFound
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read icon: %w", err)
+	}
+
+	contentType, err := os.ReadFile(iconPath + ".type")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read icon content type: %w", err)
+	}
+	return iconBytes, strings.TrimSpace(string(contentType)), nil
+}
+
+// DataURI returns the stored icon at iconURL as a base64 data URI, for output that
+// must be self-contained
+func (s *Store) DataURI(iconURL string) (string, error) {
+	hash, ok := strings.CutPrefix(iconURL, s.urlPath)
+	if !ok {
+		return "", ErrIconNotFound
+	}
+	iconBytes, contentType, err := s.Get(hash)
+	if err != nil {
+		return "", err
+	}
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(iconBytes), nil
+}
+
+// IsStoredIconURL reports whether s is the URL of a stored icon
+func IsStoredIconURL(s string) bool {
+	return IsStoredURL(s, StoredIconPath)
+}
+
+// IsStoredURL reports whether s is the URL of an image stored under urlPath
+func IsStoredURL(s, urlPath string) bool {
+	hash, ok := strings.CutPrefix(s, urlPath)
+	return ok && validHash(hash)
+}
+
+// validHash reports whether hash is a lowercase hex SHA-256, which also keeps
+// lookups from escaping the store directory
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place so
+// concurrent readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".icon-*")
+	if err != nil {
+		return fmt.Errorf("failed to store icon: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to store icon: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to store icon: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store icon: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package favicon
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	// Register the decoders for the formats transcodePNG accepts
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+// maxTranscodePixels caps the pixel count of icons decoded for transcoding, so a small
+// file declaring huge dimensions can't make the decoder allocate gigabytes
+const maxTranscodePixels = 1024 * 1024
+
+// SetTranscodeSize makes the fetcher re-encode raster icons as size x size PNGs before
+// they are base64-encoded, so stored favicons share one format and stay small.
+// SVG and formats the standard library cannot decode (ICO, WebP) are kept as fetched.
+// Zero, the default, turns transcoding off.
+func (f *Fetcher) SetTranscodeSize(size int) {
+	f.transcodeSize = max(size, 0)
+}
+
+// transcodePNG decodes a PNG, GIF or JPEG icon and re-encodes it as a size x size PNG
+func transcodePNG(iconBytes []byte, contentType string, size int) ([]byte, error) {
+	if strings.HasPrefix(contentType, "image/svg") {
+		return nil, fmt.Errorf("vector icons are not transcoded")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(iconBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode icon: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > maxTranscodePixels/config.Height {
+		return nil, fmt.Errorf("icon dimensions %dx%d exceed %d pixels", config.Width, config.Height, maxTranscodePixels)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(iconBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode icon: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaleNearest(src, size)); err != nil {
+		return nil, fmt.Errorf("failed to encode icon: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// scaleNearest resizes src to size x size using nearest-neighbour sampling
+func scaleNearest(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	if bounds.Dx() == size && bounds.Dy() == size {
+		draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+		return dst
+	}
+
+	for y := 0; y < size; y++ {
+		srcY := bounds.Min.Y + y*bounds.Dy()/size
+		for x := 0; x < size; x++ {
+			srcX := bounds.Min.X + x*bounds.Dx()/size
+			dst.Set(x, y, src.At(srcX, srcY))
+		}
+	}
+	return dst
+}
@@ -12,44 +12,86 @@ type User struct {
 	PasswordHash  string    `json:"-"`
 	OAuthProvider *string   `json:"oauth_provider,omitempty"`
 	OAuthSub      *string   `json:"oauth_sub,omitempty"`
+	IsAdmin       bool      `json:"is_admin"`
+	EmailVerified bool      `json:"-"`                       // Set only from identity provider claims
+	HomeBoardID   *int      `json:"home_board_id,omitempty"` // Landing board; the default board when nil
 	CreatedAt     time.Time `json:"created_at"`
 }
 
 // Board represents a collection of lists
 type Board struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Title     string    `json:"title"`
-	IsDefault bool      `json:"is_default"`
-	UpdatedAt time.Time `json:"updated_at"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id,omitempty"` // left out of public board responses
+	Title      string    `json:"title"`
+	IsDefault  bool      `json:"is_default"`
+	PublicRead bool      `json:"public_read"`
+	Archived   bool      `json:"archived"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 // List represents a collection of bookmarks
 type List struct {
 	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
+	UserID    int       `json:"user_id,omitempty"` // left out of public board responses
 	BoardID   int       `json:"board_id"`
 	Title     string    `json:"title"`
 	Color     string    `json:"color"`
 	Position  int       `json:"position"`
 	Collapsed bool      `json:"collapsed"`
+	SortMode  string    `json:"sort_mode"` // "manual", "title" or "created"
 	CreatedAt time.Time `json:"created_at"`
 }
 
-// Item represents a single item (bookmark or note)
+// Item represents a single item (bookmark, note or separator)
 type Item struct {
-	ID            int       `json:"id"`
-	ListID        int       `json:"list_id"`
-	Type          string    `json:"type"` // "bookmark" or "note"
-	Title         *string   `json:"title,omitempty"`
-	URL           *string   `json:"url,omitempty"`
-	Content       *string   `json:"content,omitempty"`
-	FaviconURL    *string   `json:"favicon_url"`
-	IconSource    string    `json:"icon_source"`               // "auto", "custom", "service"
-	CustomIconURL *string   `json:"custom_icon_url,omitempty"` // Custom icon URL or service slug
-	Position      int       `json:"position"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID              int       `json:"id"`
+	ListID          int       `json:"list_id"`
+	Type            string    `json:"type"` // "bookmark", "note" or "separator"
+	Title           *string   `json:"title,omitempty"`
+	URL             *string   `json:"url,omitempty"`
+	Content         *string   `json:"content,omitempty"`
+	ContentFormat   string    `json:"content_format"` // "text", "markdown", "html"
+	FaviconURL      *string   `json:"favicon_url"`
+	IconSource      string    `json:"icon_source"`                 // "auto", "custom", "service"
+	CustomIconURL   *string   `json:"custom_icon_url,omitempty"`   // Custom icon URL or service slug
+	PreviewImageURL *string   `json:"preview_image_url,omitempty"` // The page's og:image, stored under /api/previews/
+	OpenInNewTab    bool      `json:"open_in_new_tab"`
+	IsPinned        bool      `json:"is_pinned"`
+	Position        int       `json:"position"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ItemWithBoard is an item annotated with the titles of its list and board
+type ItemWithBoard struct {
+	Item
+	BoardID    int    `json:"board_id"`
+	BoardTitle string `json:"board_title"`
+	ListTitle  string `json:"list_title"`
+}
+
+// ItemLocation identifies where an item lives
+type ItemLocation struct {
+	ID      int `json:"id"`
+	ListID  int `json:"list_id"`
+	BoardID int `json:"board_id"`
+}
+
+// OrphanedList is a list whose board is missing: its board_id is null, names a board
+// that no longer exists, or names another user's board
+type OrphanedList struct {
+	ID      int    `json:"id"`
+	UserID  int    `json:"user_id"`
+	BoardID *int   `json:"board_id"`
+	Title   string `json:"title"`
+}
+
+// OrphanedItem is an item whose list no longer exists
+type OrphanedItem struct {
+	ID     int     `json:"id"`
+	ListID int     `json:"list_id"`
+	Type   string  `json:"type"`
+	Title  *string `json:"title,omitempty"`
 }
 
 // Bookmark represents a single bookmark (for backward compatibility)
@@ -74,16 +116,19 @@ type Note struct {
 
 // Session represents a user session
 type Session struct {
-	ID        string    `json:"id"`
-	UserID    int       `json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
-	ExpiresAt time.Time `json:"expires_at"`
+	ID         string     `json:"id"`
+	UserID     int        `json:"user_id"`
+	UserAgent  *string    `json:"user_agent,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastActive *time.Time `json:"last_active,omitempty"`
 }
 
 // ExportData represents the structure for exporting user data
 type ExportData struct {
 	Version    int          `json:"version"`
 	ExportedAt time.Time    `json:"exported_at"`
+	BoardTitle string       `json:"board_title,omitempty"` // Set by single-board exports
 	Lists      []ExportList `json:"lists"`
 }
 
@@ -94,20 +139,26 @@ type ExportList struct {
 	Color     string           `json:"color"`
 	Position  int              `json:"position"`
 	Collapsed bool             `json:"collapsed"`
-	Bookmarks []ExportBookmark `json:"bookmarks"` // For backward compatibility
+	SortMode  string           `json:"sort_mode,omitempty"`  // Absent in older exports (treated as manual)
+	CreatedAt *time.Time       `json:"created_at,omitempty"` // Absent in older exports
+	Bookmarks []ExportBookmark `json:"bookmarks"`            // For backward compatibility
 	Notes     []ExportNote     `json:"notes,omitempty"`
 	Items     []ExportItem     `json:"items,omitempty"` // New unified format
 }
 
 // ExportItem represents an item in export format
 type ExportItem struct {
-	ID         int     `json:"id"`
-	Type       string  `json:"type"`
-	Title      *string `json:"title,omitempty"`
-	URL        *string `json:"url,omitempty"`
-	Content    *string `json:"content,omitempty"`
-	FaviconURL *string `json:"favicon_url,omitempty"`
-	Position   int     `json:"position"`
+	ID              int        `json:"id"`
+	Type            string     `json:"type"`
+	Title           *string    `json:"title,omitempty"`
+	URL             *string    `json:"url,omitempty"`
+	Content         *string    `json:"content,omitempty"`
+	ContentFormat   string     `json:"content_format,omitempty"`
+	FaviconURL      *string    `json:"favicon_url,omitempty"`
+	PreviewImageURL *string    `json:"preview_image_url,omitempty"`
+	OpenInNewTab    *bool      `json:"open_in_new_tab,omitempty"` // Absent in older exports (treated as true)
+	Position        int        `json:"position"`
+	CreatedAt       *time.Time `json:"created_at,omitempty"` // Absent in older exports
 }
 
 // ExportBookmark represents a bookmark in export format (for backward compatibility)
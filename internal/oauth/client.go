@@ -2,7 +2,9 @@ package oauth
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/coreos/go-oidc/v3/oidc"
@@ -11,21 +13,33 @@ import (
 
 // Client handles OAuth2/OIDC authentication
 type Client struct {
-	provider *oidc.Provider
-	config   *oauth2.Config
-	verifier *oidc.IDTokenVerifier
+	provider    *oidc.Provider
+	config      *oauth2.Config
+	verifier    *oidc.IDTokenVerifier
+	groupsClaim string
 }
 
+// DefaultGroupsClaim is the ID token claim read for group membership
+const DefaultGroupsClaim = "groups"
+
 // UserInfo contains user information extracted from ID token
 type UserInfo struct {
 	Email         string `json:"email"`
 	EmailVerified bool   `json:"email_verified"`
 	Name          string `json:"name"`
 	Sub           string `json:"sub"`
+
+	// Groups is read from the configured groups claim rather than a fixed key
+	Groups []string `json:"-"`
 }
 
-// NewClient creates a new OAuth2/OIDC client with auto-discovery
-func NewClient(issuerURL, clientID, clientSecret, redirectURL string) (*Client, error) {
+// NewClient creates a new OAuth2/OIDC client with auto-discovery.
+// groupsClaim names the ID token claim listing the user's groups (DefaultGroupsClaim if empty).
+func NewClient(issuerURL, clientID, clientSecret, redirectURL, groupsClaim string) (*Client, error) {
+	if groupsClaim == "" {
+		groupsClaim = DefaultGroupsClaim
+	}
+
 	ctx := context.Background()
 
 	// Strip .well-known/openid-configuration if accidentally included
@@ -52,9 +66,10 @@ func NewClient(issuerURL, clientID, clientSecret, redirectURL string) (*Client,
 	verifier := provider.Verifier(&oidc.Config{ClientID: clientID})
 
 	return &Client{
-		provider: provider,
-		config:   config,
-		verifier: verifier,
+		provider:    provider,
+		config:      config,
+		verifier:    verifier,
+		groupsClaim: groupsClaim,
 	}, nil
 }
 
@@ -63,6 +78,15 @@ func (c *Client) AuthCodeURL(state string) string {
 	return c.config.AuthCodeURL(state)
 }
 
+// RedirectHost returns the host (and port, if any) of the configured redirect URL
+func (c *Client) RedirectHost() string {
+	parsed, err := url.Parse(c.config.RedirectURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
 // Exchange exchanges the authorization code for an OAuth2 token
 func (c *Client) Exchange(ctx context.Context, code string) (*oauth2.Token, error) {
 	return c.config.Exchange(ctx, code)
@@ -79,5 +103,32 @@ func (c *Client) GetUserInfo(ctx context.Context, idToken *oidc.IDToken) (*UserI
 	if err := idToken.Claims(&claims); err != nil {
 		return nil, fmt.Errorf("failed to parse claims: %w", err)
 	}
+
+	var raw map[string]json.RawMessage
+	if err := idToken.Claims(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse claims: %w", err)
+	}
+	claims.Groups = parseGroups(raw[c.groupsClaim])
+
 	return &claims, nil
 }
+
+// parseGroups reads a groups claim, which providers send either as a list of
+// strings or as a single string; anything else yields no groups
+func parseGroups(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var groups []string
+	if err := json.Unmarshal(raw, &groups); err == nil {
+		return groups
+	}
+
+	var group string
+	if err := json.Unmarshal(raw, &group); err == nil && group != "" {
+		return []string{group}
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package oauth
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestParseGroups(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{name: "missing", raw: "", want: nil},
+		{name: "list", raw: `["loom-admins","staff"]`, want: []string{"loom-admins", "staff"}},
+		{name: "single string", raw: `"loom-admins"`, want: []string{"loom-admins"}},
+		{name: "unsupported shape", raw: `{"name":"loom-admins"}`, want: nil},
+	}
+	for _, tt := range tests {
+		if got := parseGroups(json.RawMessage(tt.raw)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: parseGroups() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
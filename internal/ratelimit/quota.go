@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Quota counts requests per key per calendar day. All counts reset at local midnight.
+type Quota struct {
+	limit int
+	now   func() time.Time
+
+	mu   sync.Mutex
+	day  time.Time
+	used map[string]int
+}
+
+// NewQuota creates a quota allowing limit requests per key per day
+func NewQuota(limit int) *Quota {
+	return &Quota{
+		limit: limit,
+		now:   time.Now,
+		used:  make(map[string]int),
+	}
+}
+
+// Take counts a request against key's quota for today. Once the quota is used up
+// it returns false without counting. It also returns the requests key has left
+// today and when the quota resets.
+func (q *Quota) Take(key string) (bool, int, time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	y, m, d := now.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	if !day.Equal(q.day) {
+		q.day = day
+		clear(q.used)
+	}
+	reset := day.AddDate(0, 0, 1)
+
+	used := q.used[key]
+	if used >= q.limit {
+		return false, 0, reset
+	}
+	q.used[key] = used + 1
+	return true, q.limit - used - 1, reset
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := New(2, 3)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Fatalf("request %d within burst was limited", i+1)
+		}
+	}
+
+	ok, wait := limiter.Allow("a")
+	if ok {
+		t.Fatalf("request beyond burst was allowed")
+	}
+	if wait != 500*time.Millisecond {
+		t.Fatalf("wait = %v, want %v", wait, 500*time.Millisecond)
+	}
+
+	if ok, _ := limiter.Allow("b"); !ok {
+		t.Fatalf("other key was limited")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Fatalf("request after refill was limited")
+	}
+}
+
+func TestQuotaTake(t *testing.T) {
+	now := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	quota := NewQuota(2)
+	quota.now = func() time.Time { return now }
+	midnight := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	for want := 1; want >= 0; want-- {
+		ok, remaining, reset := quota.Take("a")
+		if !ok || remaining != want || !reset.Equal(midnight) {
+			t.Fatalf("Take = %v, %d, %v; want true, %d, %v", ok, remaining, reset, want, midnight)
+		}
+	}
+
+	if ok, remaining, _ := quota.Take("a"); ok || remaining != 0 {
+		t.Fatalf("request beyond quota was allowed (remaining %d)", remaining)
+	}
+	if ok, _, _ := quota.Take("b"); !ok {
+		t.Fatalf("other key was limited")
+	}
+
+	now = midnight.Add(time.Minute)
+	if ok, remaining, _ := quota.Take("a"); !ok || remaining != 1 {
+		t.Fatalf("quota did not reset at midnight (ok %v, remaining %d)", ok, remaining)
+	}
+}
@@ -0,0 +1,522 @@
+package sanitize
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Supported note content formats
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// DefaultFormat is the format used when none is specified (notes are rendered with Marked.js)
+const DefaultFormat = FormatMarkdown
+
+var (
+	tagNamePattern   = regexp.MustCompile(`^(/?)([a-zA-Z][a-zA-Z0-9]*)`)
+	attributePattern = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+)))?`)
+	// eventHandlerPattern errs on the side of matching, since a false match only
+	// means the tag is rebuilt from the allowlist
+	eventHandlerPattern = regexp.MustCompile(`(?i)(?:^|[^a-z0-9_-])on[a-z]`)
+	autolinkPattern     = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.\-]{1,31}):[^\s<>]*>`)
+	// destinationPrefix matches from the ']' of link text or a reference label to its destination
+	destinationPrefix = regexp.MustCompile(`^\](?:\(|:)[ \t]*(?:\n[ \t>]*)?`)
+)
+
+// htmlAllowedTags are the elements kept by the strict HTML policy
+var htmlAllowedTags = map[string]bool{
+	"a": true, "b": true, "blockquote": true, "br": true, "code": true, "del": true,
+	"em": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"hr": true, "i": true, "img": true, "li": true, "ol": true, "p": true, "pre": true,
+	"s": true, "strong": true, "sub": true, "sup": true, "table": true, "tbody": true,
+	"td": true, "th": true, "thead": true, "tr": true, "u": true, "ul": true,
+}
+
+// blockedTags are never kept, even by the permissive Markdown policy
+var blockedTags = map[string]bool{
+	"base": true, "button": true, "embed": true, "form": true, "frame": true,
+	"frameset": true, "iframe": true, "input": true, "link": true, "math": true,
+	"meta": true, "object": true, "script": true, "select": true, "style": true,
+	"svg": true, "textarea": true,
+}
+
+// dropContentTags have their inner content removed along with the element
+var dropContentTags = map[string]bool{
+	"script": true,
+	"style":  true,
+}
+
+// allowedAttributes are the only attributes kept on any element
+var allowedAttributes = map[string]bool{
+	"alt":   true,
+	"href":  true,
+	"src":   true,
+	"title": true,
+}
+
+// urlAttributes must carry a safe URL scheme
+var urlAttributes = map[string]bool{
+	"action":     true,
+	"background": true,
+	"cite":       true,
+	"formaction": true,
+	"href":       true,
+	"poster":     true,
+	"src":        true,
+	"xlink:href": true,
+}
+
+// riskyAttributes make raw HTML in Markdown get rebuilt from the allowlist
+var riskyAttributes = map[string]bool{
+	"srcdoc": true,
+	"srcset": true,
+	"style":  true,
+}
+
+// allowedSchemes are the URL schemes permitted in href and src attributes
+var allowedSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// maxPasses bounds the re-sanitization loop used to defeat tags reassembled from fragments
+const maxPasses = 8
+
+// policy controls which elements survive sanitization
+type policy struct {
+	allowedTags map[string]bool // nil allows any element that is not blocked
+	escapeStray bool            // escape '<' characters that do not start a tag
+	markdown    bool            // skip code, keep harmless tags verbatim and check link destinations
+}
+
+// markdownState tracks where Markdown syntax applies. Inside raw HTML blocks
+// backticks and backslashes are literal, so code is only skipped outside them.
+type markdownState struct {
+	rawHTML bool // a raw tag was kept since the last blank line
+	inPre   bool // inside a <pre> element, which runs across blank lines
+}
+
+// IsValidFormat reports whether format is a supported content format
+func IsValidFormat(format string) bool {
+	return format == FormatText || format == FormatMarkdown || format == FormatHTML
+}
+
+// Content normalizes note content for storage according to its format.
+// Plain text is stored as-is, Markdown has any embedded raw HTML made safe,
+// and HTML is reduced to a strict allowlist of elements and attributes.
+func Content(format, content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.TrimSpace(content)
+
+	switch format {
+	case FormatHTML:
+		return HTML(content)
+	case FormatMarkdown:
+		return Markdown(content)
+	default:
+		return content
+	}
+}
+
+// HTML strips every element and attribute not on the allowlist
+func HTML(input string) string {
+	return policy{allowedTags: htmlAllowedTags, escapeStray: true}.apply(input)
+}
+
+// Markdown removes dangerous raw HTML and link destinations from Markdown source
+// while leaving code, text and harmless markup untouched
+func Markdown(input string) string {
+	return policy{markdown: true}.apply(input)
+}
+
+// apply sanitizes repeatedly until the output is stable, so that removing one
+// tag cannot splice the surrounding text into a new one
+func (p policy) apply(input string) string {
+	for range maxPasses {
+		output := p.sanitize(input)
+		if output == input {
+			return output
+		}
+		input = output
+	}
+	return html.EscapeString(input)
+}
+
+// sanitize walks the input, rewriting or dropping each tag according to the policy
+func (p policy) sanitize(input string) string {
+	var out strings.Builder
+	out.Grow(len(input))
+	var md markdownState
+	special := "<"
+	if p.markdown {
+		special = "<`\\]\n"
+	}
+
+	for i := 0; i < len(input); {
+		if p.markdown {
+			if n := md.markdownSyntax(input, i, &out); n > 0 {
+				i += n
+				continue
+			}
+		}
+
+		if input[i] != '<' {
+			// Always consume at least one byte so unhandled Markdown specials pass through
+			next := strings.IndexAny(input[i+1:], special)
+			if next == -1 {
+				out.WriteString(input[i:])
+				break
+			}
+			out.WriteString(input[i : i+1+next])
+			i += 1 + next
+			continue
+		}
+
+		if p.markdown {
+			if m := autolinkPattern.FindStringSubmatch(input[i:]); m != nil {
+				if allowedSchemes[strings.ToLower(m[1])] {
+					out.WriteString(m[0])
+					i += len(m[0])
+				} else {
+					out.WriteString("&lt;")
+					i++
+				}
+				continue
+			}
+			// Processing instructions and declarations open raw HTML blocks
+			if strings.HasPrefix(input[i:], "<?") || (strings.HasPrefix(input[i:], "<!") && !strings.HasPrefix(input[i:], "<!--")) {
+				out.WriteString("&lt;")
+				i++
+				continue
+			}
+		}
+
+		// Comments are always dropped
+		if strings.HasPrefix(input[i:], "<!--") {
+			end := strings.Index(input[i+4:], "-->")
+			if end == -1 {
+				break
+			}
+			i += 4 + end + 3
+			continue
+		}
+
+		end := findTagEnd(input, i+1)
+		match := tagNamePattern.FindStringSubmatch(input[i+1:])
+		if end == -1 || match == nil {
+			// Not a tag; optionally escape the bracket so it can never open one
+			if p.escapeStray {
+				out.WriteString("&lt;")
+			} else {
+				out.WriteByte('<')
+			}
+			i++
+			continue
+		}
+
+		closing := match[1] == "/"
+		name := strings.ToLower(match[2])
+		rawTag := input[i : end+1]
+		rawAttrs := input[i+1+len(match[0]) : end]
+		i = end + 1
+
+		if !closing && dropContentTags[name] {
+			closeTag := "</" + name
+			closeAt := strings.Index(strings.ToLower(input[i:]), closeTag)
+			if closeAt == -1 {
+				break
+			}
+			i += closeAt
+			if tagEnd := strings.IndexByte(input[i:], '>'); tagEnd != -1 {
+				i += tagEnd + 1
+			} else {
+				i = len(input)
+			}
+			continue
+		}
+
+		if !p.allows(name) {
+			continue
+		}
+
+		if p.markdown {
+			md.rawHTML = true
+			if name == "pre" {
+				md.inPre = !closing
+			}
+			if closing || !hasRiskyAttributes(rawAttrs) {
+				out.WriteString(rawTag)
+				continue
+			}
+		}
+
+		if closing {
+			out.WriteString("</" + name + ">")
+			continue
+		}
+
+		out.WriteString("<" + name)
+		out.WriteString(sanitizeAttributes(rawAttrs))
+		if strings.HasSuffix(strings.TrimSpace(rawAttrs), "/") {
+			out.WriteString(" /")
+		}
+		out.WriteString(">")
+	}
+
+	return out.String()
+}
+
+// markdownSyntax copies Markdown constructs at input[i] that need no HTML
+// sanitizing (code, escapes) or whose link destination is unsafe, and returns the
+// number of bytes consumed; zero leaves input[i] to the HTML sanitizer
+func (md *markdownState) markdownSyntax(input string, i int, out *strings.Builder) int {
+	lineStart := i == 0 || input[i-1] == '\n'
+	if lineStart && !md.inPre && strings.TrimSpace(lineAt(input, i)) == "" {
+		md.rawHTML = false
+	}
+	if md.rawHTML || md.inPre {
+		return 0
+	}
+
+	if lineStart {
+		if n := fencedCodeLength(input, i); n > 0 {
+			out.WriteString(input[i : i+n])
+			return n
+		}
+	}
+
+	switch input[i] {
+	case '`':
+		n := codeSpanLength(input, i)
+		out.WriteString(input[i : i+n])
+		return n
+	case '\\':
+		if i+1 < len(input) && isASCIIPunct(input[i+1]) {
+			out.WriteString(input[i : i+2])
+			return 2
+		}
+	case ']':
+		// Reference definitions may sit in containers, so "]:" is checked anywhere
+		if prefix := destinationPrefix.FindString(input[i:]); prefix != "" {
+			return replaceUnsafeDestination(input, i, len(prefix), out)
+		}
+	}
+	return 0
+}
+
+// replaceUnsafeDestination checks the link destination starting prefix bytes after
+// input[i]. An unsafe one is replaced with "#" and consumed along with the prefix;
+// a safe one is left for normal processing, since it may not really be a link.
+func replaceUnsafeDestination(input string, i, prefix int, out *strings.Builder) int {
+	dest := linkDestination(input[i+prefix:])
+	if dest == "" || isSafeURL(unescapeMarkdown(strings.Trim(dest, "<>"))) {
+		return 0
+	}
+	out.WriteString(input[i : i+prefix])
+	out.WriteString("#")
+	return prefix + len(dest)
+}
+
+// linkDestination returns the link destination at the start of s, either <bracketed>
+// or running to whitespace or an unbalanced closing parenthesis
+func linkDestination(s string) string {
+	if strings.HasPrefix(s, "<") {
+		if end := strings.IndexAny(s[1:], "<>\n"); end != -1 && s[1+end] == '>' {
+			return s[:end+2]
+		}
+		return ""
+	}
+
+	depth := 0
+	for j := 0; j < len(s); j++ {
+		switch c := s[j]; {
+		case c == '\\' && j+1 < len(s):
+			j++
+		case c <= ' ':
+			return s[:j]
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				return s[:j]
+			}
+			depth--
+		}
+	}
+	return s
+}
+
+// unescapeMarkdown resolves backslash escapes and character references the way a
+// Markdown renderer does before using a link destination
+func unescapeMarkdown(s string) string {
+	var out strings.Builder
+	for j := 0; j < len(s); j++ {
+		if s[j] == '\\' && j+1 < len(s) && isASCIIPunct(s[j+1]) {
+			j++
+		}
+		out.WriteByte(s[j])
+	}
+	return html.UnescapeString(out.String())
+}
+
+// fencedCodeLength returns the length of the fenced code block starting at input[i]
+// through its closing fence, or zero. Only unindented fences with a closing fence
+// count: an indented one may sit in a list item that ends before the closing fence.
+func fencedCodeLength(input string, i int) int {
+	line := lineAt(input, i)
+	fence := line[:len(line)-len(strings.TrimLeft(line, "`~"))]
+	if len(fence) < 3 || strings.Count(fence, fence[:1]) != len(fence) {
+		return 0
+	}
+	if fence[0] == '`' && strings.Contains(line[len(fence):], "`") {
+		return 0
+	}
+
+	for pos := i + len(line); pos < len(input); {
+		pos++ // the newline ending the previous line
+		next := lineAt(input, pos)
+		trimmed := strings.TrimLeft(next, " ")
+		if len(next)-len(trimmed) <= 3 && strings.HasPrefix(trimmed, fence) &&
+			strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1])) == "" {
+			return pos + len(next) - i
+		}
+		pos += len(next)
+	}
+	return 0
+}
+
+// codeSpanLength returns the length of the code span opened by the backtick run at
+// input[i], or of the run alone when no run of the same length closes it on that line
+func codeSpanLength(input string, i int) int {
+	run := backtickRun(input, i)
+	line := lineAt(input, i)
+	for j := run; j < len(line); {
+		if line[j] != '`' {
+			j++
+			continue
+		}
+		n := backtickRun(line, j)
+		if n == run {
+			return j + n
+		}
+		j += n
+	}
+	return run
+}
+
+// backtickRun returns the number of consecutive backticks starting at s[i]
+func backtickRun(s string, i int) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == '`' {
+		n++
+	}
+	return n
+}
+
+// lineAt returns the rest of the line starting at input[i], without its newline
+func lineAt(input string, i int) string {
+	if end := strings.IndexByte(input[i:], '\n'); end != -1 {
+		return input[i : i+end]
+	}
+	return input[i:]
+}
+
+// isASCIIPunct reports whether c can be backslash-escaped in Markdown
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) != -1
+}
+
+// hasRiskyAttributes reports whether a raw tag's attributes could run script or
+// load an unsafe URL, so the tag must be rebuilt from the allowlist
+func hasRiskyAttributes(raw string) bool {
+	if eventHandlerPattern.MatchString(raw) {
+		return true
+	}
+	for _, m := range attributePattern.FindAllStringSubmatch(raw, -1) {
+		name := strings.ToLower(m[1])
+		if riskyAttributes[name] {
+			return true
+		}
+		if urlAttributes[name] && !isSafeURL(html.UnescapeString(m[2]+m[3]+m[4])) {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether the policy keeps the named element
+func (p policy) allows(name string) bool {
+	if blockedTags[name] {
+		return false
+	}
+	if p.allowedTags == nil {
+		return true
+	}
+	return p.allowedTags[name]
+}
+
+// findTagEnd returns the index of the '>' closing the tag starting at start, honoring quoted values
+func findTagEnd(input string, start int) int {
+	var quote byte
+	for j := start; j < len(input); j++ {
+		c := input[j]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '<':
+			return -1
+		case c == '>':
+			return j
+		}
+	}
+	return -1
+}
+
+// sanitizeAttributes keeps only allowlisted attributes, re-quoting and escaping their values
+func sanitizeAttributes(raw string) string {
+	var out strings.Builder
+	for _, m := range attributePattern.FindAllStringSubmatch(raw, -1) {
+		name := strings.ToLower(m[1])
+		if !allowedAttributes[name] {
+			continue
+		}
+
+		value := html.UnescapeString(m[2] + m[3] + m[4])
+		if urlAttributes[name] && !isSafeURL(value) {
+			continue
+		}
+
+		out.WriteString(" " + name + `="` + html.EscapeString(value) + `"`)
+	}
+	return out.String()
+}
+
+// isSafeURL reports whether a URL is relative or uses an allowed scheme
+func isSafeURL(rawURL string) bool {
+	// Browsers ignore embedded whitespace and control characters in schemes
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, rawURL)
+
+	parsed, err := url.Parse(cleaned)
+	if err != nil {
+		return false
+	}
+	if parsed.Scheme == "" {
+		return true
+	}
+	return allowedSchemes[strings.ToLower(parsed.Scheme)]
+}
@@ -0,0 +1,131 @@
+package sanitize
+
+import "testing"
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "allowed markup preserved",
+			input: "<p>Hello <strong>world</strong></p>",
+			want:  "<p>Hello <strong>world</strong></p>",
+		},
+		{
+			name:  "script removed with content",
+			input: "before<script>alert(1)</script>after",
+			want:  "beforeafter",
+		},
+		{
+			name:  "event handlers stripped",
+			input: `<img src="https://example.com/a.png" onerror="alert(1)">`,
+			want:  `<img src="https://example.com/a.png">`,
+		},
+		{
+			name:  "javascript urls stripped",
+			input: `<a href="java&#x09;script:alert(1)">x</a>`,
+			want:  `<a>x</a>`,
+		},
+		{
+			name:  "disallowed element unwrapped",
+			input: "<div>text</div>",
+			want:  "text",
+		},
+		{
+			name:  "stray bracket escaped",
+			input: "1 < 2",
+			want:  "1 &lt; 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.input); got != tt.want {
+				t.Fatalf("HTML(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "plain markdown untouched",
+			input: "# Title\n\n- a < b\n- `x > y`",
+			want:  "# Title\n\n- a < b\n- `x > y`",
+		},
+		{
+			name:  "iframe removed",
+			input: `text <iframe src="https://evil.example"></iframe>`,
+			want:  "text ",
+		},
+		{
+			name:  "reassembled tag is sanitized",
+			input: "<<script>x</script>img src=x onerror=alert(1)>",
+			want:  `<img src="x">`,
+		},
+		{
+			name:  "comparisons are not tags",
+			input: "if a<b and c>d",
+			want:  "if a<b and c>d",
+		},
+		{
+			name:  "code left as written",
+			input: "Use `<script>alert(1)</script>` here\n\n```html\n<div onclick=\"go()\">hi</div>\n```",
+			want:  "Use `<script>alert(1)</script>` here\n\n```html\n<div onclick=\"go()\">hi</div>\n```",
+		},
+		{
+			name:  "harmless raw html kept verbatim",
+			input: `<span title='x'>hi</span> <a href="https://example.com">x</a>`,
+			want:  `<span title='x'>hi</span> <a href="https://example.com">x</a>`,
+		},
+		{
+			name:  "backticks inside raw html are not code",
+			input: "<div>\n`<img src=x onerror=alert(1)>`\n</div>",
+			want:  "<div>\n`<img src=\"x\">`\n</div>",
+		},
+		{
+			name:  "unsafe link destinations replaced",
+			input: "[x](javascript:alert(1)) ![y](  JaVaScRiPt&#58;alert(1)) [z](<java\\script:alert(1)>)",
+			want:  "[x](#) ![y](  #) [z](#)",
+		},
+		{
+			name:  "unsafe reference definition replaced",
+			input: "[x][r]\n\n> [r]: javascript:alert(1)",
+			want:  "[x][r]\n\n> [r]: #",
+		},
+		{
+			name:  "safe links untouched",
+			input: "[x](https://example.com/a_(b)) [m](mailto:a@example.com) [rel](/docs)",
+			want:  "[x](https://example.com/a_(b)) [m](mailto:a@example.com) [rel](/docs)",
+		},
+		{
+			name:  "unsafe autolink neutralized",
+			input: "<javascript:alert(1)> <https://example.com>",
+			want:  "&lt;javascript:alert(1)> <https://example.com>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Markdown(tt.input); got != tt.want {
+				t.Fatalf("Markdown(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContent(t *testing.T) {
+	if got, want := Content(FormatText, "  <b>hi</b>\r\nthere  "), "<b>hi</b>\nthere"; got != want {
+		t.Fatalf("Content(text) = %q, want %q", got, want)
+	}
+	if IsValidFormat("rtf") {
+		t.Fatalf("IsValidFormat(rtf) = true, want false")
+	}
+}
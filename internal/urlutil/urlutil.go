@@ -0,0 +1,51 @@
+package urlutil
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Domain extracts the lowercase host name from a URL, without port, userinfo
+// or IPv6 brackets. URLs without a scheme are treated as https.
+func Domain(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", fmt.Errorf("empty URL")
+	}
+
+	// Add scheme if missing
+	if strings.HasPrefix(rawURL, "//") {
+		rawURL = "https:" + rawURL
+	} else if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+
+	domain := strings.TrimSuffix(strings.ToLower(parsedURL.Hostname()), ".")
+	if domain == "" {
+		return "", fmt.Errorf("no domain found in URL")
+	}
+
+	return domain, nil
+}
+
+// NormalizeForMatch lowercases the scheme and host and drops the fragment and
+// trailing slash, so equivalent spellings of a URL compare equal
+func NormalizeForMatch(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return strings.TrimSuffix(rawURL, "/")
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+	return parsed.String()
+}